@@ -18,6 +18,8 @@
 package attr
 
 import "fmt"
+import "strconv"
+import "strings"
 
 // Represents the shape of a node.
 //
@@ -32,6 +34,20 @@ func (s *NodeShape) String() string {
 	return s.name
 }
 
+// Returns the predefined shape with the given name, or a new shape if there is
+// no such predefined shape.  Returns nil if name is empty.
+func ParseNodeShape(name string) *NodeShape {
+	if name == "" {
+		return nil
+	}
+	for _, s := range shapes {
+		if s.name == name {
+			return s
+		}
+	}
+	return &NodeShape{name}
+}
+
 // Represents the kind of graph.
 //
 // There are only two (valid) possibilites.  If Name returns "graph" then an
@@ -62,6 +78,32 @@ func (k *GraphKind) Delimiter() string {
 	return k.delimiter
 }
 
+// Represents the direction in which dot lays out the ranks of a graph.
+//
+// There are predefined variables "TopToBottom", "LeftToRight", "BottomToTop"
+// and "RightToLeft" which contain the possible values.
+//
+// Resources:
+//   http://www.graphviz.org/doc/info/attrs.html#k:rankdir
+type RankDir struct {
+	name string
+}
+
+func (d *RankDir) String() string {
+	return d.name
+}
+
+// Returns the predefined direction with the given name, such as "LR", or nil
+// if there is no such direction.
+func ParseRankDir(name string) *RankDir {
+	for _, d := range []*RankDir{TopToBottom, LeftToRight, BottomToTop, RightToLeft} {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
 // Represents a two dimensional point.  Usually used to position a node or edge.
 //
 // Resources:
@@ -84,13 +126,45 @@ func (p Point) String() string {
 	return fmt.Sprintf("%f,%f", p.X, p.Y)
 }
 
+// Parses a point in the "%f,%f('!')?" format produced by String.
+func ParsePoint(str string) (*Point, error) {
+	p := new(Point)
+	if strings.HasSuffix(str, "!") {
+		p.Lock = true
+		str = str[:len(str)-1]
+	}
+	xy := strings.Split(str, ",")
+	if len(xy) < 2 {
+		return nil, fmt.Errorf("attr: invalid point %q", str)
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(xy[0]), 32)
+	if err != nil {
+		return nil, fmt.Errorf("attr: invalid point %q", str)
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(xy[1]), 32)
+	if err != nil {
+		return nil, fmt.Errorf("attr: invalid point %q", str)
+	}
+	p.X, p.Y = float32(x), float32(y)
+	return p, nil
+}
+
 var (
 	Directed   = &GraphKind{"digraph", "->"}
 	Undirected = &GraphKind{"graph", "--"}
 )
 
+var (
+	TopToBottom = &RankDir{"TB"}
+	LeftToRight = &RankDir{"LR"}
+	BottomToTop = &RankDir{"BT"}
+	RightToLeft = &RankDir{"RL"}
+)
+
 var (
 	Box    = &NodeShape{"box"}
 	Circle = &NodeShape{"circle"}
 	Rect   = &NodeShape{"rect"}
 )
+
+var shapes = []*NodeShape{Box, Circle, Rect}
@@ -24,6 +24,9 @@ supported.
 */
 package color
 
+import "fmt"
+import "math"
+
 // Represents the color of a node, edge, or subgraph background.
 //
 // Resources:
@@ -41,6 +44,65 @@ func (c Named) String() string {
 	return c.name
 }
 
+// Returns the color with the given name or value.  Any string accepted by
+// Graphviz may be used, for example "red", "/blues9/3" or "#ff000080".
+// Returns nil if name is empty.
+func Parse(name string) Color {
+	if name == "" {
+		return nil
+	}
+	return Named{name}
+}
+
+// A color given by its red, green and blue components, written in the
+// "#rrggbb" form.
+type RGB struct {
+	R, G, B uint8
+}
+
+func (c RGB) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// A gradient between two or more colors, evenly spaced.  Used to map numbers
+// onto colors.
+type Scale []RGB
+
+// Returns the color at position "t" along the scale, where 0 is the first
+// color and 1 is the last.  Values outside that range are clamped.  Returns
+// black if the scale is empty.
+func (s Scale) At(t float64) RGB {
+	if len(s) == 0 {
+		return RGB{}
+	}
+	if len(s) == 1 || t <= 0 || math.IsNaN(t) {
+		return s[0]
+	}
+	if t >= 1 {
+		return s[len(s)-1]
+	}
+	pos := t * float64(len(s)-1)
+	i := int(pos)
+	frac := pos - float64(i)
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Floor(float64(a) + (float64(b)-float64(a))*frac + 0.5))
+	}
+	a, b := s[i], s[i+1]
+	return RGB{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
+
+// Some commonly used scales.
+var (
+	// From light yellow through orange to dark red.
+	Heat = Scale{{0xff, 0xff, 0xb2}, {0xfd, 0x8d, 0x3c}, {0xbd, 0x00, 0x26}}
+
+	// From light to dark blue.
+	Blues = Scale{{0xef, 0xf3, 0xff}, {0x6b, 0xae, 0xd6}, {0x08, 0x51, 0x9c}}
+
+	// From white to black.
+	Grays = Scale{{0xff, 0xff, 0xff}, {0x00, 0x00, 0x00}}
+)
+
 // There are thousands of colors in the X11 color scheme.  A few are available
 // here for convenience.
 //
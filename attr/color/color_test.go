@@ -0,0 +1,44 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package color
+
+import "testing"
+
+func TestComponents(t *testing.T) {
+	for _, test := range []struct {
+		color string
+		rgb   RGB
+		alpha uint8
+	}{
+		{"gray", RGB{0xbe, 0xbe, 0xbe}, 255},
+		{"Grey", RGB{0xbe, 0xbe, 0xbe}, 255},
+		{"green", RGB{0x00, 0xff, 0x00}, 255},
+		{"maroon", RGB{0xb0, 0x30, 0x60}, 255},
+		{"purple", RGB{0xa0, 0x20, 0xf0}, 255},
+		{"lightgrey", RGB{0xd3, 0xd3, 0xd3}, 255},
+		{"gray50", RGB{0x7f, 0x7f, 0x7f}, 255},
+		{"#ff000080", RGB{0xff, 0x00, 0x00}, 0x80},
+		{"0.5 0.5 0.5", RGB{0x40, 0x80, 0x80}, 255},
+	} {
+		rgb, alpha, ok := Components(Parse(test.color))
+		if !ok || rgb != test.rgb || alpha != test.alpha {
+			t.Errorf("Components of %q are %v, %d, %v; want %v, %d", test.color, rgb, alpha, ok, test.rgb, test.alpha)
+		}
+	}
+	if _, _, ok := Components(Parse("/blues9/3")); ok {
+		t.Errorf("Components of a scheme color are known")
+	}
+}
@@ -15,10 +15,15 @@
 
 package builder
 
+import "bytes"
+import "errors"
 import "fmt"
 import "reflect"
 import "strings"
 
+import "godot/attr"
+import "godot/attr/color"
+
 // Represents an attribute in the generated dot file, for example a node name
 // or an edge color.
 type attribute struct {
@@ -49,13 +54,13 @@ func (al attrlist) String(indent int, multiline bool) string {
 		atrs := make([]string, 0, len(al))
 		if multiline {
 			for _, a := range al {
-				atrs = append(atrs, fmt.Sprintf("\t%s%s=\"%s\"", idnt, a.Name, a.Value))
+				atrs = append(atrs, fmt.Sprintf("\t%s%s=\"%s\"", idnt, a.Name, escape(a.Value)))
 			}
 			str = strings.Join(atrs, "\n")
 			str = fmt.Sprintf("\n%s\n%s", str, idnt)
 		} else {
 			for _, a := range al {
-				atrs = append(atrs, fmt.Sprintf("%s%s=\"%s\"", idnt, a.Name, a.Value))
+				atrs = append(atrs, fmt.Sprintf("%s%s=\"%s\"", idnt, a.Name, escape(a.Value)))
 			}
 			str = strings.Join(atrs, ", ")
 			str = fmt.Sprintf("%s", str)
@@ -65,6 +70,24 @@ func (al attrlist) String(indent int, multiline bool) string {
 	return ""
 }
 
+// Escapes double quotes in an attribute value, unless they are escaped
+// already, so that the value may be written as a quoted dot string.
+func escape(value string) string {
+	if !strings.Contains(value, "\"") {
+		return value
+	}
+	var buf bytes.Buffer
+	prev := rune(0)
+	for _, r := range value {
+		if r == '"' && prev != '\\' {
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(r)
+		prev = r
+	}
+	return buf.String()
+}
+
 // TODO: I can't figure out how to make this work with pointers.  Currently I
 // just copy the structure being built into the obj paramater.
 // Reflects on the type of "obj" to find tagged fields.  It then extracts the
@@ -90,6 +113,18 @@ func buildAttributes(obj interface{}) []*attribute {
 	return atrs
 }
 
+// Returns the attributes set on "obj", a *Graph, *Subgraph, *Node or *Edge,
+// as pairs of dot names and values, in the order they are written to dot.
+// The inverse of calling SetAttribute for each pair.  Used to write graphs in
+// other formats.
+func Attributes(obj interface{}) [][2]string {
+	var pairs [][2]string
+	for _, a := range buildAttributes(reflect.ValueOf(obj).Elem().Interface()) {
+		pairs = append(pairs, [2]string{a.Name, a.Value})
+	}
+	return pairs
+}
+
 func getStr(name string, val reflect.Value) string {
 	if val.CanInterface() {
 		if ifc := val.Interface(); ifc != nil {
@@ -115,3 +150,87 @@ func getAttr(key string, val string, def string) *attribute {
 	}
 	return &attribute{key, val}
 }
+
+var colorType = reflect.TypeOf((*color.Color)(nil)).Elem()
+var pointType = reflect.TypeOf((*attr.Point)(nil))
+var shapeType = reflect.TypeOf((*attr.NodeShape)(nil))
+var rankDirType = reflect.TypeOf((*attr.RankDir)(nil))
+
+// The inverse of buildAttributes.  Finds the field of the structure pointed to
+// by "obj" which is tagged with "name", and sets it from the dot
+// representation "value".  Returns false if there is no such field.
+func setAttribute(obj interface{}, name string, value string) (bool, error) {
+	val := reflect.ValueOf(obj).Elem()
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Tag.Get("name") != name {
+			continue
+		}
+		fval := val.Field(i)
+		switch f.Type {
+		case colorType:
+			if c := color.Parse(value); c != nil {
+				fval.Set(reflect.ValueOf(c))
+			} else {
+				fval.Set(reflect.Zero(f.Type))
+			}
+		case pointType:
+			if value == "" {
+				fval.Set(reflect.Zero(f.Type))
+				break
+			}
+			p, err := attr.ParsePoint(value)
+			if err != nil {
+				return true, err
+			}
+			fval.Set(reflect.ValueOf(p))
+		case shapeType:
+			fval.Set(reflect.ValueOf(attr.ParseNodeShape(value)))
+		case rankDirType:
+			d := attr.ParseRankDir(value)
+			if d == nil && value != "" {
+				return true, fmt.Errorf("attr: invalid rankdir %q", value)
+			}
+			fval.Set(reflect.ValueOf(d))
+		default:
+			if f.Type.Kind() != reflect.String {
+				return true, fmt.Errorf("builder: cannot set attribute %q", name)
+			}
+			fval.SetString(value)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Returned by SetAttribute when the object has no attribute of the name
+// given.
+var ErrUnknownAttribute = errors.New("builder: unknown attribute")
+
+func setKnownAttribute(obj interface{}, name string, value string) error {
+	ok, err := setAttribute(obj, name, value)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownAttribute, name)
+	}
+	return err
+}
+
+// Copies each attribute of the structure pointed to by "dst" which has its
+// zero value from the structure pointed to by "src", which must be of the same
+// type.  Used to apply templates.
+func inheritAttributes(dst interface{}, src interface{}) {
+	dval := reflect.ValueOf(dst).Elem()
+	sval := reflect.ValueOf(src).Elem()
+	typ := dval.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Tag.Get("name") == "" {
+			continue
+		}
+		if f := dval.Field(i); reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+			f.Set(sval.Field(i))
+		}
+	}
+}
@@ -18,12 +18,14 @@ package builder
 import "io"
 import "fmt"
 
+import "godot"
 import "godot/attr"
 
 type dotgraph struct {
 	kind       *attr.GraphKind
 	nodes      []*dotnode
 	edges      []*dotedge
+	subgraphs  []*dotsubgraph
 	nTmpl      nodeattrs
 	eTmpl      edgeattrs
 	attributes graphattrs
@@ -41,14 +43,14 @@ func (g dotgraph) Write(writer io.Writer) error {
 		}
 	}
 
-	if g.nTmpl != nil {
+	if len(g.nTmpl) > 0 {
 		str := attrlist(g.nTmpl).String(2, true)
 		if _, err := fmt.Fprintf(writer, "\tnode [%s]\n\n", str); err != nil {
 			return err
 		}
 	}
 
-	if g.eTmpl != nil {
+	if len(g.eTmpl) > 0 {
 		str := attrlist(g.eTmpl).String(2, true)
 		if _, err := fmt.Fprintf(writer, "\tedge [%s]\n\n", str); err != nil {
 			return err
@@ -65,6 +67,18 @@ func (g dotgraph) Write(writer io.Writer) error {
 		return err
 	}
 
+	if len(g.subgraphs) > 0 {
+		for _, sub := range g.subgraphs {
+			if err := sub.Write(writer); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintln(writer, ""); err != nil {
+			return err
+		}
+	}
+
 	for _, edge := range g.edges {
 		if err := edge.Write(writer); err != nil {
 			return err
@@ -77,3 +91,9 @@ func (g dotgraph) Write(writer io.Writer) error {
 
 	return nil
 }
+
+// Dot writes subgraphs, clusters among them, and node positions, as the pos
+// attribute.  Graphs do not hold ports, so none are written.
+func (g dotgraph) Capabilities() godot.Capabilities {
+	return godot.Clusters | godot.Positions
+}
@@ -0,0 +1,90 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "io"
+import "fmt"
+import "strings"
+
+type dotsubgraph struct {
+	name       string
+	nodes      []*dotnode
+	subgraphs  []*dotsubgraph
+	attributes graphattrs
+}
+
+func (s dotsubgraph) Write(writer io.Writer) error {
+	return s.write(writer, 1)
+}
+
+func (s dotsubgraph) write(writer io.Writer, depth int) error {
+	idnt := strings.Repeat("\t", depth)
+
+	header := "subgraph {"
+	if s.name != "" {
+		header = fmt.Sprintf("subgraph %s {", quoteID(s.name))
+	}
+	if _, err := fmt.Fprintf(writer, "%s%s\n", idnt, header); err != nil {
+		return err
+	}
+
+	for _, a := range s.attributes {
+		if _, err := fmt.Fprintf(writer, "%s\t%s=\"%s\";\n", idnt, a.Name, escape(a.Value)); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range s.nodes {
+		if _, err := fmt.Fprintf(writer, "%s\t%d;\n", idnt, node.Id()); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range s.subgraphs {
+		if err := sub.write(writer, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(writer, "%s}\n", idnt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// The keywords of the dot language, which cannot be identifiers unquoted.
+var keywords = map[string]bool{
+	"node":     true,
+	"edge":     true,
+	"graph":    true,
+	"digraph":  true,
+	"subgraph": true,
+	"strict":   true,
+}
+
+// Returns "id" unchanged if it is a valid dot identifier, or quoted if not.
+// Keywords, in any case, are quoted.
+func quoteID(id string) string {
+	if keywords[strings.ToLower(id)] {
+		return "\"" + escape(id) + "\""
+	}
+	for i, r := range id {
+		if !isIDRune(r, i == 0) {
+			return "\"" + escape(id) + "\""
+		}
+	}
+	return id
+}
@@ -25,9 +25,26 @@ type Edge struct {
 	Src *Node
 	Dst *Node
 
+	// Style of arrowhead at the head (Dst) end of the edge.
+	// http://www.graphviz.org/doc/info/attrs.html#d:arrowhead
+	Arrowhead string `name:"arrowhead"`
+
+	// Style of arrowhead at the tail (Src) end of the edge.
+	// http://www.graphviz.org/doc/info/attrs.html#d:arrowtail
+	Arrowtail string `name:"arrowtail"`
+
+	// Classes of the SVG element drawn for the edge.  (SVG only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:class
+	Class string `name:"class"`
+
 	// Color used for edge.
 	Color color.Color `name:"color"`
 
+	// Which ends of the edge have arrowheads: "forward", "back", "both" or
+	// "none".  Defaults to "forward" in directed graphs and "none" otherwise.
+	// http://www.graphviz.org/doc/info/attrs.html#d:dir
+	Dir string `name:"dir"`
+
 	// Color used to fill the arrowhead. (only if style = filled)
 	// http://www.graphviz.org/doc/info/attrs.html#d:fillcolor
 	FillColor color.Color `name:"fillcolor"`
@@ -35,15 +52,45 @@ type Edge struct {
 	// Color used for text.
 	FontColor color.Color `name:"fontcolor"`
 
+	// Identifier of the SVG element drawn for the edge.  (SVG, PostScript and
+	// image maps only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:id
+	ID string `name:"id"`
+
 	// Label attached to edge.
 	Label string `name:"label"`
 
 	// Length of edge.
 	Length string `name:"len"`
 
+	// Minimum rank difference between the edge's endpoints.  (dot only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:minlen
+	Minlen string `name:"minlen"`
+
+	// Width of the pen used to draw the edge (points).
+	// http://www.graphviz.org/doc/info/attrs.html#d:penwidth
+	Penwidth string `name:"penwidth"`
+
 	// Set style information for the edge.
 	// http://www.graphviz.org/doc/info/attrs.html#d:style
 	Style string `name:"style"`
+
+	// Tooltip shown when the pointer is over the edge.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:tooltip
+	Tooltip string `name:"tooltip"`
+
+	// Hyperlink followed when the edge is clicked.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:URL
+	URL string `name:"URL"`
+}
+
+// Sets the edge attribute with the given dot name, for example "label", from
+// its dot representation.  Returns an error if the edge has no such
+// attribute, or if the value is invalid.
+func (eb *Edge) SetAttribute(name string, value string) error {
+	return setKnownAttribute(eb, name, value)
 }
 
 func (eb Edge) buildAttributes() edgeattrs {
@@ -0,0 +1,351 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "strconv"
+
+import "godot/attr"
+
+// Options for the graph generators.  The zero value produces an undirected
+// graph of plain nodes and edges.
+//
+// Every generator panics if given a negative size, or one giving more nodes
+// than an int can count.
+type GenOptions struct {
+	// The kind of graph to generate.  Defaults to attr.Undirected.  In
+	// directed graphs edges lead from the lower numbered node to the higher,
+	// unless the generator says otherwise.
+	Kind *attr.GraphKind
+
+	// If set, every node is a copy of this node.
+	Node *Node
+
+	// If set, every edge is a copy of this edge.
+	Edge *Edge
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Panics unless each of "sizes", given to the generator "name", is
+// non-negative.
+func checkSizes(name string, sizes ...int) {
+	for _, size := range sizes {
+		if size < 0 {
+			panic("builder: " + name + " was given a negative size")
+		}
+	}
+}
+
+// Returns the sum of "a" and "b", both non-negative, panicking if it
+// overflows.
+func addSizes(name string, a, b int) int {
+	if a > maxInt-b {
+		panic("builder: " + name + " would have too many nodes")
+	}
+	return a + b
+}
+
+// Returns the product of "a" and "b", both non-negative, panicking if it
+// overflows.
+func mulSizes(name string, a, b int) int {
+	if a != 0 && b > maxInt/a {
+		panic("builder: " + name + " would have too many nodes")
+	}
+	return a * b
+}
+
+// Helps generators create nodes and edges from the options.
+type generator struct {
+	graph *Graph
+	nodes []*Node
+	eTmpl *Edge
+}
+
+func newGenerator(count int, opts *GenOptions) *generator {
+	if opts == nil {
+		opts = new(GenOptions)
+	}
+	kind := opts.Kind
+	if kind == nil {
+		kind = attr.Undirected
+	}
+
+	var nodes []*Node
+	if opts.Node != nil {
+		nodes = GenNodesFromTempl(count, opts.Node)
+	} else {
+		nodes = GenNodes(count)
+	}
+	graph := NewGraph(kind)
+	graph.AddNodes(nodes...)
+	return &generator{graph, nodes, opts.Edge}
+}
+
+// Adds an edge from node "i" to node "j".
+func (gen *generator) link(i, j int) {
+	edge := new(Edge)
+	if gen.eTmpl != nil {
+		*edge = *gen.eTmpl
+	}
+	edge.Src, edge.Dst = gen.nodes[i], gen.nodes[j]
+	gen.graph.AddEdges(edge)
+}
+
+// Adds an edge between nodes "i" and "j", from the lower to the higher.
+func (gen *generator) join(i, j int) {
+	if i > j {
+		i, j = j, i
+	}
+	gen.link(i, j)
+}
+
+// Generates the complete graph on "n" nodes, where every pair of nodes is
+// joined by an edge.
+func GenComplete(n int, opts *GenOptions) *Graph {
+	checkSizes("GenComplete", n)
+	gen := newGenerator(n, opts)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			gen.link(i, j)
+		}
+	}
+	return gen.graph
+}
+
+// Generates a cycle of "n" nodes.  In directed graphs the edges run around
+// the cycle, from node n-1 back to node 0.
+func GenCycle(n int, opts *GenOptions) *Graph {
+	checkSizes("GenCycle", n)
+	gen := newGenerator(n, opts)
+	for i := 0; i < n; i++ {
+		if n > 2 || i+1 < n {
+			gen.link(i, (i+1)%n)
+		}
+	}
+	return gen.graph
+}
+
+// Generates a path of "n" nodes.
+func GenPath(n int, opts *GenOptions) *Graph {
+	checkSizes("GenPath", n)
+	gen := newGenerator(n, opts)
+	for i := 1; i < n; i++ {
+		gen.link(i-1, i)
+	}
+	return gen.graph
+}
+
+// Generates a star: node 0 joined to each of "n" further nodes.
+func GenStar(n int, opts *GenOptions) *Graph {
+	checkSizes("GenStar", n)
+	gen := newGenerator(addSizes("GenStar", n, 1), opts)
+	for i := 1; i <= n; i++ {
+		gen.link(0, i)
+	}
+	return gen.graph
+}
+
+// Generates a wheel: node 0 joined to each node of a cycle of "n" further
+// nodes.
+func GenWheel(n int, opts *GenOptions) *Graph {
+	checkSizes("GenWheel", n)
+	gen := newGenerator(addSizes("GenWheel", n, 1), opts)
+	for i := 1; i <= n; i++ {
+		gen.link(0, i)
+	}
+	for i := 1; i <= n; i++ {
+		if j := i%n + 1; n > 2 || i < j {
+			gen.link(i, j)
+		}
+	}
+	return gen.graph
+}
+
+// Generates a grid of "rows" by "cols" nodes, numbered row by row, each
+// joined to the nodes to its right and below it.
+func GenGrid(rows, cols int, opts *GenOptions) *Graph {
+	return genLattice("GenGrid", rows, cols, false, opts)
+}
+
+// Generates a torus: a grid of "rows" by "cols" nodes whose last row and
+// column are joined to its first.  In directed graphs the wrap-around edges
+// lead from the last row and column to the first.
+func GenTorus(rows, cols int, opts *GenOptions) *Graph {
+	return genLattice("GenTorus", rows, cols, true, opts)
+}
+
+func genLattice(name string, rows, cols int, wrap bool, opts *GenOptions) *Graph {
+	checkSizes(name, rows, cols)
+	gen := newGenerator(mulSizes(name, rows, cols), opts)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := r*cols + c
+			if c+1 < cols {
+				gen.link(i, i+1)
+			} else if wrap && cols > 2 {
+				gen.link(i, r*cols)
+			}
+			if r+1 < rows {
+				gen.link(i, i+cols)
+			} else if wrap && rows > 2 {
+				gen.link(i, c)
+			}
+		}
+	}
+	return gen.graph
+}
+
+// Generates the hypercube of dimension "dim", with 2^dim nodes.  Nodes are
+// joined if their numbers differ in exactly one bit.
+func GenHypercube(dim int, opts *GenOptions) *Graph {
+	checkSizes("GenHypercube", dim)
+	if dim >= strconv.IntSize-1 {
+		panic("builder: GenHypercube would have too many nodes")
+	}
+	n := 1 << uint(dim)
+	gen := newGenerator(n, opts)
+	for i := 0; i < n; i++ {
+		for b := 0; b < dim; b++ {
+			if j := i ^ (1 << uint(b)); j > i {
+				gen.link(i, j)
+			}
+		}
+	}
+	return gen.graph
+}
+
+// Generates the complete bipartite graph K(m,n): nodes 0 to m-1 are each
+// joined to all of nodes m to m+n-1.
+func GenCompleteBipartite(m, n int, opts *GenOptions) *Graph {
+	checkSizes("GenCompleteBipartite", m, n)
+	gen := newGenerator(addSizes("GenCompleteBipartite", m, n), opts)
+	for i := 0; i < m; i++ {
+		for j := m; j < m+n; j++ {
+			gen.link(i, j)
+		}
+	}
+	return gen.graph
+}
+
+// Generates a complete tree in which every node above the leaves has "k"
+// children, with "depth" levels below the root.  Nodes are numbered level by
+// level, starting with the root as node 0.  In directed graphs edges lead
+// from parent to child.
+func GenTree(k, depth int, opts *GenOptions) *Graph {
+	checkSizes("GenTree", k, depth)
+	n, level := 1, 1
+	for d := 0; d < depth && level > 0; d++ {
+		level = mulSizes("GenTree", level, k)
+		n = addSizes("GenTree", n, level)
+	}
+	gen := newGenerator(n, opts)
+	for i := 1; i < n; i++ {
+		gen.link((i-1)/k, i)
+	}
+	return gen.graph
+}
+
+// Generates a complete binary tree with "depth" levels below the root.  See
+// GenTree.
+func GenBinaryTree(depth int, opts *GenOptions) *Graph {
+	return GenTree(2, depth, opts)
+}
+
+// Generates the generalized Petersen graph GP(n,k): an outer cycle of nodes
+// 0 to n-1, each joined to an inner node n to 2n-1, with inner node i joined
+// to inner node i+k (mod n).  A negative "k" counts backwards.
+func GenGeneralizedPetersen(n, k int, opts *GenOptions) *Graph {
+	checkSizes("GenGeneralizedPetersen", n)
+	gen := newGenerator(mulSizes("GenGeneralizedPetersen", n, 2), opts)
+	for i := 0; i < n; i++ {
+		gen.link(i, (i+1)%n)
+	}
+	for i := 0; i < n; i++ {
+		gen.link(i, i+n)
+	}
+	linked := make(map[[2]int]bool)
+	for i := 0; i < n; i++ {
+		j := ((i+k)%n+n)%n + n
+		a, b := i+n, j
+		if a > b {
+			a, b = b, a
+		}
+		if a != b && !linked[[2]int{a, b}] {
+			linked[[2]int{a, b}] = true
+			gen.join(i+n, j)
+		}
+	}
+	return gen.graph
+}
+
+// Generates a cubic Hamiltonian graph from its LCF notation: a cycle of "n"
+// nodes, where node i is also joined to node i+shifts[i mod len(shifts)]
+// (mod n).
+//
+// Resources:
+//   http://mathworld.wolfram.com/LCFNotation.html
+func GenLCF(n int, shifts []int, opts *GenOptions) *Graph {
+	checkSizes("GenLCF", n)
+	gen := newGenerator(n, opts)
+	for i := 0; i < n; i++ {
+		gen.link(i, (i+1)%n)
+	}
+	linked := make(map[[2]int]bool)
+	for i := 0; i < n && len(shifts) > 0; i++ {
+		j := ((i+shifts[i%len(shifts)])%n + n) % n
+		a, b := i, j
+		if a > b {
+			a, b = b, a
+		}
+		if a == b || b-a == 1 || (a == 0 && b == n-1) || linked[[2]int{a, b}] {
+			continue
+		}
+		linked[[2]int{a, b}] = true
+		gen.join(i, j)
+	}
+	return gen.graph
+}
+
+// Generates the Petersen graph: 10 nodes and 15 edges, the outer pentagon
+// being nodes 0 to 4 and the inner pentagram nodes 5 to 9.
+func GenPetersen(opts *GenOptions) *Graph {
+	return GenGeneralizedPetersen(5, 2, opts)
+}
+
+// Generates the Heawood graph: 14 nodes and 21 edges.
+func GenHeawood(opts *GenOptions) *Graph {
+	return GenLCF(14, []int{5, -5}, opts)
+}
+
+// Generates the Mobius-Kantor graph: 16 nodes and 24 edges.
+func GenMobiusKantor(opts *GenOptions) *Graph {
+	return GenLCF(16, []int{5, -5}, opts)
+}
+
+// Generates the dodecahedral graph: 20 nodes and 30 edges.
+func GenDodecahedron(opts *GenOptions) *Graph {
+	return GenLCF(20, []int{10, 7, 4, -4, -7, 10, -4, 7, -7, 4}, opts)
+}
+
+// Generates the Desargues graph: 20 nodes and 30 edges.
+func GenDesargues(opts *GenOptions) *Graph {
+	return GenLCF(20, []int{5, -5, 9, -9}, opts)
+}
+
+// Generates the Frucht graph: 12 nodes and 18 edges.
+func GenFrucht(opts *GenOptions) *Graph {
+	return GenLCF(12, []int{-5, -2, -4, 2, 5, -2, 2, 5, -2, -5, 4, 2}, opts)
+}
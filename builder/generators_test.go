@@ -0,0 +1,133 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "testing"
+
+import "godot/attr"
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph *Graph
+		nodes int
+		edges int
+	}{
+		{"complete", GenComplete(5, nil), 5, 10},
+		{"cycle", GenCycle(5, nil), 5, 5},
+		{"path", GenPath(5, nil), 5, 4},
+		{"star", GenStar(5, nil), 6, 5},
+		{"wheel", GenWheel(5, nil), 6, 10},
+		{"grid", GenGrid(3, 4, nil), 12, 17},
+		{"torus", GenTorus(3, 4, nil), 12, 24},
+		{"hypercube", GenHypercube(3, nil), 8, 12},
+		{"bipartite", GenCompleteBipartite(2, 3, nil), 5, 6},
+		{"tree", GenTree(3, 2, nil), 13, 12},
+		{"binary tree", GenBinaryTree(3, nil), 15, 14},
+		{"petersen", GenPetersen(nil), 10, 15},
+		{"heawood", GenHeawood(nil), 14, 21},
+		{"mobius-kantor", GenMobiusKantor(nil), 16, 24},
+		{"dodecahedron", GenDodecahedron(nil), 20, 30},
+		{"desargues", GenDesargues(nil), 20, 30},
+		{"frucht", GenFrucht(nil), 12, 18},
+		{"barabasi-albert", GenBarabasiAlbert(20, 2, 1, nil), 20, 36},
+		{"watts-strogatz", GenWattsStrogatz(20, 4, 0.3, 1, nil), 20, 40},
+	}
+
+	for _, test := range tests {
+		if n := len(test.graph.Nodes()); n != test.nodes {
+			t.Errorf("%s: should have %d nodes, but has %d.", test.name, test.nodes, n)
+		}
+		if n := len(test.graph.Edges()); n != test.edges {
+			t.Errorf("%s: should have %d edges, but has %d.", test.name, test.edges, n)
+		}
+	}
+}
+
+func TestGenOptions(t *testing.T) {
+	g := GenPath(3, &GenOptions{
+		Kind: attr.Directed,
+		Node: &Node{Label: "n"},
+		Edge: &Edge{Style: "bold"},
+	})
+
+	if g.Kind() != attr.Directed {
+		t.Errorf("Graph should be directed.")
+	}
+	nodes := g.Nodes()
+	if nodes[0].Label != "n" || nodes[0] == nodes[1] {
+		t.Errorf("Nodes should be distinct copies of the template.")
+	}
+	for _, e := range g.Edges() {
+		if e.Style != "bold" {
+			t.Errorf("Edges should be copies of the template.")
+		}
+	}
+	if e := g.Edges()[0]; e.Src != nodes[0] || e.Dst != nodes[1] {
+		t.Errorf("Edge endpoints are incorrect.")
+	}
+}
+
+func TestGenRandomSeed(t *testing.T) {
+	a := GenErdosRenyi(30, 0.2, 42, nil)
+	b := GenErdosRenyi(30, 0.2, 42, nil)
+
+	ea, eb := a.Edges(), b.Edges()
+	if len(ea) != len(eb) {
+		t.Fatalf("Same seed should give the same graph.")
+	}
+	ia, ib := make(map[*Node]int), make(map[*Node]int)
+	for i, n := range a.Nodes() {
+		ia[n] = i
+	}
+	for i, n := range b.Nodes() {
+		ib[n] = i
+	}
+	for i := range ea {
+		if ia[ea[i].Src] != ib[eb[i].Src] || ia[ea[i].Dst] != ib[eb[i].Dst] {
+			t.Fatalf("Same seed should give the same graph.")
+		}
+	}
+}
+
+func TestGenInvalid(t *testing.T) {
+	for name, gen := range map[string]func(){
+		"GenTree(-1, 2)":        func() { GenTree(-1, 2, nil) },
+		"GenTree(2, -1)":        func() { GenTree(2, -1, nil) },
+		"GenTree(2, 100)":       func() { GenTree(2, 100, nil) },
+		"GenHypercube(-1)":      func() { GenHypercube(-1, nil) },
+		"GenHypercube(64)":      func() { GenHypercube(64, nil) },
+		"GenPath(-1)":           func() { GenPath(-1, nil) },
+		"GenGrid(-2, -3)":       func() { GenGrid(-2, -3, nil) },
+		"GenTorus(maxInt/2, 3)": func() { GenTorus(maxInt/2, 3, nil) },
+		"GenWattsStrogatz":      func() { GenWattsStrogatz(10, -2, 0.5, 1, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic.", name)
+				}
+			}()
+			gen()
+		}()
+	}
+
+	// A negative step counts backwards around the inner cycle.
+	g := GenGeneralizedPetersen(5, -2, nil)
+	if n, e := len(g.Nodes()), len(g.Edges()); n != 10 || e != 15 {
+		t.Errorf("GP(5,-2) has %d nodes and %d edges.", n, e)
+	}
+}
@@ -25,11 +25,24 @@ type Graph struct {
 	kind  *attr.GraphKind
 	nodes set.OrderedSet
 	edges set.OrderedSet
+	subgs set.OrderedSet
 	nTmpl *Node
 	eTmpl *Edge
 
 	// Label will appear centered at bottom of graph.
 	Label string `name:"label"`
+
+	// Minimum space between two adjacent nodes in the same rank (inches).
+	// http://www.graphviz.org/doc/info/attrs.html#d:nodesep
+	Nodesep string `name:"nodesep"`
+
+	// Direction in which ranks are laid out.  (dot only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:rankdir
+	Rankdir *attr.RankDir `name:"rankdir"`
+
+	// Minimum space between adjacent ranks (inches).  (dot only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:ranksep
+	Ranksep string `name:"ranksep"`
 }
 
 // Convenience constructor for the graph builder, which populates all required
@@ -38,7 +51,24 @@ type Graph struct {
 func NewGraph(kind *attr.GraphKind) *Graph {
 	nodes := set.New()
 	edges := set.New()
-	return &Graph{kind: kind, nodes: nodes, edges: edges}
+	subgs := set.New()
+	return &Graph{kind: kind, nodes: nodes, edges: edges, subgs: subgs}
+}
+
+// Returns the kind of graph (directed or undirected) being built.
+func (gb *Graph) Kind() *attr.GraphKind {
+	return gb.kind
+}
+
+// Returns a new graph with the same kind, templates and attributes as this
+// one, but without any nodes, edges or subgraphs.  Useful for algorithms which
+// derive a new graph from an existing one.
+func (gb *Graph) CloneEmpty() *Graph {
+	clone := *gb
+	clone.nodes = set.New()
+	clone.edges = set.New()
+	clone.subgs = set.New()
+	return &clone
 }
 
 // Returns a slice of nodes.  Although the nodes are mutable, assigning Nodes
@@ -59,7 +89,7 @@ func (gb *Graph) Nodes() []*Node {
 func (gb *Graph) Edges() []*Edge {
 	edges := make([]*Edge, 0, gb.edges.Count())
 
-	gb.nodes.Visit(func(e interface{}) {
+	gb.edges.Visit(func(e interface{}) {
 		edge := e.(*Edge)
 		edges = append(edges, edge)
 	})
@@ -87,6 +117,26 @@ func (gb *Graph) SetEdgeTemplate(edge *Edge) {
 	gb.eTmpl = edge
 }
 
+// Returns a copy of "node" in which each attribute that is not set is taken
+// from the node template, as Graphviz would see it.
+func (gb *Graph) ResolveNode(node *Node) *Node {
+	resolved := *node
+	if gb.nTmpl != nil {
+		inheritAttributes(&resolved, gb.nTmpl)
+	}
+	return &resolved
+}
+
+// Returns a copy of "edge" in which each attribute that is not set is taken
+// from the edge template, as Graphviz would see it.
+func (gb *Graph) ResolveEdge(edge *Edge) *Edge {
+	resolved := *edge
+	if gb.eTmpl != nil {
+		inheritAttributes(&resolved, gb.eTmpl)
+	}
+	return &resolved
+}
+
 // Adds nodes to the graph, returns number of nodes added.
 // Adding a node to the graph multiple times has no effect.
 func (gb *Graph) AddNodes(nodes ...*Node) int {
@@ -144,6 +194,32 @@ func (gb *Graph) RemoveEdges(edges ...*Edge) int {
 	return count
 }
 
+// Returns a slice of the top level subgraphs.
+func (gb *Graph) Subgraphs() []*Subgraph {
+	return subgraphSlice(gb.subgs)
+}
+
+// Adds subgraphs to the graph, returns number of subgraphs added.
+// Adding a subgraph to the graph multiple times has no effect.
+// Nodes of the subgraph, or of subgraphs nested within it, which are not
+// already in the graph will be added.  Nodes added to a subgraph after it has
+// been added to the graph must be added to the graph separately, or they will
+// not be output.
+func (gb *Graph) AddSubgraphs(subs ...*Subgraph) int {
+	for _, s := range subs {
+		if !gb.subgs.Contains(s) {
+			gb.AddNodes(s.AllNodes()...)
+		}
+	}
+	return addSubgraphs(gb.subgs, subs)
+}
+
+// Removes subgraphs from the graph, returns number of subgraphs removed.
+// The nodes of the subgraph remain in the graph.
+func (gb *Graph) RemoveSubgraphs(subs ...*Subgraph) int {
+	return removeSubgraphs(gb.subgs, subs)
+}
+
 // Returns an immutable structure representing the current graph.
 func (gb *Graph) Build() godot.Dot {
 	nodes, nodemap := buildNodes(gb.nodes)
@@ -164,12 +240,20 @@ func (gb *Graph) Build() godot.Dot {
 		kind:       gb.kind,
 		nodes:      nodes,
 		edges:      edges,
+		subgraphs:  buildSubgraphs(gb.subgs, nodemap),
 		nTmpl:      nTmpl,
 		eTmpl:      eTmpl,
 		attributes: gb.buildAttributes(),
 	}
 }
 
+// Sets the graph attribute with the given dot name, for example "label", from
+// its dot representation.  Returns an error if the graph has no such
+// attribute, or if the value is invalid.
+func (gb *Graph) SetAttribute(name string, value string) error {
+	return setKnownAttribute(gb, name, value)
+}
+
 // Reflects on the graph and extracts all dot attribute information into
 // attribute structures.
 func (gb *Graph) buildAttributes() graphattrs {
@@ -16,8 +16,10 @@
 package builder
 
 import "bytes"
+import "strings"
 import "testing"
 
+import "godot"
 import "godot/attr"
 
 func TestWrite(t *testing.T) {
@@ -65,3 +67,97 @@ func TestWrite(t *testing.T) {
 		t.Errorf("Output was incorrect.")
 	}
 }
+
+func TestCapabilities(t *testing.T) {
+	c, ok := NewGraph(attr.Directed).Build().(godot.Capable)
+	if !ok {
+		t.Fatalf("Dot does not report its capabilities")
+	}
+	if got := c.Capabilities(); got != godot.Clusters|godot.Positions {
+		t.Errorf("Dot has capabilities %b", got)
+	}
+}
+
+func TestEdges(t *testing.T) {
+	nodes := GenNodes(2)
+	e1 := &Edge{Src: nodes[0], Dst: nodes[1]}
+	e2 := &Edge{Src: nodes[1], Dst: nodes[0]}
+
+	g := NewGraph(attr.Directed)
+	g.AddEdges(e1, e2)
+
+	l := g.Edges()
+	if len(l) != 2 {
+		t.Fatalf("Length is incorrect.  Should be 2, but is %d.", len(l))
+	}
+	if l[0] != e1 || l[1] != e2 {
+		t.Errorf("Edges are out of order.")
+	}
+	if len(g.Nodes()) != 2 {
+		t.Errorf("Edge endpoints were not added.")
+	}
+}
+
+func TestWriteSubgraphs(t *testing.T) {
+	var b bytes.Buffer
+
+	nodes := GenNodes(3)
+	inner := NewSubgraph("inner")
+	inner.AddNodes(nodes[2])
+	cluster := NewSubgraph("cluster 0")
+	cluster.Label = "A"
+	cluster.AddNodes(nodes[0], nodes[1])
+	cluster.AddSubgraphs(inner)
+
+	g := NewGraph(attr.Undirected)
+	g.AddSubgraphs(cluster)
+	g.AddEdges(&Edge{Src: nodes[0], Dst: nodes[1]})
+	g.Build().Write(&b)
+
+	dot := `graph {
+	0;
+	1;
+	2;
+
+	subgraph "cluster 0" {
+		label="A";
+		0;
+		1;
+		subgraph inner {
+			2;
+		}
+	}
+
+	0 -- 1;
+}
+`
+
+	if dot != b.String() {
+		t.Errorf("Output was incorrect.")
+	}
+}
+
+func TestWriteKeywordSubgraphs(t *testing.T) {
+	nodes := GenNodes(2)
+	g := NewGraph(attr.Directed)
+	for i, name := range []string{"graph", "Node"} {
+		sub := NewSubgraph(name)
+		sub.AddNodes(nodes[i])
+		g.AddSubgraphs(sub)
+	}
+	var b bytes.Buffer
+	g.Build().Write(&b)
+	out := b.String()
+	if !strings.Contains(out, `subgraph "graph" {`) || !strings.Contains(out, `subgraph "Node" {`) {
+		t.Errorf("Keywords were not quoted:\n%s", out)
+	}
+
+	back, err := Parse(&b)
+	if err != nil {
+		t.Fatalf("Parse of the output failed: %v", err)
+	}
+	subs := back.Subgraphs()
+	if len(subs) != 2 || subs[0].Name != "graph" || subs[1].Name != "Node" {
+		t.Errorf("Subgraphs were not written with their names.")
+	}
+}
@@ -0,0 +1,517 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "encoding/json"
+import "errors"
+import "fmt"
+import "sort"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+
+// The godot JSON schema of a graph, documented at MarshalJSON.
+type jsonGraph struct {
+	Directed     bool              `json:"directed"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	NodeTemplate map[string]string `json:"nodeTemplate,omitempty"`
+	EdgeTemplate map[string]string `json:"edgeTemplate,omitempty"`
+	Nodes        []jsonNode        `json:"nodes"`
+	Edges        []jsonEdge        `json:"edges"`
+	Subgraphs    []jsonSubgraph    `json:"subgraphs,omitempty"`
+}
+
+type jsonNode struct {
+	ID         string            `json:"id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type jsonEdge struct {
+	Tail       string            `json:"tail"`
+	Head       string            `json:"head"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type jsonSubgraph struct {
+	Name       string            `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Nodes      []string          `json:"nodes,omitempty"`
+	Subgraphs  []jsonSubgraph    `json:"subgraphs,omitempty"`
+}
+
+// Returns the attributes set on "obj" as a map, or nil if there are none.
+func attributeMap(obj interface{}) map[string]string {
+	pairs := Attributes(obj)
+	if len(pairs) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(pairs))
+	for _, a := range pairs {
+		m[a[0]] = a[1]
+	}
+	return m
+}
+
+type attributeSetter interface {
+	SetAttribute(name string, value string) error
+}
+
+// Sets attributes from a map, in the order of their names.  Unknown
+// attributes are an error if "strict", and ignored otherwise.
+func setAttributeMap(obj attributeSetter, m map[string]string, strict bool) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := obj.SetAttribute(name, m[name])
+		if err != nil && (strict || !errors.Is(err, ErrUnknownAttribute)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encodes the graph in the godot JSON schema, which UnmarshalJSON reads:
+//
+//  {
+//    "directed": true,
+//    "attributes": {"label": "Services", "rankdir": "LR"},
+//    "nodeTemplate": {"shape": "box"},
+//    "edgeTemplate": {"color": "gray"},
+//    "nodes": [
+//      {"id": "0", "attributes": {"label": "api"}},
+//      {"id": "1", "attributes": {"label": "db"}}
+//    ],
+//    "edges": [
+//      {"tail": "0", "head": "1", "attributes": {"label": "reads"}}
+//    ],
+//    "subgraphs": [
+//      {"name": "cluster_0", "attributes": {"label": "Backend"},
+//       "nodes": ["1"], "subgraphs": []}
+//    ]
+//  }
+//
+// Attributes are objects mapping dot names to dot values, as given to
+// SetAttribute, and are omitted when empty, as are the templates.  Nodes
+// are identified by strings, which MarshalJSON makes their index in Nodes,
+// as in dot; edges name their endpoints by these ids.  The nodes of a
+// subgraph are those directly within it, not within its subgraphs.  Edges
+// without both endpoints are not written.
+func (gb *Graph) MarshalJSON() ([]byte, error) {
+	jg := jsonGraph{
+		Directed:   gb.kind == attr.Directed,
+		Attributes: attributeMap(gb),
+		Nodes:      []jsonNode{},
+		Edges:      []jsonEdge{},
+	}
+	if gb.nTmpl != nil {
+		jg.NodeTemplate = attributeMap(gb.nTmpl)
+	}
+	if gb.eTmpl != nil {
+		jg.EdgeTemplate = attributeMap(gb.eTmpl)
+	}
+	ids := make(map[*Node]string)
+	for i, n := range gb.Nodes() {
+		ids[n] = strconv.Itoa(i)
+		jg.Nodes = append(jg.Nodes, jsonNode{ID: ids[n], Attributes: attributeMap(n)})
+	}
+	for _, e := range gb.Edges() {
+		if e.Src != nil && e.Dst != nil {
+			jg.Edges = append(jg.Edges, jsonEdge{Tail: ids[e.Src], Head: ids[e.Dst], Attributes: attributeMap(e)})
+		}
+	}
+	var subgraphs func(subs []*Subgraph) []jsonSubgraph
+	subgraphs = func(subs []*Subgraph) []jsonSubgraph {
+		var js []jsonSubgraph
+		for _, sub := range subs {
+			s := jsonSubgraph{Name: sub.Name, Attributes: attributeMap(sub)}
+			for _, n := range sub.Nodes() {
+				if id, ok := ids[n]; ok {
+					s.Nodes = append(s.Nodes, id)
+				}
+			}
+			s.Subgraphs = subgraphs(sub.Subgraphs())
+			js = append(js, s)
+		}
+		return js
+	}
+	jg.Subgraphs = subgraphs(gb.Subgraphs())
+	return json.Marshal(jg)
+}
+
+// Decodes a graph in the godot JSON schema, described at MarshalJSON, or in
+// the structure of Graphviz's -Tjson0 and -Tjson output, which is recognized
+// by its "objects".  The graph's contents are replaced.  Unknown attributes
+// are an error in the godot schema, and are ignored in Graphviz's.
+func (gb *Graph) UnmarshalJSON(data []byte) error {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	if _, ok := probe["objects"]; ok {
+		return gb.unmarshalGraphviz(data, probe)
+	}
+
+	var jg jsonGraph
+	if err := json.Unmarshal(data, &jg); err != nil {
+		return err
+	}
+	kind := attr.Undirected
+	if jg.Directed {
+		kind = attr.Directed
+	}
+	*gb = *NewGraph(kind)
+	if err := setAttributeMap(gb, jg.Attributes, true); err != nil {
+		return err
+	}
+	if jg.NodeTemplate != nil {
+		gb.nTmpl = new(Node)
+		if err := setAttributeMap(gb.nTmpl, jg.NodeTemplate, true); err != nil {
+			return err
+		}
+	}
+	if jg.EdgeTemplate != nil {
+		gb.eTmpl = new(Edge)
+		if err := setAttributeMap(gb.eTmpl, jg.EdgeTemplate, true); err != nil {
+			return err
+		}
+	}
+
+	nodes := make(map[string]*Node)
+	for _, jn := range jg.Nodes {
+		if _, ok := nodes[jn.ID]; ok {
+			return fmt.Errorf("builder: duplicate node %q", jn.ID)
+		}
+		n := new(Node)
+		if err := setAttributeMap(n, jn.Attributes, true); err != nil {
+			return err
+		}
+		nodes[jn.ID] = n
+		gb.AddNodes(n)
+	}
+	node := func(id string) (*Node, error) {
+		if n, ok := nodes[id]; ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("builder: unknown node %q", id)
+	}
+	for _, je := range jg.Edges {
+		src, err := node(je.Tail)
+		if err != nil {
+			return err
+		}
+		dst, err := node(je.Head)
+		if err != nil {
+			return err
+		}
+		e := &Edge{Src: src, Dst: dst}
+		if err := setAttributeMap(e, je.Attributes, true); err != nil {
+			return err
+		}
+		gb.AddEdges(e)
+	}
+
+	var subgraphs func(js []jsonSubgraph) ([]*Subgraph, error)
+	subgraphs = func(js []jsonSubgraph) ([]*Subgraph, error) {
+		var subs []*Subgraph
+		for _, s := range js {
+			sub := NewSubgraph(s.Name)
+			if err := setAttributeMap(sub, s.Attributes, true); err != nil {
+				return nil, err
+			}
+			for _, id := range s.Nodes {
+				n, err := node(id)
+				if err != nil {
+					return nil, err
+				}
+				sub.AddNodes(n)
+			}
+			nested, err := subgraphs(s.Subgraphs)
+			if err != nil {
+				return nil, err
+			}
+			sub.AddSubgraphs(nested...)
+			subs = append(subs, sub)
+		}
+		return subs, nil
+	}
+	subs, err := subgraphs(jg.Subgraphs)
+	if err != nil {
+		return err
+	}
+	gb.AddSubgraphs(subs...)
+	return nil
+}
+
+// Members of Graphviz's json output which are not attributes.
+var graphvizReserved = map[string]bool{
+	"_gvid":         true,
+	"_subgraph_cnt": true,
+	"name":          true,
+	"directed":      true,
+	"strict":        true,
+	"objects":       true,
+	"edges":         true,
+	"nodes":         true,
+	"subgraphs":     true,
+	"tail":          true,
+	"head":          true,
+}
+
+// Returns the attributes of an object of Graphviz's json output: its
+// members with string values, but for those which are not attributes.
+func graphvizAttributes(obj map[string]json.RawMessage) map[string]string {
+	m := make(map[string]string)
+	for name, raw := range obj {
+		var value string
+		if graphvizReserved[name] || json.Unmarshal(raw, &value) != nil {
+			continue
+		}
+		m[name] = value
+	}
+	return m
+}
+
+// Encodes the graph in the structure of Graphviz's -Tjson0 output, without
+// layout, so that tools which read Graphviz's json can read godot graphs.
+// Subgraphs are the first objects, numbered by their "_gvid" from zero
+// depth first, followed by the nodes, which are named by their index in
+// Nodes, as in dot.  Templates are applied to each node and edge, as
+// Graphviz's json has none.
+func (gb *Graph) MarshalGraphvizJSON() ([]byte, error) {
+	var subs []*Subgraph
+	var visit func(ss []*Subgraph)
+	visit = func(ss []*Subgraph) {
+		for _, s := range ss {
+			subs = append(subs, s)
+			visit(s.Subgraphs())
+		}
+	}
+	visit(gb.Subgraphs())
+	subIDs := make(map[*Subgraph]int)
+	for i, s := range subs {
+		subIDs[s] = i
+	}
+	nodes := gb.Nodes()
+	gvids := make(map[*Node]int)
+	for i, n := range nodes {
+		gvids[n] = len(subs) + i
+	}
+
+	withAttributes := func(obj map[string]interface{}, atrs map[string]string) map[string]interface{} {
+		for name, value := range atrs {
+			obj[name] = value
+		}
+		return obj
+	}
+
+	objects := make([]interface{}, 0, len(subs)+len(nodes))
+	for i, s := range subs {
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("%%%d", i)
+		}
+		obj := withAttributes(map[string]interface{}{"_gvid": i, "name": name}, attributeMap(s))
+		var members []int
+		for _, n := range s.AllNodes() {
+			if id, ok := gvids[n]; ok {
+				members = append(members, id)
+			}
+		}
+		if len(members) > 0 {
+			obj["nodes"] = members
+		}
+		var nested []int
+		for _, sub := range s.Subgraphs() {
+			nested = append(nested, subIDs[sub])
+		}
+		if len(nested) > 0 {
+			obj["subgraphs"] = nested
+		}
+		objects = append(objects, obj)
+	}
+	for i, n := range nodes {
+		obj := map[string]interface{}{"_gvid": gvids[n], "name": strconv.Itoa(i)}
+		objects = append(objects, withAttributes(obj, attributeMap(gb.ResolveNode(n))))
+	}
+
+	edges := []interface{}{}
+	for _, e := range gb.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		obj := map[string]interface{}{"_gvid": len(edges), "tail": gvids[e.Src], "head": gvids[e.Dst]}
+		edges = append(edges, withAttributes(obj, attributeMap(gb.ResolveEdge(e))))
+	}
+
+	top := withAttributes(map[string]interface{}{
+		"name":          "G",
+		"directed":      gb.kind == attr.Directed,
+		"strict":        false,
+		"_subgraph_cnt": len(subs),
+		"objects":       objects,
+	}, attributeMap(gb))
+	if len(edges) > 0 {
+		top["edges"] = edges
+	}
+	return json.Marshal(top)
+}
+
+// The members of an object of Graphviz's json output which are not
+// attributes.
+type graphvizMember struct {
+	Name      string `json:"name"`
+	Nodes     []int  `json:"nodes"`
+	Subgraphs []int  `json:"subgraphs"`
+	Tail      int    `json:"tail"`
+	Head      int    `json:"head"`
+}
+
+// Decodes an object, returning all of its members.
+func (m *graphvizMember) decode(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(raw, &obj)
+	return obj, err
+}
+
+// Decodes Graphviz's json output.  Nodes without labels, or with the
+// default label "\N", are labelled with their names, as Parse labels them.
+func (gb *Graph) unmarshalGraphviz(data []byte, top map[string]json.RawMessage) error {
+	var gv struct {
+		Directed    bool              `json:"directed"`
+		SubgraphCnt int               `json:"_subgraph_cnt"`
+		Objects     []json.RawMessage `json:"objects"`
+		Edges       []json.RawMessage `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &gv); err != nil {
+		return err
+	}
+	kind := attr.Undirected
+	if gv.Directed {
+		kind = attr.Directed
+	}
+	*gb = *NewGraph(kind)
+	if err := setAttributeMap(gb, graphvizAttributes(top), false); err != nil {
+		return err
+	}
+
+	// Objects are numbered by their index, which is their _gvid.
+	members := make([]graphvizMember, len(gv.Objects))
+	objects := make([]interface{}, len(gv.Objects))
+	for i, raw := range gv.Objects {
+		obj, err := members[i].decode(raw)
+		if err != nil {
+			return err
+		}
+		if i < gv.SubgraphCnt {
+			name := members[i].Name
+			if strings.HasPrefix(name, "%") {
+				name = ""
+			}
+			sub := NewSubgraph(name)
+			if err := setAttributeMap(sub, graphvizAttributes(obj), false); err != nil {
+				return err
+			}
+			objects[i] = sub
+			continue
+		}
+		n := new(Node)
+		atrs := graphvizAttributes(obj)
+		if label, ok := atrs["label"]; !ok || label == `\N` {
+			atrs["label"] = members[i].Name
+		}
+		if err := setAttributeMap(n, atrs, false); err != nil {
+			return err
+		}
+		objects[i] = n
+		gb.AddNodes(n)
+	}
+	object := func(gvid int) interface{} {
+		if gvid < 0 || gvid >= len(objects) {
+			return nil
+		}
+		return objects[gvid]
+	}
+
+	// The nodes of a subgraph include those of its subgraphs.
+	nested := make(map[*Subgraph]bool)
+	for i := 0; i < gv.SubgraphCnt && i < len(objects); i++ {
+		sub := objects[i].(*Subgraph)
+		for _, id := range members[i].Subgraphs {
+			child, ok := object(id).(*Subgraph)
+			if !ok || nested[child] || child == sub {
+				return fmt.Errorf("builder: invalid subgraph %d of %q", id, members[i].Name)
+			}
+			nested[child] = true
+			sub.AddSubgraphs(child)
+		}
+	}
+	var place func(sub *Subgraph, i int) error
+	place = func(sub *Subgraph, i int) error {
+		inner := make(map[*Node]bool)
+		for _, id := range members[i].Subgraphs {
+			if err := place(objects[id].(*Subgraph), id); err != nil {
+				return err
+			}
+			for _, n := range objects[id].(*Subgraph).AllNodes() {
+				inner[n] = true
+			}
+		}
+		for _, id := range members[i].Nodes {
+			n, ok := object(id).(*Node)
+			if !ok {
+				return fmt.Errorf("builder: invalid node %d of %q", id, members[i].Name)
+			}
+			if !inner[n] {
+				sub.AddNodes(n)
+			}
+		}
+		return nil
+	}
+	for i := 0; i < gv.SubgraphCnt && i < len(objects); i++ {
+		if sub := objects[i].(*Subgraph); !nested[sub] {
+			if err := place(sub, i); err != nil {
+				return err
+			}
+			gb.AddSubgraphs(sub)
+		}
+	}
+
+	for _, raw := range gv.Edges {
+		var ends graphvizMember
+		obj, err := ends.decode(raw)
+		if err != nil {
+			return err
+		}
+		src, ok1 := object(ends.Tail).(*Node)
+		dst, ok2 := object(ends.Head).(*Node)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("builder: edge between invalid objects %d and %d", ends.Tail, ends.Head)
+		}
+		e := &Edge{Src: src, Dst: dst}
+		if err := setAttributeMap(e, graphvizAttributes(obj), false); err != nil {
+			return err
+		}
+		gb.AddEdges(e)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "bytes"
+import "encoding/json"
+import "errors"
+import "strings"
+import "testing"
+
+import "godot/attr"
+
+func dotOf(t *testing.T, g *Graph) string {
+	var buf bytes.Buffer
+	if err := g.Build().Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+const jsonSource = `digraph {
+	label="Services";
+	rankdir=LR;
+	node [shape=box];
+	edge [color=gray];
+	a [pos="1,2!", fillcolor=red];
+	subgraph cluster_0 { label=Backend; b; subgraph cluster_1 { c } }
+	subgraph { d }
+	a -> b [label=reads]; b -> c; c -> d; d -> a;
+}`
+
+func TestJSONRoundTrip(t *testing.T) {
+	g, err := Parse(strings.NewReader(jsonSource))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var back Graph
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, data)
+	}
+	if want, got := dotOf(t, g), dotOf(t, &back); got != want {
+		t.Errorf("Round trip through\n%s\ngave\n%s\nwanted\n%s", data, got, want)
+	}
+	for _, want := range []string{
+		`"directed":true`,
+		`"nodeTemplate":{"shape":"box"}`,
+		`{"tail":"0","head":"1","attributes":{"label":"reads"}}`,
+		`"name":"cluster_0"`,
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("JSON does not contain %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"nodes": [{"id": "a", "attributes": {"colour": "red"}}]}`,
+		`{"nodes": [{"id": "a"}, {"id": "a"}]}`,
+		`{"nodes": [{"id": "a"}], "edges": [{"tail": "a", "head": "b"}]}`,
+		`{"nodes": [{"id": "a"}], "subgraphs": [{"nodes": ["b"]}]}`,
+		`{"attributes": {"rankdir": "sideways"}}`,
+		`[]`,
+	} {
+		var g Graph
+		if err := json.Unmarshal([]byte(src), &g); err == nil {
+			t.Errorf("Unmarshal of %s did not fail.", src)
+		}
+	}
+	var g Graph
+	err := json.Unmarshal([]byte(`{"attributes": {"colour": "red"}}`), &g)
+	if !errors.Is(err, ErrUnknownAttribute) {
+		t.Errorf("Unmarshal of an unknown attribute returned %v.", err)
+	}
+}
+
+func TestGraphvizJSON(t *testing.T) {
+	g, err := Parse(strings.NewReader(jsonSource))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data, err := g.MarshalGraphvizJSON()
+	if err != nil {
+		t.Fatalf("MarshalGraphvizJSON failed: %v", err)
+	}
+	for _, want := range []string{
+		`"_subgraph_cnt":3`,
+		`"name":"cluster_0","nodes":[4,5],"subgraphs":[1]`,
+		`"_gvid":3,"fillcolor":"red","label":"a","name":"0","pos":"1.000000,2.000000!","shape":"box"`,
+		`"_gvid":0,"color":"gray","head":4,"label":"reads","tail":3`,
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("JSON does not contain %s:\n%s", want, data)
+		}
+	}
+
+	var back Graph
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, data)
+	}
+	if back.Kind() != attr.Directed || back.Label != "Services" {
+		t.Errorf("Graph attributes were not read.")
+	}
+	nodes := back.Nodes()
+	if len(nodes) != 4 || nodes[0].Label != "a" || nodes[0].Shape.String() != "box" {
+		t.Fatalf("Graph has nodes %v.", nodes)
+	}
+	subs := back.Subgraphs()
+	if len(subs) != 2 || subs[0].Name != "cluster_0" || subs[1].Name != "" {
+		t.Fatalf("Graph has subgraphs %v.", subs)
+	}
+	if inner := subs[0].Subgraphs(); len(subs[0].Nodes()) != 1 || len(inner) != 1 || inner[0].Nodes()[0] != nodes[2] {
+		t.Errorf("Nested subgraphs were not read.")
+	}
+	if edges := back.Edges(); len(edges) != 4 || edges[0].Src != nodes[0] || edges[0].Dst != nodes[1] || edges[0].Label != "reads" {
+		t.Errorf("Graph has edges %v.", edges)
+	}
+}
+
+func TestUnmarshalGraphvizJSON(t *testing.T) {
+	// As written by "dot -Tjson0".
+	src := `{
+		"name": "G", "directed": false, "strict": false, "bb": "0,0,62,108",
+		"label": "Net", "_subgraph_cnt": 1,
+		"objects": [
+			{"_gvid": 0, "name": "%1", "bb": "8,8,54,100", "nodes": [1], "edges": []},
+			{"_gvid": 1, "name": "x", "height": "0.5", "label": "\\N", "pos": "27,90", "width": "0.75"},
+			{"_gvid": 2, "name": "y", "label": "Why"}
+		],
+		"edges": [
+			{"_gvid": 0, "tail": 1, "head": 2, "pos": "27,72 27,36", "color": "red"}
+		]
+	}`
+	var g Graph
+	if err := json.Unmarshal([]byte(src), &g); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	nodes := g.Nodes()
+	if g.Kind() != attr.Undirected || g.Label != "Net" || len(nodes) != 2 {
+		t.Fatalf("Graph was not read: %s", dotOf(t, &g))
+	}
+	if nodes[0].Label != "x" || nodes[1].Label != "Why" || nodes[0].Position == nil {
+		t.Errorf("Node attributes were not read: %s", dotOf(t, &g))
+	}
+	if subs := g.Subgraphs(); len(subs) != 1 || subs[0].Name != "" || len(subs[0].Nodes()) != 1 {
+		t.Errorf("Subgraph was not read: %v", subs)
+	}
+	if edges := g.Edges(); len(edges) != 1 || edges[0].Color.String() != "red" {
+		t.Errorf("Edge was not read: %v", edges)
+	}
+
+	bad := strings.Replace(src, `"head": 2`, `"head": 0`, 1)
+	if err := json.Unmarshal([]byte(bad), &g); err == nil {
+		t.Errorf("Unmarshal of an edge to a subgraph did not fail.")
+	}
+}
@@ -21,6 +21,10 @@ import "godot/attr/color"
 // A builder for a dot edge.
 // For a list of all dot attrs, see: http://www.graphviz.org/doc/info/attrs.html
 type Node struct {
+	// Classes of the SVG element drawn for the node.  (SVG only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:class
+	Class string `name:"class"`
+
 	// Color used for node.
 	Color color.Color `name:"color"`
 
@@ -34,12 +38,25 @@ type Node struct {
 
 	Group string `name:"group"`
 
+	// Height of the node (inches).
+	// http://www.graphviz.org/doc/info/attrs.html#d:height
+	Height string `name:"height"`
+
+	// Identifier of the SVG element drawn for the node.  (SVG, PostScript and
+	// image maps only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:id
+	ID string `name:"id"`
+
 	// Label attached to node.
 	// BUG: To make the label appear blank, specify a space: " " 
 	// This bug will be fixed at some point, I am just not sure the best way of
 	// going about it.
 	Label string `name:"label"`
 
+	// Width of the pen used to draw the node's outline (points).
+	// http://www.graphviz.org/doc/info/attrs.html#d:penwidth
+	Penwidth string `name:"penwidth"`
+
 	// Position of the node (inches)
 	Position *attr.Point `name:"pos"`
 
@@ -49,6 +66,27 @@ type Node struct {
 	// Set style information for the node.
 	// http://www.graphviz.org/doc/info/attrs.html#d:style
 	Style string `name:"style"`
+
+	// Tooltip shown when the pointer is over the node.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:tooltip
+	Tooltip string `name:"tooltip"`
+
+	// Width of the node (inches).
+	// http://www.graphviz.org/doc/info/attrs.html#d:width
+	Width string `name:"width"`
+
+	// Hyperlink followed when the node is clicked.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:URL
+	URL string `name:"URL"`
+}
+
+// Sets the node attribute with the given dot name, for example "label", from
+// its dot representation.  Returns an error if the node has no such
+// attribute, or if the value is invalid.
+func (nb *Node) SetAttribute(name string, value string) error {
+	return setKnownAttribute(nb, name, value)
 }
 
 func (nb Node) buildAttributes() nodeattrs {
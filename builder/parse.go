@@ -0,0 +1,778 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "bufio"
+import "bytes"
+import "fmt"
+import "io"
+import "strings"
+
+import "godot/attr"
+
+// The kinds of token produced by the lexer.
+const (
+	tokEOF = iota
+	tokID
+	tokQuoted
+	tokEdgeOp
+	tokPunct
+)
+
+type token struct {
+	kind int
+	text string
+	line int
+}
+
+// Splits dot source into tokens.  Comments, and lines starting with '#'
+// (preprocessor output), are skipped.
+type lexer struct {
+	src  *bufio.Reader
+	line int
+
+	// Whether only spaces have been read since the last newline, or the
+	// start of the source.
+	lineStart bool
+}
+
+func (l *lexer) read() (rune, bool) {
+	r, _, err := l.src.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	if r == '\n' {
+		l.line++
+	}
+	return r, true
+}
+
+func (l *lexer) unread(r rune) {
+	l.src.UnreadRune()
+	if r == '\n' {
+		l.line--
+	}
+}
+
+func (l *lexer) peek() rune {
+	r, ok := l.read()
+	if !ok {
+		return 0
+	}
+	l.unread(r)
+	return r
+}
+
+func (l *lexer) skipSpace() error {
+	for {
+		r, ok := l.read()
+		if !ok {
+			return nil
+		}
+		switch {
+		case r == '\n':
+			l.lineStart = true
+			continue
+		case r == ' ' || r == '\t' || r == '\r':
+			continue
+		case r == '#' && l.lineStart:
+			for r != '\n' {
+				if r, ok = l.read(); !ok {
+					return nil
+				}
+			}
+			continue
+		case r == '/':
+			// A slash starts a comment only if followed by another slash or
+			// a star; otherwise both runes are left to the tokenizer, which
+			// rejects the slash.
+			r2, ok := l.read()
+			switch {
+			case ok && r2 == '/':
+				for r2 != '\n' {
+					if r2, ok = l.read(); !ok {
+						return nil
+					}
+				}
+				l.lineStart = true
+				continue
+			case ok && r2 == '*':
+				line := l.line
+				prev := rune(0)
+				for {
+					if r2, ok = l.read(); !ok {
+						return fmt.Errorf("builder: line %d: unterminated comment", line)
+					}
+					if prev == '*' && r2 == '/' {
+						break
+					}
+					prev = r2
+				}
+				l.lineStart = false
+				continue
+			}
+			if ok {
+				l.unread(r2)
+			}
+			l.lineStart = false
+			return fmt.Errorf("builder: line %d: unexpected character %q", l.line, r)
+		}
+		l.unread(r)
+		l.lineStart = false
+		return nil
+	}
+}
+
+func isIDRune(r rune, first bool) bool {
+	switch {
+	case r == '_' || r >= 0200:
+		return true
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return true
+	case r >= '0' && r <= '9':
+		return !first
+	}
+	return false
+}
+
+func isNumeralRune(r rune) bool {
+	return r == '.' || (r >= '0' && r <= '9')
+}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	line := l.line
+	r, ok := l.read()
+	if !ok {
+		return token{tokEOF, "", line}, nil
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case r == '-' && (l.peek() == '>' || l.peek() == '-'):
+		op, _ := l.read()
+		return token{tokEdgeOp, string([]rune{r, op}), line}, nil
+	case strings.ContainsRune("{}[]=;,:", r):
+		return token{tokPunct, string(r), line}, nil
+	case r == '"':
+		return l.quoted(line)
+	case r == '<':
+		return l.html(line)
+	case isIDRune(r, true):
+		for ; ok && isIDRune(r, false); r, ok = l.read() {
+			buf.WriteRune(r)
+		}
+	case r == '-' || isNumeralRune(r):
+		buf.WriteRune(r)
+		for r, ok = l.read(); ok && isNumeralRune(r); r, ok = l.read() {
+			buf.WriteRune(r)
+		}
+	default:
+		return token{}, fmt.Errorf("builder: line %d: unexpected character %q", line, r)
+	}
+	if ok {
+		l.unread(r)
+	}
+	return token{tokID, buf.String(), line}, nil
+}
+
+// Reads a double quoted string, the opening quote having been consumed.
+// Escaped quotes are unescaped, escaped newlines are removed and all other
+// escape sequences are kept as they are, since they have meaning to Graphviz.
+// Strings joined with '+' are concatenated.
+func (l *lexer) quoted(line int) (token, error) {
+	var buf bytes.Buffer
+	for {
+		r, ok := l.read()
+		if !ok {
+			return token{}, fmt.Errorf("builder: line %d: unterminated string", line)
+		}
+		if r == '"' {
+			break
+		}
+		if r == '\\' {
+			next, ok := l.read()
+			if !ok {
+				continue
+			}
+			switch next {
+			case '"':
+				buf.WriteRune(next)
+			case '\n':
+			default:
+				buf.WriteRune(r)
+				buf.WriteRune(next)
+			}
+			continue
+		}
+		buf.WriteRune(r)
+	}
+
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	if r, ok := l.read(); ok {
+		if r == '+' {
+			if err := l.skipSpace(); err != nil {
+				return token{}, err
+			}
+			if q, ok := l.read(); ok && q == '"' {
+				more, err := l.quoted(line)
+				if err != nil {
+					return token{}, err
+				}
+				buf.WriteString(more.text)
+			} else {
+				return token{}, fmt.Errorf("builder: line %d: expected string after '+'", l.line)
+			}
+		} else {
+			l.unread(r)
+		}
+	}
+	return token{tokQuoted, buf.String(), line}, nil
+}
+
+// Reads an HTML string, the opening '<' having been consumed.  The outermost
+// angle brackets are not included in the token text.
+func (l *lexer) html(line int) (token, error) {
+	var buf bytes.Buffer
+	depth := 1
+	for {
+		r, ok := l.read()
+		if !ok {
+			return token{}, fmt.Errorf("builder: line %d: unterminated HTML string", line)
+		}
+		if r == '<' {
+			depth++
+		} else if r == '>' {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+		buf.WriteRune(r)
+	}
+	return token{tokQuoted, buf.String(), line}, nil
+}
+
+// Attribute defaults in effect within a graph or subgraph.
+type scope struct {
+	nodeDefs [][2]string
+	edgeDefs [][2]string
+
+	// The subgraph declared by the scope, if any, and the subgraph which
+	// nodes mentioned within the scope belong to, if any.
+	owner *Subgraph
+	sub   *Subgraph
+
+	// Nodes mentioned within the scope, in order, for use as edge endpoints.
+	nodes []*Node
+}
+
+type parser struct {
+	lex      *lexer
+	tok      token
+	graph    *Graph
+	edgeop   string
+	names    map[string]*Node
+	nodes    []*Node
+	order    []string
+	labelled map[*Node]bool
+	subs     map[string]*Subgraph
+
+	// Every attribute as written, by object, if the caller wants them.
+	raw map[interface{}]map[string]string
+}
+
+// Parses a graph written in the dot language.  Only the first graph in "r" is
+// read.
+//
+// Attributes are assigned to the fields of Graph, Node and Edge tagged with
+// their names; attributes without a corresponding field are ignored.  Node
+// and edge attribute statements at the top level of the graph become the node
+// and edge templates.  Since nodes in a Graph have no names, a node without a
+// label is given its name as its label, unless the node template has a label.
+//
+// Statements introduced by the "subgraph" keyword become Subgraphs, and
+// subgraphs with the same name are merged.  Anonymous groups of statements in
+// braces, as in "a -> { b c }", are flattened into the enclosing graph or
+// subgraph.  Edges always belong to the graph, and ports are ignored.
+func Parse(r io.Reader) (*Graph, error) {
+	p := newParser(r)
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.graph, nil
+}
+
+// A graph read by ParseRaw, with what Parse discards.
+type RawGraph struct {
+	Graph *Graph
+
+	// The name of each node in the source.
+	Names map[*Node]string
+
+	// Every attribute set on each object, the *Graph, or a *Subgraph, *Node
+	// or *Edge, as written in the source, including those without a
+	// corresponding field.  Attributes of the node and edge templates are
+	// not repeated for each node and edge.
+	Attributes map[interface{}]map[string]string
+}
+
+// Parses a graph written in the dot language, as Parse does, also returning
+// the names of its nodes and all of its attributes.  Used to read the output
+// of Graphviz, whose layout attributes, such as "pos" on edges, have no
+// fields.
+func ParseRaw(r io.Reader) (*RawGraph, error) {
+	p := newParser(r)
+	p.raw = make(map[interface{}]map[string]string)
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	names := make(map[*Node]string, len(p.names))
+	for name, n := range p.names {
+		names[n] = name
+	}
+	return &RawGraph{Graph: p.graph, Names: names, Attributes: p.raw}, nil
+}
+
+func newParser(r io.Reader) *parser {
+	return &parser{
+		lex:      &lexer{src: bufio.NewReader(r), line: 1, lineStart: true},
+		names:    make(map[string]*Node),
+		labelled: make(map[*Node]bool),
+		subs:     make(map[string]*Subgraph),
+	}
+}
+
+func (p *parser) parse() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	return p.parseGraph()
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("builder: line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+}
+
+// Reports whether the current token is the keyword "kw".
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokID && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) punct(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		return p.errorf("expected %q, found %q", s, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *parser) id() (string, error) {
+	if p.tok.kind != tokID && p.tok.kind != tokQuoted {
+		return "", p.errorf("expected identifier, found %q", p.tok.text)
+	}
+	text := p.tok.text
+	return text, p.advance()
+}
+
+func (p *parser) parseGraph() error {
+	if p.keyword("strict") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+
+	var kind *attr.GraphKind
+	switch {
+	case p.keyword("graph"):
+		kind = attr.Undirected
+	case p.keyword("digraph"):
+		kind = attr.Directed
+	default:
+		return p.errorf("expected graph or digraph, found %q", p.tok.text)
+	}
+	p.graph = NewGraph(kind)
+	p.edgeop = kind.Delimiter()
+	if err := p.advance(); err != nil {
+		return err
+	}
+
+	if !p.punct("{") {
+		if _, err := p.id(); err != nil {
+			return err
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	if err := p.parseStmts(&scope{}, true); err != nil {
+		return err
+	}
+	if err := p.expect("}"); err != nil {
+		return err
+	}
+
+	if tmpl := p.graph.NodeTemplate(); tmpl == nil || tmpl.Label == "" {
+		for i, n := range p.nodes {
+			if !p.labelled[n] {
+				n.Label = p.order[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseStmts(s *scope, top bool) error {
+	for !p.punct("}") {
+		if p.tok.kind == tokEOF {
+			return p.errorf("unexpected end of input")
+		}
+		if err := p.parseStmt(s, top); err != nil {
+			return err
+		}
+		if p.punct(";") {
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseStmt(s *scope, top bool) error {
+	switch {
+	case p.keyword("graph") || p.keyword("node") || p.keyword("edge"):
+		kw := strings.ToLower(p.tok.text)
+		if err := p.advance(); err != nil {
+			return err
+		}
+		atrs, err := p.parseAttrList()
+		if err != nil {
+			return err
+		}
+		return p.applyDefaults(s, top, kw, atrs)
+	case p.keyword("subgraph") || p.punct("{"):
+		nodes, err := p.parseSubgraph(s)
+		if err != nil {
+			return err
+		}
+		return p.parseEdgeRHS(s, nodes)
+	}
+
+	name, err := p.id()
+	if err != nil {
+		return err
+	}
+	if p.punct("=") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		value, err := p.id()
+		if err != nil {
+			return err
+		}
+		return p.setGraph(s, top, name, value)
+	}
+
+	if err := p.skipPort(); err != nil {
+		return err
+	}
+	node, err := p.node(s, name)
+	if err != nil {
+		return err
+	}
+	if p.tok.kind == tokEdgeOp {
+		return p.parseEdgeRHS(s, []*Node{node})
+	}
+	atrs, err := p.parseAttrList()
+	if err != nil {
+		return err
+	}
+	for _, a := range atrs {
+		if err := p.setNode(node, a[0], a[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) skipPort() error {
+	for i := 0; i < 2 && p.punct(":"); i++ {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.id(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) applyDefaults(s *scope, top bool, kw string, atrs [][2]string) error {
+	switch kw {
+	case "graph":
+		for _, a := range atrs {
+			if err := p.setGraph(s, top, a[0], a[1]); err != nil {
+				return err
+			}
+		}
+	case "node":
+		if !top {
+			s.nodeDefs = append(s.nodeDefs, atrs...)
+			break
+		}
+		if p.graph.NodeTemplate() == nil {
+			p.graph.SetNodeTemplate(new(Node))
+		}
+		for _, a := range atrs {
+			if err := p.set(p.graph.NodeTemplate(), a[0], a[1]); err != nil {
+				return err
+			}
+		}
+	case "edge":
+		if !top {
+			s.edgeDefs = append(s.edgeDefs, atrs...)
+			break
+		}
+		if p.graph.EdgeTemplate() == nil {
+			p.graph.SetEdgeTemplate(new(Edge))
+		}
+		for _, a := range atrs {
+			if err := p.set(p.graph.EdgeTemplate(), a[0], a[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sets an attribute of the graph, or of the subgraph declared by the scope.
+// Attributes of anonymous subgraphs are ignored.
+func (p *parser) setGraph(s *scope, top bool, name string, value string) error {
+	switch {
+	case top:
+		return p.set(p.graph, name, value)
+	case s.owner != nil:
+		return p.set(s.owner, name, value)
+	}
+	return nil
+}
+
+// Parses a subgraph, returning the nodes mentioned within it.
+func (p *parser) parseSubgraph(parent *scope) ([]*Node, error) {
+	s := &scope{
+		nodeDefs: append([][2]string(nil), parent.nodeDefs...),
+		edgeDefs: append([][2]string(nil), parent.edgeDefs...),
+		sub:      parent.sub,
+	}
+
+	if p.keyword("subgraph") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name := ""
+		if !p.punct("{") {
+			var err error
+			if name, err = p.id(); err != nil {
+				return nil, err
+			}
+		}
+		s.owner = p.subgraph(parent, name)
+		s.sub = s.owner
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseStmts(s, false); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	parent.nodes = append(parent.nodes, s.nodes...)
+	return s.nodes, nil
+}
+
+// Parses the remainder of an edge statement whose first operand is "tails".
+// Does nothing if the current token is not an edge operator.
+func (p *parser) parseEdgeRHS(s *scope, tails []*Node) error {
+	var pairs [][2]*Node
+	for p.tok.kind == tokEdgeOp {
+		if p.tok.text != p.edgeop {
+			return p.errorf("edge operator %q used in %s", p.tok.text, p.graph.Kind().Name())
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+
+		var heads []*Node
+		if p.keyword("subgraph") || p.punct("{") {
+			nodes, err := p.parseSubgraph(s)
+			if err != nil {
+				return err
+			}
+			heads = nodes
+		} else {
+			name, err := p.id()
+			if err != nil {
+				return err
+			}
+			if err := p.skipPort(); err != nil {
+				return err
+			}
+			node, err := p.node(s, name)
+			if err != nil {
+				return err
+			}
+			heads = []*Node{node}
+		}
+
+		for _, t := range tails {
+			for _, h := range heads {
+				pairs = append(pairs, [2]*Node{t, h})
+			}
+		}
+		tails = heads
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	atrs, err := p.parseAttrList()
+	if err != nil {
+		return err
+	}
+	atrs = append(append([][2]string(nil), s.edgeDefs...), atrs...)
+	for _, pair := range pairs {
+		edge := &Edge{Src: pair[0], Dst: pair[1]}
+		for _, a := range atrs {
+			if err := p.set(edge, a[0], a[1]); err != nil {
+				return err
+			}
+		}
+		p.graph.AddEdges(edge)
+	}
+	return nil
+}
+
+// Returns the subgraph called "name", creating it within the parent scope's
+// subgraph, or the graph, if necessary.  Unnamed subgraphs are always new.
+func (p *parser) subgraph(parent *scope, name string) *Subgraph {
+	if sub, ok := p.subs[name]; ok && name != "" {
+		return sub
+	}
+	sub := NewSubgraph(name)
+	p.subs[name] = sub
+	if parent.sub != nil {
+		parent.sub.AddSubgraphs(sub)
+	} else {
+		p.graph.AddSubgraphs(sub)
+	}
+	return sub
+}
+
+// Returns the node called "name", creating it if necessary.
+func (p *parser) node(s *scope, name string) (*Node, error) {
+	node, ok := p.names[name]
+	if !ok {
+		node = new(Node)
+		p.names[name] = node
+		p.nodes = append(p.nodes, node)
+		p.order = append(p.order, name)
+		p.graph.AddNodes(node)
+		for _, a := range s.nodeDefs {
+			if err := p.setNode(node, a[0], a[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.sub != nil {
+		s.sub.AddNodes(node)
+	}
+	s.nodes = append(s.nodes, node)
+	return node, nil
+}
+
+func (p *parser) setNode(n *Node, name string, value string) error {
+	if name == "label" {
+		p.labelled[n] = true
+	}
+	return p.set(n, name, value)
+}
+
+func (p *parser) set(obj interface{}, name string, value string) error {
+	if p.raw != nil {
+		if p.raw[obj] == nil {
+			p.raw[obj] = make(map[string]string)
+		}
+		p.raw[obj][name] = value
+	}
+	if _, err := setAttribute(obj, name, value); err != nil {
+		return p.errorf("%s", err)
+	}
+	return nil
+}
+
+// Parses zero or more bracketed attribute lists.
+func (p *parser) parseAttrList() ([][2]string, error) {
+	var atrs [][2]string
+	for p.punct("[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.punct("]") {
+			name, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			value := "true"
+			if p.punct("=") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				if value, err = p.id(); err != nil {
+					return nil, err
+				}
+			}
+			atrs = append(atrs, [2]string{name, value})
+			if p.punct(",") || p.punct(";") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return atrs, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/attr/color"
+
+func TestParse(t *testing.T) {
+	src := `/* comment */
+digraph G {
+	label = "A \"quoted\" label";
+	node [shape=circle];
+	a [fillcolor=red, pos="1,2!"];
+	a -> b -> { c d } [label=x]; // comment
+	subgraph s { node [style=filled]; e }
+}`
+	g, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Kind() != attr.Directed {
+		t.Errorf("Graph should be directed.")
+	}
+	if g.Label != `A "quoted" label` {
+		t.Errorf("Graph label is incorrect: %q", g.Label)
+	}
+	if g.NodeTemplate() == nil || g.NodeTemplate().Shape != attr.Circle {
+		t.Errorf("Node template was not set.")
+	}
+
+	nodes := g.Nodes()
+	if len(nodes) != 5 {
+		t.Fatalf("Length is incorrect.  Should be 5, but is %d.", len(nodes))
+	}
+	a := nodes[0]
+	if a.Label != "a" || a.FillColor != color.Red {
+		t.Errorf("Node attributes were not set.")
+	}
+	if a.Position == nil || a.Position.X != 1 || a.Position.Y != 2 || !a.Position.Lock {
+		t.Errorf("Node position was not set.")
+	}
+	if nodes[4].Style != "filled" {
+		t.Errorf("Subgraph node defaults were not applied.")
+	}
+
+	subs := g.Subgraphs()
+	if len(subs) != 1 || subs[0].Name != "s" {
+		t.Fatalf("Subgraph was not created.")
+	}
+	if l := subs[0].Nodes(); len(l) != 1 || l[0] != nodes[4] {
+		t.Errorf("Subgraph nodes are incorrect.")
+	}
+
+	edges := g.Edges()
+	if len(edges) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(edges))
+	}
+	if edges[2].Src != nodes[1] || edges[2].Dst != nodes[3] || edges[2].Label != "x" {
+		t.Errorf("Edge chain was not expanded.")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"digraph { a -- b }",
+		"graph { a -- }",
+		"graph { a [label=\"x] }",
+		"tree { }",
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("Expected an error parsing %q.", src)
+		}
+	}
+}
+
+func TestParseSlash(t *testing.T) {
+	for _, src := range []string{
+		"digraph { a [label=x/y] }",
+		"graph { a / b }",
+	} {
+		_, err := Parse(strings.NewReader(src))
+		if err == nil || !strings.Contains(err.Error(), "'/'") {
+			t.Errorf("Parsing %q gave %v.", src, err)
+		}
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	g, err := Parse(strings.NewReader("# 1\ndigraph { a -> b # x\n c }"))
+	if err == nil {
+		t.Errorf("A # within a line was read as a comment.")
+	}
+	g, err = Parse(strings.NewReader("digraph { a // x\n# y\n  # z\nb /* w */ }"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(g.Nodes()) != 2 {
+		t.Errorf("Comments were read as nodes: %d nodes.", len(g.Nodes()))
+	}
+}
+
+func TestParseWrite(t *testing.T) {
+	var b bytes.Buffer
+
+	dot := `graph {
+
+	label="test"
+
+	node [
+		label=" "
+		shape="box"
+	]
+
+	0 [fillcolor="red"];
+	1;
+
+	0 -- 1 [label="a \"b\""];
+}
+`
+	g, err := Parse(strings.NewReader(dot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Build().Write(&b)
+
+	if dot != b.String() {
+		t.Errorf("Output was incorrect.")
+	}
+}
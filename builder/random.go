@@ -0,0 +1,133 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "math/rand"
+
+import "godot/attr"
+
+// Generates an Erdos-Renyi random graph G(n,p), in which each pair of nodes
+// is joined with probability "p".  In directed graphs each ordered pair of
+// nodes is considered, so edges may lead either way.  The same seed always
+// produces the same graph.
+func GenErdosRenyi(n int, p float64, seed int64, opts *GenOptions) *Graph {
+	checkSizes("GenErdosRenyi", n)
+	rng := rand.New(rand.NewSource(seed))
+	gen := newGenerator(n, opts)
+	directed := gen.graph.Kind() == attr.Directed
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j || (!directed && j < i) {
+				continue
+			}
+			if rng.Float64() < p {
+				gen.link(i, j)
+			}
+		}
+	}
+	return gen.graph
+}
+
+// Generates a Barabasi-Albert scale-free random graph of "n" nodes.  Starting
+// from "m" unconnected nodes, each further node is joined to "m" distinct
+// earlier nodes, chosen with probability proportional to their degree.  In
+// directed graphs edges lead from each new node to the nodes it joins.  The
+// same seed always produces the same graph.
+func GenBarabasiAlbert(n, m int, seed int64, opts *GenOptions) *Graph {
+	checkSizes("GenBarabasiAlbert", n, m)
+	rng := rand.New(rand.NewSource(seed))
+	gen := newGenerator(n, opts)
+	if m < 1 {
+		return gen.graph
+	}
+
+	// Each node appears in "repeated" once per edge it has, so that picking
+	// from it uniformly picks nodes in proportion to their degree.
+	var repeated []int
+	targets := make([]int, 0, m)
+	for i := 0; i < m && i < n; i++ {
+		targets = append(targets, i)
+	}
+	for v := m; v < n; v++ {
+		for _, t := range targets {
+			gen.link(v, t)
+			repeated = append(repeated, v, t)
+		}
+		chosen := make(map[int]bool)
+		targets = targets[:0]
+		for len(targets) < m {
+			t := repeated[rng.Intn(len(repeated))]
+			if !chosen[t] {
+				chosen[t] = true
+				targets = append(targets, t)
+			}
+		}
+	}
+	return gen.graph
+}
+
+// Generates a Watts-Strogatz small-world random graph of "n" nodes.  A ring
+// is formed with each node joined to its "k" nearest neighbours (k/2 on each
+// side), then the far end of each edge is moved to a random node with
+// probability "beta", avoiding self-loops and parallel edges.  The same seed
+// always produces the same graph.
+func GenWattsStrogatz(n, k int, beta float64, seed int64, opts *GenOptions) *Graph {
+	checkSizes("GenWattsStrogatz", n, k)
+	rng := rand.New(rand.NewSource(seed))
+	gen := newGenerator(n, opts)
+
+	type pair struct{ a, b int }
+	key := func(a, b int) pair {
+		if a > b {
+			a, b = b, a
+		}
+		return pair{a, b}
+	}
+
+	var edges []pair
+	exists := make(map[pair]bool)
+	for j := 1; j <= k/2 && j < n; j++ {
+		for i := 0; i < n; i++ {
+			p := key(i, (i+j)%n)
+			if !exists[p] {
+				exists[p] = true
+				edges = append(edges, pair{i, (i + j) % n})
+			}
+		}
+	}
+
+	for e, edge := range edges {
+		if rng.Float64() >= beta {
+			continue
+		}
+		u := edge.a
+		w := rng.Intn(n)
+		for tries := 0; (w == u || exists[key(u, w)]) && tries < n; tries++ {
+			w = rng.Intn(n)
+		}
+		if w == u || exists[key(u, w)] {
+			continue
+		}
+		delete(exists, key(edge.a, edge.b))
+		exists[key(u, w)] = true
+		edges[e].b = w
+	}
+
+	for _, edge := range edges {
+		gen.join(edge.a, edge.b)
+	}
+	return gen.graph
+}
@@ -0,0 +1,204 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import "godot/attr/color"
+import "godot/set"
+
+// A builder for a subgraph.  Subgraphs group nodes so that attributes can be
+// applied to them together.  Subgraphs whose names begin with "cluster" are
+// drawn by dot with their nodes laid out together inside a box.
+//
+// References:
+//   http://www.graphviz.org/doc/info/lang.html#h:subgraphs
+type Subgraph struct {
+	nodes     set.OrderedSet
+	subgraphs set.OrderedSet
+
+	// Name of the subgraph.  May be empty.
+	Name string
+
+	// Classes of the SVG element drawn for the cluster.  (SVG only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:class
+	Class string `name:"class"`
+
+	// Color used for the cluster's outline.
+	Color color.Color `name:"color"`
+
+	// Color used to fill the background of the cluster, assuming
+	// style=filled.
+	// http://www.graphviz.org/doc/info/attrs.html#d:fillcolor
+	FillColor color.Color `name:"fillcolor"`
+
+	// Color used for text.
+	FontColor color.Color `name:"fontcolor"`
+
+	// Identifier of the SVG element drawn for the cluster.  (SVG, PostScript and
+	// image maps only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:id
+	ID string `name:"id"`
+
+	// Label of the cluster.
+	Label string `name:"label"`
+
+	// Set style information for the cluster.
+	// http://www.graphviz.org/doc/info/attrs.html#d:style
+	Style string `name:"style"`
+
+	// Tooltip shown when the pointer is over the cluster.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:tooltip
+	Tooltip string `name:"tooltip"`
+
+	// Hyperlink followed when the cluster is clicked.  (SVG and image maps
+	// only)
+	// http://www.graphviz.org/doc/info/attrs.html#d:URL
+	URL string `name:"URL"`
+}
+
+// Convenience constructor for the subgraph builder, which populates all
+// required fields.
+func NewSubgraph(name string) *Subgraph {
+	return &Subgraph{nodes: set.New(), subgraphs: set.New(), Name: name}
+}
+
+// Returns a slice of the nodes directly within the subgraph, not including
+// those of nested subgraphs.
+func (sb *Subgraph) Nodes() []*Node {
+	nodes := make([]*Node, 0, sb.nodes.Count())
+
+	sb.nodes.Visit(func(e interface{}) {
+		nodes = append(nodes, e.(*Node))
+	})
+
+	return nodes
+}
+
+// Adds nodes to the subgraph, returns number of nodes added.
+// Adding a node to the subgraph multiple times has no effect.
+func (sb *Subgraph) AddNodes(nodes ...*Node) int {
+	count := 0
+	for _, n := range nodes {
+		if sb.nodes.Add(n) {
+			count++
+		}
+	}
+	return count
+}
+
+// Removes nodes from the subgraph, returns number of nodes removed.
+func (sb *Subgraph) RemoveNodes(nodes ...*Node) int {
+	count := 0
+	for _, n := range nodes {
+		if sb.nodes.Remove(n) {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns a slice of the subgraphs nested directly within this one.
+func (sb *Subgraph) Subgraphs() []*Subgraph {
+	return subgraphSlice(sb.subgraphs)
+}
+
+// Nests subgraphs within this one, returns number of subgraphs added.
+func (sb *Subgraph) AddSubgraphs(subs ...*Subgraph) int {
+	return addSubgraphs(sb.subgraphs, subs)
+}
+
+// Removes nested subgraphs, returns number of subgraphs removed.
+func (sb *Subgraph) RemoveSubgraphs(subs ...*Subgraph) int {
+	return removeSubgraphs(sb.subgraphs, subs)
+}
+
+// Returns every node in the subgraph, including those of nested subgraphs,
+// each once.
+func (sb *Subgraph) AllNodes() []*Node {
+	seen := set.New()
+	var nodes []*Node
+	var visit func(s *Subgraph)
+	visit = func(s *Subgraph) {
+		for _, n := range s.Nodes() {
+			if seen.Add(n) {
+				nodes = append(nodes, n)
+			}
+		}
+		for _, sub := range s.Subgraphs() {
+			visit(sub)
+		}
+	}
+	visit(sb)
+	return nodes
+}
+
+// Sets the subgraph attribute with the given dot name, for example "label",
+// from its dot representation.  Returns an error if the subgraph has no such
+// attribute, or if the value is invalid.
+func (sb *Subgraph) SetAttribute(name string, value string) error {
+	return setKnownAttribute(sb, name, value)
+}
+
+func (sb Subgraph) buildAttributes() graphattrs {
+	return buildAttributes(sb)
+}
+
+func (sb *Subgraph) build(nm map[*Node]*dotnode) *dotsubgraph {
+	sub := &dotsubgraph{name: sb.Name, attributes: sb.buildAttributes()}
+	sb.nodes.Visit(func(e interface{}) {
+		if node, ok := nm[e.(*Node)]; ok {
+			sub.nodes = append(sub.nodes, node)
+		}
+	})
+	sub.subgraphs = buildSubgraphs(sb.subgraphs, nm)
+	return sub
+}
+
+func subgraphSlice(subs set.OrderedSet) []*Subgraph {
+	slice := make([]*Subgraph, 0, subs.Count())
+	subs.Visit(func(e interface{}) {
+		slice = append(slice, e.(*Subgraph))
+	})
+	return slice
+}
+
+func addSubgraphs(set set.OrderedSet, subs []*Subgraph) int {
+	count := 0
+	for _, s := range subs {
+		if set.Add(s) {
+			count++
+		}
+	}
+	return count
+}
+
+func removeSubgraphs(set set.OrderedSet, subs []*Subgraph) int {
+	count := 0
+	for _, s := range subs {
+		if set.Remove(s) {
+			count++
+		}
+	}
+	return count
+}
+
+func buildSubgraphs(bldrs set.OrderedSet, nm map[*Node]*dotnode) []*dotsubgraph {
+	subs := make([]*dotsubgraph, 0, bldrs.Count())
+	bldrs.Visit(func(e interface{}) {
+		subs = append(subs, e.(*Subgraph).build(nm))
+	})
+	return subs
+}
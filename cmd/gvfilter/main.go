@@ -0,0 +1,195 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command gvfilter runs the graph filters in package godot/filter over a graph
+// written in the dot language, after the Graphviz programs of the same names.
+//
+// The graph is read with builder.Parse and written as package builder writes
+// it, so only what a builder.Graph holds is kept.  Unlike the Graphviz
+// programs, gvfilter renames nodes by their index, drops ports and the
+// graph's name, and loses attributes without a field in package builder,
+// such as splines, fontname or weight.
+//
+// Usage:
+//
+//   gvfilter tred [-d] [file]
+//   gvfilter ccomps [file]
+//   gvfilter sccmap [file]
+//   gvfilter acyclic [-n] [file]
+//   gvfilter unflatten [-l len] [-f] [-c len] [file]
+//   gvfilter nop [file]
+//
+// The graph is read from "file", or from standard input if no file is given.
+// Results are written to standard output.  Commands producing several graphs
+// write them one after the other.
+package main
+
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+
+import "godot/builder"
+import "godot/filter"
+
+type command func(args []string, in io.Reader, out io.Writer) error
+
+var commands = map[string]command{
+	"tred":      tred,
+	"ccomps":    ccomps,
+	"sccmap":    sccmap,
+	"acyclic":   acyclic,
+	"unflatten": unflatten,
+	"nop":       nop,
+}
+
+// Returned by acyclic -n when the graph has a cycle.
+var errCyclic = errors.New("graph is cyclic")
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gvfilter tred|ccomps|sccmap|acyclic|unflatten|nop [flags] [file]")
+	fmt.Fprintln(os.Stderr, "Node names, ports, the graph name and attributes unknown to godot are not kept.")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+		if err != errCyclic {
+			fmt.Fprintf(os.Stderr, "gvfilter %s: %s\n", os.Args[1], err)
+		}
+		os.Exit(1)
+	}
+}
+
+// Parses the flags of a command, then opens the named input file, if any.
+func parse(flags *flag.FlagSet, args []string, in io.Reader) (io.Reader, func(), error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if flags.NArg() == 0 {
+		return in, func() {}, nil
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// Parses the flags of a command, then reads the input graph.
+func read(flags *flag.FlagSet, args []string, in io.Reader) (*builder.Graph, error) {
+	r, done, err := parse(flags, args, in)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return builder.Parse(r)
+}
+
+func write(out io.Writer, graphs ...*builder.Graph) error {
+	for _, g := range graphs {
+		if err := g.Build().Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tred(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("tred", flag.ContinueOnError)
+	dashed := flags.Bool("d", false, "keep removed edges as dashed edges")
+	g, err := read(flags, args, in)
+	if err != nil {
+		return err
+	}
+	r, err := filter.Tred(g, &filter.TredOptions{Dashed: *dashed})
+	if err != nil {
+		return err
+	}
+	return write(out, r)
+}
+
+func ccomps(args []string, in io.Reader, out io.Writer) error {
+	g, err := read(flag.NewFlagSet("ccomps", flag.ContinueOnError), args, in)
+	if err != nil {
+		return err
+	}
+	return write(out, filter.Ccomps(g)...)
+}
+
+func sccmap(args []string, in io.Reader, out io.Writer) error {
+	g, err := read(flag.NewFlagSet("sccmap", flag.ContinueOnError), args, in)
+	if err != nil {
+		return err
+	}
+	scc, err := filter.Sccmap(g)
+	if err != nil {
+		return err
+	}
+	if err := write(out, scc.Components...); err != nil {
+		return err
+	}
+	return write(out, scc.Map)
+}
+
+func acyclic(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("acyclic", flag.ContinueOnError)
+	check := flags.Bool("n", false, "only check; exit with status 1 if the graph is cyclic")
+	g, err := read(flags, args, in)
+	if err != nil {
+		return err
+	}
+	r, changed, err := filter.Acyclic(g)
+	if err != nil {
+		return err
+	}
+	if *check {
+		if changed > 0 {
+			return errCyclic
+		}
+		return nil
+	}
+	return write(out, r)
+}
+
+func unflatten(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("unflatten", flag.ContinueOnError)
+	opts := new(filter.UnflattenOptions)
+	flags.IntVar(&opts.MaxMinlen, "l", 0, "stagger the minlen of leaf edges between 1 and `len`")
+	flags.BoolVar(&opts.Fanout, "f", false, "also stagger edges to chain nodes (with -l)")
+	flags.IntVar(&opts.ChainLimit, "c", 0, "form isolated nodes into chains of up to `len` nodes")
+	g, err := read(flags, args, in)
+	if err != nil {
+		return err
+	}
+	return write(out, filter.Unflatten(g, opts))
+}
+
+func nop(args []string, in io.Reader, out io.Writer) error {
+	r, done, err := parse(flag.NewFlagSet("nop", flag.ContinueOnError), args, in)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return filter.Nop(out, r)
+}
@@ -0,0 +1,235 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package coloring assigns colors to the nodes or edges of a builder.Graph so
+that no two adjacent nodes, or no two edges sharing a node, have the same
+color.
+
+Colors are numbered from zero.  Node colorings ignore edge direction and
+self-loops.  A coloring can be applied to the graph with a palette of
+color.Color values, for example:
+
+  palette := []color.Color{color.Red, color.Cornflowerblue, color.Chartreuse}
+  coloring.DSatur(g).Apply(palette)
+
+Resources:
+  http://en.wikipedia.org/wiki/Graph_coloring
+*/
+package coloring
+
+import "errors"
+import "fmt"
+import "sort"
+
+import "godot/attr/color"
+import "godot/builder"
+import "godot/internal/adj"
+
+// The largest graph, in nodes, that Exact will color.
+const MaxExactNodes = 64
+
+// Returned by Exact for graphs with more than MaxExactNodes nodes.
+var ErrTooLarge = errors.New("coloring: graph too large for an exact coloring")
+
+// A color index for each node of a graph.
+type Coloring map[*builder.Node]int
+
+// Returns the number of colors used.
+func (c Coloring) Count() int {
+	max := -1
+	for _, i := range c {
+		if i > max {
+			max = i
+		}
+	}
+	return max + 1
+}
+
+// Sets the FillColor of each node to its color from "palette".  Returns an
+// error, without changing any node, if the palette has too few colors.  Fill
+// colors are only drawn for nodes with style=filled.
+func (c Coloring) Apply(palette []color.Color) error {
+	if n := c.Count(); n > len(palette) {
+		return fmt.Errorf("coloring: %d colors needed but palette has %d", n, len(palette))
+	}
+	for n, i := range c {
+		n.FillColor = palette[i]
+	}
+	return nil
+}
+
+// Returns the color of each node by id as a Coloring.
+func coloring(ix *adj.Index, colors []int) Coloring {
+	c := make(Coloring, len(colors))
+	for v, n := range ix.Nodes {
+		c[n] = colors[v]
+	}
+	return c
+}
+
+// Returns the distinct neighbours of each node.
+func neighbors(ix *adj.Index) [][]int {
+	nbrs := make([][]int, ix.Len())
+	for v := range nbrs {
+		nbrs[v] = ix.Neighbors(v)
+	}
+	return nbrs
+}
+
+// Returns the smallest color not used by any colored neighbour of "v".
+func smallestFree(v int, nbrs [][]int, colors []int) int {
+	used := make(map[int]bool)
+	for _, w := range nbrs[v] {
+		if colors[w] >= 0 {
+			used[colors[w]] = true
+		}
+	}
+	c := 0
+	for used[c] {
+		c++
+	}
+	return c
+}
+
+// Colors the nodes greedily in the order given.
+func greedy(order []int, nbrs [][]int) []int {
+	colors := make([]int, len(nbrs))
+	for i := range colors {
+		colors[i] = -1
+	}
+	for _, v := range order {
+		colors[v] = smallestFree(v, nbrs, colors)
+	}
+	return colors
+}
+
+// Colors the graph using the Welsh-Powell algorithm: nodes are colored
+// greedily in order of decreasing degree, each taking the smallest color
+// unused by its neighbours.  Uses at most one more color than the largest
+// degree.  Ties are broken by the order the nodes were added to the graph.
+func WelshPowell(g *builder.Graph) Coloring {
+	ix := adj.New(g)
+	nbrs := neighbors(ix)
+	order := make([]int, ix.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(nbrs[order[i]]) > len(nbrs[order[j]])
+	})
+	return coloring(ix, greedy(order, nbrs))
+}
+
+// Colors the graph using Brelaz's DSatur algorithm: the next node colored is
+// always the one with the most distinctly colored neighbours, ties going to
+// the node with the most neighbours and then to the node added first.  Each
+// node takes the smallest color unused by its neighbours.  Usually uses fewer
+// colors than WelshPowell, and is exact for bipartite graphs.
+func DSatur(g *builder.Graph) Coloring {
+	ix := adj.New(g)
+	return coloring(ix, dsatur(neighbors(ix)))
+}
+
+func dsatur(nbrs [][]int) []int {
+	n := len(nbrs)
+	colors := make([]int, n)
+	for i := range colors {
+		colors[i] = -1
+	}
+	for colored := 0; colored < n; colored++ {
+		v := mostSaturated(nbrs, colors)
+		colors[v] = smallestFree(v, nbrs, colors)
+	}
+	return colors
+}
+
+// Returns the uncolored node with the most distinct neighbour colors, ties
+// going to the node with the most neighbours and then the lowest id.
+func mostSaturated(nbrs [][]int, colors []int) int {
+	best, bestSat := -1, -1
+	for v := range nbrs {
+		if colors[v] >= 0 {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, w := range nbrs[v] {
+			if colors[w] >= 0 {
+				seen[colors[w]] = true
+			}
+		}
+		sat := len(seen)
+		if sat > bestSat || (sat == bestSat && len(nbrs[v]) > len(nbrs[best])) {
+			best, bestSat = v, sat
+		}
+	}
+	return best
+}
+
+// Colors the graph with as few colors as possible, by a branch and bound
+// search seeded with the DSatur coloring.  The search takes exponential time
+// in the worst case, so graphs of more than MaxExactNodes nodes are refused.
+func Exact(g *builder.Graph) (Coloring, error) {
+	ix := adj.New(g)
+	if ix.Len() > MaxExactNodes {
+		return nil, ErrTooLarge
+	}
+	nbrs := neighbors(ix)
+
+	best := dsatur(nbrs)
+	bestCount := 0
+	for _, c := range best {
+		if c+1 > bestCount {
+			bestCount = c + 1
+		}
+	}
+
+	colors := make([]int, ix.Len())
+	for i := range colors {
+		colors[i] = -1
+	}
+	var search func(colored int, used int)
+	search = func(colored int, used int) {
+		if colored == len(colors) {
+			if used < bestCount {
+				best, bestCount = append([]int(nil), colors...), used
+			}
+			return
+		}
+		v := mostSaturated(nbrs, colors)
+		for c := 0; c <= used && c < bestCount-1; c++ {
+			free := true
+			for _, w := range nbrs[v] {
+				if colors[w] == c {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+			colors[v] = c
+			if c == used {
+				search(colored+1, used+1)
+			} else {
+				search(colored+1, used)
+			}
+			colors[v] = -1
+		}
+	}
+	search(0, 0)
+
+	return coloring(ix, best), nil
+}
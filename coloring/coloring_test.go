@@ -0,0 +1,163 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package coloring
+
+import "math/rand"
+import "testing"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+
+func petersen() *builder.Graph {
+	nodes := builder.GenNodes(10)
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(nodes...)
+	for i := 0; i < 5; i++ {
+		g.AddEdges(
+			&builder.Edge{Src: nodes[i], Dst: nodes[(i+1)%5]},
+			&builder.Edge{Src: nodes[i], Dst: nodes[i+5]},
+			&builder.Edge{Src: nodes[i+5], Dst: nodes[(i+2)%5+5]},
+		)
+	}
+	return g
+}
+
+func random(seed int64, n int, p float64) *builder.Graph {
+	rng := rand.New(rand.NewSource(seed))
+	nodes := builder.GenNodes(n)
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(nodes...)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if rng.Float64() < p {
+				g.AddEdges(&builder.Edge{Src: nodes[i], Dst: nodes[j]})
+			}
+		}
+	}
+	return g
+}
+
+func checkNodes(t *testing.T, name string, g *builder.Graph, c Coloring) {
+	if len(c) != len(g.Nodes()) {
+		t.Errorf("%s: not every node was colored.", name)
+	}
+	for _, e := range g.Edges() {
+		if c[e.Src] == c[e.Dst] {
+			t.Errorf("%s: adjacent nodes have the same color.", name)
+		}
+	}
+}
+
+func maxDegree(g *builder.Graph) int {
+	degree := make(map[*builder.Node]int)
+	max := 0
+	for _, e := range g.Edges() {
+		degree[e.Src]++
+		degree[e.Dst]++
+		if degree[e.Src] > max {
+			max = degree[e.Src]
+		}
+		if degree[e.Dst] > max {
+			max = degree[e.Dst]
+		}
+	}
+	return max
+}
+
+func TestNodeColorings(t *testing.T) {
+	g := petersen()
+
+	checkNodes(t, "WelshPowell", g, WelshPowell(g))
+	checkNodes(t, "DSatur", g, DSatur(g))
+
+	c, err := Exact(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNodes(t, "Exact", g, c)
+	if n := c.Count(); n != 3 {
+		t.Errorf("Exact should use 3 colors, but uses %d.", n)
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		g := random(seed, 20, 0.3)
+		c, _ := Exact(g)
+		checkNodes(t, "Exact", g, c)
+		if c.Count() > DSatur(g).Count() {
+			t.Errorf("Exact uses more colors than DSatur.")
+		}
+	}
+}
+
+func TestExactTooLarge(t *testing.T) {
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(builder.GenNodes(MaxExactNodes + 1)...)
+	if _, err := Exact(g); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got %v.", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	g := petersen()
+	c, _ := Exact(g)
+
+	if err := c.Apply([]color.Color{color.Red, color.Blue}); err == nil {
+		t.Errorf("Expected an error for a small palette.")
+	}
+	if err := c.Apply([]color.Color{color.Red, color.Blue, color.Cyan}); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range g.Edges() {
+		if e.Src.FillColor == nil || e.Src.FillColor == e.Dst.FillColor {
+			t.Errorf("Fill colors were not applied.")
+		}
+	}
+}
+
+func TestEdges(t *testing.T) {
+	graphs := []*builder.Graph{petersen()}
+	for seed := int64(0); seed < 20; seed++ {
+		graphs = append(graphs, random(seed, 15, 0.4))
+	}
+
+	for _, g := range graphs {
+		c, err := Edges(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := c.Count(); n > maxDegree(g)+1 {
+			t.Errorf("Uses %d colors, but maximum degree is %d.", n, maxDegree(g))
+		}
+		at := make(map[*builder.Node]map[int]bool)
+		for _, e := range g.Edges() {
+			for _, n := range []*builder.Node{e.Src, e.Dst} {
+				if at[n] == nil {
+					at[n] = make(map[int]bool)
+				}
+				if at[n][c[e]] {
+					t.Fatalf("Edges sharing a node have the same color.")
+				}
+				at[n][c[e]] = true
+			}
+		}
+	}
+
+	g := builder.NewGraph(attr.Directed)
+	if _, err := Edges(g); err != ErrDirected {
+		t.Errorf("Expected ErrDirected, got %v.", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package coloring
+
+import "errors"
+import "fmt"
+
+import "godot/attr/color"
+import "godot/builder"
+import "godot/internal/adj"
+
+// Returned by Edges for directed graphs.
+var ErrDirected = errors.New("coloring: edge coloring requires an undirected graph")
+
+// Returned by Edges for graphs with self-loops, which cannot be edge colored.
+var ErrSelfLoop = errors.New("coloring: graph has a self-loop")
+
+// A color index for each edge of a graph.
+type EdgeColoring map[*builder.Edge]int
+
+// Returns the number of colors used.
+func (c EdgeColoring) Count() int {
+	max := -1
+	for _, i := range c {
+		if i > max {
+			max = i
+		}
+	}
+	return max + 1
+}
+
+// Sets the Color of each edge to its color from "palette".  Returns an error,
+// without changing any edge, if the palette has too few colors.
+func (c EdgeColoring) Apply(palette []color.Color) error {
+	if n := c.Count(); n > len(palette) {
+		return fmt.Errorf("coloring: %d colors needed but palette has %d", n, len(palette))
+	}
+	for e, i := range c {
+		e.Color = palette[i]
+	}
+	return nil
+}
+
+// Colors the edges of an undirected graph so that no two edges sharing a
+// node have the same color.  Graphs without parallel edges are colored by the
+// Misra-Gries algorithm, using at most one more color than the largest
+// degree.  Graphs with parallel edges are colored greedily in edge order.
+// Edges with a nil endpoint are not colored.
+func Edges(g *builder.Graph) (EdgeColoring, error) {
+	ix := adj.New(g)
+	if ix.Directed {
+		return nil, ErrDirected
+	}
+
+	simple := true
+	seen := make(map[[2]int]bool)
+	for e := range ix.Edges {
+		s, d := ix.Src[e], ix.Dst[e]
+		if s == d {
+			return nil, ErrSelfLoop
+		}
+		if s > d {
+			s, d = d, s
+		}
+		if seen[[2]int{s, d}] {
+			simple = false
+		}
+		seen[[2]int{s, d}] = true
+	}
+
+	var colors []int
+	if simple {
+		colors = misraGries(ix)
+	} else {
+		colors = greedyEdges(ix)
+	}
+
+	c := make(EdgeColoring, len(colors))
+	for e, edge := range ix.Edges {
+		c[edge] = colors[e]
+	}
+	return c, nil
+}
+
+// Colors each edge in turn with the smallest color unused at either end.
+func greedyEdges(ix *adj.Index) []int {
+	at := make([]map[int]bool, ix.Len())
+	for v := range at {
+		at[v] = make(map[int]bool)
+	}
+	colors := make([]int, len(ix.Edges))
+	for e := range ix.Edges {
+		s, d := ix.Src[e], ix.Dst[e]
+		c := 0
+		for at[s][c] || at[d][c] {
+			c++
+		}
+		colors[e] = c
+		at[s][c], at[d][c] = true, true
+	}
+	return colors
+}
+
+// The state of a partial edge coloring of a simple graph.
+type edgeColors struct {
+	// at[v][c] is the neighbour joined to v by the edge colored c.
+	at []map[int]int
+}
+
+func (ec *edgeColors) free(v, c int) bool {
+	_, used := ec.at[v][c]
+	return !used
+}
+
+func (ec *edgeColors) smallestFree(v int) int {
+	c := 0
+	for !ec.free(v, c) {
+		c++
+	}
+	return c
+}
+
+// Returns the color of the edge between "u" and "v", or -1 if it is
+// uncolored.
+func (ec *edgeColors) color(u, v int) int {
+	for c, w := range ec.at[u] {
+		if w == v {
+			return c
+		}
+	}
+	return -1
+}
+
+func (ec *edgeColors) set(u, v, c int) {
+	ec.at[u][c] = v
+	ec.at[v][c] = u
+}
+
+func (ec *edgeColors) clear(u, v int) {
+	if c := ec.color(u, v); c >= 0 {
+		delete(ec.at[u], c)
+		delete(ec.at[v], c)
+	}
+}
+
+// Colors the edges of a simple graph with the Misra-Gries algorithm.
+func misraGries(ix *adj.Index) []int {
+	ec := &edgeColors{at: make([]map[int]int, ix.Len())}
+	for v := range ec.at {
+		ec.at[v] = make(map[int]int)
+	}
+	nbrs := neighbors(ix)
+
+	for e := range ix.Edges {
+		u, v := ix.Src[e], ix.Dst[e]
+
+		// Build a maximal fan of u starting at v: each edge of the fan is
+		// colored with a color free on the previous fan node.
+		fan := []int{v}
+		inFan := map[int]bool{v: true}
+		for extended := true; extended; {
+			extended = false
+			last := fan[len(fan)-1]
+			for _, w := range nbrs[u] {
+				if c := ec.color(u, w); !inFan[w] && c >= 0 && ec.free(last, c) {
+					fan = append(fan, w)
+					inFan[w] = true
+					extended = true
+					break
+				}
+			}
+		}
+
+		c := ec.smallestFree(u)
+		d := ec.smallestFree(fan[len(fan)-1])
+
+		// Invert the path from u whose edges alternate between d and c.
+		if c != d {
+			type step struct{ a, b, c int }
+			var path []step
+			x, want, other := u, d, c
+			for {
+				y, ok := ec.at[x][want]
+				if !ok {
+					break
+				}
+				path = append(path, step{x, y, other})
+				x, want, other = y, other, want
+			}
+			for _, s := range path {
+				ec.clear(s.a, s.b)
+			}
+			for _, s := range path {
+				ec.set(s.a, s.b, s.c)
+			}
+		}
+
+		// Find the first node of the fan on which d is free, such that the
+		// fan up to it is still a fan.
+		w := len(fan) - 1
+		for i, f := range fan {
+			if i > 0 && !ec.free(fan[i-1], ec.color(u, f)) {
+				break
+			}
+			if ec.free(f, d) {
+				w = i
+				break
+			}
+		}
+
+		// Rotate the fan up to w, then color the edge to w with d.
+		for i := 0; i < w; i++ {
+			next := ec.color(u, fan[i+1])
+			ec.clear(u, fan[i+1])
+			ec.set(u, fan[i], next)
+		}
+		ec.set(u, fan[w], d)
+	}
+
+	colors := make([]int, len(ix.Edges))
+	for e := range ix.Edges {
+		colors[e] = ec.color(ix.Src[e], ix.Dst[e])
+	}
+	return colors
+}
@@ -0,0 +1,211 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package community finds groups of densely connected nodes in a
+builder.Graph, and turns them into cluster subgraphs or node groups.
+
+Two algorithms are provided: Louvain, which greedily maximizes modularity and
+usually finds the better partition, and LabelPropagation, which is faster but
+less stable.  Both ignore edge direction and treat parallel edges as a single
+heavier edge.  Both are randomized, and seeded through Options so that the
+same graph and seed always give the same communities.
+
+A short example, drawing each community of a graph as a labelled cluster:
+
+  comms := community.Louvain(g, &community.Options{Seed: 1, HubLabels: true})
+  community.Cluster(g, comms)
+
+Resources:
+  http://arxiv.org/abs/0803.0476
+  http://arxiv.org/abs/0709.2938
+*/
+package community
+
+import "fmt"
+import "sort"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Options for community detection.
+type Options struct {
+	// Seed for the random number generator.
+	Seed int64
+
+	// Communities with fewer members than MinSize are left out of the
+	// result, so that their nodes are not grouped.
+	MinSize int
+
+	// If set, each community is labelled with the label of its hub, the
+	// member with the most edges.
+	HubLabels bool
+}
+
+// A group of nodes.
+type Community struct {
+	// The members of the community, in the order they were added to the
+	// graph.
+	Nodes []*builder.Node
+
+	// The member with the most edges.  Ties go to the earliest member.
+	Hub *builder.Node
+
+	// Label for the community's cluster.  Empty unless Options.HubLabels is
+	// set.
+	Label string
+}
+
+// An undirected weighted graph, used as the working representation during
+// detection.  Edge weights are held in both directions; self-loop weights
+// are held once in "self".
+type weighted struct {
+	nbrs    [][]int
+	weights [][]float64
+	self    []float64
+}
+
+func newWeighted(ix *adj.Index) *weighted {
+	n := ix.Len()
+	w := &weighted{
+		nbrs:    make([][]int, n),
+		weights: make([][]float64, n),
+		self:    make([]float64, n),
+	}
+	slot := make([]map[int]int, n)
+	for i := range slot {
+		slot[i] = make(map[int]int)
+	}
+	link := func(a, b int) {
+		if i, ok := slot[a][b]; ok {
+			w.weights[a][i]++
+			return
+		}
+		slot[a][b] = len(w.nbrs[a])
+		w.nbrs[a] = append(w.nbrs[a], b)
+		w.weights[a] = append(w.weights[a], 1)
+	}
+	for e := range ix.Edges {
+		s, d := ix.Src[e], ix.Dst[e]
+		if s == d {
+			w.self[s]++
+			continue
+		}
+		link(s, d)
+		link(d, s)
+	}
+	return w
+}
+
+// Returns the total weight of the edges at each node.  Self-loops count
+// twice.
+func (w *weighted) degrees() []float64 {
+	k := make([]float64, len(w.nbrs))
+	for v := range w.nbrs {
+		k[v] = 2 * w.self[v]
+		for _, wt := range w.weights[v] {
+			k[v] += wt
+		}
+	}
+	return k
+}
+
+// Converts a community assignment for each node into communities, applying
+// the options.
+func communities(ix *adj.Index, comm []int, opts *Options) []*Community {
+	degree := make([]int, ix.Len())
+	for v := range ix.Nodes {
+		degree[v] = len(ix.In[v]) + len(ix.Out[v])
+	}
+
+	byID := make(map[int]*Community)
+	var result []*Community
+	hubs := make(map[*Community]int)
+	for v, n := range ix.Nodes {
+		c, ok := byID[comm[v]]
+		if !ok {
+			c = new(Community)
+			byID[comm[v]] = c
+			result = append(result, c)
+		}
+		c.Nodes = append(c.Nodes, n)
+		if c.Hub == nil || degree[v] > hubs[c] {
+			c.Hub, hubs[c] = n, degree[v]
+		}
+	}
+
+	kept := result[:0]
+	for _, c := range result {
+		if len(c.Nodes) < opts.MinSize {
+			continue
+		}
+		if opts.HubLabels {
+			c.Label = c.Hub.Label
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// Adds a cluster subgraph to "g" for each community, named "cluster_0",
+// "cluster_1" and so on, and labelled with the community's label.  Returns
+// the subgraphs in the same order as the communities.
+func Cluster(g *builder.Graph, comms []*Community) []*builder.Subgraph {
+	subs := make([]*builder.Subgraph, len(comms))
+	for i, c := range comms {
+		sub := builder.NewSubgraph(fmt.Sprintf("cluster_%d", i))
+		sub.Label = c.Label
+		sub.AddNodes(c.Nodes...)
+		subs[i] = sub
+	}
+	g.AddSubgraphs(subs...)
+	return subs
+}
+
+// Sets the Group of the members of each community to "community_0",
+// "community_1" and so on, so that dot keeps the edges between them straight.
+func Group(comms []*Community) {
+	for i, c := range comms {
+		for _, n := range c.Nodes {
+			n.Group = fmt.Sprintf("community_%d", i)
+		}
+	}
+}
+
+// Returns the keys of a map in ascending order, so that it may be iterated
+// deterministically.
+func sortedKeys(keys map[int]float64) []int {
+	sorted := make([]int, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+// Renumbers community ids so that they are dense, in order of first member.
+// Returns the number of communities.
+func renumber(comm []int) int {
+	ids := make(map[int]int)
+	for v, c := range comm {
+		id, ok := ids[c]
+		if !ok {
+			id = len(ids)
+			ids[c] = id
+		}
+		comm[v] = id
+	}
+	return len(ids)
+}
@@ -0,0 +1,99 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package community
+
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+// Returns two complete graphs of four nodes, joined by a single edge.
+func barbell() (*builder.Graph, []*builder.Node) {
+	nodes := builder.GenNodes(8)
+	for i, n := range nodes {
+		n.Label = string(rune('a' + i))
+	}
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(nodes...)
+	for _, half := range [][]*builder.Node{nodes[:4], nodes[4:]} {
+		for i := range half {
+			for j := i + 1; j < len(half); j++ {
+				g.AddEdges(&builder.Edge{Src: half[i], Dst: half[j]})
+			}
+		}
+	}
+	g.AddEdges(&builder.Edge{Src: nodes[3], Dst: nodes[4]})
+	return g, nodes
+}
+
+func check(t *testing.T, name string, comms []*Community, nodes []*builder.Node) {
+	if len(comms) != 2 {
+		t.Fatalf("%s: should find 2 communities, but found %d.", name, len(comms))
+	}
+	for i, c := range comms {
+		if len(c.Nodes) != 4 {
+			t.Fatalf("%s: community %d should have 4 members, but has %d.", name, i, len(c.Nodes))
+		}
+		for j, n := range c.Nodes {
+			if n != nodes[i*4+j] {
+				t.Errorf("%s: community %d has the wrong members.", name, i)
+			}
+		}
+	}
+}
+
+func TestLouvain(t *testing.T) {
+	g, nodes := barbell()
+	check(t, "Louvain", Louvain(g, &Options{Seed: 1}), nodes)
+}
+
+func TestLabelPropagation(t *testing.T) {
+	g, nodes := barbell()
+	check(t, "LabelPropagation", LabelPropagation(g, &Options{Seed: 1}), nodes)
+}
+
+func TestOptions(t *testing.T) {
+	g, nodes := barbell()
+	extra := &builder.Node{Label: "alone"}
+	g.AddNodes(extra)
+
+	comms := Louvain(g, &Options{Seed: 7, MinSize: 2, HubLabels: true})
+	check(t, "Louvain", comms, nodes)
+	if comms[0].Hub != nodes[3] || comms[0].Label != "d" {
+		t.Errorf("Hub should be %q, but is %q.", "d", comms[0].Label)
+	}
+	if comms[1].Hub != nodes[4] || comms[1].Label != "e" {
+		t.Errorf("Hub should be %q, but is %q.", "e", comms[1].Label)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	g, nodes := barbell()
+	comms := Louvain(g, nil)
+
+	subs := Cluster(g, comms)
+	if l := g.Subgraphs(); len(l) != 2 || l[0] != subs[0] {
+		t.Fatalf("Clusters were not added to the graph.")
+	}
+	if subs[1].Name != "cluster_1" || subs[1].Nodes()[0] != nodes[4] {
+		t.Errorf("Cluster is incorrect.")
+	}
+
+	Group(comms)
+	if nodes[0].Group != "community_0" || nodes[7].Group != "community_1" {
+		t.Errorf("Groups are incorrect.")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package community
+
+import "math/rand"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Finds communities by label propagation.  Every node starts with a label of
+// its own, then repeatedly takes the label carried by most of its neighbours
+// until no label changes.  Ties are broken randomly, except that a node keeps
+// its current label if it is among the most common.  Communities are ordered
+// by their first member.
+//
+// "opts" may be nil.
+func LabelPropagation(g *builder.Graph, opts *Options) []*Community {
+	const maxRounds = 100
+
+	if opts == nil {
+		opts = new(Options)
+	}
+	ix := adj.New(g)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	w := newWeighted(ix)
+
+	labels := make([]int, ix.Len())
+	for v := range labels {
+		labels[v] = v
+	}
+
+	for round := 0; round < maxRounds; round++ {
+		changed := false
+		for _, v := range rng.Perm(len(labels)) {
+			if len(w.nbrs[v]) == 0 {
+				continue
+			}
+			counts := make(map[int]float64)
+			for i, u := range w.nbrs[v] {
+				counts[labels[u]] += w.weights[v][i]
+			}
+
+			most := 0.0
+			for _, c := range counts {
+				if c > most {
+					most = c
+				}
+			}
+			if counts[labels[v]] == most {
+				continue
+			}
+			var best []int
+			for _, l := range sortedKeys(counts) {
+				if counts[l] == most {
+					best = append(best, l)
+				}
+			}
+			labels[v] = best[rng.Intn(len(best))]
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+
+	renumber(labels)
+	return communities(ix, labels, opts)
+}
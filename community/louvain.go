@@ -0,0 +1,143 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package community
+
+import "math/rand"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Finds communities using the Louvain method.  Nodes are repeatedly moved to
+// the neighbouring community which most increases the modularity of the
+// partition, then each community is collapsed into a single node and the
+// process repeated, until no move improves the partition.  Communities are
+// ordered by their first member.
+//
+// "opts" may be nil.
+func Louvain(g *builder.Graph, opts *Options) []*Community {
+	if opts == nil {
+		opts = new(Options)
+	}
+	ix := adj.New(g)
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	member := make([]int, ix.Len())
+	for v := range member {
+		member[v] = v
+	}
+
+	w := newWeighted(ix)
+	for {
+		comm, count, moved := louvainLevel(w, rng)
+		if !moved {
+			break
+		}
+		for v := range member {
+			member[v] = comm[member[v]]
+		}
+		w = w.aggregate(comm, count)
+	}
+	return communities(ix, member, opts)
+}
+
+// Runs the local moving phase of the Louvain method.  Returns the community
+// of each node, the number of communities and whether any node was moved.
+func louvainLevel(w *weighted, rng *rand.Rand) ([]int, int, bool) {
+	const maxPasses = 100
+
+	n := len(w.nbrs)
+	comm := make([]int, n)
+	for v := range comm {
+		comm[v] = v
+	}
+
+	k := w.degrees()
+	tot := append([]float64(nil), k...)
+	total := 0.0
+	for _, kv := range k {
+		total += kv
+	}
+	if total == 0 {
+		return comm, n, false
+	}
+
+	moved := false
+	for pass := 0; pass < maxPasses; pass++ {
+		moves := 0
+		for _, v := range rng.Perm(n) {
+			links := make(map[int]float64)
+			for i, u := range w.nbrs[v] {
+				links[comm[u]] += w.weights[v][i]
+			}
+
+			old := comm[v]
+			tot[old] -= k[v]
+			best := old
+			bestGain := links[old] - tot[old]*k[v]/total
+			for _, c := range sortedKeys(links) {
+				if gain := links[c] - tot[c]*k[v]/total; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			tot[best] += k[v]
+			comm[v] = best
+			if best != old {
+				moves++
+			}
+		}
+		if moves == 0 {
+			break
+		}
+		moved = true
+	}
+
+	count := renumber(comm)
+	return comm, count, moved
+}
+
+// Collapses each community into a single node.  Edges within a community
+// become a self-loop on its node.
+func (w *weighted) aggregate(comm []int, count int) *weighted {
+	agg := &weighted{
+		nbrs:    make([][]int, count),
+		weights: make([][]float64, count),
+		self:    make([]float64, count),
+	}
+	slot := make([]map[int]int, count)
+	for i := range slot {
+		slot[i] = make(map[int]int)
+	}
+	for v := range w.nbrs {
+		cv := comm[v]
+		agg.self[cv] += w.self[v]
+		for i, u := range w.nbrs[v] {
+			cu, wt := comm[u], w.weights[v][i]
+			if cu == cv {
+				// Seen from both ends.
+				agg.self[cv] += wt / 2
+				continue
+			}
+			if j, ok := slot[cv][cu]; ok {
+				agg.weights[cv][j] += wt
+				continue
+			}
+			slot[cv][cu] = len(agg.nbrs[cv])
+			agg.nbrs[cv] = append(agg.nbrs[cv], cu)
+			agg.weights[cv] = append(agg.weights[cv], wt)
+		}
+	}
+	return agg
+}
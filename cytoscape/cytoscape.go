@@ -0,0 +1,291 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package cytoscape writes graphs as Cytoscape.js elements, so that a graph
+written as dot can be drawn in the browser as well.
+
+Each node, edge and cluster becomes an element whose data holds its id, its
+dot attributes and, for edges, its source and target.  Clusters become
+compound nodes, the parents of the nodes within them.  The dot styles of
+elements are written as style overrides, so that the graph looks as it does
+in dot without a stylesheet, and their classes as classes.  Nodes with
+positions are given them, converted from inches to pixels:
+
+  els := cytoscape.Elements(g)
+  json.NewEncoder(w).Encode(els)
+
+  // In the browser:
+  cytoscape({container: div, elements: els, layout: {name: "preset"}});
+
+Resources:
+  https://js.cytoscape.org/#notation/elements-json
+  https://js.cytoscape.org/#style
+*/
+package cytoscape
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "strconv"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+
+func init() {
+	format.Register("cytoscape", []string{".cyjs"}, nil,
+		format.NewEncoder(Encode, godot.Clusters|godot.Positions), nil)
+}
+
+// An element, a node or an edge.
+type Element struct {
+	// "nodes" or "edges".
+	Group string `json:"group"`
+
+	// The id, the dot attributes, and "parent", or "source" and "target".
+	Data map[string]string `json:"data"`
+
+	Position *Position `json:"position,omitempty"`
+
+	// Whether the node has a locked position, which the user cannot drag.
+	Locked bool `json:"locked,omitempty"`
+
+	Classes string                 `json:"classes,omitempty"`
+	Style   map[string]interface{} `json:"style,omitempty"`
+}
+
+// The position of a node, in pixels, with y increasing downwards.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// The Cytoscape shapes of dot shapes.
+var shapes = map[string]string{
+	"box":           "rectangle",
+	"rect":          "rectangle",
+	"rectangle":     "rectangle",
+	"square":        "rectangle",
+	"ellipse":       "ellipse",
+	"oval":          "ellipse",
+	"circle":        "ellipse",
+	"doublecircle":  "ellipse",
+	"point":         "ellipse",
+	"diamond":       "diamond",
+	"hexagon":       "hexagon",
+	"octagon":       "octagon",
+	"pentagon":      "pentagon",
+	"triangle":      "triangle",
+	"star":          "star",
+	"cylinder":      "barrel",
+	"parallelogram": "rhomboid",
+}
+
+// The Cytoscape arrow shapes of dot arrowheads, and whether they are
+// hollow.
+var arrows = map[string]struct {
+	shape  string
+	hollow bool
+}{
+	"normal":   {"triangle", false},
+	"onormal":  {"triangle", true},
+	"vee":      {"vee", false},
+	"tee":      {"tee", false},
+	"dot":      {"circle", false},
+	"odot":     {"circle", true},
+	"diamond":  {"diamond", false},
+	"odiamond": {"diamond", true},
+	"box":      {"square", false},
+	"obox":     {"square", true},
+	"inv":      {"triangle-backcurve", false},
+}
+
+// Returns the data of an object: its dot attributes, but for its id.
+func data(obj interface{}, id string) map[string]string {
+	d := map[string]string{"id": id}
+	for _, a := range builder.Attributes(obj) {
+		if a[0] != "id" && a[0] != "pos" {
+			d[a[0]] = a[1]
+		}
+	}
+	return d
+}
+
+// Sets the style of a node or cluster's border and background.
+func box(style map[string]interface{}, fill, line, font string, penwidth float64, s geom.Style) {
+	if fill != "" {
+		style["background-color"] = fill
+	} else {
+		style["background-opacity"] = 0
+	}
+	if line == "" {
+		line = "#000000"
+	}
+	style["border-color"] = line
+	style["border-width"] = penwidth
+	switch {
+	case s.Dashed:
+		style["border-style"] = "dashed"
+	case s.Dotted:
+		style["border-style"] = "dotted"
+	}
+	if font != "" {
+		style["color"] = font
+	}
+	if s.Invisible {
+		style["visibility"] = "hidden"
+	}
+}
+
+// Returns the elements of a graph: the clusters, each before those nested
+// in it, then the nodes, then the edges with both endpoints.  Elements are
+// given the ids of Diagram.IDs in package godot/diagram: their dot ids, if
+// they are set and unique, and otherwise "n0", "n1" and so on for nodes,
+// "e0", "e1" and so on for edges, and "s0", "s1" and so on for clusters.
+// A node of several clusters is the child of the first.
+func Elements(g *builder.Graph) []Element {
+	d := diagram.New(g)
+	ids := d.IDs()
+	var els []Element
+
+	var cluster func(c *diagram.Cluster)
+	cluster = func(c *diagram.Cluster) {
+		el := Element{
+			Group:   "nodes",
+			Data:    data(c.Subgraph, ids[c]),
+			Classes: c.Class,
+			Style:   map[string]interface{}{"text-valign": "top"},
+		}
+		if c.Parent != nil {
+			el.Data["parent"] = ids[c.Parent]
+		}
+		if c.Label != "" {
+			el.Style["label"] = c.Label
+		}
+		box(el.Style, c.Fill, c.Color, c.FontColor, geom.PenWidth("", c.Style), c.Style)
+		els = append(els, el)
+		for _, sub := range c.Clusters {
+			cluster(sub)
+		}
+	}
+	for _, c := range d.Clusters {
+		cluster(c)
+	}
+
+	for _, n := range d.AllNodes {
+		el := Element{
+			Group:   "nodes",
+			Data:    data(n.Node, ids[n]),
+			Classes: n.Class,
+			Style: map[string]interface{}{
+				"label":       n.Label,
+				"text-valign": "center",
+				"text-halign": "center",
+				"text-wrap":   "wrap",
+			},
+		}
+		if n.Parent != nil {
+			el.Data["parent"] = ids[n.Parent]
+		}
+		if p := n.Node.Position; p != nil {
+			el.Position = &Position{
+				X: diagram.Points(float64(p.X)),
+				Y: -diagram.Points(float64(p.Y)),
+			}
+			el.Locked = p.Lock
+		}
+		shape := shapes[n.Shape]
+		if shape == "rectangle" && n.Style.Rounded {
+			shape = "round-rectangle"
+		}
+		if shape != "" {
+			el.Style["shape"] = shape
+		}
+		for _, size := range []struct{ name, inches string }{{"width", n.Node.Width}, {"height", n.Node.Height}} {
+			if v, err := strconv.ParseFloat(size.inches, 64); err == nil {
+				el.Style[size.name] = diagram.Points(v)
+			}
+		}
+		box(el.Style, n.Fill, n.Color, n.FontColor, geom.PenWidth(n.Node.Penwidth, n.Style), n.Style)
+		els = append(els, el)
+	}
+
+	for _, e := range d.Edges {
+		eid := ids[e]
+		el := Element{
+			Group:   "edges",
+			Data:    data(e.Edge, eid),
+			Classes: e.Class,
+			Style: map[string]interface{}{
+				"curve-style": "bezier",
+				"width":       geom.PenWidth(e.Edge.Penwidth, e.Style),
+			},
+		}
+		el.Data["source"], el.Data["target"] = ids[e.Tail], ids[e.Head]
+		if e.Label != "" {
+			el.Style["label"] = e.Label
+		}
+		line := e.Color
+		if line == "" {
+			line = "#000000"
+		}
+		el.Style["line-color"] = line
+		switch {
+		case e.Style.Dashed:
+			el.Style["line-style"] = "dashed"
+		case e.Style.Dotted:
+			el.Style["line-style"] = "dotted"
+		}
+		if e.FontColor != "" {
+			el.Style["color"] = e.FontColor
+		}
+		if e.Style.Invisible {
+			el.Style["visibility"] = "hidden"
+		}
+		for _, end := range []struct {
+			prefix    string
+			drawn     bool
+			arrowhead string
+		}{
+			{"source", e.TailArrow, e.Arrowtail},
+			{"target", e.HeadArrow, e.Arrowhead},
+		} {
+			a, ok := arrows[end.arrowhead]
+			if !end.drawn || !ok {
+				continue
+			}
+			el.Style[end.prefix+"-arrow-shape"] = a.shape
+			el.Style[end.prefix+"-arrow-color"] = line
+			if a.hollow {
+				el.Style[end.prefix+"-arrow-fill"] = "hollow"
+			}
+		}
+		els = append(els, el)
+	}
+	return els
+}
+
+// Writes the elements of a graph as a JSON array.
+func Encode(w io.Writer, g *builder.Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(Elements(g)); err != nil {
+		return fmt.Errorf("cytoscape: %v", err)
+	}
+	return nil
+}
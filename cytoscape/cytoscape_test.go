@@ -0,0 +1,91 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cytoscape
+
+import "bytes"
+import "encoding/json"
+import "reflect"
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+func TestElements(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		a [id=start, label="A", shape=box, style="filled,rounded", fillcolor=red, pos="1,2!", width=1, class="x y"];
+		b [color=blue];
+		subgraph cluster_0 { label=Outer; id=n1; b }
+		a -> b [label=ab, style=dashed, arrowhead=odot];
+		b -> a [dir=none, id=start];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	els := Elements(g)
+	if len(els) != 5 {
+		t.Fatalf("Elements gave %d elements", len(els))
+	}
+	cluster, a, b, ab, ba := els[0], els[1], els[2], els[3], els[4]
+
+	if cluster.Group != "nodes" || cluster.Data["id"] != "n1" || cluster.Style["label"] != "Outer" {
+		t.Errorf("Cluster is %+v", cluster)
+	}
+	if a.Data["id"] != "start" || a.Data["fillcolor"] != "red" || a.Classes != "x y" || !a.Locked {
+		t.Errorf("Node a is %+v", a)
+	}
+	if *a.Position != (Position{72, -144}) {
+		t.Errorf("Node a is at %+v", *a.Position)
+	}
+	if a.Style["shape"] != "round-rectangle" || a.Style["background-color"] != "#ff0000" || a.Style["width"] != 72.0 {
+		t.Errorf("Node a has style %v", a.Style)
+	}
+	// The cluster's dot id is b's id in the diagram.
+	if b.Data["id"] != "n1_2" || b.Data["parent"] != "n1" {
+		t.Errorf("Node b is %+v", b)
+	}
+	if b.Style["border-color"] != "#0000ff" || b.Style["background-opacity"] != 0 {
+		t.Errorf("Node b has style %v", b.Style)
+	}
+
+	want := map[string]interface{}{
+		"curve-style":        "bezier",
+		"label":              "ab",
+		"line-color":         "#000000",
+		"line-style":         "dashed",
+		"target-arrow-color": "#000000",
+		"target-arrow-fill":  "hollow",
+		"target-arrow-shape": "circle",
+		"width":              1.0,
+	}
+	if ab.Group != "edges" || ab.Data["source"] != "start" || ab.Data["target"] != b.Data["id"] || !reflect.DeepEqual(ab.Style, want) {
+		t.Errorf("Edge ab is %+v", ab)
+	}
+	if ba.Data["id"] != "e1" || ba.Style["target-arrow-shape"] != nil {
+		t.Errorf("Edge ba is %+v", ba)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var decoded []Element
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Encode wrote invalid JSON: %v", err)
+	}
+	if len(decoded) != len(els) || decoded[1].Data["id"] != "start" {
+		t.Errorf("Encode wrote %s", buf.String())
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package d3 writes graphs as the {nodes, links} JSON of D3 force layouts.
+
+Links name their source and target by node id, as d3.forceLink expects when
+given an id accessor.  Nodes carry their label, their cluster and dot group,
+their position if they have one, and their dot style in SVG terms, and both
+nodes and links carry their dot attributes:
+
+  const sim = d3.forceSimulation(graph.nodes)
+    .force("link", d3.forceLink(graph.links).id(d => d.id));
+
+Resources:
+  https://d3js.org/d3-force
+*/
+package d3
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "strconv"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+
+func init() {
+	format.Register("d3", nil, nil, format.NewEncoder(Encode, godot.Positions), nil)
+}
+
+// A graph.
+type Graph struct {
+	Directed bool   `json:"directed"`
+	Nodes    []Node `json:"nodes"`
+	Links    []Link `json:"links"`
+}
+
+// A node.  Colors are in CSS notation, and lengths in pixels.
+type Node struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+
+	// The dot group of the node.
+	Group string `json:"group,omitempty"`
+
+	// The id of the cluster holding the node, if any.
+	Cluster string `json:"cluster,omitempty"`
+
+	// The position of the node, with y increasing downwards.  A locked
+	// position is also given as fx and fy, which D3 does not move.
+	X  *float64 `json:"x,omitempty"`
+	Y  *float64 `json:"y,omitempty"`
+	FX *float64 `json:"fx,omitempty"`
+	FY *float64 `json:"fy,omitempty"`
+
+	// Half the larger of the node's width and height, if either is set.
+	Radius float64 `json:"radius,omitempty"`
+
+	// The dot shape.
+	Shape string `json:"shape,omitempty"`
+
+	Fill            string  `json:"fill"`
+	Stroke          string  `json:"stroke"`
+	StrokeWidth     float64 `json:"strokeWidth"`
+	StrokeDasharray string  `json:"strokeDasharray,omitempty"`
+	TextColor       string  `json:"textColor"`
+	Hidden          bool    `json:"hidden,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// A link between two nodes.
+type Link struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Label  string `json:"label,omitempty"`
+
+	// Whether arrowheads are drawn at the target and source.
+	TargetArrow bool `json:"targetArrow,omitempty"`
+	SourceArrow bool `json:"sourceArrow,omitempty"`
+
+	Stroke          string  `json:"stroke"`
+	StrokeWidth     float64 `json:"strokeWidth"`
+	StrokeDasharray string  `json:"strokeDasharray,omitempty"`
+	TextColor       string  `json:"textColor"`
+	Hidden          bool    `json:"hidden,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Returns the attributes of an object, but for those written otherwise.
+func attributes(obj interface{}) map[string]string {
+	m := make(map[string]string)
+	for _, a := range builder.Attributes(obj) {
+		switch a[0] {
+		case "id", "label", "group", "pos", "shape":
+		default:
+			m[a[0]] = a[1]
+		}
+	}
+	return m
+}
+
+func dasharray(s geom.Style) string {
+	var str string
+	for i, v := range geom.Dashes(s) {
+		if i > 0 {
+			str += ","
+		}
+		str += strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return str
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Returns the D3 graph of a graph.  Clusters, nodes and links are given the
+// ids of Diagram.IDs in package godot/diagram, as they are by package
+// godot/cytoscape.  Unfilled nodes have the fill "none".
+func New(g *builder.Graph) *Graph {
+	d := diagram.New(g)
+	ids := d.IDs()
+	gr := &Graph{Directed: d.Directed, Nodes: []Node{}, Links: []Link{}}
+	for _, n := range d.AllNodes {
+		r := n.Node
+		dn := Node{
+			ID:              ids[n],
+			Label:           n.Label,
+			Group:           r.Group,
+			Shape:           n.Shape,
+			Fill:            or(n.Fill, "none"),
+			Stroke:          or(n.Color, "#000000"),
+			StrokeWidth:     geom.PenWidth(r.Penwidth, n.Style),
+			StrokeDasharray: dasharray(n.Style),
+			TextColor:       or(n.FontColor, "#000000"),
+			Hidden:          n.Style.Invisible,
+			Attributes:      attributes(r),
+		}
+		if n.Parent != nil {
+			dn.Cluster = ids[n.Parent]
+		}
+		if p := r.Position; p != nil {
+			x := diagram.Points(float64(p.X))
+			y := -diagram.Points(float64(p.Y))
+			dn.X, dn.Y = &x, &y
+			if p.Lock {
+				dn.FX, dn.FY = &x, &y
+			}
+		}
+		w, _ := strconv.ParseFloat(r.Width, 64)
+		h, _ := strconv.ParseFloat(r.Height, 64)
+		dn.Radius = diagram.Points(math.Max(w, h) / 2)
+		gr.Nodes = append(gr.Nodes, dn)
+	}
+
+	for _, e := range d.Edges {
+		gr.Links = append(gr.Links, Link{
+			ID:              ids[e],
+			Source:          ids[e.Tail],
+			Target:          ids[e.Head],
+			Label:           e.Label,
+			TargetArrow:     e.HeadArrow,
+			SourceArrow:     e.TailArrow,
+			Stroke:          or(e.Color, "#000000"),
+			StrokeWidth:     geom.PenWidth(e.Edge.Penwidth, e.Style),
+			StrokeDasharray: dasharray(e.Style),
+			TextColor:       or(e.FontColor, "#000000"),
+			Hidden:          e.Style.Invisible,
+			Attributes:      attributes(e.Edge),
+		})
+	}
+	return gr
+}
+
+// Writes the D3 graph of a graph as JSON.
+func Encode(w io.Writer, g *builder.Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(New(g)); err != nil {
+		return fmt.Errorf("d3: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package d3
+
+import "bytes"
+import "encoding/json"
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+func TestEncode(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		a [id=start, label="A", shape=box, style=filled, fillcolor=red, pos="1,2!", width=1];
+		b [color=blue, group=g1, pos="2,0"];
+		subgraph cluster_0 { label=Outer; b }
+		a -> b [label=ab, style=dashed];
+		b -> a [dir=none, id=back];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Encode wrote invalid JSON: %v", err)
+	}
+	nodes := got["nodes"].([]interface{})
+	links := got["links"].([]interface{})
+	if got["directed"] != true || len(nodes) != 2 || len(links) != 2 {
+		t.Fatalf("Encode wrote %s", buf.String())
+	}
+
+	a, b := nodes[0].(map[string]interface{}), nodes[1].(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"id": "start", "label": "A", "shape": "box", "fill": "#ff0000", "stroke": "#000000",
+		"x": 72.0, "y": -144.0, "fx": 72.0, "fy": -144.0, "radius": 36.0,
+	} {
+		if a[key] != want {
+			t.Errorf("Node a has %s %v, wanted %v", key, a[key], want)
+		}
+	}
+	for key, want := range map[string]interface{}{
+		"id": "n1", "group": "g1", "cluster": "s0", "fill": "none", "stroke": "#0000ff", "x": 144.0,
+	} {
+		if b[key] != want {
+			t.Errorf("Node b has %s %v, wanted %v", key, b[key], want)
+		}
+	}
+	if _, ok := b["fx"]; ok {
+		t.Errorf("Node b without a locked position has fx")
+	}
+
+	ab, ba := links[0].(map[string]interface{}), links[1].(map[string]interface{})
+	if ab["id"] != "e0" || ab["source"] != "start" || ab["target"] != "n1" || ab["targetArrow"] != true || ab["strokeDasharray"] != "5,2" {
+		t.Errorf("Link ab is %v", ab)
+	}
+	if ba["id"] != "back" || ba["targetArrow"] != nil || ba["attributes"].(map[string]interface{})["dir"] != "none" {
+		t.Errorf("Link ba is %v", ba)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package d2 writes graphs in D2, the declarative diagram language.
+
+Nodes become shapes, keyed by their ids, clusters become containers holding
+them, and edges connections between their keys, such as "s0.n2".  Dot
+shapes, colors, line styles, arrowheads, links and tooltips are mapped onto
+the D2 ones, and the rank direction onto the diagram's direction.
+
+Resources:
+  https://d2lang.com/tour/intro
+*/
+package d2
+
+import "bytes"
+import "fmt"
+import "io"
+import "strconv"
+import "strings"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+
+func init() {
+	format.Register("d2", []string{".d2"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return diagram.Write(w, g, Backend{})
+		}, godot.Clusters), nil)
+}
+
+// The D2 shapes of dot shapes.
+var shapes = map[string]string{
+	"box":           "rectangle",
+	"rect":          "rectangle",
+	"rectangle":     "rectangle",
+	"square":        "square",
+	"ellipse":       "oval",
+	"oval":          "oval",
+	"circle":        "circle",
+	"doublecircle":  "circle",
+	"point":         "circle",
+	"diamond":       "diamond",
+	"hexagon":       "hexagon",
+	"cylinder":      "cylinder",
+	"parallelogram": "parallelogram",
+	"note":          "page",
+	"folder":        "package",
+	"tab":           "package",
+	"cds":           "step",
+	"rarrow":        "step",
+	"plaintext":     "text",
+	"plain":         "text",
+	"none":          "text",
+}
+
+// The D2 arrowhead shapes of dot arrowheads, and whether they are filled.
+var arrowheads = map[string]struct {
+	shape  string
+	filled bool
+}{
+	"onormal":  {"triangle", false},
+	"vee":      {"arrow", true},
+	"dot":      {"circle", true},
+	"odot":     {"circle", false},
+	"diamond":  {"diamond", true},
+	"odiamond": {"diamond", false},
+	"box":      {"box", true},
+	"obox":     {"box", false},
+	"crow":     {"cf-many", true},
+	"tee":      {"cf-one", true},
+}
+
+// The D2 directions of rank directions.
+var directions = map[string]string{
+	"LR": "right",
+	"RL": "left",
+	"BT": "up",
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Returns text as a quoted D2 string.
+func quote(text string) string {
+	return `"` + escaper.Replace(text) + `"`
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Writes the fields of a shape or connection within braces, or nothing if
+// there are none.
+func block(buf *bytes.Buffer, indent string, fields [][2]string) {
+	if len(fields) == 0 {
+		buf.WriteString("\n")
+		return
+	}
+	buf.WriteString(" {\n")
+	for _, f := range fields {
+		fmt.Fprintf(buf, "%s  %s: %s\n", indent, f[0], f[1])
+	}
+	fmt.Fprintf(buf, "%s}\n", indent)
+}
+
+// Appends the style fields of a line, and of the fill, to fields.
+func styles(fields [][2]string, fill, stroke, font string, penwidth float64, bold, dashed, dotted, invisible bool) [][2]string {
+	if fill != "" {
+		fields = append(fields, [2]string{"style.fill", quote(fill)})
+	}
+	if stroke != "" {
+		fields = append(fields, [2]string{"style.stroke", quote(stroke)})
+	}
+	if font != "" {
+		fields = append(fields, [2]string{"style.font-color", quote(font)})
+	}
+	switch {
+	case penwidth > 0:
+		fields = append(fields, [2]string{"style.stroke-width", num(penwidth)})
+	case bold:
+		fields = append(fields, [2]string{"style.stroke-width", "2"})
+	}
+	switch {
+	case dashed:
+		fields = append(fields, [2]string{"style.stroke-dash", "5"})
+	case dotted:
+		fields = append(fields, [2]string{"style.stroke-dash", "2"})
+	}
+	if invisible {
+		fields = append(fields, [2]string{"style.opacity", "0"})
+	}
+	return fields
+}
+
+func links(fields [][2]string, url, tooltip string) [][2]string {
+	if url != "" {
+		fields = append(fields, [2]string{"link", quote(url)})
+	}
+	if tooltip != "" {
+		fields = append(fields, [2]string{"tooltip", quote(tooltip)})
+	}
+	return fields
+}
+
+// Writes D2.  Nodes of several clusters are written in the first, and the
+// minimum lengths of edges are not written.
+type Backend struct{}
+
+func (Backend) Write(w io.Writer, d *diagram.Diagram) error {
+	var buf bytes.Buffer
+	if dir, ok := directions[d.Rankdir]; ok {
+		fmt.Fprintf(&buf, "direction: %s\n", dir)
+	}
+	if d.Title != "" {
+		fmt.Fprintf(&buf, "title: %s {\n  shape: text\n  near: top-center\n}\n", quote(d.Title))
+	}
+
+	node := func(n *diagram.Node, indent string) {
+		var fields [][2]string
+		if shape, ok := shapes[n.Shape]; ok {
+			fields = append(fields, [2]string{"shape", shape})
+		}
+		fields = styles(fields, n.Fill, n.Color, n.FontColor, n.Penwidth,
+			n.Style.Bold, n.Style.Dashed, n.Style.Dotted, n.Style.Invisible)
+		if n.Style.Rounded {
+			fields = append(fields, [2]string{"style.border-radius", "8"})
+		}
+		if n.Shape == "doublecircle" {
+			fields = append(fields, [2]string{"style.double-border", "true"})
+		}
+		fields = links(fields, n.URL, n.Tooltip)
+		fmt.Fprintf(&buf, "%s%s: %s", indent, n.ID, quote(n.Label))
+		block(&buf, indent, fields)
+	}
+	var cluster func(c *diagram.Cluster, indent string)
+	cluster = func(c *diagram.Cluster, indent string) {
+		label := c.Label
+		if label == "" {
+			label = c.Name
+		}
+		fmt.Fprintf(&buf, "%s%s: %s {\n", indent, c.ID, quote(label))
+		inner := indent + "  "
+		fields := styles(nil, c.Fill, c.Color, c.FontColor, 0,
+			c.Style.Bold, c.Style.Dashed, c.Style.Dotted, c.Style.Invisible)
+		if c.Style.Rounded {
+			fields = append(fields, [2]string{"style.border-radius", "8"})
+		}
+		for _, f := range links(fields, c.URL, c.Tooltip) {
+			fmt.Fprintf(&buf, "%s%s: %s\n", inner, f[0], f[1])
+		}
+		for _, n := range c.Nodes {
+			node(n, inner)
+		}
+		for _, sub := range c.Clusters {
+			cluster(sub, inner)
+		}
+		fmt.Fprintf(&buf, "%s}\n", indent)
+	}
+	for _, n := range d.Nodes {
+		node(n, "")
+	}
+	for _, c := range d.Clusters {
+		cluster(c, "")
+	}
+
+	for _, e := range d.Edges {
+		conn := "--"
+		switch {
+		case e.TailArrow && e.HeadArrow:
+			conn = "<->"
+		case e.TailArrow:
+			conn = "<-"
+		case e.HeadArrow:
+			conn = "->"
+		}
+		fmt.Fprintf(&buf, "%s %s %s", strings.Join(e.Tail.Path(), "."), conn, strings.Join(e.Head.Path(), "."))
+		if e.Label != "" {
+			fmt.Fprintf(&buf, ": %s", quote(e.Label))
+		}
+		fields := styles(nil, "", e.Color, e.FontColor, e.Penwidth,
+			e.Style.Bold, e.Style.Dashed, e.Style.Dotted, e.Style.Invisible)
+		for _, end := range []struct {
+			key       string
+			arrow     bool
+			arrowhead string
+		}{
+			{"source-arrowhead", e.TailArrow, e.Arrowtail},
+			{"target-arrowhead", e.HeadArrow, e.Arrowhead},
+		} {
+			if a, ok := arrowheads[end.arrowhead]; ok && end.arrow {
+				fields = append(fields, [2]string{end.key + ".shape", a.shape})
+				if !a.filled {
+					fields = append(fields, [2]string{end.key + ".style.filled", "false"})
+				}
+			}
+		}
+		block(&buf, "", fields)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,93 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package d2
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/diagram"
+
+func TestBackend(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		label="Flow"; rankdir=LR;
+		node [shape=box];
+		a [label="A \"x\"\nB", fillcolor=red, style=filled, URL="http://a", tooltip=tip];
+		b [shape=cylinder, color=blue];
+		s [shape=point];
+		subgraph cluster_0 { label=Outer; style=dashed; c [shape=ellipse]; subgraph cluster_1 { d [shape=doublecircle] } }
+		s -> a;
+		a -> b [label=ab, color=green];
+		b -> c [style=dashed, minlen=2, arrowhead=odot];
+		c -> d [dir=both, style=bold];
+		d -> a [dir=none];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := diagram.Write(&buf, g, Backend{}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := `direction: right
+title: "Flow" {
+  shape: text
+  near: top-center
+}
+n0: "A \"x\"\nB" {
+  shape: rectangle
+  style.fill: "#ff0000"
+  link: "http://a"
+  tooltip: "tip"
+}
+n1: "b" {
+  shape: cylinder
+  style.stroke: "#0000ff"
+}
+n2: "s" {
+  shape: circle
+}
+s0: "Outer" {
+  style.stroke-dash: 5
+  n3: "c" {
+    shape: oval
+  }
+  s1: "cluster_1" {
+    n4: "d" {
+      shape: circle
+      style.double-border: true
+    }
+  }
+}
+n2 -> n0
+n0 -> n1: "ab" {
+  style.stroke: "#00ff00"
+}
+n1 -> s0.n3 {
+  style.stroke-dash: 5
+  target-arrowhead.shape: circle
+  target-arrowhead.style.filled: false
+}
+s0.n3 <-> s0.s1.n4 {
+  style.stroke-width: 2
+}
+s0.s1.n4 -- n0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Write wrote\n%s\nwanted\n%s", got, want)
+	}
+}
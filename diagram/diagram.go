@@ -0,0 +1,393 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package diagram writes graphs in the text languages of diagram tools other
+than dot.
+
+A Diagram is a graph reduced to what such languages share: nodes with
+labels, shapes and colors, edges with labels, arrows and line styles, and
+clusters nesting nodes and other clusters.  Its attributes are resolved
+against the graph's templates and dot's defaults, so that a Backend writing
+one language has only to spell them.  The backends of packages
+godot/diagram/plantuml, godot/diagram/d2 and godot/mermaid write PlantUML, D2
+and Mermaid:
+
+  err := diagram.Write(w, g, d2.Backend{})
+*/
+package diagram
+
+import "fmt"
+import "io"
+import "math"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/geom"
+import "godot/layout"
+
+// Writes diagrams in one language.
+type Backend interface {
+	// Writes the diagram to "w".
+	Write(w io.Writer, d *Diagram) error
+}
+
+// A graph, resolved for writing in languages other than dot.
+type Diagram struct {
+	Title    string
+	Directed bool
+
+	// The direction of ranks, TB, LR, BT or RL.
+	Rankdir string
+
+	// The nodes and clusters at the top level of the diagram, outside any
+	// cluster.
+	Nodes    []*Node
+	Clusters []*Cluster
+
+	// Every node, in the order of the graph's nodes, and every edge with
+	// both endpoints, in the order of its edges.
+	AllNodes []*Node
+	Edges    []*Edge
+
+	// The graph the diagram is of.
+	Graph *builder.Graph
+}
+
+// A node of a diagram.  Colors are in CSS notation, "#rrggbb", and empty
+// if unset or not known.
+type Node struct {
+	// "n0", "n1" and so on, in the order of the graph's nodes.
+	ID string
+
+	// The label, with dot's line breaks replaced by newlines.  Nodes without
+	// labels are labelled with their index, as they are in dot.
+	Label string
+
+	// The name of the dot shape, or empty if it is unset.
+	Shape string
+
+	Style     geom.Style
+	Color     string
+	FontColor string
+
+	// The color the node is filled with, or empty if it is not filled.
+	Fill string
+
+	// The width of the outline, or 0 if it is unset.
+	Penwidth float64
+
+	URL     string
+	Tooltip string
+	Class   string
+
+	// The cluster directly holding the node, or nil.
+	Parent *Cluster
+
+	// The node of the graph, resolved against the node template.
+	Node *builder.Node
+}
+
+// An edge of a diagram.
+type Edge struct {
+	ID         string
+	Tail, Head *Node
+	Label      string
+	Style      geom.Style
+	Color      string
+	FontColor  string
+	Penwidth   float64
+
+	// Whether the edge is drawn with an arrowhead at its head and its tail,
+	// following dot's dir attribute.
+	HeadArrow, TailArrow bool
+
+	// The dot arrowheads at each end, "normal" by default.
+	Arrowhead, Arrowtail string
+
+	// The minimum length of the edge, in ranks, 1 by default.
+	Minlen int
+
+	URL     string
+	Tooltip string
+	Class   string
+
+	// The edge of the graph, resolved against the edge template.
+	Edge *builder.Edge
+}
+
+// A cluster, a subgraph of the graph drawn as a box around its nodes.
+type Cluster struct {
+	// "s0", "s1" and so on, in the order of a depth first walk of the
+	// graph's subgraphs.
+	ID string
+
+	// The name of the subgraph.
+	Name string
+
+	Label     string
+	Style     geom.Style
+	Color     string
+	FontColor string
+	Fill      string
+	URL       string
+	Tooltip   string
+	Class     string
+
+	// The nodes directly within the cluster, and the clusters nested in it.
+	Nodes    []*Node
+	Clusters []*Cluster
+
+	// The cluster holding this one, or nil.
+	Parent *Cluster
+
+	Subgraph *builder.Subgraph
+}
+
+// Returns the ids of the clusters holding a node, outermost first,
+// followed by the node's own id.
+func (n *Node) Path() []string {
+	path := []string{n.ID}
+	for c := n.Parent; c != nil; c = c.Parent {
+		path = append([]string{c.ID}, path...)
+	}
+	return path
+}
+
+// Returns a color in CSS notation, or "" if it is nil or its components
+// are not known.
+func cssColor(c color.Color) string {
+	rgb, _, ok := color.Components(c)
+	if !ok {
+		return ""
+	}
+	return rgb.String()
+}
+
+// Returns the fill of a filled shape: its fill color, or else its line
+// color, or else light grey.
+func fill(style geom.Style, fillColor, lineColor color.Color) string {
+	switch {
+	case !style.Filled:
+		return ""
+	case fillColor != nil:
+		return cssColor(fillColor)
+	case lineColor != nil:
+		return cssColor(lineColor)
+	}
+	return "#d3d3d3"
+}
+
+// Replaces the escaped line breaks of dot labels with newlines.
+var lineBreaks = strings.NewReplacer(`\n`, "\n", `\l`, "\n", `\r`, "\n")
+
+func label(text string) string {
+	return strings.TrimSuffix(lineBreaks.Replace(text), "\n")
+}
+
+func penwidth(s string) float64 {
+	if w, err := strconv.ParseFloat(s, 64); err == nil && w >= 0 {
+		return w
+	}
+	return 0
+}
+
+// Returns the diagram of a graph.  A node is placed in the first subgraph
+// holding it, so that each node is in at most one cluster.  Every subgraph
+// is made a cluster, whether or not its name begins with "cluster".
+func New(g *builder.Graph) *Diagram {
+	d := &Diagram{
+		Title:    label(g.Label),
+		Directed: g.Kind() == attr.Directed,
+		Rankdir:  "TB",
+		Graph:    g,
+	}
+	if g.Rankdir != nil {
+		d.Rankdir = g.Rankdir.String()
+	}
+
+	nodes := make(map[*builder.Node]*Node)
+	for i, n := range g.Nodes() {
+		r := g.ResolveNode(n)
+		dn := &Node{
+			ID:        fmt.Sprintf("n%d", i),
+			Label:     label(r.Label),
+			Style:     geom.ParseStyle(r.Style),
+			Color:     cssColor(r.Color),
+			FontColor: cssColor(r.FontColor),
+			Penwidth:  penwidth(r.Penwidth),
+			URL:       r.URL,
+			Tooltip:   r.Tooltip,
+			Class:     r.Class,
+			Node:      r,
+		}
+		if r.Label == "" {
+			dn.Label = strconv.Itoa(i)
+		}
+		if r.Shape != nil {
+			dn.Shape = r.Shape.String()
+		}
+		dn.Fill = fill(dn.Style, r.FillColor, r.Color)
+		nodes[n] = dn
+		d.AllNodes = append(d.AllNodes, dn)
+	}
+
+	placed := make(map[*Node]bool)
+	count := 0
+	var walk func(subs []*builder.Subgraph, parent *Cluster) []*Cluster
+	walk = func(subs []*builder.Subgraph, parent *Cluster) []*Cluster {
+		var clusters []*Cluster
+		for _, sub := range subs {
+			style := geom.ParseStyle(sub.Style)
+			c := &Cluster{
+				ID:        fmt.Sprintf("s%d", count),
+				Name:      sub.Name,
+				Label:     label(sub.Label),
+				Style:     style,
+				Color:     cssColor(sub.Color),
+				FontColor: cssColor(sub.FontColor),
+				Fill:      fill(style, sub.FillColor, sub.Color),
+				URL:       sub.URL,
+				Tooltip:   sub.Tooltip,
+				Class:     sub.Class,
+				Parent:    parent,
+				Subgraph:  sub,
+			}
+			count++
+			for _, n := range sub.Nodes() {
+				if dn := nodes[n]; dn != nil && !placed[dn] {
+					placed[dn] = true
+					dn.Parent = c
+					c.Nodes = append(c.Nodes, dn)
+				}
+			}
+			c.Clusters = walk(sub.Subgraphs(), c)
+			clusters = append(clusters, c)
+		}
+		return clusters
+	}
+	// A subgraph takes its own nodes before those nested in it do, so that
+	// nodes are placed in the first subgraph of a depth first walk.
+	d.Clusters = walk(g.Subgraphs(), nil)
+	for _, dn := range d.AllNodes {
+		if !placed[dn] {
+			d.Nodes = append(d.Nodes, dn)
+		}
+	}
+
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		r := g.ResolveEdge(e)
+		de := &Edge{
+			ID:        fmt.Sprintf("e%d", len(d.Edges)),
+			Tail:      nodes[e.Src],
+			Head:      nodes[e.Dst],
+			Label:     label(r.Label),
+			Style:     geom.ParseStyle(r.Style),
+			Color:     cssColor(r.Color),
+			FontColor: cssColor(r.FontColor),
+			Penwidth:  penwidth(r.Penwidth),
+			Arrowhead: r.Arrowhead,
+			Arrowtail: r.Arrowtail,
+			Minlen:    1,
+			URL:       r.URL,
+			Tooltip:   r.Tooltip,
+			Class:     r.Class,
+			Edge:      r,
+		}
+		dir := r.Dir
+		if dir == "" {
+			dir = "none"
+			if d.Directed {
+				dir = "forward"
+			}
+		}
+		de.HeadArrow = dir == "forward" || dir == "both"
+		de.TailArrow = dir == "back" || dir == "both"
+		if de.Arrowhead == "" {
+			de.Arrowhead = "normal"
+		}
+		if de.Arrowtail == "" {
+			de.Arrowtail = "normal"
+		}
+		if de.Arrowhead == "none" {
+			de.HeadArrow = false
+		}
+		if de.Arrowtail == "none" {
+			de.TailArrow = false
+		}
+		if m, err := strconv.Atoi(r.Minlen); err == nil && m > 1 {
+			de.Minlen = m
+		}
+		d.Edges = append(d.Edges, de)
+	}
+	return d
+}
+
+// Returns an id: the dot id of an object if it is set and not yet used, or
+// else its id in the diagram, suffixed with "_2", "_3" and so on if a dot id
+// has taken it.
+func id(dotID, fallback string, used map[string]bool) string {
+	if dotID == "" || used[dotID] {
+		dotID = fallback
+		for i := 2; used[dotID]; i++ {
+			dotID = fallback + "_" + strconv.Itoa(i)
+		}
+	}
+	used[dotID] = true
+	return dotID
+}
+
+// Returns ids for the clusters, nodes and edges of the diagram, keyed by
+// their *Cluster, *Node and *Edge, which are unique among them all.  Each
+// is given its dot id, if it is set and not taken, or else its id in the
+// diagram.  Ids are given to the clusters first, each before those nested in
+// it, then to the nodes and then to the edges, so that formats writing the
+// same graph give its objects the same ids.
+func (d *Diagram) IDs() map[interface{}]string {
+	used := make(map[string]bool)
+	ids := make(map[interface{}]string)
+	var walk func(cs []*Cluster)
+	walk = func(cs []*Cluster) {
+		for _, c := range cs {
+			ids[c] = id(c.Subgraph.ID, c.ID, used)
+			walk(c.Clusters)
+		}
+	}
+	walk(d.Clusters)
+	for _, n := range d.AllNodes {
+		ids[n] = id(n.Node.ID, n.ID, used)
+	}
+	for _, e := range d.Edges {
+		ids[e] = id(e.Edge.ID, e.ID, used)
+	}
+	return ids
+}
+
+// Returns a length in inches, as dot gives positions and sizes, in points,
+// rounded to thousandths to hide the float32 coordinates of dot points.
+func Points(inches float64) float64 {
+	return math.Round(inches*layout.PointsPerInch*1000) / 1000
+}
+
+// Writes the diagram of a graph with a backend.
+func Write(w io.Writer, g *builder.Graph, b Backend) error {
+	return b.Write(w, New(g))
+}
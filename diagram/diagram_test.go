@@ -0,0 +1,104 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package diagram
+
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+func TestNew(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		label="Two\nlines"; rankdir=BT;
+		node [color=blue, style=filled];
+		edge [dir=both, arrowtail=none];
+		a [label=""]; b [fillcolor=red, shape=box]; c [style=dashed]; d;
+		subgraph cluster_0 { b; subgraph cluster_1 { b; c } }
+		subgraph cluster_2 { style=filled; c; d }
+		a -> b [minlen=3]; b -> c [dir=back, arrowtail=dot]; c -> d [arrowhead=none];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	d := New(g)
+
+	if d.Title != "Two\nlines" || !d.Directed || d.Rankdir != "BT" {
+		t.Errorf("New gave title %q, directed %v, rankdir %s", d.Title, d.Directed, d.Rankdir)
+	}
+	a, b, c, dn := d.AllNodes[0], d.AllNodes[1], d.AllNodes[2], d.AllNodes[3]
+	if a.ID != "n0" || a.Label != "0" || a.Fill != "#0000ff" || a.Color != "#0000ff" {
+		t.Errorf("Node a is %+v", a)
+	}
+	if b.Shape != "box" || b.Fill != "#ff0000" {
+		t.Errorf("Node b is %+v", b)
+	}
+	if c.Fill != "" || !c.Style.Dashed {
+		t.Errorf("Node c is %+v", c)
+	}
+
+	if len(d.Nodes) != 1 || d.Nodes[0] != a || len(d.Clusters) != 2 {
+		t.Fatalf("New placed %d nodes and %d clusters at the top", len(d.Nodes), len(d.Clusters))
+	}
+	s0, s2 := d.Clusters[0], d.Clusters[1]
+	s1 := s0.Clusters[0]
+	if s0.ID != "s0" || s1.ID != "s1" || s2.ID != "s2" || s1.Parent != s0 || s2.Fill != "#d3d3d3" {
+		t.Errorf("New gave clusters %+v, %+v, %+v", s0, s1, s2)
+	}
+	if b.Parent != s0 || c.Parent != s1 || dn.Parent != s2 {
+		t.Errorf("New placed b in %v, c in %v and d in %v", b.Parent, c.Parent, dn.Parent)
+	}
+	if p := strings.Join(c.Path(), "."); p != "s0.s1.n2" {
+		t.Errorf("Path of c is %s", p)
+	}
+
+	if len(d.Edges) != 3 {
+		t.Fatalf("New gave %d edges", len(d.Edges))
+	}
+	for i, want := range []struct {
+		head, tail bool
+		arrowtail  string
+		minlen     int
+	}{
+		{true, false, "none", 3},
+		{false, true, "dot", 1},
+		{false, false, "none", 1},
+	} {
+		e := d.Edges[i]
+		if e.HeadArrow != want.head || e.TailArrow != want.tail || e.Arrowtail != want.arrowtail || e.Minlen != want.minlen {
+			t.Errorf("Edge %d is %+v", i, e)
+		}
+	}
+}
+
+func TestIDs(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		a [id=e0]; b [id=s0]; c [id=e0];
+		subgraph cluster_0 { id=n2; c }
+		a -> b; b -> c [id=n0];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	d := New(g)
+	ids := d.IDs()
+	var got []string
+	for _, obj := range []interface{}{d.Clusters[0], d.AllNodes[0], d.AllNodes[1], d.AllNodes[2], d.Edges[0], d.Edges[1]} {
+		got = append(got, ids[obj])
+	}
+	if s := strings.Join(got, " "); s != "n2 e0 s0 n2_2 e0_2 n0" {
+		t.Errorf("IDs gave %s", s)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package plantuml writes graphs as PlantUML component and activity diagrams.
+
+The Component backend writes every node as an element of a component
+diagram, whose kind follows the dot shape: cylinders become databases,
+ellipses use cases, boxes rectangles and so on.  Clusters become nested
+rectangles, and colors and line styles are written inline.  The Activity
+backend writes the graph in the legacy activity syntax, whose arrows may
+join activities in any way: nodes become activities, points and double
+circles the start and end, and top level clusters partitions.
+
+Resources:
+  https://plantuml.com/component-diagram
+  https://plantuml.com/activity-diagram-legacy
+*/
+package plantuml
+
+import "bytes"
+import "fmt"
+import "io"
+import "strconv"
+import "strings"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+
+func init() {
+	format.Register("plantuml", []string{".puml", ".plantuml"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return diagram.Write(w, g, Component{})
+		}, godot.Clusters), nil)
+}
+
+// The component diagram elements of dot shapes.
+var elements = map[string]string{
+	"box":          "rectangle",
+	"rect":         "rectangle",
+	"rectangle":    "rectangle",
+	"square":       "rectangle",
+	"ellipse":      "usecase",
+	"oval":         "usecase",
+	"circle":       "circle",
+	"doublecircle": "circle",
+	"point":        "circle",
+	"cylinder":     "database",
+	"component":    "component",
+	"folder":       "folder",
+	"tab":          "folder",
+	"note":         "file",
+	"box3d":        "node",
+	"hexagon":      "hexagon",
+	"plaintext":    "label",
+	"plain":        "label",
+	"none":         "label",
+	"underline":    "card",
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, "<U+0022>")
+
+// Returns text as a quoted PlantUML string.
+func quote(text string) string {
+	return `"` + escaper.Replace(text) + `"`
+}
+
+// Returns a color as PlantUML writes it after a property, without "#".
+func hex(css string) string {
+	return strings.TrimPrefix(css, "#")
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Returns the inline style of an element, "#back;line:color;text:color",
+// or "" if it has none.
+func inline(fill, line, text string, dashed, dotted, bold bool) string {
+	var parts []string
+	if fill != "" {
+		parts = append(parts, "back:"+hex(fill))
+	}
+	if line != "" {
+		parts = append(parts, "line:"+hex(line))
+	}
+	switch {
+	case dashed:
+		parts = append(parts, "line.dashed")
+	case dotted:
+		parts = append(parts, "line.dotted")
+	}
+	if bold {
+		parts = append(parts, "line.bold")
+	}
+	if text != "" {
+		parts = append(parts, "text:"+hex(text))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " #" + strings.Join(parts, ";")
+}
+
+// Returns the link of an element, "[[url{tooltip}]]", or "".
+func link(url, tooltip string) string {
+	if url == "" {
+		return ""
+	}
+	if tooltip != "" {
+		url += "{" + tooltip + "}"
+	}
+	return " [[" + url + "]]"
+}
+
+// Returns an arrow, with the options of an edge's line between its first
+// and second characters, as many dashes as its minimum length asks, and
+// the arrowheads dot draws.
+func arrow(e *diagram.Edge) string {
+	var opts []string
+	if e.Color != "" {
+		opts = append(opts, e.Color)
+	}
+	switch {
+	case e.Style.Invisible:
+		opts = append(opts, "hidden")
+	case e.Style.Dotted:
+		opts = append(opts, "dotted")
+	case e.Style.Bold && e.Penwidth == 0:
+		opts = append(opts, "bold")
+	}
+	if e.Penwidth > 0 {
+		opts = append(opts, "thickness="+num(e.Penwidth))
+	}
+	line := "-"
+	if e.Style.Dashed {
+		line = "."
+	}
+	var b strings.Builder
+	if e.TailArrow {
+		b.WriteString("<")
+	}
+	b.WriteString(line)
+	if len(opts) > 0 {
+		fmt.Fprintf(&b, "[%s]", strings.Join(opts, ","))
+	}
+	b.WriteString(strings.Repeat(line, e.Minlen))
+	if e.HeadArrow {
+		b.WriteString(">")
+	}
+	return b.String()
+}
+
+func title(buf *bytes.Buffer, d *diagram.Diagram) {
+	if d.Title != "" {
+		fmt.Fprintf(buf, "title %s\n", escaper.Replace(d.Title))
+	}
+	if d.Rankdir == "LR" || d.Rankdir == "RL" {
+		buf.WriteString("left to right direction\n")
+	}
+}
+
+// Writes component diagrams.  Nodes whose shape has no element, or is
+// unset, become components.  Edges of undirected graphs are written as
+// plain lines, and arrowheads of other kinds than dot's normal one as
+// arrows.  Nodes of several clusters are written in the first.
+type Component struct{}
+
+func (Component) Write(w io.Writer, d *diagram.Diagram) error {
+	var buf bytes.Buffer
+	buf.WriteString("@startuml\n")
+	title(&buf, d)
+
+	node := func(n *diagram.Node, indent string) {
+		elem, ok := elements[n.Shape]
+		if !ok {
+			elem = "component"
+		}
+		bold := n.Style.Bold || n.Penwidth >= 2
+		fmt.Fprintf(&buf, "%s%s %s as %s%s%s\n", indent, elem, quote(n.Label), n.ID,
+			inline(n.Fill, n.Color, n.FontColor, n.Style.Dashed, n.Style.Dotted, bold),
+			link(n.URL, n.Tooltip))
+	}
+	var cluster func(c *diagram.Cluster, indent string)
+	cluster = func(c *diagram.Cluster, indent string) {
+		label := c.Label
+		if label == "" {
+			label = c.Name
+		}
+		fmt.Fprintf(&buf, "%srectangle %s as %s%s%s {\n", indent, quote(label), c.ID,
+			inline(c.Fill, c.Color, c.FontColor, c.Style.Dashed, c.Style.Dotted, c.Style.Bold),
+			link(c.URL, c.Tooltip))
+		for _, n := range c.Nodes {
+			node(n, indent+"  ")
+		}
+		for _, sub := range c.Clusters {
+			cluster(sub, indent+"  ")
+		}
+		fmt.Fprintf(&buf, "%s}\n", indent)
+	}
+	for _, n := range d.Nodes {
+		node(n, "")
+	}
+	for _, c := range d.Clusters {
+		cluster(c, "")
+	}
+
+	for _, e := range d.Edges {
+		fmt.Fprintf(&buf, "%s %s %s", e.Tail.ID, arrow(e), e.Head.ID)
+		if e.Label != "" {
+			fmt.Fprintf(&buf, " : %s", escaper.Replace(e.Label))
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("@enduml\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Writes activity diagrams in the legacy syntax, in which activities are
+// declared by the arrows leading to and from them.  Nodes without edges
+// therefore cannot be written, and are left out.  Edges are written in the
+// partition of their tail, and each node is placed in the partition of the
+// first edge to mention it.  Nested clusters are merged into the partition
+// of the outermost one, and the fill is the only color of an activity
+// which is written.
+type Activity struct{}
+
+func (Activity) Write(w io.Writer, d *diagram.Diagram) error {
+	var buf bytes.Buffer
+	buf.WriteString("@startuml\n")
+	title(&buf, d)
+
+	// The top level cluster holding each node, if any.
+	partition := make(map[*diagram.Node]*diagram.Cluster)
+	var walk func(c, top *diagram.Cluster)
+	walk = func(c, top *diagram.Cluster) {
+		for _, n := range c.Nodes {
+			partition[n] = top
+		}
+		for _, sub := range c.Clusters {
+			walk(sub, top)
+		}
+	}
+	for _, c := range d.Clusters {
+		walk(c, c)
+	}
+
+	declared := make(map[*diagram.Node]bool)
+	ref := func(n *diagram.Node) string {
+		if n.Shape == "point" || n.Shape == "doublecircle" {
+			return "(*)"
+		}
+		if declared[n] {
+			return n.ID
+		}
+		declared[n] = true
+		s := quote(n.Label) + " as " + n.ID
+		if n.Fill != "" {
+			s += " " + n.Fill
+		}
+		return s
+	}
+	edge := func(e *diagram.Edge, indent string) {
+		fmt.Fprintf(&buf, "%s%s ", indent, ref(e.Tail))
+		a := arrow(e)
+		if !strings.HasSuffix(a, ">") {
+			a += ">"
+		}
+		buf.WriteString(strings.TrimPrefix(a, "<"))
+		if e.Label != "" {
+			fmt.Fprintf(&buf, "[%s]", escaper.Replace(e.Label))
+		}
+		fmt.Fprintf(&buf, " %s\n", ref(e.Head))
+	}
+
+	for _, e := range d.Edges {
+		if partition[e.Tail] == nil {
+			edge(e, "")
+		}
+	}
+	for _, c := range d.Clusters {
+		var edges []*diagram.Edge
+		for _, e := range d.Edges {
+			if partition[e.Tail] == c {
+				edges = append(edges, e)
+			}
+		}
+		if len(edges) == 0 {
+			continue
+		}
+		label := c.Label
+		if label == "" {
+			label = c.Name
+		}
+		fmt.Fprintf(&buf, "partition %s {\n", quote(label))
+		for _, e := range edges {
+			edge(e, "  ")
+		}
+		buf.WriteString("}\n")
+	}
+	buf.WriteString("@enduml\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
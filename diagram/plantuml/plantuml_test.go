@@ -0,0 +1,92 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plantuml
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/diagram"
+
+const src = `digraph {
+		label="Flow"; rankdir=LR;
+		node [shape=box];
+		a [label="A \"x\"\nB", fillcolor=red, style=filled, URL="http://a", tooltip=tip];
+		b [shape=cylinder, color=blue];
+		s [shape=point];
+		subgraph cluster_0 { label=Outer; style=dashed; c [shape=ellipse]; subgraph cluster_1 { d [shape=doublecircle] } }
+		s -> a;
+		a -> b [label=ab, color=green];
+		b -> c [style=dashed, minlen=2, arrowhead=odot];
+		c -> d [dir=both, style=bold];
+		d -> a [dir=none];
+	}`
+
+func write(t *testing.T, b diagram.Backend) string {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := diagram.Write(&buf, g, b); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestComponent(t *testing.T) {
+	want := `@startuml
+title Flow
+left to right direction
+rectangle "A <U+0022>x<U+0022>\nB" as n0 #back:ff0000 [[http://a{tip}]]
+database "b" as n1 #line:0000ff
+circle "s" as n2
+rectangle "Outer" as s0 #line.dashed {
+  usecase "c" as n3
+  rectangle "cluster_1" as s1 {
+    circle "d" as n4
+  }
+}
+n2 --> n0
+n0 -[#00ff00]-> n1 : ab
+n1 ...> n3
+n3 <-[bold]-> n4
+n4 -- n0
+@enduml
+`
+	if got := write(t, Component{}); got != want {
+		t.Errorf("Component wrote\n%s\nwanted\n%s", got, want)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	want := `@startuml
+title Flow
+left to right direction
+(*) --> "A <U+0022>x<U+0022>\nB" as n0 #ff0000
+n0 -[#00ff00]->[ab] "b" as n1
+n1 ...> "c" as n3
+partition "Outer" {
+  n3 -[bold]-> (*)
+  (*) --> n0
+}
+@enduml
+`
+	if got := write(t, Activity{}); got != want {
+		t.Errorf("Activity wrote\n%s\nwanted\n%s", got, want)
+	}
+}
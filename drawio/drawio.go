@@ -0,0 +1,440 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package drawio writes laid out graphs as draw.io diagrams, the mxGraph XML
+which draw.io and diagrams.net open for editing.
+
+Nodes become vertices with their shapes, colors and labels, at their laid
+out positions, and edges become edges connected to them, bent at the points
+of their routes, so that moving a node in draw.io drags its edges along.
+Clusters become containers holding their nodes.  A node's or edge's URL and
+tooltip become a link and tooltip in draw.io.  Invisible nodes and clusters,
+and edges ending at invisible nodes, are not written.
+
+  err := drawio.Write(w, g, layout.Layered(g))
+
+Graphs whose nodes all have a Position may be written without a layout by
+passing nil.
+
+Resources:
+  https://www.drawio.com/doc/faq/drawio-style-reference
+*/
+package drawio
+
+import "encoding/xml"
+import "fmt"
+import "io"
+import "math"
+import "strings"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("drawio", []string{".drawio"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return Write(w, g, layout.Default(g))
+		}, godot.Clusters|godot.Positions), nil)
+}
+
+// Space left around the diagram, in points.
+const margin = 20
+
+// The draw.io styles of dot shapes.  Other shapes are drawn as rectangles,
+// and nodes without a shape as ellipses, as they are in dot.
+var shapes = map[string]string{
+	"box":           "rounded=0",
+	"rect":          "rounded=0",
+	"rectangle":     "rounded=0",
+	"square":        "rounded=0",
+	"ellipse":       "ellipse",
+	"oval":          "ellipse",
+	"circle":        "ellipse",
+	"point":         "ellipse",
+	"doublecircle":  "ellipse;shape=doubleEllipse",
+	"diamond":       "rhombus",
+	"hexagon":       "shape=hexagon;perimeter=hexagonPerimeter2",
+	"triangle":      "triangle;direction=north",
+	"cylinder":      "shape=cylinder3",
+	"parallelogram": "shape=parallelogram;perimeter=parallelogramPerimeter",
+	"trapezium":     "shape=trapezoid;perimeter=trapezoidPerimeter",
+	"note":          "shape=note",
+	"folder":        "shape=folder",
+	"tab":           "shape=folder",
+	"box3d":         "shape=cube",
+	"plaintext":     "text",
+	"plain":         "text",
+	"none":          "text",
+}
+
+// The draw.io arrows of dot arrowheads, and whether they are filled.
+// Other arrowheads are drawn as "classic".
+var arrows = map[string]struct {
+	arrow  string
+	filled bool
+}{
+	"normal":   {"classic", true},
+	"inv":      {"classic", true},
+	"onormal":  {"classic", false},
+	"empty":    {"block", false},
+	"vee":      {"open", true},
+	"dot":      {"oval", true},
+	"odot":     {"oval", false},
+	"diamond":  {"diamond", true},
+	"odiamond": {"diamond", false},
+	"box":      {"box", true},
+	"obox":     {"box", false},
+	"tee":      {"dash", true},
+	"crow":     {"ERmany", true},
+}
+
+type file struct {
+	XMLName xml.Name `xml:"mxfile"`
+	Host    string   `xml:"host,attr"`
+	Diagram page     `xml:"diagram"`
+}
+
+type page struct {
+	ID    string `xml:"id,attr"`
+	Name  string `xml:"name,attr"`
+	Model model  `xml:"mxGraphModel"`
+}
+
+type model struct {
+	Grid     int           `xml:"grid,attr"`
+	GridSize int           `xml:"gridSize,attr"`
+	Guides   int           `xml:"guides,attr"`
+	Arrows   int           `xml:"arrows,attr"`
+	Connect  int           `xml:"connect,attr"`
+	Page     int           `xml:"page,attr"`
+	Cells    []interface{} `xml:"root>mxCell"`
+}
+
+// A cell: a vertex or an edge, or one of the two cells at the root of every
+// diagram.
+type cell struct {
+	XMLName  xml.Name  `xml:"mxCell"`
+	ID       string    `xml:"id,attr,omitempty"`
+	Value    string    `xml:"value,attr,omitempty"`
+	Style    string    `xml:"style,attr,omitempty"`
+	Vertex   string    `xml:"vertex,attr,omitempty"`
+	Edge     string    `xml:"edge,attr,omitempty"`
+	Parent   string    `xml:"parent,attr,omitempty"`
+	Source   string    `xml:"source,attr,omitempty"`
+	Target   string    `xml:"target,attr,omitempty"`
+	Geometry *geometry `xml:"mxGeometry"`
+}
+
+// A cell with a link or tooltip, which draw.io wraps in an object holding
+// them with the cell's id and label.
+type object struct {
+	XMLName xml.Name `xml:"UserObject"`
+	ID      string   `xml:"id,attr"`
+	Label   string   `xml:"label,attr"`
+	Link    string   `xml:"link,attr,omitempty"`
+	Tooltip string   `xml:"tooltip,attr,omitempty"`
+	Cell    cell
+}
+
+type geometry struct {
+	X        float64 `xml:"x,attr,omitempty"`
+	Y        float64 `xml:"y,attr,omitempty"`
+	Width    float64 `xml:"width,attr,omitempty"`
+	Height   float64 `xml:"height,attr,omitempty"`
+	Relative int     `xml:"relative,attr,omitempty"`
+	As       string  `xml:"as,attr"`
+	Points   *points `xml:"Array"`
+}
+
+// The points an edge bends at.
+type points struct {
+	As     string  `xml:"as,attr"`
+	Points []point `xml:"mxPoint"`
+}
+
+type point struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+// A draw.io style: key=value pairs, or bare names, separated by ';'.
+type style []string
+
+func (s *style) set(key string, value interface{}) {
+	*s = append(*s, fmt.Sprintf("%s=%v", key, value))
+}
+
+func (s style) String() string {
+	return strings.Join(s, ";") + ";"
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// The state of a diagram being built.
+type drawing struct {
+	g *builder.Graph
+	d *diagram.Diagram
+	l *layout.Layout
+
+	// Origin of the diagram's coordinates, top left, in layout points.
+	left, top float64
+
+	// The top left corners of the containers of clusters, by cluster.
+	origins map[*diagram.Cluster]layout.Point
+
+	cells []interface{}
+}
+
+// Converts a layout point to draw.io coordinates, with y increasing
+// downwards.
+func (d *drawing) xy(p layout.Point) (float64, float64) {
+	return p.X - d.left, d.top - p.Y
+}
+
+// Returns the id of the container holding a node or cluster, and the offset
+// of its origin, skipping clusters which were not laid out or written.
+func (d *drawing) parent(c *diagram.Cluster) (string, float64, float64) {
+	for ; c != nil; c = c.Parent {
+		if o, ok := d.origins[c]; ok {
+			return c.ID, o.X, o.Y
+		}
+	}
+	return "1", 0, 0
+}
+
+// Adds a cell, wrapped in an object if it has a link or tooltip.
+func (d *drawing) add(c cell, url, tooltip string) {
+	if url == "" && tooltip == "" {
+		d.cells = append(d.cells, c)
+		return
+	}
+	o := object{ID: c.ID, Label: c.Value, Link: url, Tooltip: tooltip, Cell: c}
+	o.Cell.ID, o.Cell.Value = "", ""
+	d.cells = append(d.cells, o)
+}
+
+// Adds the lines of a shape to its style.
+func lines(s *style, color string, pen float64, st geom.Style) {
+	if color == "" {
+		color = "#000000"
+	}
+	s.set("strokeColor", color)
+	if pen != 1 {
+		s.set("strokeWidth", round(pen))
+	}
+	if st.Dashed || st.Dotted {
+		s.set("dashed", 1)
+		if st.Dotted {
+			s.set("dashPattern", "1 4")
+		}
+	}
+}
+
+func (d *drawing) clusters(cs []*diagram.Cluster) {
+	for _, c := range cs {
+		r, ok := d.l.Clusters[c.Subgraph]
+		if !ok || c.Style.Invisible {
+			d.clusters(c.Clusters)
+			continue
+		}
+		parent, dx, dy := d.parent(c.Parent)
+		x, y := d.xy(layout.Point{X: r.Min.X, Y: r.Max.Y})
+		d.origins[c] = layout.Point{X: x, Y: y}
+		w, h := r.Size()
+
+		s := style{"rounded=0", "whiteSpace=wrap", "container=1", "collapsible=0", "verticalAlign=top"}
+		fill := c.Fill
+		if fill == "" {
+			fill = "none"
+		}
+		s.set("fillColor", fill)
+		lines(&s, c.Color, 1, c.Style)
+		if c.FontColor != "" {
+			s.set("fontColor", c.FontColor)
+		}
+		d.add(cell{
+			ID: c.ID, Value: c.Label, Style: s.String(), Vertex: "1", Parent: parent,
+			Geometry: &geometry{X: round(x - dx), Y: round(y - dy), Width: round(w), Height: round(h), As: "geometry"},
+		}, c.URL, c.Tooltip)
+		d.clusters(c.Clusters)
+	}
+}
+
+func (d *drawing) node(n *diagram.Node, nl *layout.NodeLayout) {
+	s := style{"ellipse"}
+	if n.Shape != "" {
+		s[0] = "rounded=0"
+		if shape, ok := shapes[n.Shape]; ok {
+			s[0] = shape
+		}
+	}
+	if n.Style.Rounded && s[0] == "rounded=0" {
+		s[0] = "rounded=1"
+	}
+	s = append(s, "whiteSpace=wrap")
+	switch {
+	case s[0] == "text":
+		s = append(s, "strokeColor=none", "fillColor=none")
+	case n.Shape == "point":
+		fill := n.Color
+		if fill == "" {
+			fill = "#000000"
+		}
+		s.set("fillColor", fill)
+		lines(&s, n.Color, geom.PenWidth(n.Node.Penwidth, n.Style), n.Style)
+	default:
+		fill := n.Fill
+		if fill == "" {
+			fill = "none"
+		}
+		s.set("fillColor", fill)
+		lines(&s, n.Color, geom.PenWidth(n.Node.Penwidth, n.Style), n.Style)
+	}
+	if n.FontColor != "" {
+		s.set("fontColor", n.FontColor)
+	}
+	if n.Style.Bold {
+		s.set("fontStyle", 1)
+	}
+
+	value := n.Label
+	if n.Shape == "point" {
+		value = ""
+	}
+	parent, dx, dy := d.parent(n.Parent)
+	x, y := d.xy(nl.Center)
+	d.add(cell{
+		ID: n.ID, Value: value, Style: s.String(), Vertex: "1", Parent: parent,
+		Geometry: &geometry{
+			X: round(x - nl.Width/2 - dx), Y: round(y - nl.Height/2 - dy),
+			Width: round(nl.Width), Height: round(nl.Height), As: "geometry",
+		},
+	}, n.URL, n.Tooltip)
+}
+
+func arrow(s *style, key string, drawn bool, kind string) {
+	if !drawn {
+		s.set(key+"Arrow", "none")
+		return
+	}
+	a, ok := arrows[kind]
+	if !ok {
+		a = arrows["normal"]
+	}
+	s.set(key+"Arrow", a.arrow)
+	if !a.filled {
+		s.set(key+"Fill", 0)
+	}
+}
+
+func (d *drawing) edge(e *diagram.Edge, el *layout.EdgeLayout) {
+	s := style{"edgeStyle=none", "rounded=0"}
+	arrow(&s, "end", e.HeadArrow, e.Arrowhead)
+	arrow(&s, "start", e.TailArrow, e.Arrowtail)
+	lines(&s, e.Color, geom.PenWidth(e.Edge.Penwidth, e.Style), e.Style)
+	if e.FontColor != "" {
+		s.set("fontColor", e.FontColor)
+	}
+	gm := &geometry{Relative: 1, As: "geometry"}
+	if el != nil && len(el.Points) > 2 {
+		gm.Points = &points{As: "points"}
+		for _, p := range el.Points[1 : len(el.Points)-1] {
+			x, y := d.xy(p)
+			gm.Points.Points = append(gm.Points.Points, point{round(x), round(y)})
+		}
+	}
+	d.add(cell{
+		ID: e.ID, Value: e.Label, Style: s.String(), Edge: "1", Parent: "1",
+		Source: e.Tail.ID, Target: e.Head.ID, Geometry: gm,
+	}, e.URL, e.Tooltip)
+}
+
+// Writes "g", laid out as "l", as a draw.io diagram to "w".  If "l" is nil
+// the graph is drawn from the Position of each node, and
+// layout.ErrUnpositioned is returned if a node has none.  Nodes which were
+// not laid out are not written, nor are edges ending at them.
+func Write(w io.Writer, g *builder.Graph, l *layout.Layout) error {
+	if l == nil {
+		var err error
+		if l, err = layout.FromPositions(g); err != nil {
+			return err
+		}
+	}
+	d := &drawing{
+		g:       g,
+		d:       diagram.New(g),
+		l:       l,
+		left:    l.Bounds.Min.X - margin,
+		top:     l.Bounds.Max.Y + margin,
+		origins: make(map[*diagram.Cluster]layout.Point),
+	}
+	d.cells = []interface{}{cell{ID: "0"}, cell{ID: "1", Parent: "0"}}
+	d.clusters(d.d.Clusters)
+
+	written := make(map[*diagram.Node]bool)
+	for i, n := range g.Nodes() {
+		dn := d.d.AllNodes[i]
+		if nl, ok := l.Nodes[n]; ok && !dn.Style.Invisible {
+			d.node(dn, nl)
+			written[dn] = true
+		}
+	}
+	i := 0
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		de := d.d.Edges[i]
+		i++
+		if written[de.Tail] && written[de.Head] && !de.Style.Invisible {
+			d.edge(de, l.Edges[e])
+		}
+	}
+
+	if d.d.Title != "" {
+		b := l.Bounds
+		lh := layout.TextHeight(g.Label, layout.DefaultFontSize)
+		lw := math.Max(b.Max.X-b.Min.X, layout.TextWidth(g.Label, layout.DefaultFontSize))
+		x, y := d.xy(layout.Point{X: (b.Min.X + b.Max.X - lw) / 2, Y: b.Min.Y})
+		d.cells = append(d.cells, cell{
+			ID: "title", Value: d.d.Title, Style: "text;whiteSpace=wrap;strokeColor=none;fillColor=none;", Vertex: "1", Parent: "1",
+			Geometry: &geometry{X: round(x), Y: round(y), Width: round(lw), Height: round(lh), As: "geometry"},
+		})
+	}
+
+	name := d.d.Title
+	if name == "" {
+		name = "Page-1"
+	}
+	f := file{Host: "godot", Diagram: page{
+		ID:    "godot",
+		Name:  name,
+		Model: model{Grid: 1, GridSize: 10, Guides: 1, Arrows: 1, Connect: 1, Cells: d.cells},
+	}}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package drawio
+
+import "bytes"
+import "encoding/xml"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/layout"
+
+func TestWrite(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		subgraph cluster_0 { label=C; a [shape=box, style="filled,rounded", fillcolor=red, pos="1,2!", URL="http://a"] }
+		b [shape=diamond, color=blue, pos="1,0!"];
+		c [style=invis, pos="3,0!"];
+		a -> b [label="x\ny", style=dotted, arrowhead=odot];
+		b -> c;
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, g, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Fatalf("Write wrote invalid XML: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		`<mxCell id="s0" value="C" style="rounded=0;whiteSpace=wrap;container=1;`,
+		`<UserObject id="n0" label="a" link="http://a">`,
+		`style="rounded=1;whiteSpace=wrap;fillColor=#ff0000;strokeColor=#000000;" vertex="1" parent="s0"`,
+		`<mxCell id="n1" value="b" style="rhombus;whiteSpace=wrap;fillColor=none;strokeColor=#0000ff;" vertex="1" parent="1">`,
+		`<mxCell id="e0" value="x&#xA;y" style="edgeStyle=none;rounded=0;endArrow=oval;endFill=0;startArrow=none;strokeColor=#000000;dashed=1;dashPattern=1 4;" edge="1" parent="1" source="n0" target="n1">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Write did not write %s in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `id="n2"`) || strings.Contains(out, `id="e1"`) {
+		t.Errorf("Write wrote an invisible node or its edge:\n%s", out)
+	}
+	// b is 2 inches below a, which is placed within its cluster at 20,20.
+	if !strings.Contains(out, `<mxGeometry x="28" y="188.8" width="54" height="36" as="geometry">`) {
+		t.Errorf("Node b is misplaced in\n%s", out)
+	}
+
+	g.Nodes()[0].Position = nil
+	if err := Write(&buf, g, nil); err != layout.ErrUnpositioned {
+		t.Errorf("Write of an unpositioned node gave %v", err)
+	}
+}
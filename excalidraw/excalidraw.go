@@ -0,0 +1,446 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package excalidraw writes laid out graphs as Excalidraw scenes, the JSON
+which Excalidraw opens for editing.
+
+Nodes become rectangles, ellipses and diamonds, at their laid out positions,
+holding their labels as bound text, and edges become arrows bound to them, so
+that moving a node in Excalidraw drags its edges along.  Clusters become
+rectangles behind their nodes, grouped with them.  Invisible nodes and
+clusters, and edges ending at invisible nodes, are not written.
+
+  scene, err := excalidraw.New(g, layout.Layered(g))
+
+Graphs whose nodes all have a Position may be written without a layout by
+passing nil.
+
+Resources:
+  https://docs.excalidraw.com/docs/codebase/json-schema
+*/
+package excalidraw
+
+import "encoding/json"
+import "hash/fnv"
+import "io"
+import "math"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("excalidraw", []string{".excalidraw"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return Write(w, g, layout.Default(g))
+		}, godot.Clusters|godot.Positions), nil)
+}
+
+// The Excalidraw types of dot shapes.  Other shapes are drawn as rectangles,
+// and nodes without a shape as ellipses, as they are in dot.
+var types = map[string]string{
+	"ellipse":      "ellipse",
+	"oval":         "ellipse",
+	"circle":       "ellipse",
+	"doublecircle": "ellipse",
+	"point":        "ellipse",
+	"diamond":      "diamond",
+	"plaintext":    "text",
+	"plain":        "text",
+	"none":         "text",
+}
+
+// The Excalidraw arrowheads of dot arrowheads.  Other arrowheads are drawn
+// as "arrow".
+var arrowheads = map[string]string{
+	"normal":  "triangle",
+	"inv":     "triangle",
+	"onormal": "triangle",
+	"empty":   "triangle",
+	"dot":     "dot",
+	"odot":    "dot",
+	"tee":     "bar",
+}
+
+// The font of labels: 1 is Excalidraw's hand-drawn font, 2 a sans serif
+// and 3 a monospaced one.
+const fontFamily = 1
+
+// An Excalidraw scene.
+type Scene struct {
+	Type     string                 `json:"type"`
+	Version  int                    `json:"version"`
+	Source   string                 `json:"source"`
+	Elements []*Element             `json:"elements"`
+	AppState map[string]interface{} `json:"appState"`
+	Files    map[string]interface{} `json:"files"`
+}
+
+// An element of a scene: a shape, a text, or an arrow.  Coordinates are in
+// pixels, with y increasing downwards.  Fields Excalidraw does not need are
+// left for it to fill in when the scene is opened.
+type Element struct {
+	ID              string     `json:"id"`
+	Type            string     `json:"type"`
+	X               float64    `json:"x"`
+	Y               float64    `json:"y"`
+	Width           float64    `json:"width"`
+	Height          float64    `json:"height"`
+	Angle           float64    `json:"angle"`
+	StrokeColor     string     `json:"strokeColor"`
+	BackgroundColor string     `json:"backgroundColor"`
+	FillStyle       string     `json:"fillStyle"`
+	StrokeWidth     float64    `json:"strokeWidth"`
+	StrokeStyle     string     `json:"strokeStyle"`
+	Roughness       int        `json:"roughness"`
+	Opacity         int        `json:"opacity"`
+	GroupIDs        []string   `json:"groupIds"`
+	Roundness       *Roundness `json:"roundness"`
+	Seed            int        `json:"seed"`
+	Version         int        `json:"version"`
+	VersionNonce    int        `json:"versionNonce"`
+	IsDeleted       bool       `json:"isDeleted"`
+	BoundElements   []Bound    `json:"boundElements"`
+	Link            *string    `json:"link"`
+	Locked          bool       `json:"locked"`
+
+	// Texts.
+	Text          string  `json:"text,omitempty"`
+	OriginalText  string  `json:"originalText,omitempty"`
+	FontSize      float64 `json:"fontSize,omitempty"`
+	FontFamily    int     `json:"fontFamily,omitempty"`
+	TextAlign     string  `json:"textAlign,omitempty"`
+	VerticalAlign string  `json:"verticalAlign,omitempty"`
+	LineHeight    float64 `json:"lineHeight,omitempty"`
+	ContainerID   *string `json:"containerId,omitempty"`
+
+	// Arrows.  Points are relative to X and Y.
+	Points         [][2]float64 `json:"points,omitempty"`
+	StartBinding   *Binding     `json:"startBinding,omitempty"`
+	EndBinding     *Binding     `json:"endBinding,omitempty"`
+	StartArrowhead *string      `json:"startArrowhead,omitempty"`
+	EndArrowhead   *string      `json:"endArrowhead,omitempty"`
+}
+
+// The rounding of an element's corners.
+type Roundness struct {
+	Type int `json:"type"`
+}
+
+// A text or arrow bound to a shape.
+type Bound struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// The shape an end of an arrow is bound to.
+type Binding struct {
+	ElementID string  `json:"elementId"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// Returns a seed for the hand-drawn strokes of an element, from its id, so
+// that a graph is drawn the same each time.
+func seed(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() & 0x7fffffff)
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func newElement(id, typ string) *Element {
+	return &Element{
+		ID:              id,
+		Type:            typ,
+		StrokeColor:     "#000000",
+		BackgroundColor: "transparent",
+		FillStyle:       "solid",
+		StrokeWidth:     1,
+		StrokeStyle:     "solid",
+		Roughness:       1,
+		Opacity:         100,
+		GroupIDs:        []string{},
+		Seed:            seed(id),
+		Version:         1,
+		VersionNonce:    seed(id + "'"),
+	}
+}
+
+// Sets the lines of an element.
+func (el *Element) lines(c string, pen float64, dashed, dotted bool) {
+	el.StrokeColor = or(c, "#000000")
+	el.StrokeWidth = round(pen)
+	switch {
+	case dotted:
+		el.StrokeStyle = "dotted"
+	case dashed:
+		el.StrokeStyle = "dashed"
+	}
+}
+
+// The state of a scene being built.
+type scene struct {
+	l *layout.Layout
+
+	// Origin of the scene's coordinates, top left, in layout points.
+	left, top float64
+
+	elements []*Element
+	shapes   map[*diagram.Node]*Element
+}
+
+// Converts a layout point to scene coordinates, with y increasing
+// downwards.
+func (s *scene) xy(p layout.Point) (float64, float64) {
+	return round(p.X - s.left), round(s.top - p.Y)
+}
+
+// Returns the groups of the elements of a cluster or node, the innermost
+// first, skipping clusters which were not drawn.
+func (s *scene) groups(c *diagram.Cluster) []string {
+	ids := []string{}
+	for ; c != nil; c = c.Parent {
+		if _, ok := s.l.Clusters[c.Subgraph]; ok && !c.Style.Invisible {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// Adds a text, centered on "center" unless it is bound to a container.
+func (s *scene) text(id, text, c string, center layout.Point, container *Element, valign string) *Element {
+	t := newElement(id, "text")
+	t.Text, t.OriginalText = text, text
+	t.StrokeColor = or(c, "#000000")
+	t.FontSize = layout.DefaultFontSize
+	t.FontFamily = fontFamily
+	t.TextAlign = "center"
+	t.VerticalAlign = valign
+	t.LineHeight = 1.25
+	t.Width = round(layout.TextWidth(text, layout.DefaultFontSize))
+	t.Height = round(float64(len(layout.LabelLines(text))) * t.LineHeight * t.FontSize)
+	x, y := s.xy(center)
+	t.X, t.Y = round(x-t.Width/2), round(y-t.Height/2)
+	if container != nil {
+		t.ContainerID = &container.ID
+		t.GroupIDs = container.GroupIDs
+		container.BoundElements = append(container.BoundElements, Bound{id, "text"})
+		if valign == "top" {
+			t.Y = container.Y + 5
+		}
+	}
+	s.elements = append(s.elements, t)
+	return t
+}
+
+func (s *scene) clusters(cs []*diagram.Cluster) {
+	for _, c := range cs {
+		r, ok := s.l.Clusters[c.Subgraph]
+		if ok && !c.Style.Invisible {
+			el := newElement(c.ID, "rectangle")
+			x, y := s.xy(layout.Point{X: r.Min.X, Y: r.Max.Y})
+			w, h := r.Size()
+			el.X, el.Y, el.Width, el.Height = x, y, round(w), round(h)
+			el.BackgroundColor = or(c.Fill, "transparent")
+			el.lines(c.Color, 1, c.Style.Dashed, c.Style.Dotted)
+			el.GroupIDs = s.groups(c)
+			if c.Style.Rounded {
+				el.Roundness = &Roundness{3}
+			}
+			if c.URL != "" {
+				el.Link = &c.URL
+			}
+			s.elements = append(s.elements, el)
+			if c.Label != "" {
+				s.text(c.ID+"-label", c.Label, c.FontColor, layout.Point{X: (r.Min.X + r.Max.X) / 2, Y: r.Max.Y}, el, "top")
+			}
+		}
+		s.clusters(c.Clusters)
+	}
+}
+
+func (s *scene) node(n *diagram.Node, nl *layout.NodeLayout) {
+	typ := "ellipse"
+	if n.Shape != "" {
+		typ = "rectangle"
+		if t, ok := types[n.Shape]; ok {
+			typ = t
+		}
+	}
+	if typ == "text" {
+		t := s.text(n.ID, n.Label, n.FontColor, nl.Center, nil, "middle")
+		t.GroupIDs = s.groups(n.Parent)
+		s.shapes[n] = t
+		return
+	}
+
+	el := newElement(n.ID, typ)
+	x, y := s.xy(nl.Center)
+	el.X, el.Y = round(x-nl.Width/2), round(y-nl.Height/2)
+	el.Width, el.Height = round(nl.Width), round(nl.Height)
+	el.lines(n.Color, geom.PenWidth(n.Node.Penwidth, n.Style), n.Style.Dashed, n.Style.Dotted)
+	el.BackgroundColor = or(n.Fill, "transparent")
+	if n.Shape == "point" {
+		el.BackgroundColor = or(n.Color, "#000000")
+	}
+	if n.Style.Rounded || typ != "rectangle" {
+		el.Roundness = &Roundness{2}
+		if typ == "rectangle" {
+			el.Roundness.Type = 3
+		}
+	}
+	el.GroupIDs = s.groups(n.Parent)
+	if n.URL != "" {
+		el.Link = &n.URL
+	}
+	s.elements = append(s.elements, el)
+	s.shapes[n] = el
+	if n.Shape != "point" && n.Label != "" {
+		s.text(n.ID+"-label", n.Label, n.FontColor, nl.Center, el, "middle")
+	}
+}
+
+func arrowhead(drawn bool, kind string) *string {
+	if !drawn {
+		return nil
+	}
+	a, ok := arrowheads[kind]
+	if !ok {
+		a = "arrow"
+	}
+	return &a
+}
+
+func (s *scene) edge(e *diagram.Edge, el *layout.EdgeLayout) {
+	tail, head := s.shapes[e.Tail], s.shapes[e.Head]
+	route := el.Points
+	if len(route) < 2 {
+		return
+	}
+	a := newElement(e.ID, "arrow")
+	a.X, a.Y = s.xy(route[0])
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range route {
+		x, y := s.xy(p)
+		a.Points = append(a.Points, [2]float64{round(x - a.X), round(y - a.Y)})
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	a.Width, a.Height = round(maxX-minX), round(maxY-minY)
+	a.lines(e.Color, geom.PenWidth(e.Edge.Penwidth, e.Style), e.Style.Dashed, e.Style.Dotted)
+	a.Roundness = &Roundness{2}
+	a.StartBinding = &Binding{ElementID: tail.ID, Gap: 1}
+	a.EndBinding = &Binding{ElementID: head.ID, Gap: 1}
+	a.StartArrowhead = arrowhead(e.TailArrow, e.Arrowtail)
+	a.EndArrowhead = arrowhead(e.HeadArrow, e.Arrowhead)
+	if e.URL != "" {
+		a.Link = &e.URL
+	}
+	tail.BoundElements = append(tail.BoundElements, Bound{a.ID, "arrow"})
+	if head != tail {
+		head.BoundElements = append(head.BoundElements, Bound{a.ID, "arrow"})
+	}
+	s.elements = append(s.elements, a)
+	if e.Label != "" {
+		center := route[len(route)/2]
+		if len(route)%2 == 0 {
+			p, q := route[len(route)/2-1], route[len(route)/2]
+			center = layout.Point{X: (p.X + q.X) / 2, Y: (p.Y + q.Y) / 2}
+		}
+		if el.LabelPos != nil {
+			center = *el.LabelPos
+		}
+		s.text(e.ID+"-label", e.Label, e.FontColor, center, a, "middle")
+	}
+}
+
+// Returns the scene of "g", laid out as "l".  If "l" is nil the graph is
+// drawn from the Position of each node, and layout.ErrUnpositioned is
+// returned if a node has none.  Nodes which were not laid out are not drawn,
+// nor are edges ending at them.
+func New(g *builder.Graph, l *layout.Layout) (*Scene, error) {
+	if l == nil {
+		var err error
+		if l, err = layout.FromPositions(g); err != nil {
+			return nil, err
+		}
+	}
+	d := diagram.New(g)
+	s := &scene{
+		l:      l,
+		left:   l.Bounds.Min.X,
+		top:    l.Bounds.Max.Y,
+		shapes: make(map[*diagram.Node]*Element),
+	}
+	s.clusters(d.Clusters)
+	for i, n := range g.Nodes() {
+		dn := d.AllNodes[i]
+		if nl, ok := l.Nodes[n]; ok && !dn.Style.Invisible {
+			s.node(dn, nl)
+		}
+	}
+	i := 0
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		de := d.Edges[i]
+		i++
+		el, ok := l.Edges[e]
+		if ok && s.shapes[de.Tail] != nil && s.shapes[de.Head] != nil && !de.Style.Invisible {
+			s.edge(de, el)
+		}
+	}
+	if d.Title != "" {
+		b := l.Bounds
+		h := layout.TextHeight(g.Label, layout.DefaultFontSize)
+		s.text("title", d.Title, "", layout.Point{X: (b.Min.X + b.Max.X) / 2, Y: b.Min.Y - h}, nil, "middle")
+	}
+
+	return &Scene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "godot",
+		Elements: s.elements,
+		AppState: map[string]interface{}{"viewBackgroundColor": "#ffffff", "gridSize": nil},
+		Files:    map[string]interface{}{},
+	}, nil
+}
+
+// Writes the scene of "g", laid out as "l", as JSON to "w".
+func Write(w io.Writer, g *builder.Graph, l *layout.Layout) error {
+	s, err := New(g, l)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,95 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package excalidraw
+
+import "bytes"
+import "encoding/json"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/layout"
+
+func TestNew(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		subgraph cluster_0 { label=C; a [shape=box, style=filled, fillcolor=red, pos="1,2!", URL="http://a"] }
+		b [shape=diamond, color=blue, pos="1,0!"];
+		c [style=invis, pos="3,0!"];
+		a -> b [label=ab, style=dashed, arrowhead=tee, dir=both];
+		b -> c;
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	s, err := New(g, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	elements := make(map[string]*Element)
+	var ids []string
+	for _, el := range s.Elements {
+		elements[el.ID] = el
+		ids = append(ids, el.ID)
+	}
+	if want := "s0 s0-label n0 n0-label n1 n1-label e0 e0-label"; strings.Join(ids, " ") != want {
+		t.Fatalf("New made elements %v, want %s", ids, want)
+	}
+
+	a, b, ab := elements["n0"], elements["n1"], elements["e0"]
+	if a.Type != "rectangle" || a.BackgroundColor != "#ff0000" || *a.Link != "http://a" || a.GroupIDs[0] != "s0" {
+		t.Errorf("Node a is %+v", a)
+	}
+	if b.Type != "diamond" || b.StrokeColor != "#0000ff" || b.BackgroundColor != "transparent" || len(b.GroupIDs) != 0 {
+		t.Errorf("Node b is %+v", b)
+	}
+	if b.Y-a.Y != 144 || b.X != a.X {
+		t.Errorf("Node b is at %v,%v and a at %v,%v", b.X, b.Y, a.X, a.Y)
+	}
+	if label := elements["n0-label"]; label.Text != "a" || *label.ContainerID != "n0" || a.BoundElements[0].ID != "n0-label" {
+		t.Errorf("Label of a is %+v", label)
+	}
+
+	if ab.Type != "arrow" || ab.StartBinding.ElementID != "n0" || ab.EndBinding.ElementID != "n1" || ab.StrokeStyle != "dashed" {
+		t.Errorf("Edge ab is %+v", ab)
+	}
+	if *ab.StartArrowhead != "triangle" || *ab.EndArrowhead != "bar" {
+		t.Errorf("Edge ab has arrowheads %v and %v", *ab.StartArrowhead, *ab.EndArrowhead)
+	}
+	end := ab.Points[len(ab.Points)-1]
+	if ab.X != a.X+a.Width/2 || ab.Y != a.Y+a.Height || ab.Y+end[1] != b.Y {
+		t.Errorf("Edge ab runs from %v,%v by %v", ab.X, ab.Y, ab.Points)
+	}
+	if b.BoundElements[1].ID != "e0" {
+		t.Errorf("Node b is bound to %v", b.BoundElements)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, g, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var scene map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &scene); err != nil {
+		t.Fatalf("Write wrote invalid JSON: %v", err)
+	}
+	if scene["type"] != "excalidraw" {
+		t.Errorf("Write wrote a scene of type %v", scene["type"])
+	}
+
+	g.Nodes()[0].Position = nil
+	if _, err := New(g, nil); err != layout.ErrUnpositioned {
+		t.Errorf("New of an unpositioned node gave %v", err)
+	}
+}
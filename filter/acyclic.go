@@ -0,0 +1,103 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Makes a directed graph acyclic by reversing edges, the equivalent of the
+// Graphviz "acyclic" program.  A depth first search is made from each node in
+// turn, and every edge which closes a cycle is reversed.  If the reversed edge
+// would duplicate an existing edge it is removed instead.  Self-loops are
+// left as they are.
+//
+// Reversed edges are copies of the originals with Src and Dst exchanged; all
+// other edges are the originals.  Also returns the number of edges reversed
+// or removed, which is zero if the graph was already acyclic.
+func Acyclic(g *builder.Graph) (*builder.Graph, int, error) {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return nil, 0, ErrUndirected
+	}
+
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, ix.Len())
+	back := make([]bool, len(ix.Edges))
+
+	type frame struct {
+		node int
+		next int
+	}
+	for root := range ix.Nodes {
+		if state[root] != unvisited {
+			continue
+		}
+		state[root] = active
+		stack := []frame{{node: root}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			out := ix.Out[f.node]
+			if f.next == len(out) {
+				state[f.node] = done
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			e := out[f.next]
+			f.next++
+			w := ix.Dst[e]
+			switch {
+			case w == f.node:
+			case state[w] == active:
+				back[e] = true
+			case state[w] == unvisited:
+				state[w] = active
+				stack = append(stack, frame{node: w})
+			}
+		}
+	}
+
+	exists := make(map[[2]int]bool)
+	for e := range ix.Edges {
+		if !back[e] {
+			exists[[2]int{ix.Src[e], ix.Dst[e]}] = true
+		}
+	}
+
+	result := g.CloneEmpty()
+	result.AddNodes(g.Nodes()...)
+	result.AddSubgraphs(g.Subgraphs()...)
+	changed := 0
+	for e, edge := range ix.Edges {
+		if !back[e] {
+			result.AddEdges(edge)
+			continue
+		}
+		changed++
+		key := [2]int{ix.Dst[e], ix.Src[e]}
+		if exists[key] {
+			continue
+		}
+		exists[key] = true
+		reversed := *edge
+		reversed.Src, reversed.Dst = edge.Dst, edge.Src
+		result.AddEdges(&reversed)
+	}
+	return result, changed, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "fmt"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Splits a graph into its connected components, the equivalent of the
+// Graphviz "ccomps" program.  Edge direction is ignored.  Each component is
+// returned as a graph with the same kind, templates and attributes as "g",
+// holding the original nodes and edges of the component in their original
+// order.  Components are ordered by their first node.
+func Ccomps(g *builder.Graph) []*builder.Graph {
+	ix := adj.New(g)
+	comp, count := ix.Components()
+
+	graphs := make([]*builder.Graph, count)
+	for i := range graphs {
+		graphs[i] = g.CloneEmpty()
+	}
+	for v, n := range ix.Nodes {
+		graphs[comp[v]].AddNodes(n)
+	}
+	for e, edge := range ix.Edges {
+		graphs[comp[ix.Src[e]]].AddEdges(edge)
+	}
+	return graphs
+}
+
+// The strongly connected components of a directed graph, and the map of the
+// relationships between them, as computed by Sccmap.
+type SCCMap struct {
+	// One graph per strongly connected component, holding the original nodes
+	// of the component and the edges between them.  Components are in
+	// topological order, so edges between components always lead from an
+	// earlier component to a later one.
+	Components []*builder.Graph
+
+	// A graph with one node per component, in the same order as Components,
+	// and an edge wherever an edge of the original graph connects two
+	// components.  Parallel edges are merged.  Nodes are labelled
+	// "cluster_0", "cluster_1" and so on.
+	Map *builder.Graph
+}
+
+// Decomposes a directed graph into its strongly connected components, the
+// equivalent of the Graphviz "sccmap" program.
+func Sccmap(g *builder.Graph) (*SCCMap, error) {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return nil, ErrUndirected
+	}
+
+	// Tarjan numbers components in reverse topological order.
+	comp, count := ix.StrongComponents()
+	for v := range comp {
+		comp[v] = count - 1 - comp[v]
+	}
+
+	scc := &SCCMap{
+		Components: make([]*builder.Graph, count),
+		Map:        builder.NewGraph(g.Kind()),
+	}
+	mapNodes := make([]*builder.Node, count)
+	for i := range scc.Components {
+		scc.Components[i] = g.CloneEmpty()
+		mapNodes[i] = &builder.Node{Label: fmt.Sprintf("cluster_%d", i)}
+	}
+	scc.Map.AddNodes(mapNodes...)
+
+	for v, n := range ix.Nodes {
+		scc.Components[comp[v]].AddNodes(n)
+	}
+	linked := make(map[[2]int]bool)
+	for e, edge := range ix.Edges {
+		cs, cd := comp[ix.Src[e]], comp[ix.Dst[e]]
+		if cs == cd {
+			scc.Components[cs].AddEdges(edge)
+			continue
+		}
+		if !linked[[2]int{cs, cd}] {
+			linked[[2]int{cs, cd}] = true
+			scc.Map.AddEdges(&builder.Edge{Src: mapNodes[cs], Dst: mapNodes[cd]})
+		}
+	}
+	return scc, nil
+}
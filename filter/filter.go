@@ -0,0 +1,39 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package filter provides graph-to-graph transformations modelled on the filter
+programs distributed with Graphviz: tred, ccomps, sccmap, acyclic, unflatten
+and nop.  The gvfilter command makes them available from the command line.
+
+Filters never modify the graph they are given.  They return a new graph which
+shares the original *builder.Node and *builder.Edge objects wherever those
+appear unchanged in the result, so attributes set on them carry over.  Filters
+which keep every node of the graph also keep its subgraphs.
+
+Resources:
+  http://www.graphviz.org/pdf/tred.1.pdf
+  http://www.graphviz.org/pdf/ccomps.1.pdf
+  http://www.graphviz.org/pdf/sccmap.1.pdf
+  http://www.graphviz.org/pdf/acyclic.1.pdf
+  http://www.graphviz.org/pdf/unflatten.1.pdf
+  http://www.graphviz.org/pdf/nop.1.pdf
+*/
+package filter
+
+import "errors"
+
+// Returned by filters which only operate on directed graphs.
+var ErrUndirected = errors.New("filter: graph must be directed")
@@ -0,0 +1,147 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func TestCcomps(t *testing.T) {
+	nodes := builder.GenNodes(5)
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(nodes...)
+	g.AddEdges(
+		&builder.Edge{Src: nodes[0], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[3], Dst: nodes[1]},
+	)
+
+	comps := Ccomps(g)
+	if len(comps) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(comps))
+	}
+	if l := comps[0].Nodes(); len(l) != 2 || l[0] != nodes[0] || l[1] != nodes[2] {
+		t.Errorf("First component is incorrect.")
+	}
+	if l := comps[1].Nodes(); len(l) != 2 || l[0] != nodes[1] || l[1] != nodes[3] {
+		t.Errorf("Second component is incorrect.")
+	}
+	if l := comps[2].Edges(); len(l) != 0 {
+		t.Errorf("Third component should have no edges.")
+	}
+}
+
+func TestSccmap(t *testing.T) {
+	nodes := builder.GenNodes(4)
+	g := builder.NewGraph(attr.Directed)
+	g.AddEdges(
+		&builder.Edge{Src: nodes[2], Dst: nodes[3]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[0]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[2]},
+	)
+
+	scc, err := Sccmap(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scc.Components) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(scc.Components))
+	}
+	if l := scc.Components[0].Nodes(); len(l) != 2 {
+		t.Errorf("First component should hold the cycle, but has %d nodes.", len(l))
+	}
+	if l := scc.Map.Edges(); len(l) != 2 {
+		t.Errorf("Map should have 2 edges, but has %d.", len(l))
+	}
+}
+
+func TestAcyclic(t *testing.T) {
+	nodes := builder.GenNodes(3)
+	edges := []*builder.Edge{
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[2], Dst: nodes[0], Label: "back"},
+	}
+	g := builder.NewGraph(attr.Directed)
+	g.AddEdges(edges...)
+
+	r, changed, err := Acyclic(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Errorf("Should have reversed 1 edge, but reversed %d.", changed)
+	}
+	l := r.Edges()
+	if l[2].Src != nodes[0] || l[2].Dst != nodes[2] || l[2].Label != "back" {
+		t.Errorf("Back edge was not reversed.")
+	}
+	if edges[2].Src != nodes[2] {
+		t.Errorf("Original edge was modified.")
+	}
+
+	if _, changed, _ := Acyclic(r); changed != 0 {
+		t.Errorf("Result should be acyclic.")
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	nodes := builder.GenNodes(6)
+	g := builder.NewGraph(attr.Directed)
+	g.AddNodes(nodes...)
+	g.AddEdges(
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[3]},
+	)
+
+	r := Unflatten(g, &UnflattenOptions{MaxMinlen: 2, ChainLimit: 2})
+	l := r.Edges()
+	if len(l) != 4 {
+		t.Fatalf("Length is incorrect.  Should be 4, but is %d.", len(l))
+	}
+	for i, minlen := range []string{"1", "2", "1"} {
+		if l[i].Minlen != minlen {
+			t.Errorf("edges[%d].Minlen should be %q, but is %q.", i, minlen, l[i].Minlen)
+		}
+	}
+	if l[3].Src != nodes[4] || l[3].Dst != nodes[5] || l[3].Style != "invis" {
+		t.Errorf("Isolated nodes were not chained.")
+	}
+}
+
+func TestNop(t *testing.T) {
+	var b bytes.Buffer
+	src := "digraph G { node [fontname=Helvetica]; a -> b [label=x] }"
+	if err := Nop(&b, strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := `digraph {
+	0 [label="a"];
+	1 [label="b"];
+
+	0 -> 1 [label="x"];
+}
+`
+	if dot != b.String() {
+		t.Errorf("Output was incorrect.")
+	}
+}
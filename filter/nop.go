@@ -0,0 +1,33 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "io"
+
+import "godot/builder"
+
+// Reads a graph in the dot language from "r" and writes it to "w" in the
+// canonical form produced by Build, after the Graphviz "nop" program.  Unlike
+// nop, it does not keep the graph: nodes are renamed by their index, and
+// ports, the graph's name and attributes which builder.Parse ignores are
+// lost.
+func Nop(w io.Writer, r io.Reader) error {
+	g, err := builder.Parse(r)
+	if err != nil {
+		return err
+	}
+	return g.Build().Write(w)
+}
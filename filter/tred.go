@@ -0,0 +1,186 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "sort"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Options for Tred.
+type TredOptions struct {
+	// If set, edges removed by the reduction are kept in the result as dashed
+	// copies rather than being dropped.
+	Dashed bool
+}
+
+// Computes the transitive reduction of a directed graph, the equivalent of
+// the Graphviz "tred" program.  An edge u -> v is removed if v can be reached
+// from u without using it.  The result has the same nodes as "g", in the same
+// order, and the edges which remain are the original *builder.Edge objects.
+//
+// The transitive reduction of a graph with cycles is not unique.  Edges
+// within a strongly connected component, including self-loops, are always
+// kept; only edges between components are reduced.
+//
+// "opts" may be nil.
+func Tred(g *builder.Graph, opts *TredOptions) (*builder.Graph, error) {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return nil, ErrUndirected
+	}
+	if opts == nil {
+		opts = new(TredOptions)
+	}
+
+	comp, count := ix.StrongComponents()
+	succ := make([][]int, count)
+	first := make(map[[2]int]int)
+	for e := range ix.Edges {
+		cs, cd := comp[ix.Src[e]], comp[ix.Dst[e]]
+		if cs == cd {
+			continue
+		}
+		key := [2]int{cs, cd}
+		if _, ok := first[key]; !ok {
+			first[key] = e
+			succ[cs] = append(succ[cs], cd)
+		}
+	}
+
+	// Successor components always have lower numbers, so every reach set a
+	// component depends upon has been computed by the time it is needed.
+	reach := make([]bitset, count)
+	for c := 0; c < count; c++ {
+		reach[c] = newBitset(count)
+		for _, d := range succ[c] {
+			reach[c].set(d)
+			reach[c].union(reach[d])
+		}
+	}
+
+	keep := make([]bool, len(ix.Edges))
+	for e := range ix.Edges {
+		cs, cd := comp[ix.Src[e]], comp[ix.Dst[e]]
+		if cs == cd {
+			keep[e] = true
+			continue
+		}
+		if first[[2]int{cs, cd}] != e {
+			continue
+		}
+		keep[e] = true
+		for _, d := range succ[cs] {
+			if d != cd && reach[d].has(cd) {
+				keep[e] = false
+				break
+			}
+		}
+	}
+
+	result := g.CloneEmpty()
+	result.AddNodes(g.Nodes()...)
+	result.AddSubgraphs(g.Subgraphs()...)
+	ids := make(map[*builder.Edge]int, len(ix.Edges))
+	for i, e := range ix.Edges {
+		ids[e] = i
+	}
+	for _, e := range g.Edges() {
+		i, ok := ids[e]
+		switch {
+		case !ok || keep[i]:
+			result.AddEdges(e)
+		case opts.Dashed:
+			dashed := *e
+			dashed.Style = "dashed"
+			result.AddEdges(&dashed)
+		}
+	}
+	return result, nil
+}
+
+// Computes the transitive closure of a directed graph.  For every pair of
+// nodes u and v where v can be reached from u, the result contains an edge
+// u -> v.  All the edges of "g" are kept, and missing edges are added after
+// them.  A node gets a self-loop only if it lies on a cycle.
+//
+// Added edges are copies of "tmpl", or have no attributes if it is nil.
+func Closure(g *builder.Graph, tmpl *builder.Edge) (*builder.Graph, error) {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return nil, ErrUndirected
+	}
+
+	comp, count := ix.StrongComponents()
+	members := make([][]int, count)
+	for v, c := range comp {
+		members[c] = append(members[c], v)
+	}
+
+	cyclic := make([]bool, count)
+	succ := make([][]int, count)
+	for e := range ix.Edges {
+		cs, cd := comp[ix.Src[e]], comp[ix.Dst[e]]
+		if cs == cd {
+			cyclic[cs] = true
+		} else {
+			succ[cs] = append(succ[cs], cd)
+		}
+	}
+
+	reach := make([]bitset, count)
+	for c := 0; c < count; c++ {
+		reach[c] = newBitset(count)
+		if cyclic[c] {
+			reach[c].set(c)
+		}
+		for _, d := range succ[c] {
+			reach[c].set(d)
+			reach[c].union(reach[d])
+		}
+	}
+
+	exists := make(map[[2]int]bool, len(ix.Edges))
+	for e := range ix.Edges {
+		exists[[2]int{ix.Src[e], ix.Dst[e]}] = true
+	}
+
+	result := g.CloneEmpty()
+	result.AddNodes(g.Nodes()...)
+	result.AddSubgraphs(g.Subgraphs()...)
+	result.AddEdges(g.Edges()...)
+	for u := range ix.Nodes {
+		var targets []int
+		for c := 0; c < count; c++ {
+			if reach[comp[u]].has(c) {
+				targets = append(targets, members[c]...)
+			}
+		}
+		sort.Ints(targets)
+		for _, v := range targets {
+			if exists[[2]int{u, v}] {
+				continue
+			}
+			edge := new(builder.Edge)
+			if tmpl != nil {
+				*edge = *tmpl
+			}
+			edge.Src, edge.Dst = ix.Nodes[u], ix.Nodes[v]
+			result.AddEdges(edge)
+		}
+	}
+	return result, nil
+}
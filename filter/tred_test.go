@@ -0,0 +1,147 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func chain() (*builder.Graph, []*builder.Node, []*builder.Edge) {
+	nodes := builder.GenNodes(3)
+	edges := []*builder.Edge{
+		&builder.Edge{Src: nodes[0], Dst: nodes[1], Label: "ab"},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2], Label: "bc"},
+		&builder.Edge{Src: nodes[0], Dst: nodes[2], Label: "ac"},
+	}
+	g := builder.NewGraph(attr.Directed)
+	g.AddNodes(nodes...)
+	g.AddEdges(edges...)
+	return g, nodes, edges
+}
+
+func TestTred(t *testing.T) {
+	g, _, edges := chain()
+
+	r, err := Tred(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := r.Edges()
+	if len(l) != 2 {
+		t.Fatalf("Length is incorrect.  Should be 2, but is %d.", len(l))
+	}
+	if l[0] != edges[0] || l[1] != edges[1] {
+		t.Errorf("Original edges were not preserved.")
+	}
+	if len(g.Edges()) != 3 {
+		t.Errorf("Input graph was modified.")
+	}
+}
+
+func TestTredDangling(t *testing.T) {
+	nodes := builder.GenNodes(3)
+	g := builder.NewGraph(attr.Directed)
+	g.AddNodes(nodes...)
+	dangling := &builder.Edge{Dst: nodes[0]}
+	g.AddEdges(
+		dangling,
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[2]},
+	)
+
+	r, err := Tred(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := r.Edges()
+	if len(l) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(l))
+	}
+	if l[0] != dangling || l[2].Src != nodes[1] {
+		t.Errorf("Wrong edges were kept.")
+	}
+}
+
+func TestTredDashed(t *testing.T) {
+	g, _, edges := chain()
+
+	r, err := Tred(g, &TredOptions{Dashed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := r.Edges()
+	if len(l) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(l))
+	}
+	if l[2] == edges[2] || l[2].Style != "dashed" || l[2].Label != "ac" {
+		t.Errorf("Removed edge should be a dashed copy.")
+	}
+	if edges[2].Style != "" {
+		t.Errorf("Original edge was modified.")
+	}
+}
+
+func TestTredCycle(t *testing.T) {
+	nodes := builder.GenNodes(3)
+	g := builder.NewGraph(attr.Directed)
+	g.AddEdges(
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[0]},
+		&builder.Edge{Src: nodes[0], Dst: nodes[2]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2]},
+	)
+
+	r, err := Tred(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := r.Edges(); len(l) != 3 {
+		t.Errorf("Length is incorrect.  Should be 3, but is %d.", len(l))
+	}
+}
+
+func TestClosure(t *testing.T) {
+	nodes := builder.GenNodes(3)
+	g := builder.NewGraph(attr.Directed)
+	g.AddEdges(
+		&builder.Edge{Src: nodes[0], Dst: nodes[1]},
+		&builder.Edge{Src: nodes[1], Dst: nodes[2]},
+	)
+
+	r, err := Closure(g, &builder.Edge{Style: "dotted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := r.Edges()
+	if len(l) != 3 {
+		t.Fatalf("Length is incorrect.  Should be 3, but is %d.", len(l))
+	}
+	if l[2].Src != nodes[0] || l[2].Dst != nodes[2] || l[2].Style != "dotted" {
+		t.Errorf("Closure edge is incorrect.")
+	}
+}
+
+func TestUndirected(t *testing.T) {
+	g := builder.NewGraph(attr.Undirected)
+	if _, err := Tred(g, nil); err != ErrUndirected {
+		t.Errorf("Expected ErrUndirected, got %v.", err)
+	}
+}
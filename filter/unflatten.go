@@ -0,0 +1,126 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filter
+
+import "strconv"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Options for Unflatten.
+type UnflattenOptions struct {
+	// If at least 1, the minlen of edges between a node and its leaves is
+	// staggered between 1 and MaxMinlen, so that the leaves are spread over
+	// several ranks.  Edges whose Minlen is already set are left alone.
+	MaxMinlen int
+
+	// If set, edges to nodes with exactly one incoming and one outgoing edge
+	// are staggered as though those nodes were leaves.
+	Fanout bool
+
+	// If at least 1, isolated nodes are formed into chains of up to
+	// ChainLimit nodes using invisible edges.
+	ChainLimit int
+}
+
+// Improves the aspect ratio of wide, flat graphs laid out by dot, the
+// equivalent of the Graphviz "unflatten" program.  Edges whose minlen is
+// changed are copies of the originals; all other nodes and edges are the
+// originals.
+//
+// "opts" may be nil, in which case the graph is copied unchanged.
+func Unflatten(g *builder.Graph, opts *UnflattenOptions) *builder.Graph {
+	if opts == nil {
+		opts = new(UnflattenOptions)
+	}
+	ix := adj.New(g)
+
+	degree := func(v int) (int, int) {
+		in, out := 0, 0
+		for _, e := range ix.In[v] {
+			if ix.Src[e] != v {
+				in++
+			}
+		}
+		for _, e := range ix.Out[v] {
+			if ix.Dst[e] != v {
+				out++
+			}
+		}
+		return in, out
+	}
+	isLeaf := func(v int) bool {
+		in, out := degree(v)
+		return in+out == 1
+	}
+	isChainNode := func(v int) bool {
+		in, out := degree(v)
+		return in == 1 && out == 1
+	}
+
+	minlen := make(map[int]int)
+	stagger := func(e int, count *int) {
+		if ix.Edges[e].Minlen == "" {
+			minlen[e] = *count%opts.MaxMinlen + 1
+		}
+		*count++
+	}
+
+	result := g.CloneEmpty()
+	result.AddNodes(g.Nodes()...)
+	result.AddSubgraphs(g.Subgraphs()...)
+
+	var chain *builder.Node
+	chainSize := 0
+	var chainEdges []*builder.Edge
+	for v, n := range ix.Nodes {
+		in, out := degree(v)
+		switch {
+		case in+out == 0 && opts.ChainLimit >= 1:
+			if chain != nil {
+				chainEdges = append(chainEdges, &builder.Edge{Src: chain, Dst: n, Style: "invis"})
+			}
+			chain = n
+			if chainSize++; chainSize >= opts.ChainLimit {
+				chain, chainSize = nil, 0
+			}
+		case in+out > 1 && opts.MaxMinlen >= 1:
+			count := 0
+			for _, e := range ix.In[v] {
+				if isLeaf(ix.Src[e]) {
+					stagger(e, &count)
+				}
+			}
+			count = 0
+			for _, e := range ix.Out[v] {
+				if w := ix.Dst[e]; isLeaf(w) || (opts.Fanout && isChainNode(w)) {
+					stagger(e, &count)
+				}
+			}
+		}
+	}
+
+	for e, edge := range ix.Edges {
+		if l, ok := minlen[e]; ok {
+			staggered := *edge
+			staggered.Minlen = strconv.Itoa(l)
+			edge = &staggered
+		}
+		result.AddEdges(edge)
+	}
+	result.AddEdges(chainEdges...)
+	return result
+}
@@ -0,0 +1,95 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "regexp"
+
+import "godot"
+import "godot/attr"
+import "godot/builder"
+
+// Matches the start of a DOT graph: comments, and a graph's header up to
+// its opening brace.
+var dotHeader = regexp.MustCompile(`(?is)^\s*((//|#)[^\n]*\n\s*|/\*.*?\*/\s*)*(strict\s+)?(di)?graph(\s+(\w+|"[^"]*"))?\s*\{`)
+
+// Returns what the DOT writer of builder.Graph can represent.
+func dotCapabilities() godot.Capabilities {
+	if c, ok := builder.NewGraph(attr.Directed).Build().(godot.Capable); ok {
+		return c.Capabilities()
+	}
+	return 0
+}
+
+// The keys of the godot JSON schema of a graph.
+var jsonKeys = map[string]bool{
+	"directed":     true,
+	"attributes":   true,
+	"nodeTemplate": true,
+	"edgeTemplate": true,
+	"nodes":        true,
+	"edges":        true,
+	"subgraphs":    true,
+}
+
+// Reads a graph in the godot JSON schema, or in Graphviz's, as
+// builder.Graph's UnmarshalJSON does.  JSON with other keys, such as that
+// of D3, is not read, rather than read as a graph without edges.
+func decodeJSON(r io.Reader) (*builder.Graph, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if _, ok := probe["objects"]; !ok {
+		for key := range probe {
+			if !jsonKeys[key] {
+				return nil, fmt.Errorf("format: %q is not a key of a godot JSON graph", key)
+			}
+		}
+	}
+	g := new(builder.Graph)
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func init() {
+	Register("dot", []string{".gv", ".dot"},
+		dotHeader.Match,
+		NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return g.Build().Write(w)
+		}, dotCapabilities()),
+		NewDecoder(builder.Parse))
+
+	Register("json", []string{".json"},
+		func(prefix []byte) bool {
+			prefix = bytes.TrimSpace(prefix)
+			return bytes.HasPrefix(prefix, []byte("{")) && bytes.Contains(prefix, []byte(`"directed"`))
+		},
+		NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return json.NewEncoder(w).Encode(g)
+		}, godot.Clusters|godot.Positions),
+		NewDecoder(decodeJSON))
+}
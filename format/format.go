@@ -0,0 +1,228 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package format registers the formats graphs are read and written in, so that
+a program may read or write a graph in a format it is given by name, by file
+extension, or, for reading, by sniffing the content of its input, as package
+image does for images.
+
+Packages writing or reading a format register it when they are initialized,
+and a program makes their formats known by importing them, if only for their
+side effects:
+
+  import _ "godot/gml"
+  import _ "godot/svg"
+
+  g, name, err := format.Decode(r)
+  err = format.ForFile("out.svg").Encoder.Encode(w, g)
+
+DOT, which package builder reads and writes, and the godot JSON schema of
+builder.Graph are registered by this package, as "dot" and "json".
+
+The formats of drawings, "svg", "png", "drawio", "excalidraw" and "tikz",
+are written from the layout of layout.Default: from the positions of the
+graph's nodes, if every node has one, or else by the layered engine.
+*/
+package format
+
+import "bufio"
+import "errors"
+import "fmt"
+import "io"
+import "path/filepath"
+import "sort"
+import "strings"
+import "sync"
+
+import "godot"
+import "godot/builder"
+
+// Returned when no registered format has the name or extension given, or
+// can read an input.
+var ErrFormat = errors.New("format: unknown format")
+
+// Writes graphs in a format.
+type Encoder interface {
+	// Writes "g" to "w".
+	Encode(w io.Writer, g *builder.Graph) error
+
+	// Returns what the format can represent of graphs.
+	godot.Capable
+}
+
+// Reads graphs in a format.
+type Decoder interface {
+	// Reads a graph from "r".
+	Decode(r io.Reader) (*builder.Graph, error)
+}
+
+type encoder struct {
+	encode       func(io.Writer, *builder.Graph) error
+	capabilities godot.Capabilities
+}
+
+func (e encoder) Encode(w io.Writer, g *builder.Graph) error {
+	return e.encode(w, g)
+}
+
+func (e encoder) Capabilities() godot.Capabilities {
+	return e.capabilities
+}
+
+// Returns an Encoder calling "encode", of a format which can represent "c".
+func NewEncoder(encode func(io.Writer, *builder.Graph) error, c godot.Capabilities) Encoder {
+	return encoder{encode, c}
+}
+
+type decoder func(io.Reader) (*builder.Graph, error)
+
+func (d decoder) Decode(r io.Reader) (*builder.Graph, error) {
+	return d(r)
+}
+
+// Returns a Decoder calling "decode".
+func NewDecoder(decode func(io.Reader) (*builder.Graph, error)) Decoder {
+	return decoder(decode)
+}
+
+// A registered format.
+type Format struct {
+	// The name of the format, such as "gml".
+	Name string
+
+	// The extensions of the names of files in the format, with their dots,
+	// such as ".gml".
+	Extensions []string
+
+	// The Encoder and Decoder of the format, or nil if it cannot be written
+	// or read.
+	Encoder Encoder
+	Decoder Decoder
+
+	sniff func(prefix []byte) bool
+}
+
+// The number of bytes of an input Decode sniffs.
+const sniffLen = 512
+
+var (
+	mu      sync.RWMutex
+	formats []*Format
+)
+
+// Registers a format with its name, the extensions of its files, a function
+// reporting whether an input beginning with "prefix" is in the format, and
+// its Encoder and Decoder.  Any of "sniff", "enc" and "dec" may be nil, but
+// not both "enc" and "dec".  Decode tries formats in the order they are
+// registered.  Register panics if a format of the name is registered
+// already.
+func Register(name string, extensions []string, sniff func(prefix []byte) bool, enc Encoder, dec Decoder) {
+	if enc == nil && dec == nil {
+		panic("format: " + name + " has neither an encoder nor a decoder")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, f := range formats {
+		if f.Name == name {
+			panic("format: " + name + " is registered twice")
+		}
+	}
+	formats = append(formats, &Format{
+		Name:       name,
+		Extensions: extensions,
+		Encoder:    enc,
+		Decoder:    dec,
+		sniff:      sniff,
+	})
+}
+
+// Returns the format of a name, or nil if none is registered.
+func Lookup(name string) *Format {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, f := range formats {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Returns the format of a file, by the extension of its name, ignoring case,
+// or nil if no registered format has the extension.  Of several which have
+// it, the first registered is returned.
+func ForFile(path string) *Format {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, f := range formats {
+		for _, e := range f.Extensions {
+			if strings.ToLower(e) == ext {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the registered formats, ordered by name.
+func Formats() []*Format {
+	mu.RLock()
+	fs := append([]*Format(nil), formats...)
+	mu.RUnlock()
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Name < fs[j].Name })
+	return fs
+}
+
+// Writes "g" to "w" in the format of a name.
+func Encode(w io.Writer, g *builder.Graph, name string) error {
+	f := Lookup(name)
+	if f == nil {
+		return fmt.Errorf("%w %q", ErrFormat, name)
+	}
+	if f.Encoder == nil {
+		return fmt.Errorf("format: %s cannot be written", name)
+	}
+	return f.Encoder.Encode(w, g)
+}
+
+// Reads a graph in the first registered format which can read it and whose
+// content it is, as sniffed from the start of "r", and returns the name of
+// the format.
+func Decode(r io.Reader) (*builder.Graph, string, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	prefix, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+	mu.RLock()
+	var found *Format
+	for _, f := range formats {
+		if f.Decoder != nil && f.sniff != nil && f.sniff(prefix) {
+			found = f
+			break
+		}
+	}
+	mu.RUnlock()
+	if found == nil {
+		return nil, "", ErrFormat
+	}
+	g, err := found.Decoder.Decode(br)
+	return g, found.Name, err
+}
@@ -0,0 +1,116 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import "bytes"
+import "errors"
+import "io"
+import "io/ioutil"
+import "strings"
+import "testing"
+
+import "godot"
+import "godot/attr"
+import "godot/builder"
+
+func TestBuiltin(t *testing.T) {
+	dot := Lookup("dot")
+	if dot == nil || ForFile("graph.GV") != dot || ForFile("a/b.dot") != dot {
+		t.Fatalf("DOT is not registered by name and extension")
+	}
+	if c := dot.Encoder.Capabilities(); !c.Has(godot.Clusters|godot.Positions) || c.Has(godot.Ports) {
+		t.Errorf("DOT has capabilities %b", c)
+	}
+
+	for _, in := range []string{
+		"digraph { a -> b }",
+		"/* comment */\n// another\nstrict graph \"G\" {\n a -- b }",
+	} {
+		g, name, err := Decode(strings.NewReader(in))
+		if err != nil || name != "dot" || len(g.Edges()) != 1 {
+			t.Errorf("Decode of %q gave %v, %q, %v", in, g, name, err)
+		}
+	}
+
+	g, err := builder.Parse(strings.NewReader(`digraph { a -> b [label=x] }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g, "json"); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	h, name, err := Decode(&buf)
+	if err != nil || name != "json" || h.Kind() != g.Kind() || h.Edges()[0].Label != "x" {
+		t.Errorf("Decode of JSON gave %v, %q, %v", h, name, err)
+	}
+	// JSON of other schemas, as D3 writes, is not read as a graph.
+	d3 := `{"directed":true,"nodes":[{"id":"n0"}],"links":[{"source":"n0","target":"n0"}]}`
+	if _, _, err := Decode(strings.NewReader(d3)); err == nil {
+		t.Errorf("Decode of D3 JSON gave no error")
+	}
+
+	if err := Encode(&buf, g, "nonesuch"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Encode in an unknown format gave %v", err)
+	}
+	if _, _, err := Decode(strings.NewReader("graph [ node [ id 1 ] ]")); err != ErrFormat {
+		t.Errorf("Decode of an unknown format gave %v", err)
+	}
+	if ForFile("graph") != nil || ForFile("graph.nonesuch") != nil {
+		t.Errorf("ForFile found a format of an unknown extension")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	decode := func(r io.Reader) (*builder.Graph, error) {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		g := builder.NewGraph(attr.Undirected)
+		for _, label := range strings.Fields(string(data))[1:] {
+			g.AddNodes(&builder.Node{Label: label})
+		}
+		return g, nil
+	}
+	sniff := func(prefix []byte) bool { return bytes.HasPrefix(prefix, []byte("NODES")) }
+	Register("test", []string{".test"}, sniff, nil, NewDecoder(decode))
+
+	// The whole input is read, not only the sniffed prefix.
+	in := "NODES" + strings.Repeat(" a", 1000)
+	g, name, err := Decode(strings.NewReader(in))
+	if err != nil || name != "test" || len(g.Nodes()) != 1000 {
+		t.Fatalf("Decode gave %q, %v", name, err)
+	}
+	if err := Encode(ioutil.Discard, g, "test"); err == nil {
+		t.Errorf("Encode in a format without an encoder succeeded")
+	}
+
+	names := make(map[string]bool)
+	for _, f := range Formats() {
+		names[f.Name] = true
+	}
+	if !names["dot"] || !names["json"] || !names["test"] {
+		t.Errorf("Formats are %v", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register of a format twice did not panic")
+		}
+	}()
+	Register("test", nil, nil, nil, NewDecoder(decode))
+}
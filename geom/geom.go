@@ -0,0 +1,388 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package geom computes the shapes drawn by the renderers: the outlines of
+nodes, the polygons of arrowheads, and the styles of lines and fills.  The
+SVG and raster renderers share it, so that a graph drawn by either has the
+same geometry.
+
+Coordinates are in points, as in package layout, with y increasing upwards.
+*/
+package geom
+
+import "math"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/layout"
+
+// Drawing style flags, parsed from a Graphviz "style" attribute.
+//
+// Resources:
+//   http://www.graphviz.org/doc/info/attrs.html#k:style
+type Style struct {
+	Filled    bool
+	Dashed    bool
+	Dotted    bool
+	Bold      bool
+	Invisible bool
+	Rounded   bool
+}
+
+// Parses a comma separated list of styles.  Unknown styles are ignored.
+func ParseStyle(style string) Style {
+	var s Style
+	for _, part := range strings.Split(style, ",") {
+		switch strings.TrimSpace(part) {
+		case "filled":
+			s.Filled = true
+		case "dashed":
+			s.Dashed = true
+		case "dotted":
+			s.Dotted = true
+		case "bold":
+			s.Bold = true
+		case "invis", "invisible":
+			s.Invisible = true
+		case "rounded":
+			s.Rounded = true
+		}
+	}
+	return s
+}
+
+// Returns the width of the pen for a penwidth attribute and style, in
+// points.  Defaults to 1, and bold lines are 2.
+func PenWidth(penwidth string, s Style) float64 {
+	w := 1.0
+	if s.Bold {
+		w = 2
+	}
+	if v, err := strconv.ParseFloat(penwidth, 64); err == nil && v >= 0 {
+		w = v
+	}
+	return w
+}
+
+// Returns the dash pattern, lengths of alternating dashes and gaps in
+// points, for a style.  Nil for solid lines.
+func Dashes(s Style) []float64 {
+	switch {
+	case s.Dashed:
+		return []float64{5, 2}
+	case s.Dotted:
+		return []float64{1, 5}
+	}
+	return nil
+}
+
+// The outline of part of a shape: either an ellipse, or a polygon.
+type Outline struct {
+	// Whether the outline is an ellipse with the given center and radii,
+	// rather than a polygon.
+	Ellipse bool
+	Center  layout.Point
+	RX, RY  float64
+
+	// Vertices of the polygon, anticlockwise.
+	Points []layout.Point
+
+	// Whether the outline is always filled, with the line color, whatever
+	// the style.
+	Solid bool
+}
+
+// Vertices of the polygonal shapes, fitted to the square from (-1, -1) to
+// (1, 1).
+var polygons = map[string][][2]float64{
+	"box":           {{-1, -1}, {1, -1}, {1, 1}, {-1, 1}},
+	"rect":          {{-1, -1}, {1, -1}, {1, 1}, {-1, 1}},
+	"rectangle":     {{-1, -1}, {1, -1}, {1, 1}, {-1, 1}},
+	"square":        {{-1, -1}, {1, -1}, {1, 1}, {-1, 1}},
+	"diamond":       {{0, -1}, {1, 0}, {0, 1}, {-1, 0}},
+	"triangle":      {{-1, -1}, {1, -1}, {0, 1}},
+	"invtriangle":   {{0, -1}, {1, 1}, {-1, 1}},
+	"parallelogram": {{-1, -1}, {0.6, -1}, {1, 1}, {-0.6, 1}},
+	"trapezium":     {{-1, -1}, {1, -1}, {0.6, 1}, {-0.6, 1}},
+	"invtrapezium":  {{-0.6, -1}, {0.6, -1}, {1, 1}, {-1, 1}},
+	"house":         {{-1, -1}, {1, -1}, {1, 0.3}, {0, 1}, {-1, 0.3}},
+	"invhouse":      {{-1, -0.3}, {0, -1}, {1, -0.3}, {1, 1}, {-1, 1}},
+	"pentagon":      regular(5, 90),
+	"hexagon":       regular(6, 0),
+	"septagon":      regular(7, 90),
+	"octagon":       regular(8, 22.5),
+}
+
+// Returns a regular polygon with "n" sides, the first vertex at angle
+// "start" degrees, stretched to fit the square.
+func regular(n int, start float64) [][2]float64 {
+	points := make([][2]float64, n)
+	maxX, maxY, minY := 0.0, 0.0, 0.0
+	for i := range points {
+		a := (start + 360*float64(i)/float64(n)) * math.Pi / 180
+		points[i] = [2]float64{math.Cos(a), math.Sin(a)}
+		maxX = math.Max(maxX, math.Abs(points[i][0]))
+		maxY = math.Max(maxY, points[i][1])
+		minY = math.Min(minY, points[i][1])
+	}
+	for i := range points {
+		points[i][0] /= maxX
+		points[i][1] = (points[i][1]-minY)/(maxY-minY)*2 - 1
+	}
+	return points
+}
+
+// Gap between the outlines of a doublecircle, in points.
+const doubleGap = 4
+
+// Returns the outlines of a node of the given shape, fitted to its box.
+// Ellipses are used for ellipse, oval, circle and unknown shapes.  The
+// plaintext, plain and none shapes have no outline.
+func NodeOutlines(shape *attr.NodeShape, n *layout.NodeLayout) []Outline {
+	name := "ellipse"
+	if shape != nil {
+		name = shape.String()
+	}
+	c := n.Center
+	rx, ry := n.Width/2, n.Height/2
+	switch name {
+	case "plaintext", "plain", "none":
+		return nil
+	case "point":
+		r := math.Min(rx, ry)
+		return []Outline{{Ellipse: true, Center: c, RX: r, RY: r, Solid: true}}
+	case "doublecircle":
+		r := math.Min(rx, ry)
+		return []Outline{
+			{Ellipse: true, Center: c, RX: r, RY: r},
+			{Ellipse: true, Center: c, RX: r - doubleGap, RY: r - doubleGap},
+		}
+	}
+	unit, ok := polygons[name]
+	if !ok {
+		return []Outline{{Ellipse: true, Center: c, RX: rx, RY: ry}}
+	}
+	points := make([]layout.Point, len(unit))
+	for i, p := range unit {
+		points[i] = layout.Point{X: c.X + p[0]*rx, Y: c.Y + p[1]*ry}
+	}
+	return []Outline{{Points: points}}
+}
+
+// An arrowhead drawn at the end of an edge.
+type Arrow struct {
+	Outline
+
+	// Whether the arrowhead is filled.
+	Filled bool
+
+	// The distance from the tip back to where the edge should end.
+	Length float64
+}
+
+// The length of an arrowhead, in points, as in Graphviz.
+const ArrowLength = 10
+
+// Returns the arrowhead of the given kind, for example "normal", "empty",
+// "vee", "dot" or "tee", with its tip at "tip" and pointing away from
+// "from".  Returns nil for "none".  Unknown kinds are drawn as "normal".  A
+// leading "o" makes a shape open, as in "odot".
+func ArrowAt(kind string, tip, from layout.Point) *Arrow {
+	if kind == "none" {
+		return nil
+	}
+	filled := true
+	switch kind {
+	case "empty":
+		kind, filled = "normal", false
+	case "open":
+		kind = "vee"
+	case "odot", "odiamond", "obox", "onormal", "oinv":
+		kind, filled = kind[1:], false
+	}
+
+	dx, dy := tip.X-from.X, tip.Y-from.Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		dx, dy, d = 0, -1, 1
+	}
+	ux, uy := dx/d, dy/d // along the edge, towards the tip
+	px, py := -uy, ux    // across the edge
+	at := func(along, across float64) layout.Point {
+		return layout.Point{
+			X: tip.X - ux*along + px*across,
+			Y: tip.Y - uy*along + py*across,
+		}
+	}
+
+	const L, W = ArrowLength, 3.5
+	a := &Arrow{Filled: filled, Length: L}
+	switch kind {
+	case "inv":
+		a.Points = []layout.Point{at(L, 0), at(0, W), at(0, -W)}
+	case "vee":
+		a.Points = []layout.Point{at(0, 0), at(L, -W), at(L*0.6, 0), at(L, W)}
+		a.Length = L * 0.6
+	case "dot":
+		r := L * 0.4
+		a.Outline = Outline{Ellipse: true, Center: at(r, 0), RX: r, RY: r}
+		a.Length = 2 * r
+	case "diamond":
+		a.Points = []layout.Point{at(0, 0), at(L*0.6, -W), at(L*1.2, 0), at(L*0.6, W)}
+		a.Length = L * 1.2
+	case "box":
+		s := L * 0.8
+		a.Points = []layout.Point{at(0, -W), at(0, W), at(s, W), at(s, -W)}
+		a.Length = s
+	case "tee":
+		a.Points = []layout.Point{at(0, -W*1.5), at(0, W*1.5), at(L*0.3, W*1.5), at(L*0.3, -W*1.5)}
+		a.Length = L * 0.3
+	default:
+		a.Points = []layout.Point{at(0, 0), at(L, -W), at(L, W)}
+	}
+	return a
+}
+
+// Shortens a spline, in the form used by layout.EdgeLayout, by "length" at
+// its start or end, to make room for an arrowhead.  The end point and its
+// neighbouring control point are moved back along the curve's direction.
+// Returns a new slice.
+func Trim(spline []layout.Point, length float64, atEnd bool) []layout.Point {
+	s := append([]layout.Point(nil), spline...)
+	if len(s) < 4 || length <= 0 {
+		return s
+	}
+	end, ctrl := 0, 1
+	if atEnd {
+		end, ctrl = len(s)-1, len(s)-2
+	}
+	dx, dy := s[end].X-s[ctrl].X, s[end].Y-s[ctrl].Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		other := len(s) - 1 - end
+		dx, dy = s[end].X-s[other].X, s[end].Y-s[other].Y
+		d = math.Hypot(dx, dy)
+		if d == 0 {
+			return s
+		}
+	}
+	mx, my := dx/d*length, dy/d*length
+	s[end].X -= mx
+	s[end].Y -= my
+	s[ctrl].X -= mx
+	s[ctrl].Y -= my
+	return s
+}
+
+// Returns which ends of an edge have arrowheads, given its "dir" attribute
+// and whether the graph is directed.
+func ArrowEnds(dir string, directed bool) (head, tail bool) {
+	switch dir {
+	case "forward":
+		return true, false
+	case "back":
+		return false, true
+	case "both":
+		return true, true
+	case "none":
+		return false, false
+	}
+	return directed, false
+}
+
+// Returns the arrowheads of a resolved edge drawn along "spline", head first,
+// and the spline trimmed to end where they begin.  Arrowheads of an unset
+// kind are drawn as "normal".
+func EdgeArrows(e *builder.Edge, spline []layout.Point, directed bool) ([]*Arrow, []layout.Point) {
+	kind := func(k string) string {
+		if k == "" {
+			return "normal"
+		}
+		return k
+	}
+	head, tail := ArrowEnds(e.Dir, directed)
+	trimmed := spline
+	var arrows []*Arrow
+	if head {
+		end := len(spline) - 1
+		if a := ArrowAt(kind(e.Arrowhead), spline[end], spline[end-1]); a != nil {
+			arrows = append(arrows, a)
+			trimmed = Trim(trimmed, a.Length, true)
+		}
+	}
+	if tail {
+		if a := ArrowAt(kind(e.Arrowtail), spline[0], spline[1]); a != nil {
+			arrows = append(arrows, a)
+			trimmed = Trim(trimmed, a.Length, false)
+		}
+	}
+	return arrows, trimmed
+}
+
+var (
+	black     = color.Parse("black")
+	lightgrey = color.Parse("lightgrey")
+)
+
+// Returns the color lines and text are drawn in: "c", or black if it is nil.
+func LineColor(c color.Color) color.Color {
+	if c == nil {
+		return black
+	}
+	return c
+}
+
+// Returns the color a shape is filled with: its fill color, or else its line
+// color, or else light grey, or nil if it is not filled.
+func FillColor(s Style, fillColor, lineColor color.Color) color.Color {
+	switch {
+	case !s.Filled:
+		return nil
+	case fillColor != nil:
+		return fillColor
+	case lineColor != nil:
+		return lineColor
+	}
+	return lightgrey
+}
+
+// Returns the color an arrowhead of an edge is filled with: the edge's fill
+// color, or else its line color, or nil if the arrowhead is open.
+func ArrowFill(a *Arrow, fillColor, lineColor color.Color) color.Color {
+	switch {
+	case !a.Filled:
+		return nil
+	case fillColor != nil:
+		return fillColor
+	}
+	return LineColor(lineColor)
+}
+
+// Returns the baselines of the lines of a label centered on "center", set in
+// a font of the given size, top line first.
+func TextLines(text string, center layout.Point, fontSize float64) ([]string, []layout.Point) {
+	lines := layout.LabelLines(text)
+	leading := 1.2 * fontSize
+	top := center.Y + leading*float64(len(lines)-1)/2
+	points := make([]layout.Point, len(lines))
+	for i := range lines {
+		points[i] = layout.Point{X: center.X, Y: top - leading*float64(i) - 0.3*fontSize}
+	}
+	return lines, points
+}
@@ -0,0 +1,388 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package gexf writes graphs in GEXF, the native format of Gephi, including
+graphs which change over time.
+
+Node and edge labels become GEXF labels, and every other dot attribute is
+written as an attribute value, declared with the defaults given by the node
+and edge templates.  Gephi's visualization attributes are derived from the
+dot ones: the fill color of nodes, or their color, and the color of edges;
+node positions and sizes, converted from inches to points; node shapes;
+and edge widths and line styles.  Subgraphs are not written.
+
+Nodes and edges may be given spells, the intervals of time in which they
+exist, which makes the graph dynamic:
+
+  opts := &gexf.Options{
+    TimeFormat: "date",
+    NodeSpells: map[*builder.Node][]gexf.Spell{
+      svc: {{Start: "2024-01-15", End: "2024-06-30"}},
+    },
+  }
+  err := gexf.Encode(w, g, opts)
+
+Resources:
+  https://gexf.net/schema.html
+*/
+package gexf
+
+import "encoding/xml"
+import "fmt"
+import "io"
+import "math"
+import "strconv"
+
+import "godot"
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("gexf", []string{".gexf"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return Encode(w, g, nil)
+		}, godot.Positions), nil)
+}
+
+// The namespaces of GEXF 1.3 documents and of their visualization
+// attributes.
+const (
+	Namespace    = "http://gexf.net/1.3"
+	VizNamespace = "http://gexf.net/1.3/viz"
+)
+
+// An interval of time in which a node or edge exists.  Start and End are
+// written in the graph's TimeFormat.  An empty Start or End leaves the
+// interval open at that end.
+type Spell struct {
+	Start, End string
+}
+
+// Options for writing GEXF.  Nodes and edges without spells exist at all
+// times.
+type Options struct {
+	NodeSpells map[*builder.Node][]Spell
+	EdgeSpells map[*builder.Edge][]Spell
+
+	// The format of the times of spells: "double", the default, "integer",
+	// "date" or "dateTime".
+	TimeFormat string
+}
+
+type document struct {
+	XMLName xml.Name `xml:"gexf"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Viz     string   `xml:"xmlns:viz,attr"`
+	Version string   `xml:"version,attr"`
+	Meta    *meta    `xml:"meta"`
+	Graph   graph    `xml:"graph"`
+}
+
+type meta struct {
+	Creator     string `xml:"creator"`
+	Description string `xml:"description,omitempty"`
+}
+
+type graph struct {
+	DefaultEdgeType string       `xml:"defaultedgetype,attr"`
+	Mode            string       `xml:"mode,attr"`
+	TimeFormat      string       `xml:"timeformat,attr,omitempty"`
+	TimeRep         string       `xml:"timerepresentation,attr,omitempty"`
+	Attributes      []attributes `xml:"attributes"`
+	Nodes           []node       `xml:"nodes>node"`
+	Edges           []edge       `xml:"edges>edge"`
+}
+
+type attributes struct {
+	Class      string      `xml:"class,attr"`
+	Attributes []attribute `xml:"attribute"`
+}
+
+type attribute struct {
+	ID      string  `xml:"id,attr"`
+	Title   string  `xml:"title,attr"`
+	Type    string  `xml:"type,attr"`
+	Default *string `xml:"default"`
+}
+
+type attvalues struct {
+	Values []attvalue `xml:"attvalue"`
+}
+
+type attvalue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type spells struct {
+	Spells []spell `xml:"spell"`
+}
+
+type spell struct {
+	Start string `xml:"start,attr,omitempty"`
+	End   string `xml:"end,attr,omitempty"`
+}
+
+type node struct {
+	ID        string     `xml:"id,attr"`
+	Label     string     `xml:"label,attr,omitempty"`
+	Start     string     `xml:"start,attr,omitempty"`
+	End       string     `xml:"end,attr,omitempty"`
+	AttValues *attvalues `xml:"attvalues"`
+	Spells    *spells    `xml:"spells"`
+	Color     *vizColor  `xml:"viz:color"`
+	Position  *position  `xml:"viz:position"`
+	Size      *value     `xml:"viz:size"`
+	Shape     *value     `xml:"viz:shape"`
+}
+
+type edge struct {
+	ID        string     `xml:"id,attr"`
+	Source    string     `xml:"source,attr"`
+	Target    string     `xml:"target,attr"`
+	Label     string     `xml:"label,attr,omitempty"`
+	Start     string     `xml:"start,attr,omitempty"`
+	End       string     `xml:"end,attr,omitempty"`
+	AttValues *attvalues `xml:"attvalues"`
+	Spells    *spells    `xml:"spells"`
+	Color     *vizColor  `xml:"viz:color"`
+	Thickness *value     `xml:"viz:thickness"`
+	Shape     *value     `xml:"viz:shape"`
+}
+
+type vizColor struct {
+	R uint8  `xml:"r,attr"`
+	G uint8  `xml:"g,attr"`
+	B uint8  `xml:"b,attr"`
+	A string `xml:"a,attr,omitempty"`
+}
+
+type position struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+type value struct {
+	Value string `xml:"value,attr"`
+}
+
+// Gephi's node shapes for the dot shapes which have one.
+var nodeShapes = map[string]string{
+	"box":      "square",
+	"rect":     "square",
+	"square":   "square",
+	"circle":   "disc",
+	"ellipse":  "disc",
+	"oval":     "disc",
+	"point":    "disc",
+	"triangle": "triangle",
+	"diamond":  "diamond",
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Returns the visualization color of a dot color, or nil if it has no
+// components.
+func newColor(c color.Color) *vizColor {
+	rgb, alpha, ok := color.Components(c)
+	if !ok {
+		return nil
+	}
+	vc := &vizColor{R: rgb.R, G: rgb.G, B: rgb.B}
+	if alpha != 255 {
+		vc.A = num(math.Round(float64(alpha)/255*1000) / 1000)
+	}
+	return vc
+}
+
+// Writes the time of the only spell as attributes, and of several as
+// elements.
+func newSpells(ss []Spell) (start, end string, elems *spells) {
+	switch len(ss) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return ss[0].Start, ss[0].End, nil
+	}
+	elems = new(spells)
+	for _, s := range ss {
+		elems.Spells = append(elems.Spells, spell{Start: s.Start, End: s.End})
+	}
+	return "", "", elems
+}
+
+// Returns the attribute values of an object, but its label, declaring the
+// attributes.
+func newAttValues(obj interface{}, decls *[]attribute, declared map[string]bool) *attvalues {
+	var values []attvalue
+	for _, a := range builder.Attributes(obj) {
+		if a[0] == "label" {
+			continue
+		}
+		if !declared[a[0]] {
+			declared[a[0]] = true
+			*decls = append(*decls, attribute{ID: a[0], Title: a[0], Type: "string"})
+		}
+		values = append(values, attvalue{For: a[0], Value: a[1]})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return &attvalues{values}
+}
+
+// Sets the defaults of declared attributes from a template, declaring those
+// not yet declared.
+func defaults(tmpl interface{}, decls *[]attribute, declared map[string]bool) {
+	for _, a := range builder.Attributes(tmpl) {
+		if a[0] == "label" {
+			continue
+		}
+		if !declared[a[0]] {
+			declared[a[0]] = true
+			*decls = append(*decls, attribute{ID: a[0], Title: a[0], Type: "string"})
+		}
+		for i := range *decls {
+			if (*decls)[i].ID == a[0] {
+				v := a[1]
+				(*decls)[i].Default = &v
+			}
+		}
+	}
+}
+
+// Writes "g" as GEXF.  Nodes are given the ids "n0", "n1" and so on, in the
+// order of g.Nodes(), as they are in dot, and edges "e0", "e1" and so on.
+// Edges without both endpoints are not written.  The options may be nil.
+func Encode(w io.Writer, g *builder.Graph, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	gr := graph{DefaultEdgeType: "undirected", Mode: "static"}
+	if g.Kind() == attr.Directed {
+		gr.DefaultEdgeType = "directed"
+	}
+	if len(opts.NodeSpells) > 0 || len(opts.EdgeSpells) > 0 {
+		gr.Mode = "dynamic"
+		gr.TimeRep = "interval"
+		gr.TimeFormat = opts.TimeFormat
+		if gr.TimeFormat == "" {
+			gr.TimeFormat = "double"
+		}
+	}
+
+	var nodeAttrs, edgeAttrs []attribute
+	nodeDeclared, edgeDeclared := make(map[string]bool), make(map[string]bool)
+	if tmpl := g.NodeTemplate(); tmpl != nil {
+		defaults(tmpl, &nodeAttrs, nodeDeclared)
+	}
+	if tmpl := g.EdgeTemplate(); tmpl != nil {
+		defaults(tmpl, &edgeAttrs, edgeDeclared)
+	}
+
+	ids := make(map[*builder.Node]string)
+	for i, n := range g.Nodes() {
+		ids[n] = fmt.Sprintf("n%d", i)
+		r := g.ResolveNode(n)
+		gn := node{
+			ID:        ids[n],
+			Label:     r.Label,
+			AttValues: newAttValues(n, &nodeAttrs, nodeDeclared),
+		}
+		gn.Start, gn.End, gn.Spells = newSpells(opts.NodeSpells[n])
+
+		c := r.Color
+		if r.FillColor != nil && (geom.ParseStyle(r.Style).Filled || r.Color == nil) {
+			c = r.FillColor
+		}
+		gn.Color = newColor(c)
+		if p := r.Position; p != nil {
+			gn.Position = &position{
+				X: float64(p.X) * layout.PointsPerInch,
+				Y: float64(p.Y) * layout.PointsPerInch,
+			}
+		}
+		width, _ := strconv.ParseFloat(r.Width, 64)
+		height, _ := strconv.ParseFloat(r.Height, 64)
+		if size := math.Max(width, height); size > 0 {
+			gn.Size = &value{num(size * layout.PointsPerInch / 2)}
+		}
+		if r.Shape != nil {
+			if shape, ok := nodeShapes[r.Shape.String()]; ok {
+				gn.Shape = &value{shape}
+			}
+		}
+		gr.Nodes = append(gr.Nodes, gn)
+	}
+
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		r := g.ResolveEdge(e)
+		ge := edge{
+			ID:        fmt.Sprintf("e%d", len(gr.Edges)),
+			Source:    ids[e.Src],
+			Target:    ids[e.Dst],
+			Label:     r.Label,
+			AttValues: newAttValues(e, &edgeAttrs, edgeDeclared),
+			Color:     newColor(r.Color),
+		}
+		ge.Start, ge.End, ge.Spells = newSpells(opts.EdgeSpells[e])
+		if r.Penwidth != "" {
+			ge.Thickness = &value{r.Penwidth}
+		}
+		switch style := geom.ParseStyle(r.Style); {
+		case style.Dashed:
+			ge.Shape = &value{"dashed"}
+		case style.Dotted:
+			ge.Shape = &value{"dotted"}
+		}
+		gr.Edges = append(gr.Edges, ge)
+	}
+
+	if len(nodeAttrs) > 0 {
+		gr.Attributes = append(gr.Attributes, attributes{Class: "node", Attributes: nodeAttrs})
+	}
+	if len(edgeAttrs) > 0 {
+		gr.Attributes = append(gr.Attributes, attributes{Class: "edge", Attributes: edgeAttrs})
+	}
+
+	doc := document{
+		Xmlns:   Namespace,
+		Viz:     VizNamespace,
+		Version: "1.3",
+		Meta:    &meta{Creator: "godot", Description: g.Label},
+		Graph:   gr,
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gexf
+
+import "bytes"
+import "encoding/xml"
+import "io"
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+func encode(t *testing.T, src string, opts func(g *builder.Graph) *Options) string {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g, opts(g)); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+		}
+	}
+	return buf.String()
+}
+
+func TestEncode(t *testing.T) {
+	out := encode(t, `digraph {
+		label=Mesh;
+		node [shape=box, color=blue];
+		a [style=filled, fillcolor="#ff000080", pos="1,2", width=1];
+		a -> b [label=ab, style=dashed, penwidth=2, color=red];
+	}`, func(*builder.Graph) *Options { return nil })
+	for _, want := range []string{
+		`<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" version="1.3">`,
+		`<description>Mesh</description>`,
+		`<graph defaultedgetype="directed" mode="static">`,
+		`<attribute id="shape" title="shape" type="string">`,
+		`<default>box</default>`,
+		`<node id="n0" label="a">`,
+		`<attvalue for="fillcolor" value="#ff000080"></attvalue>`,
+		`<viz:color r="255" g="0" b="0" a="0.502"></viz:color>`,
+		`<viz:position x="72" y="144" z="0"></viz:position>`,
+		`<viz:size value="36"></viz:size>`,
+		`<viz:shape value="square"></viz:shape>`,
+		`<viz:color r="0" g="0" b="255"></viz:color>`,
+		`<edge id="e0" source="n0" target="n1" label="ab">`,
+		`<viz:color r="255" g="0" b="0"></viz:color>`,
+		`<viz:thickness value="2"></viz:thickness>`,
+		`<viz:shape value="dashed"></viz:shape>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output does not contain %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "spell") || strings.Contains(out, "start=") {
+		t.Errorf("Static graph has spells:\n%s", out)
+	}
+}
+
+func TestSpells(t *testing.T) {
+	out := encode(t, `graph { a -- b; c }`, func(g *builder.Graph) *Options {
+		nodes, edges := g.Nodes(), g.Edges()
+		return &Options{
+			TimeFormat: "date",
+			NodeSpells: map[*builder.Node][]Spell{
+				nodes[0]: {{Start: "2024-01-01", End: "2024-03-01"}},
+				nodes[1]: {{Start: "2024-01-01", End: "2024-02-01"}, {Start: "2024-04-01"}},
+			},
+			EdgeSpells: map[*builder.Edge][]Spell{
+				edges[0]: {{End: "2024-02-01"}},
+			},
+		}
+	})
+	for _, want := range []string{
+		`<graph defaultedgetype="undirected" mode="dynamic" timeformat="date" timerepresentation="interval">`,
+		`<node id="n0" label="a" start="2024-01-01" end="2024-03-01">`,
+		`<spell start="2024-01-01" end="2024-02-01"></spell>`,
+		`<spell start="2024-04-01"></spell>`,
+		`<node id="n2" label="c">`,
+		`<edge id="e0" source="n0" target="n1" end="2024-02-01">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output does not contain %s:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gml
+
+import "bufio"
+import "errors"
+import "fmt"
+import "io"
+import "strconv"
+import "strings"
+import "unicode"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/geom"
+import "godot/layout"
+
+// A key and its value: a string or number, or a list of pairs.
+type pair struct {
+	key   string
+	value string
+	list  []pair
+	line  int
+}
+
+type parser struct {
+	src  *bufio.Reader
+	line int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("gml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// Returns the next token: a key or number, a string with its quotes, "["
+// or "]", or "" at the end of the input.  Lines starting with '#' are
+// comments.
+func (p *parser) token() (string, error) {
+	for {
+		r, _, err := p.src.ReadRune()
+		if err == io.EOF {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		switch {
+		case r == '\n':
+			p.line++
+		case unicode.IsSpace(r):
+		case r == '#':
+			if _, err := p.src.ReadString('\n'); err != nil && err != io.EOF {
+				return "", err
+			}
+			p.line++
+		case r == '[' || r == ']':
+			return string(r), nil
+		case r == '"':
+			s, err := p.src.ReadString('"')
+			if err != nil {
+				return "", p.errorf("unterminated string")
+			}
+			p.line += strings.Count(s, "\n")
+			return `"` + s, nil
+		default:
+			var b strings.Builder
+			b.WriteRune(r)
+			for {
+				r, _, err := p.src.ReadRune()
+				if err != nil {
+					break
+				}
+				if unicode.IsSpace(r) || r == '[' || r == ']' || r == '"' {
+					p.src.UnreadRune()
+					break
+				}
+				b.WriteRune(r)
+			}
+			return b.String(), nil
+		}
+	}
+}
+
+// Reads pairs until the end of a list, or of the input if "top".
+func (p *parser) list(top bool) ([]pair, error) {
+	var pairs []pair
+	for {
+		key, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "":
+			if !top {
+				return nil, p.errorf("unterminated list")
+			}
+			return pairs, nil
+		case "]":
+			if top {
+				return nil, p.errorf("unexpected ]")
+			}
+			return pairs, nil
+		case "[":
+			return nil, p.errorf("expected a key")
+		}
+		line := p.line
+		value, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch value {
+		case "", "]":
+			return nil, p.errorf("no value for %s", key)
+		case "[":
+			list, err := p.list(false)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair{key: key, list: list, line: line})
+		default:
+			if strings.HasPrefix(value, `"`) {
+				value = unescaper.Replace(value[1 : len(value)-1])
+			}
+			pairs = append(pairs, pair{key: key, value: value, line: line})
+		}
+	}
+}
+
+func find(pairs []pair, key string) (pair, bool) {
+	for _, p := range pairs {
+		if p.key == key {
+			return p, true
+		}
+	}
+	return pair{}, false
+}
+
+type attributeSetter interface {
+	SetAttribute(name string, value string) error
+}
+
+// Sets the attributes of an object from the scalar pairs of a list, but for
+// those of the keys given.  Keys which are not attributes of the object are
+// ignored.
+func set(obj attributeSetter, pairs []pair, skip ...string) error {
+next:
+	for _, p := range pairs {
+		if p.list != nil {
+			continue
+		}
+		for _, s := range skip {
+			if p.key == s {
+				continue next
+			}
+		}
+		err := obj.SetAttribute(p.key, p.value)
+		if err != nil && !errors.Is(err, builder.ErrUnknownAttribute) {
+			return fmt.Errorf("gml: line %d: %v", p.line, err)
+		}
+	}
+	return nil
+}
+
+func number(pairs []pair, key string) (float64, bool) {
+	p, ok := find(pairs, key)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(p.value, 64)
+	return f, err == nil
+}
+
+func inches(points float64) string {
+	return strconv.FormatFloat(points/layout.PointsPerInch, 'f', -1, 64)
+}
+
+// Sets the attributes of a node which are not set from its graphics.
+func nodeGraphics(n *builder.Node, gr []pair) {
+	x, okX := number(gr, "x")
+	y, okY := number(gr, "y")
+	if n.Position == nil && okX && okY {
+		n.Position = &attr.Point{
+			X: float32(x / layout.PointsPerInch),
+			Y: float32(-y / layout.PointsPerInch),
+		}
+	}
+	if w, ok := number(gr, "w"); ok && n.Width == "" {
+		n.Width = inches(w)
+	}
+	if h, ok := number(gr, "h"); ok && n.Height == "" {
+		n.Height = inches(h)
+	}
+	if t, ok := find(gr, "type"); ok && n.Shape == nil {
+		if shape, ok := shapes[t.value]; ok {
+			n.Shape = attr.ParseNodeShape(shape)
+		}
+	}
+	if f, ok := find(gr, "fill"); ok && n.FillColor == nil {
+		n.FillColor = color.Parse(f.value)
+		if !geom.ParseStyle(n.Style).Filled {
+			if n.Style == "" {
+				n.Style = "filled"
+			} else {
+				n.Style += ",filled"
+			}
+		}
+	}
+	if o, ok := find(gr, "outline"); ok && n.Color == nil {
+		n.Color = color.Parse(o.value)
+	}
+}
+
+// Sets the attributes of an edge which are not set from its graphics.
+func edgeGraphics(e *builder.Edge, gr []pair) {
+	if f, ok := find(gr, "fill"); ok && e.Color == nil {
+		e.Color = color.Parse(f.value)
+	}
+	if w, ok := find(gr, "width"); ok && e.Penwidth == "" {
+		e.Penwidth = w.value
+	}
+	if s, ok := find(gr, "style"); ok && e.Style == "" && (s.value == "dashed" || s.value == "dotted") {
+		e.Style = s.value
+	}
+}
+
+// Reads a graph written in GML.  Only the first graph of the input is read,
+// and it is directed if its "directed" key is 1.  Nodes must have ids, and
+// edges sources and targets naming them.
+func Decode(r io.Reader) (*builder.Graph, error) {
+	p := &parser{src: bufio.NewReader(r), line: 1}
+	top, err := p.list(true)
+	if err != nil {
+		return nil, err
+	}
+	gp, ok := find(top, "graph")
+	if !ok || gp.list == nil {
+		return nil, errors.New("gml: no graph")
+	}
+	kind := attr.Undirected
+	if d, ok := find(gp.list, "directed"); ok && d.value == "1" {
+		kind = attr.Directed
+	}
+	g := builder.NewGraph(kind)
+	if err := set(g, gp.list, "directed", "id"); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*builder.Node)
+	for _, np := range gp.list {
+		if np.key != "node" || np.list == nil {
+			continue
+		}
+		id, ok := find(np.list, "id")
+		if !ok {
+			return nil, fmt.Errorf("gml: line %d: node without an id", np.line)
+		}
+		if _, ok := nodes[id.value]; ok {
+			return nil, fmt.Errorf("gml: line %d: duplicate node %s", np.line, id.value)
+		}
+		n := new(builder.Node)
+		if err := set(n, np.list, "id"); err != nil {
+			return nil, err
+		}
+		if gr, ok := find(np.list, "graphics"); ok {
+			nodeGraphics(n, gr.list)
+		}
+		nodes[id.value] = n
+		g.AddNodes(n)
+	}
+
+	for _, ep := range gp.list {
+		if ep.key != "edge" || ep.list == nil {
+			continue
+		}
+		src, _ := find(ep.list, "source")
+		dst, _ := find(ep.list, "target")
+		if nodes[src.value] == nil || nodes[dst.value] == nil {
+			return nil, fmt.Errorf("gml: line %d: edge from %q to %q has an unknown endpoint", ep.line, src.value, dst.value)
+		}
+		e := &builder.Edge{Src: nodes[src.value], Dst: nodes[dst.value]}
+		if err := set(e, ep.list, "source", "target"); err != nil {
+			return nil, err
+		}
+		if gr, ok := find(ep.list, "graphics"); ok {
+			edgeGraphics(e, gr.list)
+		}
+		g.AddEdges(e)
+	}
+	return g, nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package gml reads and writes graphs in GML, the Graph Modelling Language
+read by yEd, Gephi, Cytoscape and NetworkX.
+
+Nodes are given integer ids, and edges name their endpoints by them.  Dot
+attributes are written as keys of the same names, and node positions, sizes,
+shapes and colors, and edge colors, widths and styles, also as the graphics
+of the nodes and edges, in points, with y increasing downwards.  When read,
+keys naming dot attributes set them, and graphics set those attributes which
+are not set otherwise.  Subgraphs are not written.
+
+Resources:
+  https://en.wikipedia.org/wiki/Graph_Modelling_Language
+*/
+package gml
+
+import "bufio"
+import "fmt"
+import "io"
+import "math"
+import "regexp"
+import "strconv"
+import "strings"
+
+import "godot"
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+// Matches the start of a GML file: comments, and keys before its graph.
+var header = regexp.MustCompile(`^\s*(#[^\n]*\n\s*)*(\w+\s+("[^"]*"|[^\s\[\]]+)\s+)*graph\s*\[`)
+
+func init() {
+	format.Register("gml", []string{".gml"}, header.Match,
+		format.NewEncoder(Encode, godot.Positions), format.NewDecoder(Decode))
+}
+
+// The GML graphics types of dot shapes, and the dot shapes of GML types.
+var types = map[string]string{
+	"box":       "rectangle",
+	"rect":      "rectangle",
+	"rectangle": "rectangle",
+	"square":    "rectangle",
+	"ellipse":   "ellipse",
+	"oval":      "ellipse",
+	"circle":    "ellipse",
+	"diamond":   "diamond",
+	"hexagon":   "hexagon",
+	"triangle":  "triangle",
+	"octagon":   "octagon",
+}
+
+var shapes = map[string]string{
+	"rectangle": "box",
+	"ellipse":   "ellipse",
+	"oval":      "ellipse",
+	"diamond":   "diamond",
+	"hexagon":   "hexagon",
+	"triangle":  "triangle",
+	"octagon":   "octagon",
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;")
+var unescaper = strings.NewReplacer("&amp;", "&", "&quot;", `"`)
+
+func quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// Returns a color as "#rrggbb" if its components are known.
+func hex(c color.Color) string {
+	if rgb, _, ok := color.Components(c); ok {
+		return quote(rgb.String())
+	}
+	return quote(c.String())
+}
+
+type writer struct {
+	w      *bufio.Writer
+	indent string
+}
+
+func (w *writer) key(key, value string) {
+	fmt.Fprintf(w.w, "%s%s %s\n", w.indent, key, value)
+}
+
+func (w *writer) open(key string) {
+	fmt.Fprintf(w.w, "%s%s [\n", w.indent, key)
+	w.indent += "  "
+}
+
+func (w *writer) close() {
+	w.indent = w.indent[2:]
+	fmt.Fprintf(w.w, "%s]\n", w.indent)
+}
+
+// Writes a graphics list, if it has any keys.
+func (w *writer) graphics(kvs [][2]string) {
+	if len(kvs) == 0 {
+		return
+	}
+	w.open("graphics")
+	for _, kv := range kvs {
+		w.key(kv[0], kv[1])
+	}
+	w.close()
+}
+
+// Writes the attributes of an object, but for those written otherwise.
+func (w *writer) attributes(obj interface{}, skip ...string) {
+next:
+	for _, a := range builder.Attributes(obj) {
+		for _, s := range skip {
+			if a[0] == s {
+				continue next
+			}
+		}
+		w.key(a[0], quote(a[1]))
+	}
+}
+
+// Writes "g" as GML.  Nodes are given the ids 0, 1 and so on, in the order
+// of g.Nodes(), as they are in dot, and their dot ids are not written.
+// Attributes are those of the nodes and edges resolved against the
+// templates.  Edges without both endpoints are not written.
+func Encode(w io.Writer, g *builder.Graph) error {
+	gw := &writer{w: bufio.NewWriter(w)}
+	gw.open("graph")
+	directed := 0
+	if g.Kind() == attr.Directed {
+		directed = 1
+	}
+	gw.key("directed", strconv.Itoa(directed))
+	gw.attributes(g)
+
+	ids := make(map[*builder.Node]int)
+	for i, n := range g.Nodes() {
+		ids[n] = i
+		r := g.ResolveNode(n)
+		gw.open("node")
+		gw.key("id", strconv.Itoa(i))
+		gw.attributes(r, "id")
+
+		var graphics [][2]string
+		if p := r.Position; p != nil {
+			graphics = append(graphics,
+				[2]string{"x", num(float64(p.X) * layout.PointsPerInch)},
+				[2]string{"y", num(-float64(p.Y) * layout.PointsPerInch)})
+		}
+		for _, size := range [][2]string{{"w", r.Width}, {"h", r.Height}} {
+			if v, err := strconv.ParseFloat(size[1], 64); err == nil {
+				graphics = append(graphics, [2]string{size[0], num(v * layout.PointsPerInch)})
+			}
+		}
+		if r.Shape != nil {
+			if t, ok := types[r.Shape.String()]; ok {
+				graphics = append(graphics, [2]string{"type", quote(t)})
+			}
+		}
+		style := geom.ParseStyle(r.Style)
+		if c := r.FillColor; c != nil && style.Filled {
+			graphics = append(graphics, [2]string{"fill", hex(c)})
+		}
+		if c := r.Color; c != nil {
+			graphics = append(graphics, [2]string{"outline", hex(c)})
+		}
+		gw.graphics(graphics)
+		gw.close()
+	}
+
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		r := g.ResolveEdge(e)
+		gw.open("edge")
+		gw.key("source", strconv.Itoa(ids[e.Src]))
+		gw.key("target", strconv.Itoa(ids[e.Dst]))
+		gw.attributes(r)
+
+		var graphics [][2]string
+		if c := r.Color; c != nil {
+			graphics = append(graphics, [2]string{"fill", hex(c)})
+		}
+		if r.Penwidth != "" {
+			if v, err := strconv.ParseFloat(r.Penwidth, 64); err == nil {
+				graphics = append(graphics, [2]string{"width", num(v)})
+			}
+		}
+		switch style := geom.ParseStyle(r.Style); {
+		case style.Dashed:
+			graphics = append(graphics, [2]string{"style", quote("dashed")})
+		case style.Dotted:
+			graphics = append(graphics, [2]string{"style", quote("dotted")})
+		}
+		gw.graphics(graphics)
+		gw.close()
+	}
+	gw.close()
+	return gw.w.Flush()
+}
@@ -0,0 +1,135 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gml
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot"
+import "godot/attr"
+import "godot/builder"
+import "godot/format"
+
+func TestEncode(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		label="a \"graph\"";
+		a [shape=box, style=filled, fillcolor=red, pos="1,2", width=1];
+		b;
+		a -> b [color=blue, style=dashed, penwidth=2];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	for _, want := range []string{
+		"directed 1\n",
+		`label "a &quot;graph&quot;"`,
+		"x 72\n", "y -144\n", "w 72\n",
+		`type "rectangle"`, `fill "#ff0000"`,
+		"source 0\n", "target 1\n",
+		`fill "#0000ff"`, "width 2\n", `style "dashed"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Encode did not write %q in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	g, err := Decode(strings.NewReader(`# a comment
+Creator "test"
+graph [
+  directed 1
+  label "a &amp; b"
+  node [ id 7 label "A" graphics [ x 72 y -144 w 36 type "ellipse" fill "#00ff00" ] ]
+  node [ id 8 shape "box" graphics [ type "diamond" ] weight 3 ]
+  edge [ source 7 target 8 graphics [ fill "#0000ff" width 2 style "dotted" ] ]
+]`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if g.Kind() != attr.Directed || g.Label != "a & b" {
+		t.Errorf("Graph is %v labelled %q", g.Kind(), g.Label)
+	}
+	nodes, edges := g.Nodes(), g.Edges()
+	if len(nodes) != 2 || len(edges) != 1 {
+		t.Fatalf("Decode read %d nodes and %d edges", len(nodes), len(edges))
+	}
+	a, b := nodes[0], nodes[1]
+	if a.Label != "A" || a.Position == nil || a.Position.X != 1 || a.Position.Y != 2 || a.Width != "0.5" {
+		t.Errorf("Node a is %+v", a)
+	}
+	if a.Shape.String() != "ellipse" || a.Style != "filled" || a.FillColor == nil {
+		t.Errorf("Node a is %+v", a)
+	}
+	if b.Shape.String() != "box" {
+		t.Errorf("Node b has shape %v", b.Shape)
+	}
+	if e := edges[0]; e.Src != a || e.Dst != b || e.Penwidth != "2" || e.Style != "dotted" || e.Color == nil {
+		t.Errorf("Edge is %+v", e)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`graph { a [label="x\"y", color=red]; a -- b [label=e] }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var first, second bytes.Buffer
+	if err := Encode(&first, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	h, err := Decode(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("Decode failed: %v\n%s", err, first.String())
+	}
+	if err := Encode(&second, h); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("Round trip wrote\n%s\nnot\n%s", second.String(), first.String())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, in := range []string{
+		`node [ id 1 ]`,
+		`graph [ node [ id 1 ]`,
+		`graph [ node [ label "x" ] ]`,
+		`graph [ node [ id 1 ] node [ id 1 ] ]`,
+		`graph [ node [ id 1 ] edge [ source 1 target 2 ] ]`,
+		`graph [ label "x ]`,
+		`graph [ label ]`,
+	} {
+		if _, err := Decode(strings.NewReader(in)); err == nil {
+			t.Errorf("Decode of %q succeeded", in)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	g, name, err := format.Decode(strings.NewReader("# test\nCreator \"x\"\ngraph [\n  node [ id 0 ]\n]\n"))
+	if err != nil || name != "gml" || len(g.Nodes()) != 1 {
+		t.Errorf("format.Decode gave %q, %v", name, err)
+	}
+	if f := format.ForFile("g.gml"); f == nil || !f.Encoder.Capabilities().Has(godot.Positions) {
+		t.Errorf("GML is not registered")
+	}
+}
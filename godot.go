@@ -58,6 +58,14 @@ Which can be converted to a png file using the "dot" program available from
 www.graphviz.org:
 
   $ go run main.go | dot -Tpng > example.png
+
+Package godot/render runs Graphviz directly, without the pipe, and packages
+godot/svg and godot/raster draw graphs laid out by godot/layout without
+Graphviz at all.  Package godot/gvout reads layouts back from the plain, xdot
+and json output of Graphviz, and godot/xdot replays the drawing operations
+of xdot output.  Package godot/format looks formats up by name, by file
+extension or by sniffing their content, among those registered by the
+packages writing them.
 */
 package godot
 
@@ -68,3 +76,28 @@ type Dot interface {
 	// Writes the dot representation of this graph to "writer".
 	Write(writer io.Writer) error
 }
+
+// What a graph format can represent beyond nodes, edges and their
+// attributes.
+type Capabilities uint
+
+const (
+	// Subgraphs drawn as clusters around their nodes.
+	Clusters Capabilities = 1 << iota
+
+	// Edges ending at ports of their nodes.
+	Ports
+
+	// The positions of nodes.
+	Positions
+)
+
+// Reports whether "c" includes all of "flags".
+func (c Capabilities) Has(flags Capabilities) bool {
+	return c&flags == flags
+}
+
+// Implemented by writers which say what their format can represent.
+type Capable interface {
+	Capabilities() Capabilities
+}
@@ -0,0 +1,182 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphml
+
+import "encoding/xml"
+import "errors"
+import "fmt"
+import "io"
+
+import "godot/attr"
+import "godot/builder"
+
+type attributeSetter interface {
+	SetAttribute(name string, value string) error
+}
+
+// Builds a graph from a document.
+type decoder struct {
+	g     *builder.Graph
+	keys  map[string]key
+	nodes map[string]*builder.Node
+	edges []edge
+}
+
+// Sets the attributes of an object from its data.  Data whose key has no
+// attr.name, or names an attribute which the object does not have, is
+// ignored.
+func (d *decoder) set(obj attributeSetter, ds []data) error {
+	for _, dt := range ds {
+		name := d.keys[dt.Key].Name
+		if name == "" {
+			continue
+		}
+		if err := d.setAttribute(obj, name, dt.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) setAttribute(obj attributeSetter, name, value string) error {
+	err := obj.SetAttribute(name, value)
+	if err != nil && !errors.Is(err, builder.ErrUnknownAttribute) {
+		return fmt.Errorf("graphml: %v", err)
+	}
+	return nil
+}
+
+// Sets a template's attributes from the defaults of the keys for a kind of
+// object.  Returns false if the template has no attributes.
+func (d *decoder) template(obj attributeSetter, kind string) (bool, error) {
+	set := false
+	for _, k := range d.keys {
+		if k.Default == nil || k.Name == "" || (k.For != kind && k.For != "all") {
+			continue
+		}
+		err := obj.SetAttribute(k.Name, *k.Default)
+		if errors.Is(err, builder.ErrUnknownAttribute) {
+			continue
+		} else if err != nil {
+			return false, fmt.Errorf("graphml: %v", err)
+		}
+		set = true
+	}
+	return set, nil
+}
+
+// Adds the nodes of a graph element, and of the graphs nested within it, to
+// the graph, and to "parent" if it is not nil.  Nodes holding nested graphs
+// become subgraphs.
+func (d *decoder) graph(gr *graph, parent *builder.Subgraph) error {
+	for i := range gr.Nodes {
+		nd := &gr.Nodes[i]
+		if nd.Graph != nil {
+			sub := builder.NewSubgraph("")
+			for _, dt := range nd.Graph.Data {
+				if k := d.keys[dt.Key]; k.Name == "name" && k.For != "node" && k.For != "edge" {
+					sub.Name = dt.Value
+				}
+			}
+			if err := d.set(sub, nd.Graph.Data); err != nil {
+				return err
+			}
+			if parent != nil {
+				parent.AddSubgraphs(sub)
+			} else {
+				d.g.AddSubgraphs(sub)
+			}
+			if err := d.graph(nd.Graph, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := d.nodes[nd.ID]; ok {
+			return fmt.Errorf("graphml: duplicate node %q", nd.ID)
+		}
+		n := new(builder.Node)
+		if err := d.set(n, nd.Data); err != nil {
+			return err
+		}
+		d.nodes[nd.ID] = n
+		d.g.AddNodes(n)
+		if parent != nil {
+			parent.AddNodes(n)
+		}
+	}
+	d.edges = append(d.edges, gr.Edges...)
+	return nil
+}
+
+// Reads a graph written in GraphML.  Only the first graph of the document
+// is read.  The graph is directed if its edgedefault is "directed".  Data
+// whose key's attr.name is a dot attribute of the object, such as "label",
+// sets that attribute, and the defaults of keys set the attributes of the
+// node and edge templates; other data, such as yEd's graphics, is ignored.
+// Ports and hyperedges are not supported.
+func Decode(r io.Reader) (*builder.Graph, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("graphml: %v", err)
+	}
+	if len(doc.Graphs) == 0 {
+		return nil, errors.New("graphml: no graph")
+	}
+	top := &doc.Graphs[0]
+	kind := attr.Undirected
+	if top.EdgeDefault == "directed" {
+		kind = attr.Directed
+	}
+	d := &decoder{
+		g:     builder.NewGraph(kind),
+		keys:  make(map[string]key),
+		nodes: make(map[string]*builder.Node),
+	}
+	for _, k := range doc.Keys {
+		d.keys[k.ID] = k
+	}
+
+	if err := d.set(d.g, top.Data); err != nil {
+		return nil, err
+	}
+	nTmpl, eTmpl := new(builder.Node), new(builder.Edge)
+	if ok, err := d.template(nTmpl, "node"); err != nil {
+		return nil, err
+	} else if ok {
+		d.g.SetNodeTemplate(nTmpl)
+	}
+	if ok, err := d.template(eTmpl, "edge"); err != nil {
+		return nil, err
+	} else if ok {
+		d.g.SetEdgeTemplate(eTmpl)
+	}
+
+	if err := d.graph(top, nil); err != nil {
+		return nil, err
+	}
+	for _, ed := range d.edges {
+		src, dst := d.nodes[ed.Source], d.nodes[ed.Target]
+		if src == nil || dst == nil {
+			return nil, fmt.Errorf("graphml: edge from %q to %q has an unknown endpoint", ed.Source, ed.Target)
+		}
+		e := &builder.Edge{Src: src, Dst: dst}
+		if err := d.set(e, ed.Data); err != nil {
+			return nil, err
+		}
+		d.g.AddEdges(e)
+	}
+	return d.g, nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package graphml reads and writes graphs in GraphML, the XML format of yEd,
+Gephi and many other tools.
+
+Attributes of the graph, its subgraphs, nodes and edges are written as
+<data> elements, each declared by a <key> whose attr.name is the dot name of
+the attribute, such as "label" or "color".  Attributes of the node and edge
+templates become the defaults of their keys.  A subgraph is written as a
+node holding a nested <graph>, whose "name" data is the subgraph's name.
+Nodes are written in the first subgraph which holds them, as GraphML cannot
+express a node in several subgraphs.
+
+Resources:
+  http://graphml.graphdrawing.org/specification.html
+*/
+package graphml
+
+import "bytes"
+import "encoding/xml"
+import "fmt"
+import "io"
+import "sort"
+
+import "godot"
+import "godot/attr"
+import "godot/builder"
+import "godot/format"
+
+func init() {
+	format.Register("graphml", []string{".graphml"},
+		func(prefix []byte) bool { return bytes.Contains(prefix, []byte("<graphml")) },
+		format.NewEncoder(Encode, godot.Clusters|godot.Positions), format.NewDecoder(Decode))
+}
+
+// The namespace of GraphML documents.
+const Namespace = "http://graphml.graphdrawing.org/xmlns"
+
+type document struct {
+	XMLName xml.Name `xml:"graphml"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Keys    []key    `xml:"key"`
+	Graphs  []graph  `xml:"graph"`
+}
+
+type key struct {
+	ID      string  `xml:"id,attr"`
+	For     string  `xml:"for,attr,omitempty"`
+	Name    string  `xml:"attr.name,attr,omitempty"`
+	Type    string  `xml:"attr.type,attr,omitempty"`
+	Default *string `xml:"default"`
+}
+
+type graph struct {
+	ID          string `xml:"id,attr,omitempty"`
+	EdgeDefault string `xml:"edgedefault,attr"`
+	Data        []data `xml:"data"`
+	Nodes       []node `xml:"node"`
+	Edges       []edge `xml:"edge"`
+}
+
+type node struct {
+	ID    string `xml:"id,attr"`
+	Data  []data `xml:"data"`
+	Graph *graph `xml:"graph"`
+}
+
+type edge struct {
+	ID       string `xml:"id,attr,omitempty"`
+	Source   string `xml:"source,attr"`
+	Target   string `xml:"target,attr"`
+	Directed string `xml:"directed,attr,omitempty"`
+	Data     []data `xml:"data"`
+}
+
+type data struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// The id of the key for the attribute "name" of objects of a kind, "graph",
+// "node" or "edge".
+func keyID(kind, name string) string {
+	return kind[:1] + "_" + name
+}
+
+// Builds a document from a graph, declaring keys as attributes are used.
+type encoder struct {
+	ids     map[*builder.Node]string
+	written map[*builder.Node]bool
+	keys    map[string]*key
+	subs    int
+}
+
+// Returns the data of an object's attributes, declaring their keys.
+func (e *encoder) data(kind string, obj interface{}) []data {
+	var ds []data
+	for _, a := range builder.Attributes(obj) {
+		ds = append(ds, data{Key: e.key(kind, a[0]).ID, Value: a[1]})
+	}
+	return ds
+}
+
+func (e *encoder) key(kind, name string) *key {
+	id := keyID(kind, name)
+	k, ok := e.keys[id]
+	if !ok {
+		k = &key{ID: id, For: kind, Name: name, Type: "string"}
+		e.keys[id] = k
+	}
+	return k
+}
+
+func (e *encoder) node(n *builder.Node) node {
+	e.written[n] = true
+	return node{ID: e.ids[n], Data: e.data("node", n)}
+}
+
+// Writes a subgraph as a node holding a nested graph, with the nodes of the
+// subgraph not yet written.
+func (e *encoder) subgraph(sub *builder.Subgraph, edgeDefault string) node {
+	id := fmt.Sprintf("s%d", e.subs)
+	e.subs++
+	inner := &graph{ID: id + ":", EdgeDefault: edgeDefault}
+	if sub.Name != "" {
+		inner.Data = append(inner.Data, data{Key: e.key("graph", "name").ID, Value: sub.Name})
+	}
+	inner.Data = append(inner.Data, e.data("graph", sub)...)
+	for _, n := range sub.Nodes() {
+		if !e.written[n] {
+			inner.Nodes = append(inner.Nodes, e.node(n))
+		}
+	}
+	for _, s := range sub.Subgraphs() {
+		inner.Nodes = append(inner.Nodes, e.subgraph(s, edgeDefault))
+	}
+	return node{ID: id, Graph: inner}
+}
+
+// Writes "g" as GraphML.  Nodes are given the ids "n0", "n1" and so on, in
+// the order of g.Nodes(), as they are in dot, and edges "e0", "e1" and so
+// on.  Edges without both endpoints are not written.
+func Encode(w io.Writer, g *builder.Graph) error {
+	e := &encoder{
+		ids:     make(map[*builder.Node]string),
+		written: make(map[*builder.Node]bool),
+		keys:    make(map[string]*key),
+	}
+	edgeDefault := "undirected"
+	if g.Kind() == attr.Directed {
+		edgeDefault = "directed"
+	}
+	nodes := g.Nodes()
+	for i, n := range nodes {
+		e.ids[n] = fmt.Sprintf("n%d", i)
+	}
+
+	top := graph{ID: "G", EdgeDefault: edgeDefault, Data: e.data("graph", g)}
+
+	// Each subgraph is written where its first node would be, so that the
+	// order of the nodes is kept where it can be.
+	subs := g.Subgraphs()
+	first := make(map[*builder.Node]*builder.Subgraph)
+	for i := len(subs) - 1; i >= 0; i-- {
+		for _, n := range subs[i].AllNodes() {
+			first[n] = subs[i]
+		}
+	}
+	done := make(map[*builder.Subgraph]bool)
+	for _, n := range nodes {
+		if e.written[n] {
+			continue
+		}
+		if sub := first[n]; sub != nil && !done[sub] {
+			done[sub] = true
+			top.Nodes = append(top.Nodes, e.subgraph(sub, edgeDefault))
+		}
+		if !e.written[n] {
+			top.Nodes = append(top.Nodes, e.node(n))
+		}
+	}
+	for _, sub := range subs {
+		if !done[sub] {
+			top.Nodes = append(top.Nodes, e.subgraph(sub, edgeDefault))
+		}
+	}
+
+	for _, ed := range g.Edges() {
+		if ed.Src == nil || ed.Dst == nil {
+			continue
+		}
+		top.Edges = append(top.Edges, edge{
+			ID:     fmt.Sprintf("e%d", len(top.Edges)),
+			Source: e.ids[ed.Src],
+			Target: e.ids[ed.Dst],
+			Data:   e.data("edge", ed),
+		})
+	}
+
+	// The templates give the defaults of keys.
+	if tmpl := g.NodeTemplate(); tmpl != nil {
+		for _, a := range builder.Attributes(tmpl) {
+			value := a[1]
+			e.key("node", a[0]).Default = &value
+		}
+	}
+	if tmpl := g.EdgeTemplate(); tmpl != nil {
+		for _, a := range builder.Attributes(tmpl) {
+			value := a[1]
+			e.key("edge", a[0]).Default = &value
+		}
+	}
+
+	doc := document{Xmlns: Namespace, Graphs: []graph{top}}
+	for _, k := range e.keys {
+		doc.Keys = append(doc.Keys, *k)
+	}
+	sort.Slice(doc.Keys, func(i, j int) bool { return doc.Keys[i].ID < doc.Keys[j].ID })
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
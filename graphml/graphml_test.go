@@ -0,0 +1,141 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphml
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+// Returns the dot written for a graph.
+func dot(t *testing.T, g *builder.Graph) string {
+	var buf bytes.Buffer
+	if err := g.Build().Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		`digraph {
+			label="A <graph> & \"more\"";
+			rankdir=LR;
+			node [shape=box, color=blue];
+			edge [style=dashed];
+			a [fillcolor=red, pos="1,2!", URL="http://example.com/?a=1&b=2"];
+			subgraph cluster_0 { label=Outer; b; c; subgraph cluster_1 { style=filled; d } }
+			e;
+			a -> b [label=ab]; b -> c; d -> e [color=green]; e -> a;
+		}`,
+		`graph { a -- b -- c -- a; d }`,
+	} {
+		g, err := builder.Parse(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := Encode(&buf, g); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		back, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Decode failed: %v\n%s", err, buf.String())
+		}
+		if want, got := dot(t, g), dot(t, back); got != want {
+			t.Errorf("Round trip through\n%s\ngave\n%s\nwanted\n%s", buf.String(), got, want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`graph { node [shape=circle]; subgraph cluster_x { a } a -- b [label=ab] }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`,
+		`<key id="n_shape" for="node" attr.name="shape" attr.type="string">`,
+		`<default>circle</default>`,
+		`<key id="e_label" for="edge" attr.name="label" attr.type="string"></key>`,
+		`<graph id="G" edgedefault="undirected">`,
+		`<data key="g_name">cluster_x</data>`,
+		`<node id="n0">`,
+		`<edge id="e0" source="n0" target="n1">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output does not contain %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// As written by yEd, with graphics data and a node holding a group.
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:y="http://www.yworks.com/xml/graphml">
+  <key for="node" id="d0" yfiles.type="nodegraphics"/>
+  <key attr.name="label" attr.type="string" for="all" id="d1"/>
+  <key attr.name="weight" attr.type="double" for="edge" id="d2"><default>1.0</default></key>
+  <graph edgedefault="directed" id="G">
+    <node id="a"><data key="d0"><y:ShapeNode><y:NodeLabel>A</y:NodeLabel></y:ShapeNode></data><data key="d1">A</data></node>
+    <node id="grp">
+      <graph edgedefault="directed" id="grp:">
+        <node id="b"><data key="d1">B</data></node>
+      </graph>
+    </node>
+    <edge source="a" target="b"><data key="d1">ab</data><data key="d2">2.5</data></edge>
+  </graph>
+</graphml>`
+	g, err := Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if g.Kind() != attr.Directed {
+		t.Errorf("Graph should be directed.")
+	}
+	nodes, edges, subs := g.Nodes(), g.Edges(), g.Subgraphs()
+	if len(nodes) != 2 || nodes[0].Label != "A" || nodes[1].Label != "B" {
+		t.Fatalf("Graph has nodes %v.", nodes)
+	}
+	if len(edges) != 1 || edges[0].Src != nodes[0] || edges[0].Dst != nodes[1] || edges[0].Label != "ab" {
+		t.Errorf("Graph has edges %v.", edges)
+	}
+	if len(subs) != 1 || len(subs[0].Nodes()) != 1 || subs[0].Nodes()[0] != nodes[1] {
+		t.Errorf("Graph has subgraphs %v.", subs)
+	}
+	if g.EdgeTemplate() != nil {
+		t.Errorf("Unknown attributes set the edge template.")
+	}
+
+	for _, bad := range []string{
+		`<graphml><graph edgedefault="directed"><node id="a"/><edge source="a" target="x"/></graph></graphml>`,
+		`<graphml><graph edgedefault="directed"><node id="a"/><node id="a"/></graph></graphml>`,
+		`<graphml><key id="p" for="node" attr.name="pos"/><graph><node id="a"><data key="p">x</data></node></graph></graphml>`,
+		`<graphml></graphml>`,
+		`<graphml><graph>`,
+	} {
+		if _, err := Decode(strings.NewReader(bad)); err == nil {
+			t.Errorf("Decode of %s did not fail.", bad)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package gvout reads the layouts Graphviz computes back into a program.
+
+Graphviz is given a graph written by godot, and asked for one of its layout
+formats: -Tplain, -Tdot or -Txdot, or -Tjson.  The readers here parse that
+output and match its nodes, edges and clusters with those of the original
+builder.Graph, using the ids godot wrote for the nodes and the order and
+endpoints of the edges.  Each node's Position, Width and Height are set from
+the layout, and the layout is returned, with the spline and label position of
+each edge:
+
+  res, err := (&render.Renderer{Format: "xdot"}).Render(ctx, g.Build())
+  ...
+  x, err := gvout.ReadXdot(bytes.NewReader(res.Output), g)
+
+The returned layouts are in points, as in package layout.
+*/
+package gvout
+
+import "errors"
+import "fmt"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+import "godot/builder"
+import "godot/layout"
+
+// Returned when the output names a node or edge which is not in the graph.
+var ErrMismatch = errors.New("gvout: output does not match the graph")
+
+// Matches the objects of Graphviz output with those of the graph it was
+// given.
+type matcher struct {
+	nodes []*builder.Node
+	edges map[[2]*builder.Node][]*builder.Edge
+	subs  map[string]*builder.Subgraph
+	kind  *attr.GraphKind
+	l     *layout.Layout
+}
+
+func newMatcher(g *builder.Graph) *matcher {
+	m := &matcher{
+		nodes: g.Nodes(),
+		edges: make(map[[2]*builder.Node][]*builder.Edge),
+		subs:  make(map[string]*builder.Subgraph),
+		kind:  g.Kind(),
+		l: &layout.Layout{
+			Nodes:    make(map[*builder.Node]*layout.NodeLayout),
+			Edges:    make(map[*builder.Edge]*layout.EdgeLayout),
+			Clusters: make(map[*builder.Subgraph]layout.Rect),
+		},
+	}
+	for _, e := range g.Edges() {
+		if e.Src != nil && e.Dst != nil {
+			key := [2]*builder.Node{e.Src, e.Dst}
+			m.edges[key] = append(m.edges[key], e)
+		}
+	}
+	var visit func(subs []*builder.Subgraph)
+	visit = func(subs []*builder.Subgraph) {
+		for _, sub := range subs {
+			if sub.Name != "" {
+				m.subs[sub.Name] = sub
+			}
+			visit(sub.Subgraphs())
+		}
+	}
+	visit(g.Subgraphs())
+	return m
+}
+
+// Returns the node written with the id "name".
+func (m *matcher) node(name string) (*builder.Node, error) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i] // port
+	}
+	id, err := strconv.Atoi(name)
+	if err != nil || id < 0 || id >= len(m.nodes) {
+		return nil, fmt.Errorf("%w: unknown node %q", ErrMismatch, name)
+	}
+	return m.nodes[id], nil
+}
+
+// Returns the next edge from "tail" to "head" not yet matched, or in an
+// undirected graph from "head" to "tail".
+func (m *matcher) edge(tail, head *builder.Node) (*builder.Edge, error) {
+	keys := [][2]*builder.Node{{tail, head}}
+	if m.kind != attr.Directed {
+		keys = append(keys, [2]*builder.Node{head, tail})
+	}
+	for _, key := range keys {
+		if queue := m.edges[key]; len(queue) > 0 {
+			m.edges[key] = queue[1:]
+			return queue[0], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown edge from %q to %q", ErrMismatch, tail.Label, head.Label)
+}
+
+// Records the center and size of a node, in points, and sets its Position,
+// Width and Height.
+func (m *matcher) placeNode(n *builder.Node, center layout.Point, width, height string) {
+	w, _ := strconv.ParseFloat(width, 64)
+	h, _ := strconv.ParseFloat(height, 64)
+	m.l.Nodes[n] = &layout.NodeLayout{
+		Center: center,
+		Width:  w * layout.PointsPerInch,
+		Height: h * layout.PointsPerInch,
+	}
+	n.Position = &attr.Point{
+		X: float32(center.X / layout.PointsPerInch),
+		Y: float32(center.Y / layout.PointsPerInch),
+	}
+	if width != "" {
+		n.Width = width
+	}
+	if height != "" {
+		n.Height = height
+	}
+}
+
+// Records the route of an edge from its spline control points, the tips of
+// its arrowheads if any, and its label position.  The spline is extended to
+// the tips, as the renderers expect.
+func (m *matcher) routeEdge(e *builder.Edge, spline []layout.Point, start, end, label *layout.Point) {
+	if start != nil && len(spline) > 0 {
+		spline = append(line(*start, spline[0]), spline[1:]...)
+	}
+	if end != nil && len(spline) > 0 {
+		spline = append(spline[:len(spline)-1], line(spline[len(spline)-1], *end)...)
+	}
+	el := &layout.EdgeLayout{Spline: spline, LabelPos: label}
+	for i := 0; i < len(spline); i += 3 {
+		el.Points = append(el.Points, spline[i])
+	}
+	m.l.Edges[e] = el
+}
+
+// Returns a straight cubic Bezier segment from "a" to "b".
+func line(a, b layout.Point) []layout.Point {
+	return []layout.Point{
+		a,
+		{X: a.X + (b.X-a.X)/3, Y: a.Y + (b.Y-a.Y)/3},
+		{X: a.X + 2*(b.X-a.X)/3, Y: a.Y + 2*(b.Y-a.Y)/3},
+		b,
+	}
+}
+
+// Parses a point "x,y", ignoring any further coordinates.
+func parsePoint(s string) (layout.Point, error) {
+	xy := strings.Split(strings.TrimSuffix(strings.TrimSpace(s), "!"), ",")
+	if len(xy) < 2 {
+		return layout.Point{}, fmt.Errorf("gvout: invalid point %q", s)
+	}
+	x, err1 := strconv.ParseFloat(xy[0], 64)
+	y, err2 := strconv.ParseFloat(xy[1], 64)
+	if err1 != nil || err2 != nil {
+		return layout.Point{}, fmt.Errorf("gvout: invalid point %q", s)
+	}
+	return layout.Point{X: x, Y: y}, nil
+}
+
+// Parses a rectangle "llx,lly,urx,ury".
+func parseRect(s string) (layout.Rect, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return layout.Rect{}, fmt.Errorf("gvout: invalid rectangle %q", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return layout.Rect{}, fmt.Errorf("gvout: invalid rectangle %q", s)
+		}
+		v[i] = f
+	}
+	return layout.Rect{Min: layout.Point{X: v[0], Y: v[1]}, Max: layout.Point{X: v[2], Y: v[3]}}, nil
+}
+
+// Parses the "pos" of an edge: a spline's control points, preceded by the
+// tips of its arrowheads, "s,x,y" at the start and "e,x,y" at the end.  Of
+// several splines separated by ';', only the first is read.
+func parseSpline(s string) (spline []layout.Point, start, end *layout.Point, err error) {
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+	for _, field := range strings.Fields(s) {
+		var tip **layout.Point
+		switch {
+		case strings.HasPrefix(field, "s,"):
+			tip = &start
+		case strings.HasPrefix(field, "e,"):
+			tip = &end
+		}
+		if tip != nil {
+			field = field[2:]
+		}
+		p, err := parsePoint(field)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if tip != nil {
+			*tip = &p
+		} else {
+			spline = append(spline, p)
+		}
+	}
+	return spline, start, end, nil
+}
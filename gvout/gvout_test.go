@@ -0,0 +1,180 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gvout
+
+import "errors"
+import "math"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/layout"
+
+const source = `digraph {
+	subgraph cluster_0 { a; b }
+	a -> b [label=ab];
+	b -> c;
+}`
+
+// Output of Graphviz for the source as written by godot, with nodes named
+// by their index.
+const plain = `graph 1 1.25 3.5
+node 0 0.375 3.25 0.75 0.5 a solid ellipse black lightgrey
+node 1 0.375 1.75 0.75 0.5 b solid ellipse black lightgrey
+node 2 0.375 0.25 0.75 0.5 c solid ellipse black lightgrey
+edge 0 1 4 0.375 2.995833 0.375 2.6886 0.375 2.4136 0.375 2.1508 ab 0.625 2.5 solid black
+edge 1 2 4 0.375 1.4907 0.375 1.1886 0.375 0.91361 0.375 0.65078 solid black
+stop
+`
+
+const xdot = `digraph {
+	graph [bb="0,0,90,252", _draw_="c 9 -#fffffe00 C 7 -#ffffff P 4 0 0 0 252 90 252 90 0 "];
+	node [label="\N"];
+	subgraph cluster_0 {
+		graph [bb="8,98,82,244", _draw_="c 7 -#000000 p 4 8 98 8 244 82 244 82 98 "];
+		0 [height=0.5, label=a, pos="27,234", width=0.75, _draw_="c 7 -#000000 e 27 234 27 18 "];
+		1 [height=0.5, label=b, pos="27,126", width=0.75];
+	}
+	2 [height=0.5, label=c, pos="27,18", width=0.75];
+	0 -> 1 [label=ab, lp="45,180", pos="e,27,144.1 27,215.7 27,193.6 27,176 27,154.1", _hdraw_="S 5 -solid c 7 -#000000 C 7 -#000000 P 3 30.5 154.1 27 144.1 23.5 154.1 "];
+	1 -> 2 [pos="e,27,36.1 27,107.7 27,85.6 27,68 27,46.1"];
+}
+`
+
+const jsonOut = `{
+	"name": "%3", "directed": true, "strict": false,
+	"bb": "0,0,90,252",
+	"_subgraph_cnt": 1,
+	"objects": [
+		{"_gvid": 0, "name": "cluster_0", "bb": "8,98,82,244", "nodes": [1, 2], "edges": [0]},
+		{"_gvid": 1, "name": "0", "height": "0.5", "label": "a", "pos": "27,234", "width": "0.75"},
+		{"_gvid": 2, "name": "1", "height": "0.5", "label": "b", "pos": "27,126", "width": "0.75"},
+		{"_gvid": 3, "name": "2", "height": "0.5", "label": "c", "pos": "27,18", "width": "0.75"}
+	],
+	"edges": [
+		{"_gvid": 0, "tail": 1, "head": 2, "label": "ab", "lp": "45,180", "pos": "e,27,144.1 27,215.7 27,193.6 27,176 27,154.1"},
+		{"_gvid": 1, "tail": 2, "head": 3, "pos": "e,27,36.1 27,107.7 27,85.6 27,68 27,46.1"}
+	]
+}`
+
+func parse(t *testing.T) *builder.Graph {
+	g, err := builder.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return g
+}
+
+func near(a, b layout.Point) bool {
+	return math.Abs(a.X-b.X) < 0.01 && math.Abs(a.Y-b.Y) < 0.01
+}
+
+// Checks the layout read for the source.
+func check(t *testing.T, g *builder.Graph, l *layout.Layout, arrows bool) {
+	nodes, edges := g.Nodes(), g.Edges()
+	for i, y := range []float64{234, 126, 18} {
+		nl := l.Nodes[nodes[i]]
+		if nl == nil || !near(nl.Center, layout.Point{X: 27, Y: y}) || nl.Width != 54 || nl.Height != 36 {
+			t.Errorf("Node %s has layout %+v.", nodes[i].Label, nl)
+			continue
+		}
+		if p := nodes[i].Position; p == nil || math.Abs(float64(p.Y)-y/72) > 0.01 {
+			t.Errorf("Node %s has position %v.", nodes[i].Label, p)
+		}
+		if nodes[i].Width != "0.75" || nodes[i].Height != "0.5" {
+			t.Errorf("Node %s has size %s by %s.", nodes[i].Label, nodes[i].Width, nodes[i].Height)
+		}
+	}
+	el := l.Edges[edges[0]]
+	if el == nil {
+		t.Fatalf("Edge a -> b has no layout.")
+	}
+	if el.LabelPos == nil || !near(*el.LabelPos, layout.Point{X: 45, Y: 180}) {
+		t.Errorf("Edge a -> b has label position %v.", el.LabelPos)
+	}
+	if len(el.Spline)%3 != 1 || !near(el.Spline[0], layout.Point{X: 27, Y: 215.7}) {
+		t.Errorf("Edge a -> b has spline %v.", el.Spline)
+	}
+	end := el.Spline[len(el.Spline)-1]
+	if arrows && !near(end, layout.Point{X: 27, Y: 144.1}) {
+		t.Errorf("Edge a -> b ends at %v, not at its arrowhead.", end)
+	}
+	if l.Edges[edges[1]] == nil {
+		t.Errorf("Edge b -> c has no layout.")
+	}
+}
+
+func TestReadPlain(t *testing.T) {
+	g := parse(t)
+	l, err := ReadPlain(strings.NewReader(plain), g)
+	if err != nil {
+		t.Fatalf("ReadPlain failed: %v", err)
+	}
+	check(t, g, l, false)
+	if l.Bounds.Max != (layout.Point{X: 90, Y: 252}) {
+		t.Errorf("Layout has bounds %v.", l.Bounds)
+	}
+}
+
+func TestReadXdot(t *testing.T) {
+	g := parse(t)
+	x, err := ReadXdot(strings.NewReader(xdot), g)
+	if err != nil {
+		t.Fatalf("ReadXdot failed: %v", err)
+	}
+	check(t, g, x.Layout, true)
+	sub := g.Subgraphs()[0]
+	if r := x.Clusters[sub]; r.Min != (layout.Point{X: 8, Y: 98}) || r.Max != (layout.Point{X: 82, Y: 244}) {
+		t.Errorf("Cluster has bounds %v.", r)
+	}
+	if x.Ops[g]["_draw_"] == "" || x.Ops[sub]["_draw_"] == "" {
+		t.Errorf("Graph and cluster drawing operations are missing: %v", x.Ops)
+	}
+	if !strings.HasPrefix(x.Ops[g.Nodes()[0]]["_draw_"], "c 7") {
+		t.Errorf("Node a has operations %v.", x.Ops[g.Nodes()[0]])
+	}
+	if x.Ops[g.Edges()[0]]["_hdraw_"] == "" {
+		t.Errorf("Edge a -> b has no arrowhead operations.")
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	g := parse(t)
+	l, err := ReadJSON(strings.NewReader(jsonOut), g)
+	if err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+	check(t, g, l, true)
+	if r := l.Clusters[g.Subgraphs()[0]]; r.Max != (layout.Point{X: 82, Y: 244}) {
+		t.Errorf("Cluster has bounds %v.", r)
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	g := parse(t)
+	bad := strings.Replace(plain, "edge 1 2", "edge 2 0", 1)
+	if _, err := ReadPlain(strings.NewReader(bad), g); !errors.Is(err, ErrMismatch) {
+		t.Errorf("ReadPlain of an unknown edge returned %v.", err)
+	}
+	bad = strings.Replace(plain, "node 2", "node 7", 1)
+	if _, err := ReadPlain(strings.NewReader(bad), g); !errors.Is(err, ErrMismatch) {
+		t.Errorf("ReadPlain of an unknown node returned %v.", err)
+	}
+	bad = strings.Replace(plain, "edge 1 2 4", "edge 1 2 -1", 1)
+	if _, err := ReadPlain(strings.NewReader(bad), g); err == nil || !strings.Contains(err.Error(), "invalid point count") {
+		t.Errorf("ReadPlain of a negative point count returned %v.", err)
+	}
+}
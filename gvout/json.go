@@ -0,0 +1,121 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gvout
+
+import "encoding/json"
+import "fmt"
+import "io"
+
+import "godot/builder"
+import "godot/layout"
+
+// An object of -Tjson output: a subgraph or a node.  Only the attributes
+// holding the layout are read.
+type jsonObject struct {
+	Name   string `json:"name"`
+	Pos    string `json:"pos"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+	Bb     string `json:"bb"`
+	Lp     string `json:"lp"`
+}
+
+type jsonEdge struct {
+	Tail int    `json:"tail"`
+	Head int    `json:"head"`
+	Pos  string `json:"pos"`
+	Lp   string `json:"lp"`
+}
+
+type jsonGraph struct {
+	Bb          string       `json:"bb"`
+	SubgraphCnt int          `json:"_subgraph_cnt"`
+	Objects     []jsonObject `json:"objects"`
+	Edges       []jsonEdge   `json:"edges"`
+}
+
+// Reads the output of "dot -Tjson" or "dot -Tjson0" for "g".  Nodes, edges
+// including their arrowheads, and clusters are read; drawing operations are
+// not.
+func ReadJSON(r io.Reader, g *builder.Graph) (*layout.Layout, error) {
+	var out jsonGraph
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return nil, fmt.Errorf("gvout: %v", err)
+	}
+	m := newMatcher(g)
+	var err error
+	if out.Bb != "" {
+		if m.l.Bounds, err = parseRect(out.Bb); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subgraphs come first, then nodes, each numbered by its _gvid, which
+	// is its index.
+	nodes := make(map[int]*builder.Node)
+	for i, obj := range out.Objects {
+		if i < out.SubgraphCnt {
+			if sub, ok := m.subs[obj.Name]; ok && obj.Bb != "" {
+				r, err := parseRect(obj.Bb)
+				if err != nil {
+					return nil, err
+				}
+				m.l.Clusters[sub] = r
+			}
+			continue
+		}
+		n, err := m.node(obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+		if obj.Pos != "" {
+			center, err := parsePoint(obj.Pos)
+			if err != nil {
+				return nil, err
+			}
+			m.placeNode(n, center, obj.Width, obj.Height)
+		}
+	}
+
+	for _, je := range out.Edges {
+		tail, head := nodes[je.Tail], nodes[je.Head]
+		if tail == nil || head == nil {
+			return nil, fmt.Errorf("%w: edge between unknown objects %d and %d", ErrMismatch, je.Tail, je.Head)
+		}
+		e, err := m.edge(tail, head)
+		if err != nil {
+			return nil, err
+		}
+		if je.Pos == "" {
+			continue
+		}
+		spline, start, end, err := parseSpline(je.Pos)
+		if err != nil {
+			return nil, err
+		}
+		var label *layout.Point
+		if je.Lp != "" {
+			p, err := parsePoint(je.Lp)
+			if err != nil {
+				return nil, err
+			}
+			label = &p
+		}
+		m.routeEdge(e, spline, start, end, label)
+	}
+	return m.l, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gvout
+
+import "bufio"
+import "fmt"
+import "io"
+import "strconv"
+import "strings"
+
+import "godot/builder"
+import "godot/layout"
+
+// Reads the output of "dot -Tplain" or "dot -Tplain-ext" for "g".  The plain
+// format gives node centers and sizes, edge splines and label positions, but
+// neither arrowheads nor clusters.
+func ReadPlain(r io.Reader, g *builder.Graph) (*layout.Layout, error) {
+	m := newMatcher(g)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	scale := 1.0
+	inch := func(v float64) float64 { return v * scale * layout.PointsPerInch }
+	for line := 1; sc.Scan(); line++ {
+		fields, err := splitPlain(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("gvout: line %d: %v", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		nums := func(from, count int) ([]float64, error) {
+			if len(fields) < from+count {
+				return nil, fmt.Errorf("gvout: line %d: too few fields", line)
+			}
+			v := make([]float64, count)
+			for i := range v {
+				f, err := strconv.ParseFloat(fields[from+i], 64)
+				if err != nil {
+					return nil, fmt.Errorf("gvout: line %d: invalid number %q", line, fields[from+i])
+				}
+				v[i] = f
+			}
+			return v, nil
+		}
+
+		switch fields[0] {
+		case "graph":
+			v, err := nums(1, 3)
+			if err != nil {
+				return nil, err
+			}
+			scale = v[0]
+			m.l.Bounds = layout.Rect{Max: layout.Point{X: inch(v[1]), Y: inch(v[2])}}
+		case "node":
+			v, err := nums(2, 4)
+			if err != nil {
+				return nil, err
+			}
+			n, err := m.node(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			m.placeNode(n, layout.Point{X: inch(v[0]), Y: inch(v[1])}, fields[4], fields[5])
+		case "edge":
+			count, err := nums(3, 1)
+			if err != nil {
+				return nil, err
+			}
+			n := int(count[0])
+			if n < 1 {
+				return nil, fmt.Errorf("gvout: line %d: invalid point count", line)
+			}
+			v, err := nums(4, 2*n)
+			if err != nil {
+				return nil, err
+			}
+			tail, err := m.node(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			head, err := m.node(fields[2])
+			if err != nil {
+				return nil, err
+			}
+			e, err := m.edge(tail, head)
+			if err != nil {
+				return nil, err
+			}
+			spline := make([]layout.Point, n)
+			for i := range spline {
+				spline[i] = layout.Point{X: inch(v[2*i]), Y: inch(v[2*i+1])}
+			}
+
+			// A label and its position precede the style and color.
+			var label *layout.Point
+			if rest := len(fields) - 4 - 2*n; rest >= 5 {
+				lp, err := nums(5+2*n, 2)
+				if err != nil {
+					return nil, err
+				}
+				label = &layout.Point{X: inch(lp[0]), Y: inch(lp[1])}
+			}
+			m.routeEdge(e, spline, nil, nil, label)
+		case "stop":
+			return m.l, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m.l, nil
+}
+
+// Splits a line of plain output into fields separated by spaces.  Fields in
+// double quotes may contain spaces and escaped quotes.
+func splitPlain(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ' || line[i] == '\t':
+			i++
+		case line[i] == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && line[i+1] == '"' {
+					i++
+				}
+				b.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			fields = append(fields, b.String())
+			i++
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			fields = append(fields, line[start:i])
+		}
+	}
+	return fields, nil
+}
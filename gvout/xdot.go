@@ -0,0 +1,129 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gvout
+
+import "io"
+import "strings"
+
+import "godot/builder"
+import "godot/layout"
+
+// A layout read from -Tdot or -Txdot output.
+type Xdot struct {
+	*layout.Layout
+
+	// The drawing operations of each object, the *builder.Graph or a
+	// *builder.Subgraph, *builder.Node or *builder.Edge, by attribute name,
+	// for example "_draw_" or "_ldraw_".  Empty for -Tdot output.
+	Ops map[interface{}]map[string]string
+}
+
+// Reads the output of "dot -Txdot" or "dot -Tdot" for "g".  Nodes, edges
+// including their arrowheads, and clusters are read, along with the xdot
+// drawing operations of each object.
+func ReadXdot(r io.Reader, g *builder.Graph) (*Xdot, error) {
+	raw, err := builder.ParseRaw(r)
+	if err != nil {
+		return nil, err
+	}
+	m := newMatcher(g)
+	x := &Xdot{Layout: m.l, Ops: make(map[interface{}]map[string]string)}
+	ops := func(obj interface{}, atrs map[string]string) {
+		for name, value := range atrs {
+			if strings.HasPrefix(name, "_") && strings.HasSuffix(name, "draw_") {
+				if x.Ops[obj] == nil {
+					x.Ops[obj] = make(map[string]string)
+				}
+				x.Ops[obj][name] = value
+			}
+		}
+	}
+
+	out := raw.Graph
+	if bb, ok := raw.Attributes[out]["bb"]; ok {
+		if m.l.Bounds, err = parseRect(bb); err != nil {
+			return nil, err
+		}
+	}
+	ops(g, raw.Attributes[out])
+
+	nodes := make(map[*builder.Node]*builder.Node)
+	for _, on := range out.Nodes() {
+		n, err := m.node(raw.Names[on])
+		if err != nil {
+			return nil, err
+		}
+		nodes[on] = n
+		atrs := raw.Attributes[on]
+		if pos, ok := atrs["pos"]; ok {
+			center, err := parsePoint(pos)
+			if err != nil {
+				return nil, err
+			}
+			m.placeNode(n, center, atrs["width"], atrs["height"])
+		}
+		ops(n, atrs)
+	}
+
+	for _, oe := range out.Edges() {
+		e, err := m.edge(nodes[oe.Src], nodes[oe.Dst])
+		if err != nil {
+			return nil, err
+		}
+		atrs := raw.Attributes[oe]
+		if pos, ok := atrs["pos"]; ok {
+			spline, start, end, err := parseSpline(pos)
+			if err != nil {
+				return nil, err
+			}
+			var label *layout.Point
+			if lp, ok := atrs["lp"]; ok {
+				p, err := parsePoint(lp)
+				if err != nil {
+					return nil, err
+				}
+				label = &p
+			}
+			m.routeEdge(e, spline, start, end, label)
+		}
+		ops(e, atrs)
+	}
+
+	var visit func(subs []*builder.Subgraph) error
+	visit = func(subs []*builder.Subgraph) error {
+		for _, os := range subs {
+			if sub, ok := m.subs[os.Name]; ok {
+				atrs := raw.Attributes[os]
+				if bb, ok := atrs["bb"]; ok {
+					r, err := parseRect(bb)
+					if err != nil {
+						return err
+					}
+					m.l.Clusters[sub] = r
+				}
+				ops(sub, atrs)
+			}
+			if err := visit(os.Subgraphs()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(out.Subgraphs()); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
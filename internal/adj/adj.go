@@ -0,0 +1,256 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package adj indexes the nodes and edges of a builder.Graph so that graph
+// algorithms can work with dense integer ids instead of pointers.
+package adj
+
+import "sort"
+
+import "godot/attr"
+import "godot/builder"
+
+// An adjacency index over a graph.  Node ids are positions in Nodes, which is
+// in the order the nodes were added to the graph.  Edge ids are positions in
+// Edges.  Edges with a nil endpoint are not indexed.
+type Index struct {
+	Nodes    []*builder.Node
+	Edges    []*builder.Edge
+	Directed bool
+
+	// Src and Dst hold the endpoint node ids of each edge.
+	Src []int
+	Dst []int
+
+	// Out and In hold the ids of the edges leaving and entering each node, in
+	// the order the edges were added.  The direction is that of Src to Dst,
+	// even for undirected graphs.
+	Out [][]int
+	In  [][]int
+
+	ids map[*builder.Node]int
+}
+
+// Indexes the current state of "g".  Later changes to the graph are not
+// reflected in the index.
+func New(g *builder.Graph) *Index {
+	nodes := g.Nodes()
+	ix := &Index{
+		Nodes:    nodes,
+		Directed: g.Kind() == attr.Directed,
+		Out:      make([][]int, len(nodes)),
+		In:       make([][]int, len(nodes)),
+		ids:      make(map[*builder.Node]int, len(nodes)),
+	}
+	for i, n := range nodes {
+		ix.ids[n] = i
+	}
+	for _, e := range g.Edges() {
+		// AddEdges puts a nil endpoint among the graph's nodes, so edges
+		// with one are skipped here rather than by the lookups below.
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		src, ok1 := ix.ids[e.Src]
+		dst, ok2 := ix.ids[e.Dst]
+		if !ok1 || !ok2 {
+			continue
+		}
+		id := len(ix.Edges)
+		ix.Edges = append(ix.Edges, e)
+		ix.Src = append(ix.Src, src)
+		ix.Dst = append(ix.Dst, dst)
+		ix.Out[src] = append(ix.Out[src], id)
+		ix.In[dst] = append(ix.In[dst], id)
+	}
+	return ix
+}
+
+// Returns the id of "n", and false if the node is not in the index.
+func (ix *Index) ID(n *builder.Node) (int, bool) {
+	id, ok := ix.ids[n]
+	return id, ok
+}
+
+// Returns the number of indexed nodes.
+func (ix *Index) Len() int {
+	return len(ix.Nodes)
+}
+
+// Returns the endpoint of edge "e" opposite to node "n".
+func (ix *Index) Other(e, n int) int {
+	if ix.Src[e] == n {
+		return ix.Dst[e]
+	}
+	return ix.Src[e]
+}
+
+// Returns the ids of the nodes reachable from "n" over a single edge,
+// following edge direction in directed graphs.  A node appears once per edge
+// connecting it to "n".
+func (ix *Index) Succ(n int) []int {
+	succ := make([]int, 0, len(ix.Out[n]))
+	for _, e := range ix.Out[n] {
+		succ = append(succ, ix.Dst[e])
+	}
+	if !ix.Directed {
+		for _, e := range ix.In[n] {
+			if ix.Src[e] != ix.Dst[e] {
+				succ = append(succ, ix.Src[e])
+			}
+		}
+	}
+	return succ
+}
+
+// Returns the ids of the nodes from which "n" is reachable over a single
+// edge.  For undirected graphs this is the same as Succ.
+func (ix *Index) Pred(n int) []int {
+	if !ix.Directed {
+		return ix.Succ(n)
+	}
+	pred := make([]int, 0, len(ix.In[n]))
+	for _, e := range ix.In[n] {
+		pred = append(pred, ix.Src[e])
+	}
+	return pred
+}
+
+// Returns the distinct neighbours of "n", ignoring edge direction and
+// self-loops, in ascending id order.
+func (ix *Index) Neighbors(n int) []int {
+	seen := map[int]bool{n: true}
+	nbrs := make([]int, 0, len(ix.Out[n])+len(ix.In[n]))
+	add := func(m int) {
+		if !seen[m] {
+			seen[m] = true
+			nbrs = append(nbrs, m)
+		}
+	}
+	for _, e := range ix.Out[n] {
+		add(ix.Dst[e])
+	}
+	for _, e := range ix.In[n] {
+		add(ix.Src[e])
+	}
+	sort.Ints(nbrs)
+	return nbrs
+}
+
+// Computes the strongly connected components of a directed graph, following
+// edge direction, using Tarjan's algorithm.  Returns the component of each
+// node and the number of components.  Components are numbered in reverse
+// topological order: every edge between two components goes from a higher
+// numbered component to a lower numbered one.
+//
+// For undirected graphs the components are the connected components.
+func (ix *Index) StrongComponents() ([]int, int) {
+	n := len(ix.Nodes)
+	comp := make([]int, n)
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+
+	type frame struct {
+		node int
+		succ []int
+		next int
+	}
+
+	var stack []int
+	count, next := 0, 0
+	for root := 0; root < n; root++ {
+		if index[root] >= 0 {
+			continue
+		}
+		calls := []frame{{node: root, succ: ix.Succ(root)}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			if f.next < len(f.succ) {
+				w := f.succ[f.next]
+				f.next++
+				if index[w] < 0 {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					calls = append(calls, frame{node: w, succ: ix.Succ(w)})
+				} else if onStack[w] && index[w] < low[f.node] {
+					low[f.node] = index[w]
+				}
+				continue
+			}
+
+			v := f.node
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if p := calls[len(calls)-1].node; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] == index[v] {
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					comp[w] = count
+					if w == v {
+						break
+					}
+				}
+				count++
+			}
+		}
+	}
+	return comp, count
+}
+
+// Computes the connected components of the graph, ignoring edge direction.
+// Returns the component of each node and the number of components.
+// Components are numbered in order of their first node.
+func (ix *Index) Components() ([]int, int) {
+	comp := make([]int, len(ix.Nodes))
+	for i := range comp {
+		comp[i] = -1
+	}
+	count := 0
+	for root := range ix.Nodes {
+		if comp[root] >= 0 {
+			continue
+		}
+		comp[root] = count
+		queue := []int{root}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, w := range ix.Neighbors(v) {
+				if comp[w] < 0 {
+					comp[w] = count
+					queue = append(queue, w)
+				}
+			}
+		}
+		count++
+	}
+	return comp, count
+}
@@ -0,0 +1,156 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "errors"
+import "math"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Returned by BipartiteSets for graphs which are not bipartite.
+var ErrNotBipartite = errors.New("layout: graph is not bipartite")
+
+// Places the nodes evenly around a circle of radius Scale, centered on the
+// origin.  The first node is placed to the right of the center, and the
+// others follow anticlockwise.
+func Circular(nodes []*builder.Node, opts *Options) {
+	r := opts.scale()
+	for i, n := range nodes {
+		theta := 2 * math.Pi * float64(i) / float64(len(nodes))
+		opts.place(n, r*math.Cos(theta), r*math.Sin(theta))
+	}
+}
+
+// Places each shell of nodes evenly around its own circle, the circles
+// centered on the origin and Scale apart.  If the first shell has a single
+// node it is placed at the center.
+func Shells(shells [][]*builder.Node, opts *Options) {
+	step := opts.scale()
+	offset := 1
+	if len(shells) > 0 && len(shells[0]) == 1 {
+		offset = 0
+	}
+	for i, shell := range shells {
+		r := float64(i+offset) * step
+		for j, n := range shell {
+			theta := 2 * math.Pi * float64(j) / float64(len(shell))
+			opts.place(n, r*math.Cos(theta), r*math.Sin(theta))
+		}
+	}
+}
+
+// Places the nodes in a grid with "cols" columns, row by row from the top
+// left, Scale apart.  The grid is centered on the origin.  If "cols" is less
+// than 1 the grid is made as square as possible.
+func Grid(nodes []*builder.Node, cols int, opts *Options) {
+	if cols < 1 {
+		cols = int(math.Ceil(math.Sqrt(float64(len(nodes)))))
+	}
+	if cols < 1 {
+		return
+	}
+	rows := (len(nodes) + cols - 1) / cols
+	step := opts.scale()
+	if cols > len(nodes) {
+		cols = len(nodes)
+	}
+	width := float64(cols-1) * step
+	height := float64(rows-1) * step
+	for i, n := range nodes {
+		r, c := i/cols, i%cols
+		opts.place(n, float64(c)*step-width/2, height/2-float64(r)*step)
+	}
+}
+
+// Places the nodes along an Archimedean spiral centered on the origin,
+// starting at the center and winding anticlockwise.  Successive nodes, and
+// successive turns of the spiral, are Scale apart.
+func Spiral(nodes []*builder.Node, opts *Options) {
+	step := opts.scale()
+	b := step / (2 * math.Pi)
+	theta := 0.0
+	for _, n := range nodes {
+		r := b * theta
+		opts.place(n, r*math.Cos(theta), r*math.Sin(theta))
+		// Advance by roughly one step along the curve.
+		theta += step / math.Sqrt(r*r+b*b)
+	}
+}
+
+// Places each column of nodes top to bottom, Scale apart, with the columns
+// 2*Scale apart.  Each column is centered vertically, and the arrangement is
+// centered on the origin.
+func Columns(columns [][]*builder.Node, opts *Options) {
+	step := opts.scale()
+	width := float64(len(columns)-1) * 2 * step
+	for i, col := range columns {
+		x := float64(i)*2*step - width/2
+		height := float64(len(col)-1) * step
+		for j, n := range col {
+			opts.place(n, x, height/2-float64(j)*step)
+		}
+	}
+}
+
+// Places two sets of nodes in two columns.  See Columns.
+func Bipartite(left, right []*builder.Node, opts *Options) {
+	Columns([][]*builder.Node{left, right}, opts)
+}
+
+// Splits the nodes of a bipartite graph into two sets, such that every edge
+// joins a node in one set to a node in the other.  In each connected
+// component the first node added to the graph goes in the left set.  Nodes
+// keep the order they were added to the graph.  Returns ErrNotBipartite if
+// there is no such split.
+func BipartiteSets(g *builder.Graph) ([]*builder.Node, []*builder.Node, error) {
+	ix := adj.New(g)
+	side := make([]int, ix.Len())
+	for v := range side {
+		side[v] = -1
+	}
+	for root := range ix.Nodes {
+		if side[root] >= 0 {
+			continue
+		}
+		side[root] = 0
+		queue := []int{root}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, e := range append(append([]int(nil), ix.Out[v]...), ix.In[v]...) {
+				w := ix.Other(e, v)
+				switch side[w] {
+				case -1:
+					side[w] = 1 - side[v]
+					queue = append(queue, w)
+				case side[v]:
+					return nil, nil, ErrNotBipartite
+				}
+			}
+		}
+	}
+
+	var left, right []*builder.Node
+	for v, n := range ix.Nodes {
+		if side[v] == 0 {
+			left = append(left, n)
+		} else {
+			right = append(right, n)
+		}
+	}
+	return left, right, nil
+}
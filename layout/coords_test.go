@@ -0,0 +1,91 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func at(n *builder.Node, x, y float64) bool {
+	return n.Position != nil &&
+		math.Abs(float64(n.Position.X)-x) < 1e-4 &&
+		math.Abs(float64(n.Position.Y)-y) < 1e-4
+}
+
+func TestCircular(t *testing.T) {
+	nodes := builder.GenNodes(4)
+	Circular(nodes, &Options{Scale: 2, Rotation: 90, Lock: true})
+
+	if !at(nodes[0], 0, 2) || !at(nodes[1], -2, 0) {
+		t.Errorf("Positions are incorrect: %v, %v.", nodes[0].Position, nodes[1].Position)
+	}
+	if !nodes[0].Position.Lock {
+		t.Errorf("Position should be locked.")
+	}
+}
+
+func TestShells(t *testing.T) {
+	nodes := builder.GenNodes(3)
+	Shells([][]*builder.Node{nodes[:1], nodes[1:]}, nil)
+
+	if !at(nodes[0], 0, 0) || !at(nodes[1], 1, 0) || !at(nodes[2], -1, 0) {
+		t.Errorf("Positions are incorrect.")
+	}
+}
+
+func TestGrid(t *testing.T) {
+	nodes := builder.GenNodes(6)
+	Grid(nodes, 3, nil)
+
+	if !at(nodes[0], -1, 0.5) || !at(nodes[5], 1, -0.5) {
+		t.Errorf("Positions are incorrect: %v, %v.", nodes[0].Position, nodes[5].Position)
+	}
+}
+
+func TestSpiral(t *testing.T) {
+	nodes := builder.GenNodes(50)
+	Spiral(nodes, nil)
+
+	for i := 1; i < len(nodes); i++ {
+		a, b := nodes[i-1].Position, nodes[i].Position
+		d := math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+		if d < 0.5 || d > 1.5 {
+			t.Errorf("Nodes %d and %d are %v apart.", i-1, i, d)
+		}
+	}
+}
+
+func TestBipartite(t *testing.T) {
+	g := builder.GenCompleteBipartite(2, 3, nil)
+	left, right, err := BipartiteSets(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || len(right) != 3 {
+		t.Fatalf("Sets are incorrect: %d and %d nodes.", len(left), len(right))
+	}
+
+	Bipartite(left, right, nil)
+	if !at(left[0], -1, 0.5) || !at(right[0], 1, 1) {
+		t.Errorf("Positions are incorrect: %v, %v.", left[0].Position, right[0].Position)
+	}
+
+	if _, _, err := BipartiteSets(builder.GenCycle(3, &builder.GenOptions{Kind: attr.Directed})); err != ErrNotBipartite {
+		t.Errorf("Expected ErrNotBipartite, got %v.", err)
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+import "math/rand"
+import "strconv"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Options for the force-directed layouts.
+type ForceOptions struct {
+	// Seed for the random initial positions of nodes without a position.
+	// Layouts with the same seed are the same.
+	Seed int64
+
+	// The number of iterations.  Defaults to 300 for FruchtermanReingold and
+	// 200 for Stress.
+	Iterations int
+
+	// The Barnes-Hut opening angle used by FruchtermanReingold: a group of
+	// nodes whose extent seen from a node is less than Theta is treated as
+	// one.  Larger values are faster and less accurate.  Defaults to 1;
+	// negative values compute every pair of nodes exactly.
+	Theta float64
+}
+
+func (o *ForceOptions) iterations(def int) int {
+	if o == nil || o.Iterations <= 0 {
+		return def
+	}
+	return o.Iterations
+}
+
+func (o *ForceOptions) theta() float64 {
+	if o == nil || o.Theta == 0 {
+		return 1
+	}
+	return o.Theta
+}
+
+// The default ideal edge length, in points, as in neato.
+const defaultLen = 1.0 * PointsPerInch
+
+// Working state of a force-directed layout.
+type forces struct {
+	ix    *adj.Index
+	x, y  []float64
+	fixed []bool
+	ideal []float64 // ideal length of each edge, in points
+}
+
+// Sets up a force-directed layout of "g".  Nodes with a Position start
+// there, and keep it if it is locked; the rest are scattered at random.
+func newForces(g *builder.Graph, opts *ForceOptions) *forces {
+	ix := adj.New(g)
+	n := ix.Len()
+	f := &forces{
+		ix:    ix,
+		x:     make([]float64, n),
+		y:     make([]float64, n),
+		fixed: make([]bool, n),
+		ideal: make([]float64, len(ix.Edges)),
+	}
+	total := 0.0
+	for e, edge := range ix.Edges {
+		f.ideal[e] = defaultLen
+		if v, err := strconv.ParseFloat(g.ResolveEdge(edge).Length, 64); err == nil && v > 0 {
+			f.ideal[e] = v * PointsPerInch
+		}
+		total += f.ideal[e]
+	}
+	spread := defaultLen
+	if len(ix.Edges) > 0 {
+		spread = total / float64(len(ix.Edges))
+	}
+	spread *= math.Sqrt(float64(n))
+
+	var seed int64
+	if opts != nil {
+		seed = opts.Seed
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for v, node := range ix.Nodes {
+		x, y := rng.Float64()*spread, rng.Float64()*spread
+		if p := node.Position; p != nil {
+			x, y = float64(p.X)*PointsPerInch, float64(p.Y)*PointsPerInch
+			f.fixed[v] = p.Lock
+		}
+		f.x[v], f.y[v] = x, y
+	}
+	return f
+}
+
+// Returns the layout of "g" at the current positions.
+func (f *forces) layout(g *builder.Graph) *Layout {
+	l := newLayout()
+	for v, n := range f.ix.Nodes {
+		w, h := NodeSize(g.ResolveNode(n))
+		l.Nodes[n] = &NodeLayout{Center: Point{f.x[v], f.y[v]}, Width: w, Height: h}
+	}
+	l.routeStraight(g)
+	l.boxClusters(g)
+	l.computeBounds()
+	return l
+}
+
+// Lays out a graph with the force-directed method of Fruchterman and
+// Reingold: edges pull their ends together like springs of their ideal
+// length, Edge.Length inches or 1 inch by default, and all nodes push each
+// other apart, while the distance a node may move shrinks as the layout
+// cools.  Repulsion is approximated with a Barnes-Hut quadtree, so that
+// graphs of tens of thousands of nodes are laid out in seconds.
+//
+// Nodes with a locked Position stay there, and other nodes with a Position
+// start there.  Edge directions are ignored.  The layout is in points, with
+// the same origin as Node.Position.
+func FruchtermanReingold(g *builder.Graph, opts *ForceOptions) *Layout {
+	f := newForces(g, opts)
+	ix := f.ix
+	n := ix.Len()
+	if n == 0 {
+		return f.layout(g)
+	}
+
+	k := defaultLen
+	if len(ix.Edges) > 0 {
+		k = 0
+		for _, d := range f.ideal {
+			k += d
+		}
+		k /= float64(len(ix.Edges))
+	}
+	iterations := opts.iterations(300)
+	theta := opts.theta()
+	start := k * math.Sqrt(float64(n)) / 2
+	dx, dy := make([]float64, n), make([]float64, n)
+	var tree quadtree
+
+	for iter := 0; iter < iterations; iter++ {
+		for v := range dx {
+			dx[v], dy[v] = 0, 0
+		}
+
+		// Repulsion, k²/d between every pair of nodes.
+		if theta < 0 || n <= 64 {
+			for v := 0; v < n; v++ {
+				for w := v + 1; w < n; w++ {
+					ddx, ddy := f.x[v]-f.x[w], f.y[v]-f.y[w]
+					d2 := ddx*ddx + ddy*ddy
+					if d2 < 1e-4 {
+						ddx, ddy, d2 = 0.01*float64(v-w), 0.01, 1e-4
+					}
+					s := k * k / d2
+					dx[v] += ddx * s
+					dy[v] += ddy * s
+					dx[w] -= ddx * s
+					dy[w] -= ddy * s
+				}
+			}
+		} else {
+			tree.build(f.x, f.y)
+			for v := 0; v < n; v++ {
+				fx, fy := tree.repulsion(v, f.x[v], f.y[v], k*k, theta)
+				dx[v] += fx
+				dy[v] += fy
+			}
+		}
+
+		// Attraction, d²/k along each edge.
+		for e := range ix.Edges {
+			s, t := ix.Src[e], ix.Dst[e]
+			if s == t {
+				continue
+			}
+			ddx, ddy := f.x[s]-f.x[t], f.y[s]-f.y[t]
+			d := math.Hypot(ddx, ddy)
+			a := d / f.ideal[e]
+			dx[s] -= ddx * a
+			dy[s] -= ddy * a
+			dx[t] += ddx * a
+			dy[t] += ddy * a
+		}
+
+		// Move each node by at most the temperature, which falls linearly.
+		temp := start * (1 - float64(iter)/float64(iterations))
+		for v := 0; v < n; v++ {
+			if f.fixed[v] {
+				continue
+			}
+			d := math.Hypot(dx[v], dy[v])
+			if d == 0 {
+				continue
+			}
+			step := math.Min(d, temp) / d
+			f.x[v] += dx[v] * step
+			f.y[v] += dy[v] * step
+		}
+	}
+	return f.layout(g)
+}
+
+// Lays out a graph by stress majorization, as neato does: the layout
+// minimizes the Kamada-Kawai energy, the sum over all pairs of nodes of the
+// squared difference between their distance in the layout and their
+// shortest path distance in the graph, weighted by the inverse square of the
+// latter.  Edges have length Edge.Length inches, or 1 inch by default, and
+// nodes in different components are kept a little further apart than the
+// longest path.
+//
+// Nodes with a locked Position stay there, and other nodes with a Position
+// start there.  Edge directions are ignored.  The layout is in points, with
+// the same origin as Node.Position.  The time and memory taken grow with the
+// square of the number of nodes.
+func Stress(g *builder.Graph, opts *ForceOptions) *Layout {
+	f := newForces(g, opts)
+	ix := f.ix
+	n := ix.Len()
+	dist := f.distances()
+
+	iterations := opts.iterations(200)
+	for iter := 0; iter < iterations; iter++ {
+		moved := 0.0
+		for v := 0; v < n; v++ {
+			if f.fixed[v] {
+				continue
+			}
+			sx, sy, sw := 0.0, 0.0, 0.0
+			for w := 0; w < n; w++ {
+				d := dist[v][w]
+				if w == v || d == 0 {
+					continue
+				}
+				weight := 1 / (d * d)
+				ddx, ddy := f.x[v]-f.x[w], f.y[v]-f.y[w]
+				norm := math.Hypot(ddx, ddy)
+				if norm > 0 {
+					ddx, ddy = ddx*d/norm, ddy*d/norm
+				}
+				sx += weight * (f.x[w] + ddx)
+				sy += weight * (f.y[w] + ddy)
+				sw += weight
+			}
+			if sw == 0 {
+				continue
+			}
+			x, y := sx/sw, sy/sw
+			moved = math.Max(moved, math.Hypot(x-f.x[v], y-f.y[v]))
+			f.x[v], f.y[v] = x, y
+		}
+		if moved < 0.01 {
+			break
+		}
+	}
+	return f.layout(g)
+}
+
+// Returns the shortest path distances between all pairs of nodes, ignoring
+// edge directions.  Unconnected pairs are given the longest distance found
+// plus the mean edge length.
+func (f *forces) distances() [][]float64 {
+	ix := f.ix
+	n := ix.Len()
+	edges := make([][]int, n)
+	for e := range ix.Edges {
+		s, t := ix.Src[e], ix.Dst[e]
+		if s != t {
+			edges[s] = append(edges[s], e)
+			edges[t] = append(edges[t], e)
+		}
+	}
+
+	longest, mean := 0.0, defaultLen
+	if len(ix.Edges) > 0 {
+		mean = 0
+		for _, d := range f.ideal {
+			mean += d
+		}
+		mean /= float64(len(ix.Edges))
+	}
+	dist := make([][]float64, n)
+	for src := 0; src < n; src++ {
+		d := make([]float64, n)
+		for i := range d {
+			d[i] = math.Inf(1)
+		}
+		d[src] = 0
+		h := &distHeap{dist: d}
+		h.push(src)
+		for len(h.items) > 0 {
+			v := h.pop()
+			for _, e := range edges[v] {
+				w := ix.Other(e, v)
+				if nd := d[v] + f.ideal[e]; nd < d[w] {
+					d[w] = nd
+					h.push(w)
+				}
+			}
+		}
+		for _, x := range d {
+			if !math.IsInf(x, 1) && x > longest {
+				longest = x
+			}
+		}
+		dist[src] = d
+	}
+	for _, d := range dist {
+		for i := range d {
+			if math.IsInf(d[i], 1) {
+				d[i] = longest + mean
+			}
+		}
+	}
+	return dist
+}
+
+// A binary min-heap of nodes keyed by distance, allowing duplicates; stale
+// entries are skipped by the caller's relaxation test.
+type distHeap struct {
+	dist  []float64
+	items []int
+}
+
+func (h *distHeap) push(v int) {
+	h.items = append(h.items, v)
+	for i := len(h.items) - 1; i > 0; {
+		p := (i - 1) / 2
+		if h.dist[h.items[p]] <= h.dist[h.items[i]] {
+			break
+		}
+		h.items[p], h.items[i] = h.items[i], h.items[p]
+		i = p
+	}
+}
+
+func (h *distHeap) pop() int {
+	top := h.items[0]
+	last := len(h.items) - 1
+	h.items[0] = h.items[last]
+	h.items = h.items[:last]
+	for i := 0; ; {
+		c := 2*i + 1
+		if c >= last {
+			break
+		}
+		if c+1 < last && h.dist[h.items[c+1]] < h.dist[h.items[c]] {
+			c++
+		}
+		if h.dist[h.items[i]] <= h.dist[h.items[c]] {
+			break
+		}
+		h.items[i], h.items[c] = h.items[c], h.items[i]
+		i = c
+	}
+	return top
+}
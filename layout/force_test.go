@@ -0,0 +1,81 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func length(l *Layout, e *builder.Edge) float64 {
+	a, b := l.Nodes[e.Src].Center, l.Nodes[e.Dst].Center
+	return math.Hypot(a.X-b.X, a.Y-b.Y) / PointsPerInch
+}
+
+func TestFruchtermanReingold(t *testing.T) {
+	g := builder.GenBarabasiAlbert(300, 2, 1, nil)
+	pinned := g.Nodes()[0]
+	pinned.Position = &attr.Point{X: 1, Y: 2, Lock: true}
+
+	l := FruchtermanReingold(g, &ForceOptions{Seed: 7, Iterations: 50})
+	if c := l.Nodes[pinned].Center; c.X != 72 || c.Y != 144 {
+		t.Errorf("Locked node moved to %v.", c)
+	}
+	for n, nl := range l.Nodes {
+		if math.IsNaN(nl.Center.X) || math.IsNaN(nl.Center.Y) {
+			t.Fatalf("Node %s has no position.", n.Label)
+		}
+	}
+
+	again := FruchtermanReingold(g, &ForceOptions{Seed: 7, Iterations: 50})
+	for n, nl := range l.Nodes {
+		if again.Nodes[n].Center != nl.Center {
+			t.Fatalf("Layouts with the same seed differ.")
+		}
+	}
+}
+
+func TestFruchtermanReingoldExact(t *testing.T) {
+	g := builder.GenCycle(100, nil)
+	approx := FruchtermanReingold(g, nil)
+	exact := FruchtermanReingold(g, &ForceOptions{Theta: -1})
+
+	wa, ha := approx.Bounds.Size()
+	we, he := exact.Bounds.Size()
+	if math.Abs(wa*ha-we*he) > 0.25*we*he {
+		t.Errorf("Approximate layout is %vx%v, exact is %vx%v.", wa, ha, we, he)
+	}
+}
+
+func TestStress(t *testing.T) {
+	g := builder.GenPath(5, nil)
+	g.Edges()[0].Length = "2"
+	l := Stress(g, nil)
+
+	if d := length(l, g.Edges()[0]); math.Abs(d-2) > 0.05 {
+		t.Errorf("First edge is %v inches long.", d)
+	}
+	for _, e := range g.Edges()[1:] {
+		if d := length(l, e); math.Abs(d-1) > 0.05 {
+			t.Errorf("Edge is %v inches long.", d)
+		}
+	}
+	ends := length(l, &builder.Edge{Src: g.Nodes()[0], Dst: g.Nodes()[4]})
+	if math.Abs(ends-5) > 0.1 {
+		t.Errorf("Path is not straight: ends are %v inches apart.", ends)
+	}
+}
@@ -0,0 +1,646 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+import "sort"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+import "godot/builder"
+import "godot/internal/adj"
+
+// Space left between a cluster's box and its contents, in points.
+const clusterMargin = 8
+
+// The number of barycenter sweeps made when ordering ranks.
+const orderIterations = 24
+
+// The number of passes made when assigning coordinates within ranks.
+const coordIterations = 16
+
+// Working state of a layered layout.  Vertices are the nodes of the graph,
+// numbered as in the index, followed by the dummy vertices inserted where
+// edges cross ranks.
+type layered struct {
+	ix      *adj.Index
+	nodes   []*builder.Node // resolved against the node template
+	nodesep float64
+	ranksep float64
+
+	rank    []int
+	breadth []float64 // size along the rank
+	depth   []float64 // size across the ranks
+	cluster [][]int   // path of cluster ids from the outermost, per vertex
+
+	// For each edge, the vertices it passes through from tail to head, and
+	// whether the edge was reversed to break a cycle.  Nil for self-loops.
+	chains   [][]int
+	reversed []bool
+
+	up, down [][]int // neighbours in the ranks above and below
+	weight   map[[2]int]float64
+
+	ranks [][]int   // vertices of each rank, in order
+	pos   []int     // position of each vertex within its rank
+	x     []float64 // coordinate along the rank
+	y     []float64 // coordinate across the ranks
+}
+
+// Lays out a graph in layers, in the manner of dot, using the Sugiyama
+// method: cycles are broken by reversing edges, nodes are assigned to ranks
+// by longest path, ranks are ordered to reduce edge crossings by barycenter
+// sweeps, and coordinates are assigned to straighten edges while keeping
+// nodes apart.  Edges are routed through the ranks they cross and smoothed
+// into splines.
+//
+// The graph's Rankdir, Nodesep and Ranksep, and each edge's Minlen, are
+// honored.  Nodes of a cluster subgraph, one whose name begins with
+// "cluster", are kept together within each rank, and the cluster's box is
+// returned in the layout.  Undirected graphs are laid out as though each edge
+// led from Src to Dst.
+func Layered(g *builder.Graph) *Layout {
+	ix := adj.New(g)
+	l := &layered{
+		ix:      ix,
+		nodesep: inches(g.Nodesep, DefaultNodesep) * PointsPerInch,
+		ranksep: inches(g.Ranksep, DefaultRanksep) * PointsPerInch,
+		weight:  make(map[[2]int]float64),
+	}
+	rankdir := g.Rankdir
+	if rankdir == nil {
+		rankdir = attr.TopToBottom
+	}
+	sideways := rankdir == attr.LeftToRight || rankdir == attr.RightToLeft
+
+	for _, n := range ix.Nodes {
+		rn := g.ResolveNode(n)
+		w, h := NodeSize(rn)
+		if sideways {
+			w, h = h, w
+		}
+		l.nodes = append(l.nodes, rn)
+		l.breadth = append(l.breadth, w)
+		l.depth = append(l.depth, h)
+	}
+
+	l.assignClusters(g)
+	l.breakCycles()
+	l.assignRanks(g)
+	l.insertDummies()
+	l.order()
+	l.assignCoordinates()
+
+	result := newLayout()
+	transform := func(x, y float64) Point {
+		switch rankdir {
+		case attr.BottomToTop:
+			return Point{x, y}
+		case attr.LeftToRight:
+			return Point{y, -x}
+		case attr.RightToLeft:
+			return Point{-y, -x}
+		}
+		return Point{x, -y}
+	}
+
+	for v, n := range ix.Nodes {
+		w, h := l.breadth[v], l.depth[v]
+		if sideways {
+			w, h = h, w
+		}
+		result.Nodes[n] = &NodeLayout{Center: transform(l.x[v], l.y[v]), Width: w, Height: h}
+	}
+	for e, edge := range ix.Edges {
+		var points []Point
+		if l.chains[e] == nil {
+			points = selfLoop(result.Nodes[edge.Src])
+		} else {
+			for _, v := range l.chains[e] {
+				points = append(points, transform(l.x[v], l.y[v]))
+			}
+			if l.reversed[e] {
+				for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+					points[i], points[j] = points[j], points[i]
+				}
+			}
+			src, dst := edge.Src, edge.Dst
+			points[0] = clip(result.Nodes[src], l.nodes[ix.Src[e]].Shape, points[1])
+			points[len(points)-1] = clip(result.Nodes[dst], l.nodes[ix.Dst[e]].Shape, points[len(points)-2])
+		}
+		result.Edges[edge] = route(points, g.ResolveEdge(edge).Label != "")
+	}
+	result.boxClusters(g)
+
+	result.normalize()
+	return result
+}
+
+// Finds the cluster subgraphs, and records the path of clusters containing
+// each node.
+func (l *layered) assignClusters(g *builder.Graph) {
+	l.cluster = make([][]int, l.ix.Len())
+	var clusters []*builder.Subgraph
+	var visit func(subs []*builder.Subgraph, path []int)
+	visit = func(subs []*builder.Subgraph, path []int) {
+		for _, sub := range subs {
+			p := path
+			if strings.HasPrefix(sub.Name, "cluster") {
+				p = append(append([]int(nil), path...), len(clusters))
+				clusters = append(clusters, sub)
+			}
+			for _, n := range sub.Nodes() {
+				if v, ok := l.ix.ID(n); ok && len(p) > len(l.cluster[v]) {
+					l.cluster[v] = p
+				}
+			}
+			visit(sub.Subgraphs(), p)
+		}
+	}
+	visit(g.Subgraphs(), nil)
+}
+
+// Marks edges which close a cycle, found by depth first search, to be
+// reversed.
+func (l *layered) breakCycles() {
+	ix := l.ix
+	l.reversed = make([]bool, len(ix.Edges))
+	state := make([]int, ix.Len())
+	type frame struct{ node, next int }
+	for root := range ix.Nodes {
+		if state[root] != 0 {
+			continue
+		}
+		state[root] = 1
+		stack := []frame{{node: root}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.next == len(ix.Out[f.node]) {
+				state[f.node] = 2
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			e := ix.Out[f.node][f.next]
+			f.next++
+			switch w := ix.Dst[e]; state[w] {
+			case 0:
+				state[w] = 1
+				stack = append(stack, frame{node: w})
+			case 1:
+				l.reversed[e] = w != f.node
+			}
+		}
+	}
+}
+
+// Returns the tail and head of edge "e" after cycle breaking.
+func (l *layered) ends(e int) (int, int) {
+	if l.reversed[e] {
+		return l.ix.Dst[e], l.ix.Src[e]
+	}
+	return l.ix.Src[e], l.ix.Dst[e]
+}
+
+// Assigns each node the lowest rank allowed by the minimum lengths of the
+// edges into it, then moves sources down towards their successors.
+func (l *layered) assignRanks(g *builder.Graph) {
+	ix := l.ix
+	n := ix.Len()
+	minlen := make([]int, len(ix.Edges))
+	indeg := make([]int, n)
+	out := make([][]int, n)
+	in := make([][]int, n)
+	for e, edge := range ix.Edges {
+		t, h := l.ends(e)
+		if t == h {
+			continue
+		}
+		minlen[e] = 1
+		if m, err := strconv.Atoi(g.ResolveEdge(edge).Minlen); err == nil && m >= 0 {
+			minlen[e] = m
+		}
+		out[t] = append(out[t], e)
+		in[h] = append(in[h], e)
+		indeg[h]++
+	}
+
+	var topo []int
+	for v := 0; v < n; v++ {
+		if indeg[v] == 0 {
+			topo = append(topo, v)
+		}
+	}
+	for i := 0; i < len(topo); i++ {
+		for _, e := range out[topo[i]] {
+			_, h := l.ends(e)
+			if indeg[h]--; indeg[h] == 0 {
+				topo = append(topo, h)
+			}
+		}
+	}
+
+	l.rank = make([]int, n)
+	for _, v := range topo {
+		for _, e := range in[v] {
+			t, _ := l.ends(e)
+			if r := l.rank[t] + minlen[e]; r > l.rank[v] {
+				l.rank[v] = r
+			}
+		}
+	}
+	for i := len(topo) - 1; i >= 0; i-- {
+		v := topo[i]
+		if len(in[v]) > 0 || len(out[v]) == 0 {
+			continue
+		}
+		lowest := math.MaxInt32
+		for _, e := range out[v] {
+			_, h := l.ends(e)
+			if r := l.rank[h] - minlen[e]; r < lowest {
+				lowest = r
+			}
+		}
+		l.rank[v] = lowest
+	}
+
+	lowest := 0
+	for v := range l.rank {
+		if v == 0 || l.rank[v] < lowest {
+			lowest = l.rank[v]
+		}
+	}
+	for v := range l.rank {
+		l.rank[v] -= lowest
+	}
+}
+
+// Replaces each edge crossing several ranks with a chain of dummy vertices,
+// one per rank crossed.
+func (l *layered) insertDummies() {
+	ix := l.ix
+	l.chains = make([][]int, len(ix.Edges))
+	l.up = make([][]int, ix.Len())
+	l.down = make([][]int, ix.Len())
+	link := func(a, b int, w float64) {
+		l.down[a] = append(l.down[a], b)
+		l.up[b] = append(l.up[b], a)
+		l.weight[[2]int{a, b}] += w
+	}
+
+	for e := range ix.Edges {
+		t, h := l.ends(e)
+		if t == h {
+			continue
+		}
+		chain := []int{t}
+		path := commonPrefix(l.cluster[t], l.cluster[h])
+		for r := l.rank[t] + 1; r < l.rank[h]; r++ {
+			d := len(l.rank)
+			l.rank = append(l.rank, r)
+			l.breadth = append(l.breadth, 0)
+			l.depth = append(l.depth, 0)
+			l.cluster = append(l.cluster, path)
+			l.up = append(l.up, nil)
+			l.down = append(l.down, nil)
+			chain = append(chain, d)
+		}
+		chain = append(chain, h)
+		for i := 1; i < len(chain); i++ {
+			a, b := chain[i-1], chain[i]
+			if l.rank[a] == l.rank[b] {
+				continue
+			}
+			w := 1.0
+			switch {
+			case a >= ix.Len() && b >= ix.Len():
+				w = 8
+			case a >= ix.Len() || b >= ix.Len():
+				w = 2
+			}
+			link(a, b, w)
+		}
+		l.chains[e] = chain
+	}
+}
+
+func commonPrefix(a, b []int) []int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// Orders the vertices of each rank to reduce crossings.
+func (l *layered) order() {
+	top := 0
+	for _, r := range l.rank {
+		if r > top {
+			top = r
+		}
+	}
+	l.ranks = make([][]int, top+1)
+	if len(l.rank) == 0 {
+		l.ranks = nil
+	}
+
+	// Initial order: depth first from each node, so that connected vertices
+	// start out close together.
+	seen := make([]bool, len(l.rank))
+	var visit func(v int)
+	visit = func(v int) {
+		seen[v] = true
+		l.ranks[l.rank[v]] = append(l.ranks[l.rank[v]], v)
+		for _, w := range l.down[v] {
+			if !seen[w] {
+				visit(w)
+			}
+		}
+	}
+	for v := range l.rank {
+		if !seen[v] && (v >= l.ix.Len() || len(l.up[v]) == 0) {
+			visit(v)
+		}
+	}
+	for v := range l.rank {
+		if !seen[v] {
+			visit(v)
+		}
+	}
+
+	l.pos = make([]int, len(l.rank))
+	l.updatePositions()
+	l.groupClusters()
+	l.updatePositions()
+
+	best := l.copyRanks()
+	bestCrossings := l.crossings()
+	for iter := 0; iter < orderIterations && bestCrossings > 0; iter++ {
+		if iter%2 == 0 {
+			for r := 1; r < len(l.ranks); r++ {
+				l.sortRank(r, l.up)
+			}
+		} else {
+			for r := len(l.ranks) - 2; r >= 0; r-- {
+				l.sortRank(r, l.down)
+			}
+		}
+		if c := l.crossings(); c < bestCrossings {
+			best, bestCrossings = l.copyRanks(), c
+		}
+	}
+	l.ranks = best
+	l.updatePositions()
+}
+
+func (l *layered) updatePositions() {
+	for _, rank := range l.ranks {
+		for i, v := range rank {
+			l.pos[v] = i
+		}
+	}
+}
+
+func (l *layered) copyRanks() [][]int {
+	ranks := make([][]int, len(l.ranks))
+	for i, rank := range l.ranks {
+		ranks[i] = append([]int(nil), rank...)
+	}
+	return ranks
+}
+
+// Sorts rank "r" by the barycenter of each vertex's neighbours in "adj",
+// keeping the members of each cluster together.
+func (l *layered) sortRank(r int, adj [][]int) {
+	rank := l.ranks[r]
+	bary := make(map[int]float64, len(rank))
+	for _, v := range rank {
+		if len(adj[v]) == 0 {
+			bary[v] = float64(l.pos[v])
+			continue
+		}
+		sum := 0.0
+		for _, w := range adj[v] {
+			sum += float64(l.pos[w])
+		}
+		bary[v] = sum / float64(len(adj[v]))
+	}
+	l.sortGroup(rank, bary, 0)
+	for i, v := range rank {
+		l.pos[v] = i
+	}
+}
+
+// Sorts "vertices" by barycenter, treating the members of each cluster at
+// the given nesting depth as a block placed at their mean barycenter, and
+// sorting within each block recursively.
+func (l *layered) sortGroup(vertices []int, bary map[int]float64, depth int) {
+	type block struct {
+		members []int
+		key     float64
+		first   int
+	}
+	var blocks []*block
+	byCluster := make(map[int]*block)
+	for i, v := range vertices {
+		if depth < len(l.cluster[v]) {
+			c := l.cluster[v][depth]
+			if b, ok := byCluster[c]; ok {
+				b.members = append(b.members, v)
+				continue
+			}
+			b := &block{members: []int{v}, first: i}
+			byCluster[c] = b
+			blocks = append(blocks, b)
+			continue
+		}
+		blocks = append(blocks, &block{members: []int{v}, first: i})
+	}
+	for _, b := range blocks {
+		sum := 0.0
+		for _, v := range b.members {
+			sum += bary[v]
+		}
+		b.key = sum / float64(len(b.members))
+		if len(b.members) > 1 {
+			l.sortGroup(b.members, bary, depth+1)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].key != blocks[j].key {
+			return blocks[i].key < blocks[j].key
+		}
+		return blocks[i].first < blocks[j].first
+	})
+
+	i := 0
+	for _, b := range blocks {
+		i += copy(vertices[i:], b.members)
+	}
+}
+
+// Makes the members of each cluster contiguous in the initial order.
+func (l *layered) groupClusters() {
+	for _, rank := range l.ranks {
+		bary := make(map[int]float64, len(rank))
+		for _, v := range rank {
+			bary[v] = float64(l.pos[v])
+		}
+		l.sortGroup(rank, bary, 0)
+	}
+}
+
+// Counts the edge crossings between adjacent ranks.
+func (l *layered) crossings() int {
+	total := 0
+	for r := 0; r+1 < len(l.ranks); r++ {
+		type segment struct{ a, b int }
+		var segs []segment
+		for _, v := range l.ranks[r] {
+			for _, w := range l.down[v] {
+				segs = append(segs, segment{l.pos[v], l.pos[w]})
+			}
+		}
+		sort.Slice(segs, func(i, j int) bool {
+			if segs[i].a != segs[j].a {
+				return segs[i].a < segs[j].a
+			}
+			return segs[i].b < segs[j].b
+		})
+
+		// Count inversions of the lower positions with a Fenwick tree.
+		tree := make([]int, len(l.ranks[r+1])+1)
+		for i, s := range segs {
+			below := 0
+			for j := s.b + 1; j > 0; j -= j & -j {
+				below += tree[j]
+			}
+			total += i - below
+			for j := s.b + 1; j < len(tree); j += j & -j {
+				tree[j]++
+			}
+		}
+	}
+	return total
+}
+
+// Returns the minimum distance between the centers of adjacent vertices
+// "a" and "b" in a rank.
+func (l *layered) separation(a, b int) float64 {
+	sep := (l.breadth[a]+l.breadth[b])/2 + l.nodesep
+	if a >= l.ix.Len() && b >= l.ix.Len() {
+		sep = l.nodesep / 2
+	}
+	common := len(commonPrefix(l.cluster[a], l.cluster[b]))
+	crossed := len(l.cluster[a]) + len(l.cluster[b]) - 2*common
+	return sep + float64(crossed)*clusterMargin
+}
+
+// Assigns coordinates.  Across the ranks, each rank is placed below the last
+// with Ranksep between them.  Along each rank, vertices are repeatedly moved
+// towards the weighted mean of their neighbours' positions, as far as the
+// minimum separations allow.
+func (l *layered) assignCoordinates() {
+	l.x = make([]float64, len(l.rank))
+	l.y = make([]float64, len(l.rank))
+
+	y := 0.0
+	for r, rank := range l.ranks {
+		deepest := 0.0
+		for _, v := range rank {
+			deepest = math.Max(deepest, l.depth[v])
+		}
+		if r > 0 {
+			prev := 0.0
+			for _, v := range l.ranks[r-1] {
+				prev = math.Max(prev, l.depth[v])
+			}
+			y += prev/2 + l.ranksep + deepest/2
+		}
+		for i, v := range rank {
+			l.y[v] = y
+			if i > 0 {
+				l.x[v] = l.x[rank[i-1]] + l.separation(rank[i-1], v)
+			}
+		}
+	}
+
+	for iter := 0; iter < coordIterations; iter++ {
+		for r := range l.ranks {
+			if iter%2 == 1 {
+				r = len(l.ranks) - 1 - r
+			}
+			l.placeRank(l.ranks[r])
+		}
+	}
+}
+
+// Moves the vertices of a rank as close as the separations allow to the
+// weighted mean of their neighbours' positions, minimizing the weighted sum
+// of squared distances by the pool adjacent violators algorithm.
+func (l *layered) placeRank(rank []int) {
+	if len(rank) == 0 {
+		return
+	}
+	n := len(rank)
+	offset := make([]float64, n)
+	for i := 1; i < n; i++ {
+		offset[i] = offset[i-1] + l.separation(rank[i-1], rank[i])
+	}
+
+	// With y = x - offset, the separation constraints become y
+	// non-decreasing.
+	type pool struct {
+		sum, weight float64
+		count       int
+	}
+	var pools []pool
+	for i, v := range rank {
+		target, weight := l.x[v], 0.0
+		sum := 0.0
+		for _, w := range l.up[v] {
+			sum += l.weight[[2]int{w, v}] * l.x[w]
+			weight += l.weight[[2]int{w, v}]
+		}
+		for _, w := range l.down[v] {
+			sum += l.weight[[2]int{v, w}] * l.x[w]
+			weight += l.weight[[2]int{v, w}]
+		}
+		if weight > 0 {
+			target = sum / weight
+		} else {
+			weight = 0.01
+		}
+		pools = append(pools, pool{weight * (target - offset[i]), weight, 1})
+		for len(pools) > 1 {
+			a, b := pools[len(pools)-2], pools[len(pools)-1]
+			if a.sum/a.weight <= b.sum/b.weight {
+				break
+			}
+			pools = pools[:len(pools)-2]
+			pools = append(pools, pool{a.sum + b.sum, a.weight + b.weight, a.count + b.count})
+		}
+	}
+
+	i := 0
+	for _, p := range pools {
+		for j := 0; j < p.count; j++ {
+			l.x[rank[i]] = p.sum/p.weight + offset[i]
+			i++
+		}
+	}
+}
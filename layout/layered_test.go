@@ -0,0 +1,98 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+func parse(t *testing.T, src string) (*builder.Graph, map[string]*builder.Node) {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	nodes := make(map[string]*builder.Node)
+	for _, n := range g.Nodes() {
+		nodes[n.Label] = n
+	}
+	return g, nodes
+}
+
+func overlap(a, b *NodeLayout) bool {
+	ra, rb := a.Bounds(), b.Bounds()
+	return ra.Min.X < rb.Max.X && rb.Min.X < ra.Max.X &&
+		ra.Min.Y < rb.Max.Y && rb.Min.Y < ra.Max.Y
+}
+
+func TestLayered(t *testing.T) {
+	g, n := parse(t, `digraph {
+		a -> b -> c -> a; a -> c; b -> d; d -> d;
+		subgraph cluster_x { e; f }
+		a -> e; c -> f;
+	}`)
+	l := Layered(g)
+
+	if len(l.Nodes) != 6 || len(l.Edges) != 8 {
+		t.Fatalf("Layout has %d nodes and %d edges.", len(l.Nodes), len(l.Edges))
+	}
+	if !(l.Nodes[n["a"]].Center.Y > l.Nodes[n["b"]].Center.Y &&
+		l.Nodes[n["b"]].Center.Y > l.Nodes[n["c"]].Center.Y) {
+		t.Errorf("Ranks are not top to bottom.")
+	}
+	for a, al := range l.Nodes {
+		for b, bl := range l.Nodes {
+			if a != b && overlap(al, bl) {
+				t.Errorf("Nodes %s and %s overlap.", a.Label, b.Label)
+			}
+		}
+	}
+	for _, e := range g.Edges() {
+		el := l.Edges[e]
+		if len(el.Points) < 2 || len(el.Spline) != 3*len(el.Points)-2 {
+			t.Errorf("Edge %s -> %s has a bad route.", e.Src.Label, e.Dst.Label)
+		}
+	}
+
+	// The reversed edge still runs from c to a.
+	ca := l.Edges[g.Edges()[2]].Points
+	if ca[0].Y > ca[len(ca)-1].Y {
+		t.Errorf("Edge c -> a runs the wrong way.")
+	}
+
+	box := l.Clusters[g.Subgraphs()[0]]
+	for _, name := range []string{"e", "f"} {
+		if b := l.Nodes[n[name]].Bounds(); b.Union(box) != box {
+			t.Errorf("Node %s is outside its cluster.", name)
+		}
+	}
+	if l.Bounds.Min != (Point{}) {
+		t.Errorf("Bounds start at %v.", l.Bounds.Min)
+	}
+}
+
+func TestLayeredRankdir(t *testing.T) {
+	g, n := parse(t, `digraph { rankdir=LR; a -> b -> c; a -> c [minlen=3] }`)
+	l := Layered(g)
+
+	a, b, c := l.Nodes[n["a"]].Center, l.Nodes[n["b"]].Center, l.Nodes[n["c"]].Center
+	if !(a.X < b.X && b.X < c.X) {
+		t.Errorf("Ranks are not left to right: %v, %v, %v.", a, b, c)
+	}
+	if len(l.Edges[g.Edges()[2]].Points) != 4 {
+		t.Errorf("Edge a -> c should cross two ranks.")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package layout positions the nodes of a builder.Graph.
+
+The coordinate helpers arrange nodes in simple geometric patterns, circles,
+concentric shells, grids, spirals and columns, by setting Node.Position.  With
+Options.Lock set, the positions are written with a trailing '!' so that neato
+and fdp keep the nodes where they were put.  A short example, placing the
+nodes of a graph on a circle of radius 2 inches:
+
+  layout.Circular(g.Nodes(), &layout.Options{Scale: 2, Lock: true})
+
+Tree and Radial set Node.Position in the same way, arranging a graph as a
+tidy tree or as a radial tree about a root.  The positions can be passed to
+"neato -n", or drawn directly.
+
+Coordinates are in inches, with y increasing upwards, as Graphviz expects.
+
+The layout engines compute a complete drawing in pure Go, without Graphviz.
+Layered arranges a graph in ranks, as dot does.  FruchtermanReingold and
+Stress are force-directed, like fdp and neato, and keep nodes with a locked
+Position where they are.  The engines return a Layout, measured in points,
+holding the size and center of each node, the route of each edge as a
+polyline and a spline, and the box of each cluster.
+Layout.Apply copies the node positions back into the graph.
+*/
+package layout
+
+import "math"
+
+import "godot/attr"
+import "godot/builder"
+
+// Options for the coordinate helpers.
+type Options struct {
+	// The size of the arrangement, in inches.  What it measures depends on
+	// the helper; see each for details.  Defaults to 1.
+	Scale float64
+
+	// Rotation of the arrangement about its center, in degrees
+	// anticlockwise.
+	Rotation float64
+
+	// If set, the positions are locked so that the layout engines do not
+	// move the nodes.
+	Lock bool
+}
+
+func (o *Options) scale() float64 {
+	if o == nil || o.Scale == 0 {
+		return 1
+	}
+	return o.Scale
+}
+
+// Sets the position of "n" to (x, y), rotated and locked according to the
+// options.
+func (o *Options) place(n *builder.Node, x, y float64) {
+	lock := false
+	if o != nil {
+		if o.Rotation != 0 {
+			rad := o.Rotation * math.Pi / 180
+			sin, cos := math.Sin(rad), math.Cos(rad)
+			x, y = x*cos-y*sin, x*sin+y*cos
+		}
+		lock = o.Lock
+	}
+	n.Position = &attr.Point{X: float32(x), Y: float32(y), Lock: lock}
+}
@@ -0,0 +1,138 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+
+// Deepest level of the quadtree; nodes closer together than this allows
+// share a cell.
+const maxQuadDepth = 24
+
+// A Barnes-Hut quadtree over a set of points, each of unit mass.  Cells are
+// stored in a slice and refer to their children by index.
+type quadtree struct {
+	cells []quadcell
+	stack []int
+}
+
+type quadcell struct {
+	x, y, size float64 // lower left corner and side
+	cx, cy     float64 // center of mass
+	mass       float64
+	body       int    // the single point in a leaf, or -1
+	children   [4]int // 0 if absent
+}
+
+// Rebuilds the tree over the points (xs[i], ys[i]).
+func (t *quadtree) build(xs, ys []float64) {
+	t.cells = t.cells[:0]
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range xs {
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+	size := math.Max(maxX-minX, maxY-minY) + 1
+	t.cells = append(t.cells, quadcell{x: minX, y: minY, size: size, body: -1})
+	for i := range xs {
+		t.insert(i, xs[i], ys[i])
+	}
+}
+
+func (t *quadtree) insert(i int, x, y float64) {
+	c := 0
+	for depth := 0; ; depth++ {
+		cell := &t.cells[c]
+		cell.cx = (cell.cx*cell.mass + x) / (cell.mass + 1)
+		cell.cy = (cell.cy*cell.mass + y) / (cell.mass + 1)
+		cell.mass++
+		if cell.mass == 1 {
+			cell.body = i
+			return
+		}
+		if depth == maxQuadDepth {
+			return
+		}
+
+		// Push the cell's single point down before descending.
+		if cell.body >= 0 {
+			b := cell.body
+			cell.body = -1
+			bx, by := cell.cx*2-x, cell.cy*2-y // its position, from the mean
+			q := t.child(c, bx, by)
+			t.cells[q].cx, t.cells[q].cy, t.cells[q].mass, t.cells[q].body = bx, by, 1, b
+		}
+		c = t.child(c, x, y)
+	}
+}
+
+// Returns the child of cell "c" containing (x, y), creating it if need be.
+func (t *quadtree) child(c int, x, y float64) int {
+	cell := t.cells[c]
+	half := cell.size / 2
+	q, cx, cy := 0, cell.x, cell.y
+	if x >= cell.x+half {
+		q, cx = q+1, cx+half
+	}
+	if y >= cell.y+half {
+		q, cy = q+2, cy+half
+	}
+	if cell.children[q] == 0 {
+		t.cells[c].children[q] = len(t.cells)
+		t.cells = append(t.cells, quadcell{x: cx, y: cy, size: half, body: -1})
+	}
+	return t.cells[c].children[q]
+}
+
+// Returns the repulsive force, strength/d per unit mass, on point "i" at
+// (x, y) from all other points.
+func (t *quadtree) repulsion(i int, x, y, strength, theta float64) (float64, float64) {
+	var fx, fy float64
+	stack := append(t.stack[:0], 0)
+	theta2 := theta * theta
+	for len(stack) > 0 {
+		cell := &t.cells[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if cell.body == i && cell.mass == 1 {
+			continue
+		}
+		dx, dy := x-cell.cx, y-cell.cy
+		d2 := dx*dx + dy*dy
+		leaf := cell.children == [4]int{}
+		if leaf || cell.size*cell.size < theta2*d2 {
+			if leaf && cell.body == i {
+				// Other points coincide with this one.
+				d2, dx, dy = 0, 0, 0
+			}
+			if d2 < 1e-4 {
+				// Push in a direction particular to the point.
+				a := float64(i) * 2.39996
+				dx, dy, d2 = 0.01*math.Cos(a), 0.01*math.Sin(a), 1e-4
+			}
+			s := strength * cell.mass / d2
+			fx += dx * s
+			fy += dy * s
+			continue
+		}
+		for _, ch := range cell.children {
+			if ch != 0 {
+				stack = append(stack, ch)
+			}
+		}
+	}
+	t.stack = stack
+	return fx, fy
+}
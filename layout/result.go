@@ -0,0 +1,330 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "errors"
+import "math"
+import "strconv"
+import "strings"
+import "unicode/utf8"
+
+import "godot/attr"
+import "godot/builder"
+
+// Returned by FromPositions when a node has no position.
+var ErrUnpositioned = errors.New("layout: node has no position")
+
+// The number of points in an inch.  Layouts are measured in points, as in
+// Graphviz output, while node attributes are measured in inches.
+const PointsPerInch = 72
+
+// Graphviz defaults used when the graph does not say otherwise.
+const (
+	DefaultWidth    = 0.75 // inches
+	DefaultHeight   = 0.5  // inches
+	DefaultFontSize = 14   // points
+	DefaultNodesep  = 0.25 // inches
+	DefaultRanksep  = 0.5  // inches
+)
+
+// A point, in points, with y increasing upwards.
+type Point struct {
+	X, Y float64
+}
+
+// A rectangle, in points.
+type Rect struct {
+	Min, Max Point
+}
+
+// Returns the width and height of the rectangle.
+func (r Rect) Size() (float64, float64) {
+	return r.Max.X - r.Min.X, r.Max.Y - r.Min.Y
+}
+
+// Returns the smallest rectangle containing both "r" and "s".  An empty
+// rectangle is ignored.
+func (r Rect) Union(s Rect) Rect {
+	if r == (Rect{}) {
+		return s
+	}
+	if s == (Rect{}) {
+		return r
+	}
+	return Rect{
+		Point{math.Min(r.Min.X, s.Min.X), math.Min(r.Min.Y, s.Min.Y)},
+		Point{math.Max(r.Max.X, s.Max.X), math.Max(r.Max.Y, s.Max.Y)},
+	}
+}
+
+// The position and size of a laid out node.
+type NodeLayout struct {
+	// Center of the node.
+	Center Point
+
+	// Size of the node, in points.
+	Width, Height float64
+}
+
+// Returns the bounding box of the node.
+func (n *NodeLayout) Bounds() Rect {
+	return Rect{
+		Point{n.Center.X - n.Width/2, n.Center.Y - n.Height/2},
+		Point{n.Center.X + n.Width/2, n.Center.Y + n.Height/2},
+	}
+}
+
+// The route of a laid out edge.
+type EdgeLayout struct {
+	// The route as a polyline, from the boundary of the source node to the
+	// boundary of the destination node.
+	Points []Point
+
+	// The route as a piecewise cubic Bezier curve, in the form Graphviz
+	// uses: a start point followed by three points (two control points and
+	// an end point) per segment.
+	Spline []Point
+
+	// Center of the edge's label, if it has one.
+	LabelPos *Point
+}
+
+// The result of laying out a graph.
+type Layout struct {
+	Nodes    map[*builder.Node]*NodeLayout
+	Edges    map[*builder.Edge]*EdgeLayout
+	Clusters map[*builder.Subgraph]Rect
+
+	// Bounding box of everything in the layout.
+	Bounds Rect
+}
+
+func newLayout() *Layout {
+	return &Layout{
+		Nodes:    make(map[*builder.Node]*NodeLayout),
+		Edges:    make(map[*builder.Edge]*EdgeLayout),
+		Clusters: make(map[*builder.Subgraph]Rect),
+	}
+}
+
+// Recomputes Bounds from the nodes, edges and clusters of the layout.
+func (l *Layout) computeBounds() {
+	var b Rect
+	for _, n := range l.Nodes {
+		b = b.Union(n.Bounds())
+	}
+	for _, e := range l.Edges {
+		for _, p := range e.Points {
+			b = b.Union(Rect{p, p})
+		}
+	}
+	for _, c := range l.Clusters {
+		b = b.Union(c)
+	}
+	l.Bounds = b
+}
+
+// Computes the box of each cluster subgraph of "g" from its nodes and
+// nested clusters.
+func (l *Layout) boxClusters(g *builder.Graph) {
+	var box func(sub *builder.Subgraph) Rect
+	box = func(sub *builder.Subgraph) Rect {
+		if r, ok := l.Clusters[sub]; ok {
+			return r
+		}
+		var r Rect
+		for _, n := range sub.Nodes() {
+			if nl, ok := l.Nodes[n]; ok {
+				r = r.Union(nl.Bounds())
+			}
+		}
+		for _, child := range sub.Subgraphs() {
+			if strings.HasPrefix(child.Name, "cluster") {
+				r = r.Union(box(child))
+			} else {
+				for _, n := range child.AllNodes() {
+					if nl, ok := l.Nodes[n]; ok {
+						r = r.Union(nl.Bounds())
+					}
+				}
+			}
+		}
+		if r != (Rect{}) {
+			r.Min.X -= clusterMargin
+			r.Min.Y -= clusterMargin
+			r.Max.X += clusterMargin
+			r.Max.Y += clusterMargin
+			if sub.Label != "" {
+				r.Max.Y += TextHeight(sub.Label, DefaultFontSize)
+				w, _ := r.Size()
+				if grow := TextWidth(sub.Label, DefaultFontSize) + 2*clusterMargin - w; grow > 0 {
+					r.Min.X -= grow / 2
+					r.Max.X += grow / 2
+				}
+			}
+		}
+		l.Clusters[sub] = r
+		return r
+	}
+	var visit func(subs []*builder.Subgraph)
+	visit = func(subs []*builder.Subgraph) {
+		for _, sub := range subs {
+			if strings.HasPrefix(sub.Name, "cluster") {
+				box(sub)
+			}
+			visit(sub.Subgraphs())
+		}
+	}
+	visit(g.Subgraphs())
+}
+
+// Moves the layout so that its bounding box starts at the origin.
+func (l *Layout) normalize() {
+	l.computeBounds()
+	dx, dy := -l.Bounds.Min.X, -l.Bounds.Min.Y
+	move := func(p *Point) {
+		p.X += dx
+		p.Y += dy
+	}
+	for _, n := range l.Nodes {
+		move(&n.Center)
+	}
+	for _, e := range l.Edges {
+		for i := range e.Points {
+			move(&e.Points[i])
+		}
+		for i := range e.Spline {
+			move(&e.Spline[i])
+		}
+		if e.LabelPos != nil {
+			move(e.LabelPos)
+		}
+	}
+	for c, r := range l.Clusters {
+		move(&r.Min)
+		move(&r.Max)
+		l.Clusters[c] = r
+	}
+	move(&l.Bounds.Min)
+	move(&l.Bounds.Max)
+}
+
+// Makes a layout from the Position of each node, as "neato -n" does, routing
+// edges as straight lines.  Returns ErrUnpositioned if a node has no
+// position.  The layout is in points, with the same origin as Node.Position.
+func FromPositions(g *builder.Graph) (*Layout, error) {
+	l := newLayout()
+	for _, n := range g.Nodes() {
+		if n.Position == nil {
+			return nil, ErrUnpositioned
+		}
+		w, h := NodeSize(g.ResolveNode(n))
+		l.Nodes[n] = &NodeLayout{
+			Center: Point{float64(n.Position.X) * PointsPerInch, float64(n.Position.Y) * PointsPerInch},
+			Width:  w,
+			Height: h,
+		}
+	}
+	l.routeStraight(g)
+	l.boxClusters(g)
+	l.computeBounds()
+	return l, nil
+}
+
+// Returns the layout of the Position of each node, as FromPositions does, or
+// if a node has none, the layered layout of the graph.
+func Default(g *builder.Graph) *Layout {
+	if l, err := FromPositions(g); err == nil {
+		return l
+	}
+	return Layered(g)
+}
+
+// Sets Node.Position of each laid out node to its center, in inches, as the
+// input to "neato -n" or another engine.  If "lock" is set the positions are
+// locked.
+func (l *Layout) Apply(lock bool) {
+	for n, nl := range l.Nodes {
+		n.Position = &attr.Point{
+			X:    float32(nl.Center.X / PointsPerInch),
+			Y:    float32(nl.Center.Y / PointsPerInch),
+			Lock: lock,
+		}
+	}
+}
+
+// Estimates the width, in points, of "text" set in a proportional font of
+// the given size.  Lines are separated by "\n", "\l" or "\r"; the widest line
+// is measured.
+func TextWidth(text string, fontSize float64) float64 {
+	widest := 0.0
+	for _, line := range LabelLines(text) {
+		if w := float64(utf8.RuneCountInString(line)) * 0.6 * fontSize; w > widest {
+			widest = w
+		}
+	}
+	return widest
+}
+
+// Estimates the height, in points, of "text" set in a font of the given size.
+func TextHeight(text string, fontSize float64) float64 {
+	return float64(len(LabelLines(text))) * 1.2 * fontSize
+}
+
+// Splits a label into lines at Graphviz line breaks, "\n", "\l", "\r" or a
+// newline.
+func LabelLines(text string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, text[start:i])
+			start = i + 1
+		} else if text[i] == '\\' && i+1 < len(text) && (text[i+1] == 'n' || text[i+1] == 'l' || text[i+1] == 'r') {
+			lines = append(lines, text[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	if start < len(text) || len(lines) == 0 {
+		lines = append(lines, text[start:])
+	}
+	return lines
+}
+
+// Returns the size of a node in points, from its Width and Height, grown to
+// fit its label.  Circles are as wide as they are high.
+func NodeSize(n *builder.Node) (float64, float64) {
+	w := inches(n.Width, DefaultWidth) * PointsPerInch
+	h := inches(n.Height, DefaultHeight) * PointsPerInch
+	if n.Label != "" {
+		w = math.Max(w, TextWidth(n.Label, DefaultFontSize)+0.22*PointsPerInch)
+		h = math.Max(h, TextHeight(n.Label, DefaultFontSize)+0.11*PointsPerInch)
+	}
+	if n.Shape == attr.Circle {
+		w = math.Max(w, h)
+		h = w
+	}
+	return w, h
+}
+
+// Parses a length in inches, returning "def" if it is not set or invalid.
+func inches(value string, def float64) float64 {
+	if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
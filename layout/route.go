@@ -0,0 +1,133 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+
+import "godot/attr"
+import "godot/builder"
+
+// Returns the point where the line from the center of a node towards
+// "toward" leaves the node's shape.  Boxes are clipped as rectangles, and
+// every other shape as the ellipse inscribed in the node's bounding box.
+func clip(n *NodeLayout, shape *attr.NodeShape, toward Point) Point {
+	dx, dy := toward.X-n.Center.X, toward.Y-n.Center.Y
+	hw, hh := n.Width/2, n.Height/2
+	if (dx == 0 && dy == 0) || hw == 0 || hh == 0 {
+		return n.Center
+	}
+	var t float64
+	if shape == attr.Box || shape == attr.Rect {
+		t = math.Inf(1)
+		if dx != 0 {
+			t = hw / math.Abs(dx)
+		}
+		if dy != 0 {
+			t = math.Min(t, hh/math.Abs(dy))
+		}
+	} else {
+		t = 1 / math.Hypot(dx/hw, dy/hh)
+	}
+	t = math.Min(t, 1)
+	return Point{n.Center.X + t*dx, n.Center.Y + t*dy}
+}
+
+// Returns the route of an edge through "points", smoothed into a spline
+// passing through each of them.  If "labelled" is set the label is placed
+// halfway along the route.
+func route(points []Point, labelled bool) *EdgeLayout {
+	e := &EdgeLayout{Points: points}
+	if len(points) < 2 {
+		return e
+	}
+
+	// Convert the Catmull-Rom spline through the points to Bezier form.
+	at := func(i int) Point {
+		if i < 0 {
+			i = 0
+		} else if i >= len(points) {
+			i = len(points) - 1
+		}
+		return points[i]
+	}
+	e.Spline = []Point{points[0]}
+	for i := 0; i+1 < len(points); i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		e.Spline = append(e.Spline,
+			Point{p1.X + (p2.X-p0.X)/6, p1.Y + (p2.Y-p0.Y)/6},
+			Point{p2.X - (p3.X-p1.X)/6, p2.Y - (p3.Y-p1.Y)/6},
+			p2)
+	}
+
+	if labelled {
+		total := 0.0
+		for i := 1; i < len(points); i++ {
+			total += distance(points[i-1], points[i])
+		}
+		half := total / 2
+		for i := 1; i < len(points); i++ {
+			d := distance(points[i-1], points[i])
+			if d >= half || i == len(points)-1 {
+				t := 0.0
+				if d > 0 {
+					t = math.Min(half/d, 1)
+				}
+				a, b := points[i-1], points[i]
+				e.LabelPos = &Point{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)}
+				break
+			}
+			half -= d
+		}
+	}
+	return e
+}
+
+// Returns the points of a loop from a node back to itself, on its right.
+func selfLoop(n *NodeLayout) []Point {
+	x := n.Center.X + n.Width/2
+	size := math.Max(n.Height/2, 12)
+	return []Point{
+		{x, n.Center.Y + n.Height/6},
+		{x + size, n.Center.Y + size/2},
+		{x + size, n.Center.Y - size/2},
+		{x, n.Center.Y - n.Height/6},
+	}
+}
+
+func distance(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// Routes each edge of "g" as a straight line between the nodes laid out in
+// "l", and loops self-loops back to their node.
+func (l *Layout) routeStraight(g *builder.Graph) {
+	for _, e := range g.Edges() {
+		src, dst := l.Nodes[e.Src], l.Nodes[e.Dst]
+		if src == nil || dst == nil {
+			continue
+		}
+		var points []Point
+		if e.Src == e.Dst {
+			points = selfLoop(src)
+		} else {
+			points = []Point{
+				clip(src, g.ResolveNode(e.Src).Shape, dst.Center),
+				clip(dst, g.ResolveNode(e.Dst).Shape, src.Center),
+			}
+		}
+		l.Edges[e] = route(points, g.ResolveEdge(e).Label != "")
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "errors"
+import "math"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// Returned by Tree and Radial when the root is not a node of the graph.
+var ErrRoot = errors.New("layout: root is not in the graph")
+
+// A node of a spanning tree being laid out by Walker's algorithm.
+type tnode struct {
+	id       int
+	parent   *tnode
+	children []*tnode
+	number   int // position among its siblings
+	depth    int
+	width    float64 // in inches
+	leaves   int     // in its subtree
+
+	prelim, mod, shift, change float64
+	thread, ancestor           *tnode
+}
+
+// Grows a spanning forest of "g" breadth first.  Trees are grown from each
+// of "roots" in turn, skipping nodes already reached, and "next" gives the
+// neighbours of a node to reach from it.
+func spanningForest(ix *adj.Index, roots []int, next func(int) []int) []*tnode {
+	reached := make([]*tnode, ix.Len())
+	var forest []*tnode
+	for _, r := range roots {
+		if reached[r] != nil {
+			continue
+		}
+		root := &tnode{id: r}
+		root.ancestor = root
+		reached[r] = root
+		forest = append(forest, root)
+		queue := []*tnode{root}
+		for len(queue) > 0 {
+			t := queue[0]
+			queue = queue[1:]
+			for _, w := range next(t.id) {
+				if reached[w] != nil {
+					continue
+				}
+				c := &tnode{id: w, parent: t, number: len(t.children), depth: t.depth + 1}
+				c.ancestor = c
+				reached[w] = c
+				t.children = append(t.children, c)
+				queue = append(queue, c)
+			}
+		}
+	}
+	return forest
+}
+
+// Looks up the id of "root", which may be nil.
+func rootID(ix *adj.Index, root *builder.Node) (int, error) {
+	if root == nil {
+		return -1, nil
+	}
+	if id, ok := ix.ID(root); ok {
+		return id, nil
+	}
+	return -1, ErrRoot
+}
+
+// Lays out a graph as a tidy tree, using Walker's algorithm in the linear
+// time form of Buchheim, Jünger and Leipert: each parent is centered over
+// its children, subtrees are packed as closely as the graph's Nodesep allows
+// between node boxes, and identical subtrees are drawn identically.
+//
+// The root is placed at the origin and each level of the tree Scale inches
+// below the last.  If "root" is nil, the first node without predecessors is
+// used, or the first node if every node has one.  Graphs which are not trees
+// are laid out along a breadth first spanning tree, following edge direction
+// in directed graphs; nodes not reached from the root form further trees,
+// placed to the right.  Node.Position of each node is set, rotated and
+// locked according to the options.
+func Tree(g *builder.Graph, root *builder.Node, opts *Options) error {
+	ix := adj.New(g)
+	first, err := rootID(ix, root)
+	if err != nil {
+		return err
+	}
+	var roots []int
+	if first >= 0 {
+		roots = append(roots, first)
+	}
+	for v := 0; v < ix.Len(); v++ {
+		if len(ix.Pred(v)) == 0 {
+			roots = append(roots, v)
+		}
+	}
+	for v := 0; v < ix.Len(); v++ {
+		roots = append(roots, v)
+	}
+	forest := spanningForest(ix, roots, ix.Succ)
+
+	nodesep := inches(g.Nodesep, DefaultNodesep)
+	widths := make([]float64, ix.Len())
+	for v, n := range ix.Nodes {
+		w, _ := NodeSize(g.ResolveNode(n))
+		widths[v] = w / PointsPerInch
+	}
+
+	level := opts.scale()
+	x := make([]float64, ix.Len())
+	depth := make([]int, ix.Len())
+	right := 0.0
+	for i, t := range forest {
+		walk(t, func(t *tnode) { t.width = widths[t.id] })
+		w := &walker{nodesep: nodesep}
+		w.firstWalk(t)
+
+		// Place the tree to the right of the previous one.
+		lo, hi := math.Inf(1), math.Inf(-1)
+		w.secondWalk(t, -t.prelim, func(t *tnode, tx float64) {
+			x[t.id], depth[t.id] = tx, t.depth
+			lo, hi = math.Min(lo, tx-t.width/2), math.Max(hi, tx+t.width/2)
+		})
+		shift := 0.0
+		if i > 0 {
+			shift = right + nodesep - lo
+		}
+		walk(t, func(t *tnode) { x[t.id] += shift })
+		right = hi + shift
+	}
+
+	for v, n := range ix.Nodes {
+		opts.place(n, x[v], -float64(depth[v])*level)
+	}
+	return nil
+}
+
+// Calls "f" on each node of the tree rooted at "t", parents first.
+func walk(t *tnode, f func(*tnode)) {
+	f(t)
+	for _, c := range t.children {
+		walk(c, f)
+	}
+}
+
+// Walker's algorithm, with the separation between neighbouring nodes
+// depending on their widths.
+type walker struct {
+	nodesep float64
+}
+
+func (w *walker) separation(a, b *tnode) float64 {
+	return (a.width+b.width)/2 + w.nodesep
+}
+
+func leftSibling(v *tnode) *tnode {
+	if v.parent == nil || v.number == 0 {
+		return nil
+	}
+	return v.parent.children[v.number-1]
+}
+
+func nextLeft(v *tnode) *tnode {
+	if len(v.children) > 0 {
+		return v.children[0]
+	}
+	return v.thread
+}
+
+func nextRight(v *tnode) *tnode {
+	if len(v.children) > 0 {
+		return v.children[len(v.children)-1]
+	}
+	return v.thread
+}
+
+func (w *walker) firstWalk(v *tnode) {
+	if len(v.children) == 0 {
+		if s := leftSibling(v); s != nil {
+			v.prelim = s.prelim + w.separation(s, v)
+		}
+		return
+	}
+	defaultAncestor := v.children[0]
+	for _, c := range v.children {
+		w.firstWalk(c)
+		defaultAncestor = w.apportion(c, defaultAncestor)
+	}
+	executeShifts(v)
+	mid := (v.children[0].prelim + v.children[len(v.children)-1].prelim) / 2
+	if s := leftSibling(v); s != nil {
+		v.prelim = s.prelim + w.separation(s, v)
+		v.mod = v.prelim - mid
+	} else {
+		v.prelim = mid
+	}
+}
+
+// Moves the subtree rooted at "v" clear of the subtrees of its left
+// siblings, contour by contour, threading the contours for later use.
+func (w *walker) apportion(v, defaultAncestor *tnode) *tnode {
+	s := leftSibling(v)
+	if s == nil {
+		return defaultAncestor
+	}
+	vip, vop := v, v
+	vim, vom := s, v.parent.children[0]
+	sip, sop := vip.mod, vop.mod
+	sim, som := vim.mod, vom.mod
+	for nextRight(vim) != nil && nextLeft(vip) != nil {
+		vim, vip = nextRight(vim), nextLeft(vip)
+		vom, vop = nextLeft(vom), nextRight(vop)
+		vop.ancestor = v
+		shift := (vim.prelim + sim) - (vip.prelim + sip) + w.separation(vim, vip)
+		if shift > 0 {
+			a := defaultAncestor
+			if vim.ancestor.parent == v.parent {
+				a = vim.ancestor
+			}
+			moveSubtree(a, v, shift)
+			sip += shift
+			sop += shift
+		}
+		sim += vim.mod
+		sip += vip.mod
+		som += vom.mod
+		sop += vop.mod
+	}
+	if nextRight(vim) != nil && nextRight(vop) == nil {
+		vop.thread = nextRight(vim)
+		vop.mod += sim - sop
+	}
+	if nextLeft(vip) != nil && nextLeft(vom) == nil {
+		vom.thread = nextLeft(vip)
+		vom.mod += sip - som
+		defaultAncestor = v
+	}
+	return defaultAncestor
+}
+
+func moveSubtree(wm, wp *tnode, shift float64) {
+	subtrees := float64(wp.number - wm.number)
+	wp.change -= shift / subtrees
+	wp.shift += shift
+	wm.change += shift / subtrees
+	wp.prelim += shift
+	wp.mod += shift
+}
+
+func executeShifts(v *tnode) {
+	shift, change := 0.0, 0.0
+	for i := len(v.children) - 1; i >= 0; i-- {
+		c := v.children[i]
+		c.prelim += shift
+		c.mod += shift
+		change += c.change
+		shift += c.shift + change
+	}
+}
+
+func (w *walker) secondWalk(v *tnode, m float64, place func(*tnode, float64)) {
+	place(v, v.prelim+m)
+	for _, c := range v.children {
+		w.secondWalk(c, m+v.mod, place)
+	}
+}
+
+// Lays out a graph as a radial tree, as twopi does: the root is placed at
+// the origin, and the nodes at each depth on a circle Scale inches further
+// out.  Each node is given a wedge of its parent's wedge in proportion to
+// the number of leaves below it, and placed in the middle of its wedge.
+//
+// If "root" is nil, a center of the graph, a node whose greatest distance to
+// another node is least, is used.  Edge directions are ignored, and graphs
+// which are not trees are laid out along a breadth first spanning tree.
+// Each further component is laid out around its own center and placed to
+// the right.  Node.Position of each node is set, rotated and locked
+// according to the options.
+func Radial(g *builder.Graph, root *builder.Node, opts *Options) error {
+	ix := adj.New(g)
+	first, err := rootID(ix, root)
+	if err != nil {
+		return err
+	}
+	comp, count := ix.Components()
+	roots := make([]int, count)
+	for c := range roots {
+		roots[c] = -1
+	}
+	if first >= 0 {
+		roots[comp[first]] = first
+	}
+	members := make([][]int, count)
+	for v := 0; v < ix.Len(); v++ {
+		members[comp[v]] = append(members[comp[v]], v)
+	}
+	for c, r := range roots {
+		if r < 0 {
+			roots[c] = center(ix, members[c])
+		}
+	}
+	// Lay out the component of the root first.
+	if first >= 0 {
+		c := comp[first]
+		roots[0], roots[c] = roots[c], roots[0]
+	}
+	forest := spanningForest(ix, roots, ix.Neighbors)
+
+	ring := opts.scale()
+	x := make([]float64, ix.Len())
+	y := make([]float64, ix.Len())
+	right := 0.0
+	for i, t := range forest {
+		countLeaves(t)
+		radius := 0.0
+		var place func(t *tnode, from, to float64)
+		place = func(t *tnode, from, to float64) {
+			r := float64(t.depth) * ring
+			theta := (from + to) / 2
+			x[t.id], y[t.id] = r*math.Cos(theta), r*math.Sin(theta)
+			radius = math.Max(radius, r)
+			for _, c := range t.children {
+				span := (to - from) * float64(c.leaves) / float64(t.leaves)
+				place(c, from, from+span)
+				from += span
+			}
+		}
+		place(t, 0, 2*math.Pi)
+
+		// Place the component to the right of the previous one.
+		radius += ring / 2
+		shift := 0.0
+		if i > 0 {
+			shift = right + radius
+		}
+		walk(t, func(t *tnode) { x[t.id] += shift })
+		right = shift + radius
+	}
+
+	for v, n := range ix.Nodes {
+		opts.place(n, x[v], y[v])
+	}
+	return nil
+}
+
+func countLeaves(t *tnode) int {
+	t.leaves = 0
+	for _, c := range t.children {
+		t.leaves += countLeaves(c)
+	}
+	if t.leaves == 0 {
+		t.leaves = 1
+	}
+	return t.leaves
+}
+
+// Returns a node of "nodes", a connected component, with the least
+// eccentricity, preferring the earliest.
+func center(ix *adj.Index, nodes []int) int {
+	best, bestEcc := nodes[0], math.MaxInt32
+	dist := make([]int, ix.Len())
+	for _, src := range nodes {
+		for _, v := range nodes {
+			dist[v] = -1
+		}
+		dist[src] = 0
+		ecc := 0
+		queue := []int{src}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			ecc = dist[v]
+			for _, w := range ix.Neighbors(v) {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+			}
+		}
+		if ecc < bestEcc {
+			best, bestEcc = src, ecc
+		}
+	}
+	return best
+}
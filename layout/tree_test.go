@@ -0,0 +1,99 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import "math"
+import "testing"
+
+import "godot/builder"
+
+func TestTree(t *testing.T) {
+	g := builder.GenBinaryTree(3, nil)
+	if err := Tree(g, nil, nil); err != nil {
+		t.Fatalf("Tree failed: %v", err)
+	}
+
+	nodes := g.Nodes()
+	root := nodes[0]
+	if !at(root, 0, 0) {
+		t.Errorf("Root is at %v.", root.Position)
+	}
+	byLevel := make(map[float32][]*builder.Node)
+	for _, n := range nodes {
+		byLevel[n.Position.Y] = append(byLevel[n.Position.Y], n)
+	}
+	if len(byLevel) != 4 {
+		t.Errorf("Tree has %d levels.", len(byLevel))
+	}
+	for y, level := range byLevel {
+		for i := 1; i < len(level); i++ {
+			if gap := level[i].Position.X - level[i-1].Position.X; gap < 1-1e-4 {
+				t.Errorf("Nodes at level %v are %v apart.", y, gap)
+			}
+		}
+	}
+	for _, e := range g.Edges() {
+		if e.Dst.Position.Y != e.Src.Position.Y-1 {
+			t.Errorf("Child is not a level below its parent.")
+		}
+	}
+
+	// Each parent is centered over its children.
+	children := make(map[*builder.Node][]*builder.Node)
+	for _, e := range g.Edges() {
+		children[e.Src] = append(children[e.Src], e.Dst)
+	}
+	for p, cs := range children {
+		mid := (cs[0].Position.X + cs[len(cs)-1].Position.X) / 2
+		if math.Abs(float64(p.Position.X-mid)) > 1e-4 {
+			t.Errorf("Parent at %v is not centered over %v.", p.Position.X, mid)
+		}
+	}
+}
+
+func TestTreeRoot(t *testing.T) {
+	g := builder.GenPath(3, nil)
+	if err := Tree(g, g.Nodes()[1], &Options{Scale: 2}); err != nil {
+		t.Fatalf("Tree failed: %v", err)
+	}
+	if !at(g.Nodes()[1], 0, 0) || g.Nodes()[2].Position.Y != -2 {
+		t.Errorf("Tree is not rooted at the middle node.")
+	}
+	if err := Tree(g, &builder.Node{}, nil); err != ErrRoot {
+		t.Errorf("Expected ErrRoot, got %v.", err)
+	}
+}
+
+func TestRadial(t *testing.T) {
+	g := builder.GenStar(6, nil)
+	g.AddNodes(builder.GenNodes(1)...)
+	if err := Radial(g, nil, &Options{Scale: 2}); err != nil {
+		t.Fatalf("Radial failed: %v", err)
+	}
+
+	nodes := g.Nodes()
+	if !at(nodes[0], 0, 0) {
+		t.Errorf("Hub is at %v.", nodes[0].Position)
+	}
+	for _, n := range nodes[1:7] {
+		if r := math.Hypot(float64(n.Position.X), float64(n.Position.Y)); math.Abs(r-2) > 1e-4 {
+			t.Errorf("Leaf is %v from the hub.", r)
+		}
+	}
+	if nodes[7].Position.X <= 2 {
+		t.Errorf("Isolated node at %v overlaps the star.", nodes[7].Position)
+	}
+}
@@ -0,0 +1,525 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package mermaid converts graphs to and from the flowchart syntax of Mermaid,
+the diagram language understood by many Markdown renderers.
+
+Backend is the Mermaid backend of package godot/diagram.  It writes a
+flowchart whose direction is the graph's rankdir, whose node shapes are
+mapped from dot shapes, and whose subgraphs, edge labels, link styles and
+colors follow those of the graph.  Mermaid cannot express every dot
+attribute, so Encode, which writes with Backend, also reports those it could
+not write, such as node positions and sizes.  Parse reads the flowchart
+subset back.
+
+Resources:
+  https://mermaid.js.org/syntax/flowchart.html
+*/
+package mermaid
+
+import "bytes"
+import "fmt"
+import "io"
+import "regexp"
+import "strconv"
+import "strings"
+
+import "godot"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+
+// Matches the start of a Mermaid flowchart: its frontmatter and comments,
+// and its header.
+var header = regexp.MustCompile(`^\s*(---\n(?s:.*?)\n---\s*)?(%%[^\n]*\n\s*)*(flowchart|graph)([ \t]+(TB|TD|BT|RL|LR))?[ \t]*(\n|;|$)`)
+
+func init() {
+	format.Register("mermaid", []string{".mmd", ".mermaid"}, header.Match,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			_, err := Encode(w, g)
+			return err
+		}, godot.Clusters),
+		format.NewDecoder(Parse))
+}
+
+// An attribute which could not be written in Mermaid.
+type Unrepresented struct {
+	// The *builder.Graph, or the *builder.Subgraph, *builder.Node or
+	// *builder.Edge, or a node or edge template, with the attribute.
+	Object interface{}
+
+	Attribute string
+	Value     string
+}
+
+func (u Unrepresented) String() string {
+	return fmt.Sprintf("%s=%q", u.Attribute, u.Value)
+}
+
+// A dot shape, and the brackets around the text of a Mermaid node of the
+// shape.
+type shape struct {
+	name        string
+	open, close string
+}
+
+// The dot shapes which Mermaid can draw.  The first shape of each pair of
+// brackets is the one Parse gives.
+var shapes = []shape{
+	{"box", "[", "]"},
+	{"rect", "[", "]"},
+	{"rectangle", "[", "]"},
+	{"square", "[", "]"},
+	{"ellipse", "([", "])"},
+	{"oval", "([", "])"},
+	{"circle", "((", "))"},
+	{"doublecircle", "(((", ")))"},
+	{"diamond", "{", "}"},
+	{"hexagon", "{{", "}}"},
+	{"cylinder", "[(", ")]"},
+	{"component", "[[", "]]"},
+	{"cds", ">", "]"},
+	{"parallelogram", "[/", "/]"},
+	{"trapezium", "[/", `\]`},
+	{"invtrapezium", `[\`, "/]"},
+}
+
+func shapeNamed(name string) (shape, bool) {
+	for _, s := range shapes {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return shape{}, false
+}
+
+// The Mermaid link ends of dot arrowheads.
+var marks = map[string]string{
+	"":       ">",
+	"normal": ">",
+	"vee":    ">",
+	"dot":    "o",
+	"odot":   "o",
+	"tee":    "x",
+	"none":   "",
+}
+
+// The start of a link for each end mark.
+var startMarks = map[string]string{">": "<", "o": "o", "x": "x"}
+
+// Returns a color as CSS, with its alpha if it is translucent, or "" if its
+// components are not known.  Package diagram gives colors without alpha, so
+// Backend takes them from the graph.
+func cssColor(c color.Color) string {
+	rgb, alpha, ok := color.Components(c)
+	switch {
+	case !ok:
+		return ""
+	case alpha != 255:
+		return fmt.Sprintf("%s%02x", rgb, alpha)
+	}
+	return rgb.String()
+}
+
+// Returns true if a color can be written in Mermaid.
+func known(value string) bool {
+	return cssColor(color.Parse(value)) != ""
+}
+
+// Returns true if each of a comma separated list of styles is one of
+// "allowed".
+func stylesIn(style string, allowed ...string) bool {
+outer:
+	for _, part := range strings.Split(style, ",") {
+		part = strings.TrimSpace(part)
+		for _, a := range allowed {
+			if part == a {
+				continue outer
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func hasStyle(style, name string) bool {
+	for _, part := range strings.Split(style, ",") {
+		if strings.TrimSpace(part) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// Returns the text of a label as a quoted Mermaid string.  Line breaks
+// become <br> and double quotes become entities.
+func quote(label string) string {
+	r := strings.NewReplacer(`"`, "#quot;", `\n`, "<br>", `\l`, "<br>", `\r`, "<br>", "\n", "<br>")
+	return `"` + r.Replace(label) + `"`
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var nodeID = regexp.MustCompile(`^n[0-9]+$`)
+
+// Records the attributes of "obj" which "keeps" rejects.
+func check(lost []Unrepresented, obj interface{}, keeps func(name, value string) bool) []Unrepresented {
+	for _, a := range builder.Attributes(obj) {
+		if !keeps(a[0], a[1]) {
+			lost = append(lost, Unrepresented{Object: obj, Attribute: a[0], Value: a[1]})
+		}
+	}
+	return lost
+}
+
+func graphKeeps(name, value string) bool {
+	switch name {
+	case "label", "rankdir":
+		return true
+	case "nodesep", "ranksep":
+		return isNumber(value)
+	}
+	return false
+}
+
+func nodeKeeps(r *builder.Node) func(name, value string) bool {
+	return func(name, value string) bool {
+		switch name {
+		case "label", "penwidth", "class", "URL":
+			return true
+		case "shape":
+			_, ok := shapeNamed(value)
+			return ok
+		case "color", "fillcolor", "fontcolor":
+			return known(value)
+		case "style":
+			return stylesIn(value, "filled", "solid", "dashed", "dotted", "bold", "rounded")
+		case "tooltip":
+			return r.URL != ""
+		}
+		return false
+	}
+}
+
+func edgeKeeps(r *builder.Edge) func(name, value string) bool {
+	return func(name, value string) bool {
+		switch name {
+		case "label", "penwidth":
+			return true
+		case "color", "fontcolor":
+			return known(value)
+		case "style":
+			return stylesIn(value, "solid", "dashed", "dotted", "bold", "invis", "invisible")
+		case "minlen":
+			n, err := strconv.Atoi(value)
+			return err == nil && n >= 1
+		case "dir":
+			return value != "back"
+		case "arrowhead":
+			_, ok := marks[value]
+			return ok
+		case "arrowtail":
+			head, ok := marks[value]
+			if !ok || r.Dir != "both" {
+				return ok
+			}
+			return head == marks[r.Arrowhead]
+		}
+		return false
+	}
+}
+
+func subgraphKeeps(name, value string) bool {
+	switch name {
+	case "label", "class":
+		return true
+	case "color", "fillcolor", "fontcolor":
+		return known(value)
+	case "style":
+		return stylesIn(value, "filled", "solid", "dashed", "dotted", "rounded")
+	}
+	return false
+}
+
+// Returns the attributes of "g" which Backend cannot write.
+func unrepresented(g *builder.Graph) []Unrepresented {
+	lost := check(nil, g, graphKeeps)
+	if tmpl := g.NodeTemplate(); tmpl != nil {
+		lost = check(lost, tmpl, nodeKeeps(tmpl))
+	}
+	if tmpl := g.EdgeTemplate(); tmpl != nil {
+		lost = check(lost, tmpl, edgeKeeps(tmpl))
+	}
+	for _, n := range g.Nodes() {
+		lost = check(lost, n, nodeKeeps(g.ResolveNode(n)))
+	}
+	var walk func(subs []*builder.Subgraph)
+	walk = func(subs []*builder.Subgraph) {
+		for _, sub := range subs {
+			lost = check(lost, sub, subgraphKeeps)
+			walk(sub.Subgraphs())
+		}
+	}
+	walk(g.Subgraphs())
+	for _, e := range g.Edges() {
+		if e.Src != nil && e.Dst != nil {
+			lost = check(lost, e, edgeKeeps(g.ResolveEdge(e)))
+		}
+	}
+	return lost
+}
+
+// Writes Mermaid flowcharts.  Subgraphs are given the names of their
+// clusters as ids, if they are valid and unique, and the ids of the clusters
+// if not.  The graph's nodesep and ranksep, in inches, become the
+// flowchart's nodeSpacing and rankSpacing, in points.  Dot shapes without a
+// Mermaid equivalent are drawn as rectangles, arrowheads other than dots and
+// tees as arrows, and edges directed back as edges of the graph's kind.
+type Backend struct{}
+
+type writer struct {
+	buf    bytes.Buffer
+	ids    map[*diagram.Cluster]string
+	styles []string
+}
+
+// Writes a style statement for the node or subgraph "id", if it has any
+// style.
+func (wr *writer) style(id string, style geom.Style, fill, stroke, font string, penwidth float64) {
+	var parts []string
+	if fill != "" {
+		parts = append(parts, "fill:"+fill)
+	}
+	if stroke != "" {
+		parts = append(parts, "stroke:"+stroke)
+	}
+	if font != "" {
+		parts = append(parts, "color:"+font)
+	}
+	if penwidth > 0 {
+		parts = append(parts, "stroke-width:"+strconv.FormatFloat(penwidth, 'f', -1, 64)+"px")
+	} else if style.Bold {
+		parts = append(parts, "stroke-width:2px")
+	}
+	if style.Dashed {
+		parts = append(parts, "stroke-dasharray:5 5")
+	} else if style.Dotted {
+		parts = append(parts, "stroke-dasharray:1 3")
+	}
+	if len(parts) > 0 {
+		wr.styles = append(wr.styles, fmt.Sprintf("style %s %s", id, strings.Join(parts, ",")))
+	}
+}
+
+// Writes a class statement for "id", if it has classes.
+func (wr *writer) class(id, class string) {
+	if classes := strings.Fields(class); len(classes) > 0 {
+		wr.styles = append(wr.styles, fmt.Sprintf("class %s %s", id, strings.Join(classes, ",")))
+	}
+}
+
+func (wr *writer) node(n *diagram.Node, indent string) {
+	s := shape{open: "[", close: "]"}
+	if known, ok := shapeNamed(n.Shape); ok {
+		s = known
+	}
+	if s.open == "[" && n.Style.Rounded {
+		s = shape{open: "(", close: ")"}
+	}
+	if n.Node.Label == "" && s.open == "[" {
+		fmt.Fprintf(&wr.buf, "%s%s\n", indent, n.ID)
+	} else {
+		fmt.Fprintf(&wr.buf, "%s%s%s%s%s\n", indent, n.ID, s.open, quote(n.Label), s.close)
+	}
+
+	r := n.Node
+	fill := cssColor(geom.FillColor(n.Style, r.FillColor, r.Color))
+	wr.style(n.ID, n.Style, fill, cssColor(r.Color), cssColor(r.FontColor), n.Penwidth)
+	wr.class(n.ID, n.Class)
+	if n.URL != "" {
+		click := fmt.Sprintf("click %s href %s", n.ID, strconv.Quote(n.URL))
+		if n.Tooltip != "" {
+			click += " " + strconv.Quote(n.Tooltip)
+		}
+		wr.styles = append(wr.styles, click)
+	}
+}
+
+// Writes a cluster as a subgraph with its nodes, and its clusters.
+func (wr *writer) cluster(c *diagram.Cluster, depth int) {
+	indent := strings.Repeat("    ", depth)
+	id := wr.ids[c]
+	if c.Label != "" {
+		fmt.Fprintf(&wr.buf, "%ssubgraph %s [%s]\n", indent, id, quote(c.Label))
+	} else {
+		fmt.Fprintf(&wr.buf, "%ssubgraph %s\n", indent, id)
+	}
+	for _, n := range c.Nodes {
+		wr.node(n, indent+"    ")
+	}
+	for _, sub := range c.Clusters {
+		wr.cluster(sub, depth+1)
+	}
+	fmt.Fprintf(&wr.buf, "%send\n", indent)
+	sub := c.Subgraph
+	fill := cssColor(geom.FillColor(c.Style, sub.FillColor, sub.Color))
+	wr.style(id, c.Style, fill, cssColor(sub.Color), cssColor(sub.FontColor), 0)
+	wr.class(id, c.Class)
+}
+
+// Returns the link written between the endpoints of an edge.
+func linkText(e *diagram.Edge, directed bool) string {
+	if e.Style.Invisible {
+		return "~~~"
+	}
+	head, tail := e.HeadArrow, e.TailArrow
+	if tail && !head {
+		head, tail = directed, false
+	}
+	var start, end string
+	if head {
+		end = ">"
+		if mark, ok := marks[e.Arrowhead]; ok {
+			end = mark
+		}
+	}
+	if tail {
+		start = startMarks[end]
+	}
+	length := e.Minlen
+	switch {
+	case e.Style.Bold:
+		if end == "" {
+			return strings.Repeat("=", length+2)
+		}
+		return start + strings.Repeat("=", length+1) + end
+	case e.Style.Dashed || e.Style.Dotted:
+		return start + "-" + strings.Repeat(".", length) + "-" + end
+	case end == "":
+		return strings.Repeat("-", length+2)
+	}
+	return start + strings.Repeat("-", length+1) + end
+}
+
+func (Backend) Write(w io.Writer, d *diagram.Diagram) error {
+	wr := &writer{ids: make(map[*diagram.Cluster]string)}
+	g := d.Graph
+
+	var config []string
+	if d.Title != "" {
+		config = append(config, "title: "+strconv.Quote(d.Title))
+	}
+	var spacing []string
+	for _, s := range [][2]string{{"nodeSpacing", g.Nodesep}, {"rankSpacing", g.Ranksep}} {
+		if inches, err := strconv.ParseFloat(s[1], 64); err == nil {
+			spacing = append(spacing, fmt.Sprintf("    %s: %s", s[0], strconv.FormatFloat(inches*72, 'f', -1, 64)))
+		}
+	}
+	if len(spacing) > 0 {
+		config = append(config, "config:", "  flowchart:")
+		config = append(config, spacing...)
+	}
+	if len(config) > 0 {
+		fmt.Fprintf(&wr.buf, "---\n%s\n---\n", strings.Join(config, "\n"))
+	}
+	dir := d.Rankdir
+	if dir == "TB" {
+		dir = "TD"
+	}
+	fmt.Fprintf(&wr.buf, "flowchart %s\n", dir)
+
+	used := make(map[string]bool)
+	var name func(clusters []*diagram.Cluster)
+	name = func(clusters []*diagram.Cluster) {
+		for _, c := range clusters {
+			id := c.Name
+			if !identifier.MatchString(id) || nodeID.MatchString(id) || used[id] || id == "end" {
+				id = c.ID
+			}
+			used[id] = true
+			wr.ids[c] = id
+			name(c.Clusters)
+		}
+	}
+	name(d.Clusters)
+
+	// Each cluster is written where its first node would be, so that the
+	// order of the nodes is kept where it can be.
+	done := make(map[*diagram.Cluster]bool)
+	for _, n := range d.AllNodes {
+		if n.Parent == nil {
+			wr.node(n, "    ")
+			continue
+		}
+		top := n.Parent
+		for top.Parent != nil {
+			top = top.Parent
+		}
+		if !done[top] {
+			done[top] = true
+			wr.cluster(top, 1)
+		}
+	}
+	for _, c := range d.Clusters {
+		if !done[c] {
+			wr.cluster(c, 1)
+		}
+	}
+
+	for i, e := range d.Edges {
+		label := ""
+		if e.Label != "" {
+			label = "|" + quote(e.Label) + "|"
+		}
+		fmt.Fprintf(&wr.buf, "    %s %s%s %s\n", e.Tail.ID, linkText(e, d.Directed), label, e.Head.ID)
+
+		var parts []string
+		if css := cssColor(e.Edge.Color); css != "" {
+			parts = append(parts, "stroke:"+css)
+		}
+		if css := cssColor(e.Edge.FontColor); css != "" {
+			parts = append(parts, "color:"+css)
+		}
+		if e.Penwidth > 0 {
+			parts = append(parts, "stroke-width:"+strconv.FormatFloat(e.Penwidth, 'f', -1, 64)+"px")
+		}
+		if len(parts) > 0 {
+			wr.styles = append(wr.styles, fmt.Sprintf("linkStyle %d %s", i, strings.Join(parts, ",")))
+		}
+	}
+
+	for _, s := range wr.styles {
+		fmt.Fprintf(&wr.buf, "    %s\n", s)
+	}
+	_, err := w.Write(wr.buf.Bytes())
+	return err
+}
+
+// Writes "g" as a Mermaid flowchart with Backend, returning the attributes
+// which could not be written.  Nodes are given the ids of package diagram,
+// "n0", "n1" and so on, and a node is written in the first subgraph which
+// holds it, as Mermaid cannot express a node in several subgraphs.  Edges
+// without both endpoints are not written.
+func Encode(w io.Writer, g *builder.Graph) ([]Unrepresented, error) {
+	lost := unrepresented(g)
+	return lost, diagram.Write(w, g, Backend{})
+}
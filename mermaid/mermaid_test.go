@@ -0,0 +1,220 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mermaid
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/builder"
+
+// Returns the dot written for a graph.
+func dot(t *testing.T, g *builder.Graph) string {
+	var buf bytes.Buffer
+	if err := g.Build().Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+func parseDot(t *testing.T, src string) *builder.Graph {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return g
+}
+
+func TestEncode(t *testing.T) {
+	g := parseDot(t, `digraph {
+		label=T; rankdir=LR; nodesep=0.5;
+		a [label="A \"x\"", shape=circle, fillcolor=red, style=filled, pos="1,2!"];
+		b [shape=cylinder, URL="http://x", tooltip=tip];
+		subgraph cluster_0 { label=Outer; c; d [shape=diamond] }
+		a -> b [label=ab, color=blue];
+		c -> d [style=dashed, minlen=3];
+		d -> a [dir=none, style=bold];
+		a -> c [dir=back];
+	}`)
+	var buf bytes.Buffer
+	lost, err := Encode(&buf, g)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := `---
+title: "T"
+config:
+  flowchart:
+    nodeSpacing: 36
+---
+flowchart LR
+    n0(("A #quot;x#quot;"))
+    n1[("b")]
+    subgraph cluster_0 ["Outer"]
+        n2["c"]
+        n3{"d"}
+    end
+    n0 -->|"ab"| n1
+    n2 -...-> n3
+    n3 === n0
+    n0 --> n2
+    style n0 fill:#ff0000
+    click n1 href "http://x" "tip"
+    linkStyle 0 stroke:#0000ff
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Encode gave\n%s\nwanted\n%s", got, want)
+	}
+	var got []string
+	for _, u := range lost {
+		got = append(got, u.String())
+	}
+	if s := strings.Join(got, " "); s != `pos="1.000000,2.000000!" dir="back"` {
+		t.Errorf("Encode reported %s", s)
+	}
+}
+
+func TestEncodeTranslucent(t *testing.T) {
+	g := parseDot(t, `digraph {
+		a [style=filled, fillcolor="#ff000080", color="#00ff0040"];
+		subgraph cluster_0 { style=filled; color="#0000ff80"; b }
+		a -> b [color="#12345678"];
+	}`)
+	var buf bytes.Buffer
+	lost, err := Encode(&buf, g)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if len(lost) != 0 {
+		t.Errorf("Encode reported %v", lost)
+	}
+	for _, want := range []string{
+		"style n0 fill:#ff000080,stroke:#00ff0040",
+		"style cluster_0 fill:#0000ff80,stroke:#0000ff80",
+		"linkStyle 0 stroke:#12345678",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Encode gave\n%s\nwithout %s", buf.String(), want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `---
+title: Flow
+config:
+  flowchart:
+    rankSpacing: 72
+---
+graph LR
+  %% a comment
+  A[Start] --> B{Is it?} & C
+  B -- Yes --> C((ok)):::hot
+  B -.->|No| D>flag] ==> E[(db)]; C o--o D
+  D x---x E
+  F --- G
+  subgraph one ["First"]
+    G ~~~ H([stadium])
+  end
+  style A fill:#f9f,stroke:#333,stroke-width:4px
+  linkStyle 0 stroke:red
+  click A "http://a" "A tip"
+`
+	g, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := `digraph {
+
+	label="Flow"
+	rankdir="LR"
+	ranksep="1"
+
+	0 [color="#333333", fillcolor="#ff99ff", label="Start", penwidth="4", shape="box", style="filled", tooltip="A tip", URL="http://a"];
+	1 [label="Is it?", shape="diamond"];
+	2 [class="hot", label="ok", shape="circle"];
+	3 [label="flag", shape="cds"];
+	4 [label="db", shape="cylinder"];
+	5 [label="F"];
+	6 [label="G"];
+	7 [label="stadium", shape="ellipse"];
+
+	subgraph one {
+		label="First";
+		6;
+		7;
+	}
+
+	0 -> 1 [color="red"];
+	0 -> 2;
+	1 -> 2 [label="Yes"];
+	1 -> 3 [label="No", style="dashed"];
+	3 -> 4 [style="bold"];
+	2 -> 3 [arrowhead="dot", arrowtail="dot", dir="both"];
+	3 -> 4 [arrowhead="tee", arrowtail="tee", dir="both", minlen="2"];
+	5 -> 6 [dir="none"];
+	6 -> 7 [dir="none", style="invis"];
+}
+`
+	if got := dot(t, g); got != want {
+		t.Errorf("Parse gave\n%s\nwanted\n%s", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		`digraph {
+			label="A graph"; rankdir=BT;
+			a [label="A\nnode", shape=hexagon]; b [label=b, shape=box]; c [label=c, shape=ellipse];
+			subgraph outer { label=Outer; b; subgraph inner { label=Inner; c } }
+			a -> b [label=ab, color="#00ff00"]; b -> c [style=dashed, minlen=2]; c -> a [dir=none];
+		}`,
+		`graph { a [label=a, shape=box]; b [label=b, shape=box]; c [label=c, shape=circle]; a -- b -- c -- a [style=bold] }`,
+	} {
+		g := parseDot(t, src)
+		var buf bytes.Buffer
+		lost, err := Encode(&buf, g)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		if len(lost) != 0 {
+			t.Errorf("Encode reported %v", lost)
+		}
+		back, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Parse failed: %v\n%s", err, buf.String())
+		}
+		if want, got := dot(t, g), dot(t, back); got != want {
+			t.Errorf("Round trip through\n%s\ngave\n%s\nwanted\n%s", buf.String(), got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"sequenceDiagram\n  A->>B: hi\n",
+		"flowchart TD\n  end\n",
+		"flowchart TD\n  subgraph x\n  a\n",
+		"flowchart TD\n  a[text --> b\n",
+		"flowchart TD\n  a --> \n",
+		"flowchart TD\n  a --> b\n  linkStyle 3 stroke:red\n",
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("Parse of %q did not fail", src)
+		}
+	}
+}
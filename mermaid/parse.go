@@ -0,0 +1,735 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mermaid
+
+import "bufio"
+import "fmt"
+import "io"
+import "regexp"
+import "strconv"
+import "strings"
+import "unicode"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+
+// The ends of a link, by which Parse decides the direction of edges.
+type linkEnds struct {
+	edge       *builder.Edge
+	start, end string
+}
+
+type parser struct {
+	line  int
+	label string
+
+	nodes    map[string]*builder.Node
+	order    []*builder.Node
+	member   map[*builder.Node]bool
+	subs     map[string]*builder.Subgraph
+	top      []*builder.Subgraph
+	stack    []*builder.Subgraph
+	links    []linkEnds
+	styles   [][2]string
+	graph    *builder.Graph
+	nodesep  string
+	ranksep  string
+	rankdir  *attr.RankDir
+	template *builder.Edge
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("mermaid: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+var entity = regexp.MustCompile(`#([0-9]+|quot|amp|lt|gt|nbsp);`)
+var lineBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// Returns the text of a label, removing its quotes and replacing entities
+// and <br> with dot's "\n".
+func unquote(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	text = entity.ReplaceAllStringFunc(text, func(e string) string {
+		switch name := e[1 : len(e)-1]; name {
+		case "quot":
+			return `"`
+		case "amp":
+			return "&"
+		case "lt":
+			return "<"
+		case "gt":
+			return ">"
+		case "nbsp":
+			return " "
+		default:
+			n, _ := strconv.Atoi(name)
+			return string(rune(n))
+		}
+	})
+	return lineBreak.ReplaceAllString(text, `\n`)
+}
+
+func isIDRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Splits a line into statements at semicolons outside quotes.
+func statements(line string) []string {
+	var stmts []string
+	quoted := false
+	start := 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			stmts = append(stmts, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(stmts, line[start:])
+}
+
+// Reads a graph written in the Mermaid flowchart syntax: its direction,
+// nodes with their shapes and text, chains of links with "&", link labels,
+// lengths and styles, nested subgraphs, and the style, linkStyle, class and
+// click statements, and the title and node and rank spacing of the front
+// matter.  Other statements, such as classDef, are ignored.
+//
+// Nodes are labelled with their text, or their id if they have none.  The
+// graph is undirected if it has links, all of them without arrowheads, and
+// directed otherwise.  Circle and cross link ends become dot and tee
+// arrowheads, dotted links become dashed edges and thick ones bold edges.
+func Parse(r io.Reader) (*builder.Graph, error) {
+	p := &parser{
+		nodes:  make(map[string]*builder.Node),
+		member: make(map[*builder.Node]bool),
+		subs:   make(map[string]*builder.Subgraph),
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	header, frontMatter := false, false
+	for sc.Scan() {
+		p.line++
+		text := strings.TrimSpace(sc.Text())
+		if p.line == 1 && text == "---" {
+			frontMatter = true
+			continue
+		}
+		if frontMatter {
+			if text == "---" {
+				frontMatter = false
+			} else {
+				p.frontMatter(text)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "%%") {
+			continue
+		}
+		for _, stmt := range statements(text) {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" {
+				continue
+			}
+			if !header {
+				if err := p.header(stmt); err != nil {
+					return nil, err
+				}
+				header = true
+				continue
+			}
+			if err := p.statement(stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("mermaid: no flowchart")
+	}
+	if len(p.stack) > 0 {
+		return nil, p.errorf("subgraph %q has no end", p.stack[len(p.stack)-1].Name)
+	}
+	return p.build()
+}
+
+func (p *parser) frontMatter(text string) {
+	i := strings.IndexByte(text, ':')
+	if i < 0 {
+		return
+	}
+	key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+	if s, err := strconv.Unquote(value); err == nil {
+		value = s
+	} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = value[1 : len(value)-1]
+	}
+	inches := func() string {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatFloat(f/72, 'f', -1, 64)
+	}
+	switch key {
+	case "title":
+		p.label = value
+	case "nodeSpacing":
+		p.nodesep = inches()
+	case "rankSpacing":
+		p.ranksep = inches()
+	}
+}
+
+func (p *parser) header(stmt string) error {
+	fields := strings.Fields(stmt)
+	if fields[0] != "flowchart" && fields[0] != "graph" {
+		return p.errorf("expected a flowchart, not %q", stmt)
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "TB", "TD":
+		case "LR", "RL", "BT":
+			p.rankdir = attr.ParseRankDir(fields[1])
+		default:
+			return p.errorf("unknown direction %q", fields[1])
+		}
+	}
+	return nil
+}
+
+// Returns the first word of a statement and the rest.
+func keyword(stmt string) (string, string) {
+	i := strings.IndexFunc(stmt, unicode.IsSpace)
+	if i < 0 {
+		return stmt, ""
+	}
+	return stmt[:i], strings.TrimSpace(stmt[i:])
+}
+
+func (p *parser) statement(stmt string) error {
+	word, rest := keyword(stmt)
+	switch word {
+	case "subgraph":
+		return p.subgraph(rest)
+	case "end":
+		if len(p.stack) == 0 {
+			return p.errorf("end outside a subgraph")
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+		return nil
+	case "direction", "classDef":
+		return nil
+	case "style":
+		id, css := keyword(rest)
+		if sub, ok := p.subs[id]; ok {
+			return p.css(sub, css)
+		}
+		return p.css(p.node(id), css)
+	case "linkStyle":
+		indices, css := keyword(rest)
+		p.styles = append(p.styles, [2]string{indices, css})
+		return nil
+	case "class":
+		ids, classes := keyword(rest)
+		class := strings.Join(strings.Split(classes, ","), " ")
+		for _, id := range strings.Split(ids, ",") {
+			if sub, ok := p.subs[id]; ok {
+				sub.Class = class
+			} else {
+				p.node(id).Class = class
+			}
+		}
+		return nil
+	case "click":
+		return p.click(rest)
+	}
+	return p.chain(stmt)
+}
+
+// Starts a subgraph, "subgraph id", "subgraph id [title]" or
+// "subgraph title".
+func (p *parser) subgraph(rest string) error {
+	name, label := rest, ""
+	if i := strings.IndexByte(rest, '['); i >= 0 && strings.HasSuffix(rest, "]") {
+		name, label = strings.TrimSpace(rest[:i]), unquote(rest[i+1:len(rest)-1])
+	} else if strings.HasPrefix(rest, `"`) {
+		name, label = "", unquote(rest)
+	}
+	sub := builder.NewSubgraph(name)
+	sub.Label = label
+	if name != "" {
+		p.subs[name] = sub
+	}
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].AddSubgraphs(sub)
+	} else {
+		p.top = append(p.top, sub)
+	}
+	p.stack = append(p.stack, sub)
+	return nil
+}
+
+// Returns the node with an id, creating it, within the current subgraph, if
+// there is none.
+func (p *parser) node(id string) *builder.Node {
+	n, ok := p.nodes[id]
+	if !ok {
+		n = &builder.Node{Label: id}
+		p.nodes[id] = n
+		p.order = append(p.order, n)
+	}
+	if len(p.stack) > 0 && !p.member[n] {
+		p.stack[len(p.stack)-1].AddNodes(n)
+		p.member[n] = true
+	}
+	return n
+}
+
+// Returns a CSS color as a dot color.
+func cssToColor(value string) color.Color {
+	if len(value) == 4 && value[0] == '#' {
+		value = string([]byte{'#', value[1], value[1], value[2], value[2], value[3], value[3]})
+	}
+	return color.Parse(value)
+}
+
+func addStyle(style, name string) string {
+	if hasStyle(style, name) {
+		return style
+	}
+	if style == "" {
+		return name
+	}
+	return style + "," + name
+}
+
+// Applies the CSS properties of a style statement.
+func (p *parser) css(obj interface{}, css string) error {
+	for _, prop := range strings.Split(css, ",") {
+		i := strings.IndexByte(prop, ':')
+		if i < 0 {
+			return p.errorf("invalid style %q", prop)
+		}
+		name, value := strings.TrimSpace(prop[:i]), strings.TrimSpace(prop[i+1:])
+		width := strings.TrimSuffix(value, "px")
+		dash := "dashed"
+		if f := strings.Fields(value); len(f) > 0 && f[0] <= "1" {
+			dash = "dotted"
+		}
+		switch obj := obj.(type) {
+		case *builder.Node:
+			switch name {
+			case "fill":
+				obj.FillColor = cssToColor(value)
+				obj.Style = addStyle(obj.Style, "filled")
+			case "stroke":
+				obj.Color = cssToColor(value)
+			case "color":
+				obj.FontColor = cssToColor(value)
+			case "stroke-width":
+				obj.Penwidth = width
+			case "stroke-dasharray":
+				obj.Style = addStyle(obj.Style, dash)
+			}
+		case *builder.Subgraph:
+			switch name {
+			case "fill":
+				obj.FillColor = cssToColor(value)
+				obj.Style = addStyle(obj.Style, "filled")
+			case "stroke":
+				obj.Color = cssToColor(value)
+			case "color":
+				obj.FontColor = cssToColor(value)
+			case "stroke-dasharray":
+				obj.Style = addStyle(obj.Style, dash)
+			}
+		case *builder.Edge:
+			switch name {
+			case "stroke":
+				obj.Color = cssToColor(value)
+			case "color":
+				obj.FontColor = cssToColor(value)
+			case "stroke-width":
+				obj.Penwidth = width
+			}
+		}
+	}
+	return nil
+}
+
+// Reads a click statement, "click id [href] "url" ["tooltip"] [target]" or
+// "click id callback ["tooltip"]".
+func (p *parser) click(rest string) error {
+	id, rest := keyword(rest)
+	n := p.node(id)
+	var args []string
+	for rest != "" {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return p.errorf("unterminated string in click")
+			}
+			args = append(args, rest[:end+2])
+			rest = strings.TrimSpace(rest[end+2:])
+		} else {
+			var word string
+			word, rest = keyword(rest)
+			args = append(args, word)
+		}
+	}
+	if len(args) > 0 && args[0] == "href" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return p.errorf("click without an action")
+	}
+	quoted := func(s string) bool { return strings.HasPrefix(s, `"`) }
+	if quoted(args[0]) {
+		n.URL = unquote(args[0])
+	}
+	if len(args) > 1 && quoted(args[1]) {
+		n.Tooltip = unquote(args[1])
+	}
+	return nil
+}
+
+// A cursor over a statement.
+type scanner struct {
+	src string
+	pos int
+}
+
+func (s *scanner) space() {
+	for s.pos < len(s.src) && (s.src[s.pos] == ' ' || s.src[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+func (s *scanner) done() bool {
+	s.space()
+	return s.pos == len(s.src)
+}
+
+func (s *scanner) rest() string {
+	return s.src[s.pos:]
+}
+
+// Openers of node shapes, longest first so that "((" is not read as "(".
+var openers = []string{"(((", "([", "((", "[(", "[[", "[/", `[\`, "{{", "(", "[", "{", ">"}
+
+// The closers of each opener, of which the first found ends the text.
+var closers = map[string][]string{
+	"(((": {")))"},
+	"([":  {"])"},
+	"((":  {"))"},
+	"[(":  {")]"},
+	"[[":  {"]]"},
+	"[/":  {"/]", `\]`},
+	`[\`:  {`\]`, "/]"},
+	"{{":  {"}}"},
+	"(":   {")"},
+	"[":   {"]"},
+	"{":   {"}"},
+	">":   {"]"},
+}
+
+// Reads a node reference, an id with an optional shape and text and
+// ":::class".
+func (p *parser) nodeRef(s *scanner) (*builder.Node, error) {
+	s.space()
+	start := s.pos
+	for s.pos < len(s.src) {
+		r, size := rune(s.src[s.pos]), 1
+		if r >= 0x80 {
+			r, size = []rune(s.src[s.pos:])[0], len(string([]rune(s.src[s.pos:])[0]))
+		}
+		if !isIDRune(r) {
+			break
+		}
+		s.pos += size
+	}
+	if s.pos == start {
+		return nil, p.errorf("expected a node at %q", s.rest())
+	}
+	n := p.node(s.src[start:s.pos])
+
+	for _, open := range openers {
+		if !strings.HasPrefix(s.rest(), open) {
+			continue
+		}
+		s.pos += len(open)
+		text := s.rest()
+		end, close := -1, ""
+		if strings.HasPrefix(text, `"`) {
+			if q := strings.IndexByte(text[1:], '"'); q >= 0 {
+				for _, c := range closers[open] {
+					if strings.HasPrefix(text[q+2:], c) {
+						end, close = q+2, c
+						break
+					}
+				}
+			}
+		} else {
+			for _, c := range closers[open] {
+				if i := strings.Index(text, c); i >= 0 && (end < 0 || i < end) {
+					end, close = i, c
+				}
+			}
+		}
+		if end < 0 {
+			return nil, p.errorf("unterminated node text at %q", text)
+		}
+		n.Label = unquote(text[:end])
+		s.pos += end + len(close)
+		if open == "(" {
+			n.Shape = attr.Box
+			n.Style = addStyle(n.Style, "rounded")
+		} else {
+			for _, sh := range shapes {
+				if sh.open == open && sh.close == close {
+					n.Shape = attr.ParseNodeShape(sh.name)
+					break
+				}
+			}
+			if open == `[\` && close == `\]` {
+				n.Shape = attr.ParseNodeShape("parallelogram")
+			}
+		}
+		break
+	}
+
+	if strings.HasPrefix(s.rest(), ":::") {
+		s.pos += 3
+		start := s.pos
+		for s.pos < len(s.src) && (isIDRune(rune(s.src[s.pos])) || s.src[s.pos] == '-') {
+			s.pos++
+		}
+		n.Class = s.src[start:s.pos]
+	}
+	return n, nil
+}
+
+// Reads nodes separated by "&".
+func (p *parser) group(s *scanner) ([]*builder.Node, error) {
+	var nodes []*builder.Node
+	for {
+		n, err := p.nodeRef(s)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		s.space()
+		if !strings.HasPrefix(s.rest(), "&") {
+			return nodes, nil
+		}
+		s.pos++
+	}
+}
+
+// A link without text, and the start of a link with text, "-- text -->".
+var linkPattern = regexp.MustCompile(`^([<ox]?)(-{2,}|={2,}|-\.+-|~{3,})([>ox]?)`)
+var textStart = regexp.MustCompile(`^([<ox]?)(--|==|-\.)\s`)
+
+// The ends of links with text, by their starts.
+var textEnds = map[string]*regexp.Regexp{
+	"--": regexp.MustCompile(`\s(-{2,})([>ox])|\s(-{3,})()`),
+	"==": regexp.MustCompile(`\s(={2,})([>ox])|\s(={3,})()`),
+	"-.": regexp.MustCompile(`\s(\.+-)([>ox]?)`),
+}
+
+// A parsed link.
+type link struct {
+	start, end, label string
+	body              string
+	length            int
+}
+
+// Reads a link, with its label if any.
+func (p *parser) link(s *scanner) (*link, error) {
+	s.space()
+	l := &link{}
+	if m := textStart.FindStringSubmatch(s.rest()); m != nil {
+		rest := s.rest()[len(m[0]):]
+		loc := textEnds[m[2]].FindStringSubmatchIndex(rest)
+		if loc == nil {
+			return nil, p.errorf("unterminated link text at %q", rest)
+		}
+		l.start, l.label = m[1], unquote(rest[:loc[0]])
+		body, end := rest[loc[2]:loc[3]], rest[loc[4]:loc[5]]
+		if loc[2] < 0 {
+			body, end = rest[loc[6]:loc[7]], ""
+		}
+		l.end = end
+		switch m[2] {
+		case "-.":
+			l.body = "-.-"
+			l.length = len(body) - 1
+		case "==":
+			l.body = "=="
+			l.length = len(body) - 1
+		default:
+			l.body = "--"
+			l.length = len(body) - 1
+		}
+		if end == "" && m[2] != "-." {
+			l.length--
+		}
+		s.pos += len(m[0]) + loc[1]
+	} else if m := linkPattern.FindStringSubmatch(s.rest()); m != nil {
+		l.start, l.end = m[1], m[3]
+		body := m[2]
+		// "o" and "x" followed by an id begin the node, not end the link.
+		if (l.end == "o" || l.end == "x") && len(s.rest()) > len(m[0]) && isIDRune(rune(s.rest()[len(m[0])])) {
+			l.end = ""
+			m[0] = m[0][:len(m[0])-1]
+		}
+		switch {
+		case strings.HasPrefix(body, "~"):
+			l.body, l.length = "~~~", 1
+		case strings.HasPrefix(body, "-."):
+			l.body, l.length = "-.-", len(body)-2
+		case strings.HasPrefix(body, "="):
+			l.body, l.length = "==", len(body)-1
+		default:
+			l.body, l.length = "--", len(body)-1
+		}
+		if l.end == "" && l.body != "-.-" && l.body != "~~~" {
+			l.length--
+		}
+		if l.length < 1 {
+			return nil, p.errorf("invalid link %q", m[0])
+		}
+		s.pos += len(m[0])
+		s.space()
+		if strings.HasPrefix(s.rest(), "|") {
+			end := strings.IndexByte(s.rest()[1:], '|')
+			if end < 0 {
+				return nil, p.errorf("unterminated link text at %q", s.rest())
+			}
+			l.label = unquote(s.rest()[1 : end+1])
+			s.pos += end + 2
+		}
+	} else {
+		return nil, p.errorf("expected a link at %q", s.rest())
+	}
+	if l.length < 1 {
+		l.length = 1
+	}
+	return l, nil
+}
+
+// Reads a statement of nodes, and links between them.
+func (p *parser) chain(stmt string) error {
+	s := &scanner{src: stmt}
+	from, err := p.group(s)
+	if err != nil {
+		return err
+	}
+	for !s.done() {
+		l, err := p.link(s)
+		if err != nil {
+			return err
+		}
+		to, err := p.group(s)
+		if err != nil {
+			return err
+		}
+		for _, src := range from {
+			for _, dst := range to {
+				e := &builder.Edge{Src: src, Dst: dst, Label: l.label}
+				switch l.body {
+				case "-.-":
+					e.Style = "dashed"
+				case "==":
+					e.Style = "bold"
+				case "~~~":
+					e.Style = "invis"
+				}
+				if l.length > 1 {
+					e.Minlen = strconv.Itoa(l.length)
+				}
+				p.links = append(p.links, linkEnds{e, l.start, l.end})
+			}
+		}
+		from = to
+	}
+	return nil
+}
+
+// The dot arrowheads of Mermaid link ends.
+var arrowheads = map[string]string{">": "", "<": "", "o": "dot", "x": "tee"}
+
+// Builds the graph, once its kind is known.
+func (p *parser) build() (*builder.Graph, error) {
+	kind := attr.Undirected
+	for _, l := range p.links {
+		if l.end != "" || l.start != "" {
+			kind = attr.Directed
+		}
+	}
+	if len(p.links) == 0 {
+		kind = attr.Directed
+	}
+	g := builder.NewGraph(kind)
+	g.Label, g.Nodesep, g.Ranksep, g.Rankdir = p.label, p.nodesep, p.ranksep, p.rankdir
+	g.AddNodes(p.order...)
+	g.AddSubgraphs(p.top...)
+	for _, l := range p.links {
+		e := l.edge
+		if kind == attr.Directed {
+			switch {
+			case l.start != "" && l.end != "":
+				e.Dir = "both"
+				e.Arrowtail = arrowheads[l.start]
+			case l.end == "":
+				e.Dir = "none"
+			}
+			e.Arrowhead = arrowheads[l.end]
+		}
+		g.AddEdges(e)
+	}
+
+	edges := g.Edges()
+	for _, st := range p.styles {
+		if st[0] == "default" {
+			if g.EdgeTemplate() == nil {
+				g.SetEdgeTemplate(new(builder.Edge))
+			}
+			if err := p.css(g.EdgeTemplate(), st[1]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, index := range strings.Split(st[0], ",") {
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(edges) {
+				return nil, fmt.Errorf("mermaid: linkStyle of unknown link %q", index)
+			}
+			if err := p.css(edges[i], st[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import "math"
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// An edge leading from a node to one of its neighbours.
+type arc struct {
+	edge int
+	node int
+}
+
+// Returns the edges leaving "v", following edge direction in directed graphs.
+// Self-loops are omitted, since they never lie on a shortest path.
+func arcs(ix *adj.Index, v int) []arc {
+	var out []arc
+	for _, e := range ix.Out[v] {
+		if w := ix.Dst[e]; w != v {
+			out = append(out, arc{e, w})
+		}
+	}
+	if !ix.Directed {
+		for _, e := range ix.In[v] {
+			if w := ix.Src[e]; w != v {
+				out = append(out, arc{e, w})
+			}
+		}
+	}
+	return out
+}
+
+// Computes node and edge betweenness using Brandes' algorithm.
+func brandes(ix *adj.Index) ([]float64, []float64) {
+	n := ix.Len()
+	nodeBC := make([]float64, n)
+	edgeBC := make([]float64, len(ix.Edges))
+
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	delta := make([]float64, n)
+	preds := make([][]arc, n)
+	for s := 0; s < n; s++ {
+		for i := 0; i < n; i++ {
+			sigma[i], dist[i], delta[i], preds[i] = 0, -1, 0, preds[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		order := []int{s}
+		for i := 0; i < len(order); i++ {
+			v := order[i]
+			for _, a := range arcs(ix, v) {
+				w := a.node
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					order = append(order, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], arc{a.edge, v})
+				}
+			}
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, p := range preds[w] {
+				c := sigma[p.node] / sigma[w] * (1 + delta[w])
+				edgeBC[p.edge] += c
+				delta[p.node] += c
+			}
+			nodeBC[w] += delta[w]
+		}
+	}
+
+	// Every path in an undirected graph was counted from both of its ends.
+	if !ix.Directed {
+		for i := range nodeBC {
+			nodeBC[i] /= 2
+		}
+		for i := range edgeBC {
+			edgeBC[i] /= 2
+		}
+	}
+	return nodeBC, edgeBC
+}
+
+// Returns the betweenness centrality of each node: the number of shortest
+// paths between other pairs of nodes which pass through it, where pairs
+// joined by several shortest paths contribute fractionally.  Values are not
+// normalized.
+func Betweenness(g *builder.Graph) NodeMetric {
+	ix := adj.New(g)
+	bc, _ := brandes(ix)
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		m[n] = bc[v]
+	}
+	return m
+}
+
+// Returns the betweenness centrality of each edge: the number of shortest
+// paths between pairs of nodes which use it.  Edges with a nil endpoint are
+// omitted.
+func EdgeBetweenness(g *builder.Graph) EdgeMetric {
+	ix := adj.New(g)
+	_, bc := brandes(ix)
+	m := make(EdgeMetric, len(ix.Edges))
+	for e, edge := range ix.Edges {
+		m[edge] = bc[e]
+	}
+	return m
+}
+
+// Returns the closeness centrality of each node: the reciprocal of the mean
+// distance from the node to the nodes reachable from it, scaled by the
+// fraction of the graph which is reachable so that nodes in small components
+// do not score highly.  Nodes which reach nothing have a closeness of zero.
+func Closeness(g *builder.Graph) NodeMetric {
+	ix := adj.New(g)
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		dist, order := bfs(ix, v)
+		reached := float64(len(order) - 1)
+		total := 0
+		for _, w := range order {
+			total += dist[w]
+		}
+		if total == 0 {
+			m[n] = 0
+			continue
+		}
+		m[n] = reached / float64(total) * reached / float64(ix.Len()-1)
+	}
+	return m
+}
+
+// Returns the PageRank of each node, using the given damping factor (usually
+// 0.85).  Edges of undirected graphs are followed in both directions.  The
+// ranks sum to 1.  Nodes without outgoing edges distribute their rank evenly
+// over all nodes.
+func PageRank(g *builder.Graph, damping float64) NodeMetric {
+	const (
+		tolerance  = 1e-10
+		iterations = 100
+	)
+
+	ix := adj.New(g)
+	n := ix.Len()
+	m := make(NodeMetric, n)
+	if n == 0 {
+		return m
+	}
+
+	out := make([]float64, n)
+	for v := range ix.Nodes {
+		out[v] = float64(len(ix.Succ(v)))
+	}
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < iterations; iter++ {
+		dangling := 0.0
+		for v := range rank {
+			if out[v] == 0 {
+				dangling += rank[v]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for v := range next {
+			next[v] = base
+		}
+		for v := range ix.Nodes {
+			if out[v] == 0 {
+				continue
+			}
+			share := damping * rank[v] / out[v]
+			for _, w := range ix.Succ(v) {
+				next[w] += share
+			}
+		}
+
+		change := 0.0
+		for v := range rank {
+			change += math.Abs(next[v] - rank[v])
+		}
+		rank, next = next, rank
+		if change < tolerance*float64(n) {
+			break
+		}
+	}
+
+	for v, node := range ix.Nodes {
+		m[node] = rank[v]
+	}
+	return m
+}
@@ -0,0 +1,119 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import "math"
+import "strconv"
+
+import "godot/attr/color"
+
+// Returns the smallest and largest values of the metric.  Both are zero if
+// the metric is empty.
+func (m NodeMetric) Range() (float64, float64) {
+	values := make([]float64, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return valueRange(values)
+}
+
+// Returns the smallest and largest values of the metric.  Both are zero if
+// the metric is empty.
+func (m EdgeMetric) Range() (float64, float64) {
+	values := make([]float64, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return valueRange(values)
+}
+
+// Sets the Width and Height of each node in proportion to its value, from
+// "min" inches for the smallest value to "max" inches for the largest.  Nodes
+// grow to fit their labels unless the node's shape is fixed size.
+func (m NodeMetric) SetSize(min, max float64) {
+	lo, hi := m.Range()
+	for n, v := range m {
+		size := format(lerp(min, max, scale(v, lo, hi)))
+		n.Width, n.Height = size, size
+	}
+}
+
+// Sets the Penwidth of each node in proportion to its value, from "min"
+// points for the smallest value to "max" points for the largest.
+func (m NodeMetric) SetPenwidth(min, max float64) {
+	lo, hi := m.Range()
+	for n, v := range m {
+		n.Penwidth = format(lerp(min, max, scale(v, lo, hi)))
+	}
+}
+
+// Sets the FillColor of each node to its position on "s", the smallest value
+// taking the first color and the largest the last.  Fill colors are only
+// drawn for nodes with style=filled.
+func (m NodeMetric) SetFillColor(s color.Scale) {
+	lo, hi := m.Range()
+	for n, v := range m {
+		n.FillColor = s.At(scale(v, lo, hi))
+	}
+}
+
+// Sets the Penwidth of each edge in proportion to its value, from "min"
+// points for the smallest value to "max" points for the largest.
+func (m EdgeMetric) SetPenwidth(min, max float64) {
+	lo, hi := m.Range()
+	for e, v := range m {
+		e.Penwidth = format(lerp(min, max, scale(v, lo, hi)))
+	}
+}
+
+// Sets the Color of each edge to its position on "s", the smallest value
+// taking the first color and the largest the last.
+func (m EdgeMetric) SetColor(s color.Scale) {
+	lo, hi := m.Range()
+	for e, v := range m {
+		e.Color = s.At(scale(v, lo, hi))
+	}
+}
+
+func valueRange(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+// Maps "v" from the range [lo, hi] onto [0, 1].  If the range is empty every
+// value maps to 0.
+func scale(v, lo, hi float64) float64 {
+	if hi <= lo {
+		return 0
+	}
+	return (v - lo) / (hi - lo)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Formats "v" with at most three decimal places.
+func format(v float64) string {
+	return strconv.FormatFloat(math.Floor(v*1000+0.5)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,187 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package metrics computes structural measures of a builder.Graph, such as
+degree, betweenness and PageRank, and maps them onto visual attributes so
+that, for example, more important nodes are drawn larger or darker.
+
+Unless noted otherwise, measures follow edge direction in directed graphs and
+ignore it in undirected graphs.  Edges are unweighted, and parallel edges are
+counted separately.
+
+A short example, sizing nodes by PageRank and coloring them by betweenness:
+
+  metrics.PageRank(g, 0.85).SetSize(0.5, 2)
+  metrics.Betweenness(g).SetFillColor(color.Heat)
+*/
+package metrics
+
+import "godot/builder"
+import "godot/internal/adj"
+
+// A value for each node of a graph.
+type NodeMetric map[*builder.Node]float64
+
+// A value for each edge of a graph.
+type EdgeMetric map[*builder.Edge]float64
+
+// Returns the number of edges entering each node.  For undirected graphs this
+// is the same as Degree.
+func InDegree(g *builder.Graph) NodeMetric {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return degree(ix)
+	}
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		m[n] = float64(len(ix.In[v]))
+	}
+	return m
+}
+
+// Returns the number of edges leaving each node.  For undirected graphs this
+// is the same as Degree.
+func OutDegree(g *builder.Graph) NodeMetric {
+	ix := adj.New(g)
+	if !ix.Directed {
+		return degree(ix)
+	}
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		m[n] = float64(len(ix.Out[v]))
+	}
+	return m
+}
+
+// Returns the number of edges incident to each node, regardless of direction.
+// Self-loops count twice.
+func Degree(g *builder.Graph) NodeMetric {
+	return degree(adj.New(g))
+}
+
+func degree(ix *adj.Index) NodeMetric {
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		m[n] = float64(len(ix.In[v]) + len(ix.Out[v]))
+	}
+	return m
+}
+
+// Returns the local clustering coefficient of each node: the fraction of
+// pairs of its neighbours which are themselves adjacent.  Edge direction,
+// self-loops and parallel edges are ignored.  Nodes with fewer than two
+// neighbours have a coefficient of zero.
+func Clustering(g *builder.Graph) NodeMetric {
+	ix := adj.New(g)
+	linked := make(map[[2]int]bool)
+	for e := range ix.Edges {
+		linked[pair(ix.Src[e], ix.Dst[e])] = true
+	}
+
+	m := make(NodeMetric, ix.Len())
+	for v, n := range ix.Nodes {
+		nbrs := ix.Neighbors(v)
+		k := len(nbrs)
+		if k < 2 {
+			m[n] = 0
+			continue
+		}
+		links := 0
+		for i := 0; i < k; i++ {
+			for j := i + 1; j < k; j++ {
+				if linked[pair(nbrs[i], nbrs[j])] {
+					links++
+				}
+			}
+		}
+		m[n] = 2 * float64(links) / float64(k*(k-1))
+	}
+	return m
+}
+
+// Returns the ratio of the number of pairs of adjacent nodes to the number of
+// possible pairs.  In directed graphs pairs are ordered.  Self-loops and
+// parallel edges are ignored.  Graphs with fewer than two nodes have a
+// density of zero.
+func Density(g *builder.Graph) float64 {
+	ix := adj.New(g)
+	n := float64(ix.Len())
+	if n < 2 {
+		return 0
+	}
+	pairs := make(map[[2]int]bool)
+	for e := range ix.Edges {
+		s, d := ix.Src[e], ix.Dst[e]
+		if s == d {
+			continue
+		}
+		if ix.Directed {
+			pairs[[2]int{s, d}] = true
+		} else {
+			pairs[pair(s, d)] = true
+		}
+	}
+	possible := n * (n - 1)
+	if !ix.Directed {
+		possible /= 2
+	}
+	return float64(len(pairs)) / possible
+}
+
+// Returns the length of the longest shortest path between any two nodes.
+// Pairs of nodes with no path between them are ignored, so the diameter of a
+// disconnected graph is that of its widest component.
+func Diameter(g *builder.Graph) int {
+	ix := adj.New(g)
+	diameter := 0
+	for s := range ix.Nodes {
+		dist, order := bfs(ix, s)
+		if last := order[len(order)-1]; dist[last] > diameter {
+			diameter = dist[last]
+		}
+	}
+	return diameter
+}
+
+// Returns a key for an unordered pair of nodes.
+func pair(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// Finds the length of the shortest path from "s" to every node, following
+// edge direction in directed graphs.  Unreachable nodes have a distance of -1.
+// Also returns the reachable nodes in order of distance, starting with "s".
+func bfs(ix *adj.Index, s int) ([]int, []int) {
+	dist := make([]int, ix.Len())
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[s] = 0
+	order := []int{s}
+	for i := 0; i < len(order); i++ {
+		v := order[i]
+		for _, w := range ix.Succ(v) {
+			if dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				order = append(order, w)
+			}
+		}
+	}
+	return dist, order
+}
@@ -0,0 +1,135 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import "math"
+import "testing"
+
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+
+// Returns a path of "n" nodes.
+func path(kind *attr.GraphKind, n int) (*builder.Graph, []*builder.Node) {
+	nodes := builder.GenNodes(n)
+	g := builder.NewGraph(kind)
+	g.AddNodes(nodes...)
+	for i := 1; i < n; i++ {
+		g.AddEdges(&builder.Edge{Src: nodes[i-1], Dst: nodes[i]})
+	}
+	return g, nodes
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestDegree(t *testing.T) {
+	g, nodes := path(attr.Directed, 3)
+
+	if v := InDegree(g)[nodes[0]]; v != 0 {
+		t.Errorf("InDegree should be 0, but is %v.", v)
+	}
+	if v := OutDegree(g)[nodes[0]]; v != 1 {
+		t.Errorf("OutDegree should be 1, but is %v.", v)
+	}
+	if v := Degree(g)[nodes[1]]; v != 2 {
+		t.Errorf("Degree should be 2, but is %v.", v)
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	g, nodes := path(attr.Undirected, 4)
+
+	bc := Betweenness(g)
+	for i, want := range []float64{0, 2, 2, 0} {
+		if !near(bc[nodes[i]], want) {
+			t.Errorf("Betweenness of node %d should be %v, but is %v.", i, want, bc[nodes[i]])
+		}
+	}
+
+	ebc := EdgeBetweenness(g)
+	for i, e := range g.Edges() {
+		want := []float64{3, 4, 3}[i]
+		if !near(ebc[e], want) {
+			t.Errorf("Betweenness of edge %d should be %v, but is %v.", i, want, ebc[e])
+		}
+	}
+}
+
+func TestCloseness(t *testing.T) {
+	g, nodes := path(attr.Undirected, 3)
+
+	c := Closeness(g)
+	if !near(c[nodes[1]], 1) {
+		t.Errorf("Closeness of center should be 1, but is %v.", c[nodes[1]])
+	}
+	if !near(c[nodes[0]], 2.0/3) {
+		t.Errorf("Closeness of end should be 2/3, but is %v.", c[nodes[0]])
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	g, nodes := path(attr.Directed, 3)
+	g.AddEdges(&builder.Edge{Src: nodes[2], Dst: nodes[0]})
+
+	pr := PageRank(g, 0.85)
+	for _, n := range nodes {
+		if !near(pr[n], 1.0/3) {
+			t.Errorf("PageRank of a cycle should be uniform, but is %v.", pr[n])
+		}
+	}
+}
+
+func TestClusteringDensityDiameter(t *testing.T) {
+	g, nodes := path(attr.Undirected, 3)
+
+	if d := Diameter(g); d != 2 {
+		t.Errorf("Diameter should be 2, but is %d.", d)
+	}
+	if d := Density(g); !near(d, 2.0/3) {
+		t.Errorf("Density should be 2/3, but is %v.", d)
+	}
+	if c := Clustering(g)[nodes[1]]; c != 0 {
+		t.Errorf("Clustering should be 0, but is %v.", c)
+	}
+
+	g.AddEdges(&builder.Edge{Src: nodes[2], Dst: nodes[0]})
+	if c := Clustering(g)[nodes[1]]; c != 1 {
+		t.Errorf("Clustering should be 1, but is %v.", c)
+	}
+	if d := Density(g); d != 1 {
+		t.Errorf("Density should be 1, but is %v.", d)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	g, nodes := path(attr.Undirected, 3)
+
+	m := Degree(g)
+	m.SetSize(1, 2)
+	m.SetFillColor(color.Grays)
+
+	if nodes[0].Width != "1" || nodes[1].Height != "2" {
+		t.Errorf("Sizes are incorrect: %q, %q.", nodes[0].Width, nodes[1].Height)
+	}
+	if nodes[0].FillColor != (color.RGB{R: 0xff, G: 0xff, B: 0xff}) {
+		t.Errorf("Fill color is incorrect: %v.", nodes[0].FillColor)
+	}
+	if nodes[1].FillColor.String() != "#000000" {
+		t.Errorf("Fill color is incorrect: %v.", nodes[1].FillColor)
+	}
+}
@@ -0,0 +1,1010 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package raster
+
+import "strings"
+
+// Size of a glyph of the bitmap font, in font units.  A glyph's nine rows of
+// five pixels are drawn in a cell six units wide, the seventh row resting on
+// the baseline and the last two holding descenders.  A font unit is a tenth
+// of the font size, so that text is as wide as layout.TextWidth estimates.
+const (
+	glyphWidth   = 5
+	glyphHeight  = 9
+	glyphAscent  = 7
+	glyphAdvance = 6
+)
+
+// Glyphs of the printable ASCII characters, from ' ' to '~', "#" marking ink.
+// Glyphs are separated by blank lines.
+const glyphArt = `
+.....
+.....
+.....
+.....
+.....
+.....
+.....
+.....
+.....
+
+..#..
+..#..
+..#..
+..#..
+..#..
+.....
+..#..
+.....
+.....
+
+.#.#.
+.#.#.
+.#.#.
+.....
+.....
+.....
+.....
+.....
+.....
+
+.#.#.
+.#.#.
+#####
+.#.#.
+#####
+.#.#.
+.#.#.
+.....
+.....
+
+..#..
+.####
+#.#..
+.###.
+..#.#
+####.
+..#..
+.....
+.....
+
+##...
+##..#
+...#.
+..#..
+.#...
+#..##
+...##
+.....
+.....
+
+.##..
+#..#.
+#.#..
+.#...
+#.#.#
+#..#.
+.##.#
+.....
+.....
+
+..#..
+..#..
+.#...
+.....
+.....
+.....
+.....
+.....
+.....
+
+...#.
+..#..
+.#...
+.#...
+.#...
+..#..
+...#.
+.....
+.....
+
+.#...
+..#..
+...#.
+...#.
+...#.
+..#..
+.#...
+.....
+.....
+
+.....
+..#..
+#.#.#
+.###.
+#.#.#
+..#..
+.....
+.....
+.....
+
+.....
+..#..
+..#..
+#####
+..#..
+..#..
+.....
+.....
+.....
+
+.....
+.....
+.....
+.....
+.....
+.##..
+.##..
+..#..
+.#...
+
+.....
+.....
+.....
+#####
+.....
+.....
+.....
+.....
+.....
+
+.....
+.....
+.....
+.....
+.....
+.##..
+.##..
+.....
+.....
+
+.....
+....#
+...#.
+..#..
+.#...
+#....
+.....
+.....
+.....
+
+.###.
+#...#
+#..##
+#.#.#
+##..#
+#...#
+.###.
+.....
+.....
+
+..#..
+.##..
+..#..
+..#..
+..#..
+..#..
+.###.
+.....
+.....
+
+.###.
+#...#
+....#
+...#.
+..#..
+.#...
+#####
+.....
+.....
+
+#####
+...#.
+..#..
+...#.
+....#
+#...#
+.###.
+.....
+.....
+
+...#.
+..##.
+.#.#.
+#..#.
+#####
+...#.
+...#.
+.....
+.....
+
+#####
+#....
+####.
+....#
+....#
+#...#
+.###.
+.....
+.....
+
+..##.
+.#...
+#....
+####.
+#...#
+#...#
+.###.
+.....
+.....
+
+#####
+....#
+...#.
+..#..
+.#...
+.#...
+.#...
+.....
+.....
+
+.###.
+#...#
+#...#
+.###.
+#...#
+#...#
+.###.
+.....
+.....
+
+.###.
+#...#
+#...#
+.####
+....#
+...#.
+.##..
+.....
+.....
+
+.....
+.##..
+.##..
+.....
+.##..
+.##..
+.....
+.....
+.....
+
+.....
+.##..
+.##..
+.....
+.##..
+.##..
+..#..
+.#...
+.....
+
+...#.
+..#..
+.#...
+#....
+.#...
+..#..
+...#.
+.....
+.....
+
+.....
+.....
+#####
+.....
+#####
+.....
+.....
+.....
+.....
+
+.#...
+..#..
+...#.
+....#
+...#.
+..#..
+.#...
+.....
+.....
+
+.###.
+#...#
+....#
+...#.
+..#..
+.....
+..#..
+.....
+.....
+
+.###.
+#...#
+....#
+.##.#
+#.#.#
+#.#.#
+.###.
+.....
+.....
+
+.###.
+#...#
+#...#
+#####
+#...#
+#...#
+#...#
+.....
+.....
+
+####.
+#...#
+#...#
+####.
+#...#
+#...#
+####.
+.....
+.....
+
+.###.
+#...#
+#....
+#....
+#....
+#...#
+.###.
+.....
+.....
+
+###..
+#..#.
+#...#
+#...#
+#...#
+#..#.
+###..
+.....
+.....
+
+#####
+#....
+#....
+####.
+#....
+#....
+#####
+.....
+.....
+
+#####
+#....
+#....
+####.
+#....
+#....
+#....
+.....
+.....
+
+.###.
+#...#
+#....
+#.###
+#...#
+#...#
+.####
+.....
+.....
+
+#...#
+#...#
+#...#
+#####
+#...#
+#...#
+#...#
+.....
+.....
+
+.###.
+..#..
+..#..
+..#..
+..#..
+..#..
+.###.
+.....
+.....
+
+..###
+...#.
+...#.
+...#.
+...#.
+#..#.
+.##..
+.....
+.....
+
+#...#
+#..#.
+#.#..
+##...
+#.#..
+#..#.
+#...#
+.....
+.....
+
+#....
+#....
+#....
+#....
+#....
+#....
+#####
+.....
+.....
+
+#...#
+##.##
+#.#.#
+#.#.#
+#...#
+#...#
+#...#
+.....
+.....
+
+#...#
+#...#
+##..#
+#.#.#
+#..##
+#...#
+#...#
+.....
+.....
+
+.###.
+#...#
+#...#
+#...#
+#...#
+#...#
+.###.
+.....
+.....
+
+####.
+#...#
+#...#
+####.
+#....
+#....
+#....
+.....
+.....
+
+.###.
+#...#
+#...#
+#...#
+#.#.#
+#..#.
+.##.#
+.....
+.....
+
+####.
+#...#
+#...#
+####.
+#.#..
+#..#.
+#...#
+.....
+.....
+
+.####
+#....
+#....
+.###.
+....#
+....#
+####.
+.....
+.....
+
+#####
+..#..
+..#..
+..#..
+..#..
+..#..
+..#..
+.....
+.....
+
+#...#
+#...#
+#...#
+#...#
+#...#
+#...#
+.###.
+.....
+.....
+
+#...#
+#...#
+#...#
+#...#
+#...#
+.#.#.
+..#..
+.....
+.....
+
+#...#
+#...#
+#...#
+#.#.#
+#.#.#
+#.#.#
+.#.#.
+.....
+.....
+
+#...#
+#...#
+.#.#.
+..#..
+.#.#.
+#...#
+#...#
+.....
+.....
+
+#...#
+#...#
+#...#
+.#.#.
+..#..
+..#..
+..#..
+.....
+.....
+
+#####
+....#
+...#.
+..#..
+.#...
+#....
+#####
+.....
+.....
+
+.###.
+.#...
+.#...
+.#...
+.#...
+.#...
+.###.
+.....
+.....
+
+.....
+#....
+.#...
+..#..
+...#.
+....#
+.....
+.....
+.....
+
+.###.
+...#.
+...#.
+...#.
+...#.
+...#.
+.###.
+.....
+.....
+
+..#..
+.#.#.
+#...#
+.....
+.....
+.....
+.....
+.....
+.....
+
+.....
+.....
+.....
+.....
+.....
+.....
+.....
+#####
+.....
+
+.#...
+..#..
+...#.
+.....
+.....
+.....
+.....
+.....
+.....
+
+.....
+.....
+.###.
+....#
+.####
+#...#
+.####
+.....
+.....
+
+#....
+#....
+#.##.
+##..#
+#...#
+#...#
+####.
+.....
+.....
+
+.....
+.....
+.###.
+#....
+#....
+#...#
+.###.
+.....
+.....
+
+....#
+....#
+.##.#
+#..##
+#...#
+#...#
+.####
+.....
+.....
+
+.....
+.....
+.###.
+#...#
+#####
+#....
+.###.
+.....
+.....
+
+..##.
+.#..#
+.#...
+###..
+.#...
+.#...
+.#...
+.....
+.....
+
+.....
+.....
+.####
+#...#
+#...#
+.####
+....#
+....#
+.###.
+
+#....
+#....
+#.##.
+##..#
+#...#
+#...#
+#...#
+.....
+.....
+
+..#..
+.....
+.##..
+..#..
+..#..
+..#..
+.###.
+.....
+.....
+
+...#.
+.....
+..##.
+...#.
+...#.
+...#.
+...#.
+#..#.
+.##..
+
+#....
+#....
+#..#.
+#.#..
+##...
+#.#..
+#..#.
+.....
+.....
+
+.##..
+..#..
+..#..
+..#..
+..#..
+..#..
+.###.
+.....
+.....
+
+.....
+.....
+##.#.
+#.#.#
+#.#.#
+#...#
+#...#
+.....
+.....
+
+.....
+.....
+#.##.
+##..#
+#...#
+#...#
+#...#
+.....
+.....
+
+.....
+.....
+.###.
+#...#
+#...#
+#...#
+.###.
+.....
+.....
+
+.....
+.....
+####.
+#...#
+#...#
+####.
+#....
+#....
+#....
+
+.....
+.....
+.####
+#...#
+#...#
+.####
+....#
+....#
+....#
+
+.....
+.....
+#.##.
+##..#
+#....
+#....
+#....
+.....
+.....
+
+.....
+.....
+.####
+#....
+.###.
+....#
+####.
+.....
+.....
+
+.#...
+.#...
+###..
+.#...
+.#...
+.#..#
+..##.
+.....
+.....
+
+.....
+.....
+#...#
+#...#
+#...#
+#..##
+.##.#
+.....
+.....
+
+.....
+.....
+#...#
+#...#
+#...#
+.#.#.
+..#..
+.....
+.....
+
+.....
+.....
+#...#
+#...#
+#.#.#
+#.#.#
+.#.#.
+.....
+.....
+
+.....
+.....
+#...#
+.#.#.
+..#..
+.#.#.
+#...#
+.....
+.....
+
+.....
+.....
+#...#
+#...#
+#...#
+.####
+....#
+....#
+.###.
+
+.....
+.....
+#####
+...#.
+..#..
+.#...
+#####
+.....
+.....
+
+...##
+..#..
+..#..
+.#...
+..#..
+..#..
+...##
+.....
+.....
+
+..#..
+..#..
+..#..
+..#..
+..#..
+..#..
+..#..
+.....
+.....
+
+##...
+..#..
+..#..
+...#.
+..#..
+..#..
+##...
+.....
+.....
+
+.....
+.....
+.#...
+#.#.#
+...#.
+.....
+.....
+.....
+.....`
+
+// Rows of each glyph, as bit masks with the leftmost pixel in bit 4.
+var glyphs = parseGlyphs(glyphArt)
+
+func parseGlyphs(art string) [][glyphHeight]uint8 {
+	var result [][glyphHeight]uint8
+	for _, block := range strings.Split(strings.TrimSpace(art), "\n\n") {
+		var g [glyphHeight]uint8
+		for i, row := range strings.Split(block, "\n") {
+			for _, c := range row {
+				g[i] <<= 1
+				if c == '#' {
+					g[i] |= 1
+				}
+			}
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+// Returns the glyph for "r", or that of '?' if the font has none.
+func glyph(r rune) [glyphHeight]uint8 {
+	if r < ' ' || int(r-' ') >= len(glyphs) {
+		r = '?'
+	}
+	return glyphs[r-' ']
+}
@@ -0,0 +1,331 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package raster
+
+import "image"
+import "image/color"
+import "math"
+
+// A point in pixels, with y increasing downwards.
+type vec struct {
+	x, y float64
+}
+
+// An anti-aliasing coverage mask over part of an image, built by
+// accumulating the signed area each edge of a path covers in each pixel, as
+// in the font-rs rasterizer.  Overlapping paths of the same orientation
+// combine to full coverage, so a shape may be drawn as several polygons
+// provided they all wind the same way.
+type mask struct {
+	bounds image.Rectangle // pixels covered, within the image
+	w, h   int
+	acc    []float32
+}
+
+// Returns a mask for polygons within "box", clipped to "clip".  Returns nil
+// if they do not overlap.
+func newMask(box, clip image.Rectangle) *mask {
+	b := box.Intersect(clip)
+	if b.Empty() {
+		return nil
+	}
+	m := &mask{bounds: b, w: b.Dx() + 2, h: b.Dy()}
+	m.acc = make([]float32, m.w*m.h+2)
+	return m
+}
+
+// Returns the pixel bounds of a set of polygons.
+func boundsOf(polys [][]vec) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range polys {
+		for _, p := range poly {
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+		}
+	}
+	if minX > maxX {
+		return image.Rectangle{}
+	}
+	clampInt := func(v float64) int {
+		return int(math.Max(math.Min(v, 1<<24), -(1 << 24)))
+	}
+	return image.Rect(clampInt(math.Floor(minX)), clampInt(math.Floor(minY)),
+		clampInt(math.Ceil(maxX))+1, clampInt(math.Ceil(maxY))+1)
+}
+
+// Adds a closed polygon to the mask.
+func (m *mask) polygon(points []vec) {
+	for i := range points {
+		m.line(points[i], points[(i+1)%len(points)])
+	}
+}
+
+// Accumulates the area to the right of the edge from "p0" to "p1" in each
+// pixel it crosses.
+func (m *mask) line(p0, p1 vec) {
+	// Move into the mask's coordinates, clamping x so that area left or
+	// right of the mask collects at its edges.
+	ox, oy := float64(m.bounds.Min.X), float64(m.bounds.Min.Y)
+	maxX := float64(m.w) - 1.001
+	x0 := math.Max(0, math.Min(p0.x-ox, maxX))
+	y0 := p0.y - oy
+	x1 := math.Max(0, math.Min(p1.x-ox, maxX))
+	y1 := p1.y - oy
+	if y0 == y1 {
+		return
+	}
+	dir := float32(1)
+	if y0 > y1 {
+		dir = -1
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	dxdy := (x1 - x0) / (y1 - y0)
+	x := x0
+	start := int(math.Max(0, math.Floor(y0)))
+	if y0 < 0 {
+		x -= y0 * dxdy
+	}
+	end := int(math.Min(float64(m.h), math.Ceil(y1)))
+	for y := start; y < end; y++ {
+		row := y * m.w
+		dy := math.Min(float64(y+1), y1) - math.Max(float64(y), y0)
+		xnext := x + dxdy*dy
+		d := float32(dy) * dir
+		lo, hi := x, xnext
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		loFloor := math.Floor(lo)
+		loi := int(loFloor)
+		hiCeil := math.Ceil(hi)
+		hii := int(hiCeil)
+		if hii <= loi+1 {
+			xmf := float32(0.5*(x+xnext) - loFloor)
+			m.acc[row+loi] += d - d*xmf
+			m.acc[row+loi+1] += d * xmf
+		} else {
+			s := 1 / (hi - lo)
+			lof := lo - loFloor
+			a0 := float32(0.5 * s * (1 - lof) * (1 - lof))
+			hif := hi - hiCeil + 1
+			am := float32(0.5 * s * hif * hif)
+			m.acc[row+loi] += d * a0
+			if hii == loi+2 {
+				m.acc[row+loi+1] += d * (1 - a0 - am)
+			} else {
+				a1 := float32(s * (1.5 - lof))
+				m.acc[row+loi+1] += d * (a1 - a0)
+				for xi := loi + 2; xi < hii-1; xi++ {
+					m.acc[row+xi] += d * float32(s)
+				}
+				a2 := a1 + float32(hii-loi-3)*float32(s)
+				m.acc[row+hii-1] += d * (1 - a2 - am)
+			}
+			m.acc[row+hii] += d * am
+		}
+		x = xnext
+	}
+}
+
+// Blends "c" into "img" in proportion to the coverage of each pixel.
+func (m *mask) fill(img *image.RGBA, c color.NRGBA) {
+	if c.A == 0 {
+		return
+	}
+	var acc float32
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			acc += m.acc[y*m.w+x]
+			cover := acc
+			if cover < 0 {
+				cover = -cover
+			}
+			if cover > 1 {
+				cover = 1
+			}
+			px, py := m.bounds.Min.X+x, m.bounds.Min.Y+y
+			if cover < 1.0/512 || px >= m.bounds.Max.X {
+				continue
+			}
+			blend(img, px, py, c, cover)
+		}
+	}
+}
+
+// Blends "c" over the pixel at (x, y) with the given coverage.
+func blend(img *image.RGBA, x, y int, c color.NRGBA, cover float32) {
+	i := img.PixOffset(x, y)
+	a := float32(c.A) / 255 * cover
+	pix := img.Pix[i : i+4 : i+4]
+	pix[0] = uint8(float32(c.R)*a + float32(pix[0])*(1-a) + 0.5)
+	pix[1] = uint8(float32(c.G)*a + float32(pix[1])*(1-a) + 0.5)
+	pix[2] = uint8(float32(c.B)*a + float32(pix[2])*(1-a) + 0.5)
+	pix[3] = uint8(255*a + float32(pix[3])*(1-a) + 0.5)
+}
+
+// Rasterizes polygons, all wound the same way, and blends "c" into "img"
+// where they cover it.
+func fillPolygons(img *image.RGBA, polys [][]vec, c color.NRGBA) {
+	m := newMask(boundsOf(polys), img.Bounds())
+	if m == nil {
+		return
+	}
+	for _, p := range polys {
+		m.polygon(p)
+	}
+	m.fill(img, c)
+}
+
+// Returns the polygon wound anticlockwise on screen, reversing it if need
+// be.
+func wound(points []vec) []vec {
+	area := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		area += p.x*q.y - q.x*p.y
+	}
+	if area >= 0 {
+		return points
+	}
+	r := make([]vec, len(points))
+	for i, p := range points {
+		r[len(points)-1-i] = p
+	}
+	return r
+}
+
+// Returns the polygons covering a line of the given width along "points",
+// with round joins and ends, broken into dashes if "dashes" is not nil.
+func strokePolygons(points []vec, closed bool, width float64, dashes []float64) [][]vec {
+	if closed && len(points) > 0 {
+		points = append(append([]vec(nil), points...), points[0])
+	}
+	var polys [][]vec
+	for _, run := range dash(points, dashes) {
+		for i := 1; i < len(run); i++ {
+			if quad := segment(run[i-1], run[i], width/2); quad != nil {
+				polys = append(polys, quad)
+			}
+		}
+		for _, p := range run {
+			polys = append(polys, circle(p, width/2, 8))
+		}
+	}
+	return polys
+}
+
+// Returns the rectangle of half width "hw" around the segment from "a" to
+// "b", or nil if they coincide.
+func segment(a, b vec, hw float64) []vec {
+	dx, dy := b.x-a.x, b.y-a.y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return nil
+	}
+	nx, ny := -dy/d*hw, dx/d*hw
+	return wound([]vec{{a.x + nx, a.y + ny}, {b.x + nx, b.y + ny}, {b.x - nx, b.y - ny}, {a.x - nx, a.y - ny}})
+}
+
+// Returns a polygon approximating a circle, with at least "min" sides.
+func circle(c vec, r float64, min int) []vec {
+	return ellipse(c, r, r, min)
+}
+
+// Returns a polygon approximating an ellipse, with sides of a pixel or two.
+func ellipse(c vec, rx, ry float64, min int) []vec {
+	n := int(math.Ceil(math.Max(rx, ry) * 1.5))
+	if n < min {
+		n = min
+	}
+	if n > 256 {
+		n = 256
+	}
+	points := make([]vec, n)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = vec{c.x + rx*math.Cos(a), c.y + ry*math.Sin(a)}
+	}
+	return wound(points)
+}
+
+// Splits a polyline into the runs drawn by a dash pattern.  Returns the
+// whole line if the pattern is nil.
+func dash(points []vec, pattern []float64) [][]vec {
+	if pattern == nil || len(points) < 2 {
+		return [][]vec{points}
+	}
+	var runs [][]vec
+	var run []vec
+	i, left, on := 0, pattern[0], true
+	if on {
+		run = []vec{points[0]}
+	}
+	for k := 1; k < len(points); k++ {
+		a, b := points[k-1], points[k]
+		d := math.Hypot(b.x-a.x, b.y-a.y)
+		pos := 0.0
+		for d-pos > left {
+			pos += left
+			t := pos / d
+			p := vec{a.x + t*(b.x-a.x), a.y + t*(b.y-a.y)}
+			if on {
+				runs = append(runs, append(run, p))
+				run = nil
+			} else {
+				run = []vec{p}
+			}
+			on = !on
+			i = (i + 1) % len(pattern)
+			left = pattern[i]
+		}
+		left -= d - pos
+		if on {
+			run = append(run, b)
+		}
+	}
+	if on && len(run) > 1 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// Returns the points of a cubic Bezier spline, in the form used by
+// layout.EdgeLayout, flattened into a polyline.
+func flatten(spline []vec) []vec {
+	if len(spline) == 0 {
+		return nil
+	}
+	points := []vec{spline[0]}
+	for i := 0; i+3 < len(spline); i += 3 {
+		p0, p1, p2, p3 := spline[i], spline[i+1], spline[i+2], spline[i+3]
+		length := math.Hypot(p1.x-p0.x, p1.y-p0.y) + math.Hypot(p2.x-p1.x, p2.y-p1.y) + math.Hypot(p3.x-p2.x, p3.y-p2.y)
+		steps := int(math.Ceil(length / 4))
+		if steps < 1 {
+			steps = 1
+		}
+		for s := 1; s <= steps; s++ {
+			t := float64(s) / float64(steps)
+			u := 1 - t
+			a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+			points = append(points, vec{
+				a*p0.x + b*p1.x + c*p2.x + d*p3.x,
+				a*p0.y + b*p1.y + c*p2.y + d*p3.y,
+			})
+		}
+	}
+	return points
+}
@@ -0,0 +1,285 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package raster draws laid out graphs as images, without Graphviz.
+
+Shapes are drawn with anti-aliasing onto an image.RGBA, with the node
+outlines and arrowheads of package geom, so that a raster drawing matches the
+SVG drawing of the same layout.  Labels are set in a small built-in bitmap
+font.  A short example, writing a PNG:
+
+  raster.WritePNG(w, g, layout.Layered(g), nil)
+
+Graphs whose nodes all have a Position may be drawn without a layout by
+passing nil.
+*/
+package raster
+
+import "image"
+import "image/color"
+import "image/png"
+import "io"
+
+import "godot"
+import "godot/attr"
+import gvcolor "godot/attr/color"
+import "godot/builder"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("png", []string{".png"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return WritePNG(w, g, layout.Default(g), nil)
+		}, godot.Clusters|godot.Positions), nil)
+}
+
+// Options for drawing.
+type Options struct {
+	// Resolution, in pixels per inch.  Defaults to 96, as in Graphviz.
+	DPI float64
+}
+
+func (o *Options) scale() float64 {
+	if o == nil || o.DPI <= 0 {
+		return 96.0 / layout.PointsPerInch
+	}
+	return o.DPI / layout.PointsPerInch
+}
+
+// Space left around the drawing, in points.
+const margin = 4
+
+// Draws "g", laid out as "l", onto a new image with a white background.  If
+// "l" is nil the graph is drawn from the Position of each node, and
+// layout.ErrUnpositioned is returned if a node has none.  Colors this package
+// cannot interpret are drawn black.
+func Draw(g *builder.Graph, l *layout.Layout, opts *Options) (*image.RGBA, error) {
+	if l == nil {
+		var err error
+		if l, err = layout.FromPositions(g); err != nil {
+			return nil, err
+		}
+	}
+	d := &drawing{g: g, l: l, scale: opts.scale()}
+	d.draw()
+	return d.img, nil
+}
+
+// Draws "g", laid out as "l", and writes it to "w" as a PNG image.
+func WritePNG(w io.Writer, g *builder.Graph, l *layout.Layout, opts *Options) error {
+	img, err := Draw(g, l, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+type drawing struct {
+	g     *builder.Graph
+	l     *layout.Layout
+	img   *image.RGBA
+	scale float64 // pixels per point
+
+	// Origin of the image, top left, in layout points.
+	left, top float64
+}
+
+// Converts a layout point to pixels.
+func (d *drawing) px(p layout.Point) vec {
+	return vec{(p.X - d.left) * d.scale, (d.top - p.Y) * d.scale}
+}
+
+func (d *drawing) pxs(points []layout.Point) []vec {
+	v := make([]vec, len(points))
+	for i, p := range points {
+		v[i] = d.px(p)
+	}
+	return v
+}
+
+func (d *drawing) draw() {
+	b := d.l.Bounds
+	width, height := b.Size()
+	labelHeight := 0.0
+	if d.g.Label != "" {
+		labelHeight = layout.TextHeight(d.g.Label, layout.DefaultFontSize)
+		if w := layout.TextWidth(d.g.Label, layout.DefaultFontSize); w > width {
+			width = w
+		}
+	}
+	d.left, d.top = b.Min.X-margin, b.Max.Y+margin
+	width += 2 * margin
+	height += 2*margin + labelHeight
+
+	d.img = image.NewRGBA(image.Rect(0, 0, int(width*d.scale+0.5), int(height*d.scale+0.5)))
+	for i := range d.img.Pix {
+		d.img.Pix[i] = 0xff
+	}
+
+	d.drawClusters(d.g.Subgraphs())
+	for _, n := range d.g.Nodes() {
+		d.drawNode(n)
+	}
+	for _, e := range d.g.Edges() {
+		d.drawEdge(e)
+	}
+	if d.g.Label != "" {
+		center := layout.Point{X: (b.Min.X + b.Max.X) / 2, Y: b.Min.Y - margin - labelHeight/2}
+		d.text(d.g.Label, center, nil)
+	}
+}
+
+func (d *drawing) drawClusters(subs []*builder.Subgraph) {
+	for _, sub := range subs {
+		if r, ok := d.l.Clusters[sub]; ok {
+			style := geom.ParseStyle(sub.Style)
+			if !style.Invisible {
+				outline := geom.Outline{Points: []layout.Point{
+					r.Min, {X: r.Max.X, Y: r.Min.Y}, r.Max, {X: r.Min.X, Y: r.Max.Y},
+				}}
+				d.shape(outline, paint(geom.FillColor(style, sub.FillColor, sub.Color)), paint(geom.LineColor(sub.Color)), 1, style)
+				if sub.Label != "" {
+					h := layout.TextHeight(sub.Label, layout.DefaultFontSize)
+					d.text(sub.Label, layout.Point{X: (r.Min.X + r.Max.X) / 2, Y: r.Max.Y - h/2 - 4}, sub.FontColor)
+				}
+			}
+		}
+		d.drawClusters(sub.Subgraphs())
+	}
+}
+
+func (d *drawing) drawNode(node *builder.Node) {
+	nl, ok := d.l.Nodes[node]
+	if !ok {
+		return
+	}
+	n := d.g.ResolveNode(node)
+	style := geom.ParseStyle(n.Style)
+	if style.Invisible {
+		return
+	}
+	pen := geom.PenWidth(n.Penwidth, style)
+	for _, o := range geom.NodeOutlines(n.Shape, nl) {
+		f := paint(geom.FillColor(style, n.FillColor, n.Color))
+		if o.Solid {
+			f = paint(geom.LineColor(n.Color))
+		}
+		d.shape(o, f, paint(geom.LineColor(n.Color)), pen, style)
+	}
+	if n.Label != "" {
+		d.text(n.Label, nl.Center, n.FontColor)
+	}
+}
+
+func (d *drawing) drawEdge(edge *builder.Edge) {
+	el, ok := d.l.Edges[edge]
+	if !ok || len(el.Spline) < 4 {
+		return
+	}
+	e := d.g.ResolveEdge(edge)
+	style := geom.ParseStyle(e.Style)
+	if style.Invisible {
+		return
+	}
+	pen := geom.PenWidth(e.Penwidth, style)
+	arrows, spline := geom.EdgeArrows(e, el.Spline, d.g.Kind() == attr.Directed)
+
+	line := flatten(d.pxs(spline))
+	fillPolygons(d.img, strokePolygons(line, false, pen*d.scale, d.dashes(style)), paint(geom.LineColor(e.Color)))
+	for _, a := range arrows {
+		f := paint(geom.ArrowFill(a, e.FillColor, e.Color))
+		d.shape(a.Outline, f, paint(geom.LineColor(e.Color)), pen, geom.Style{})
+	}
+	if e.Label != "" && el.LabelPos != nil {
+		d.text(e.Label, *el.LabelPos, e.FontColor)
+	}
+}
+
+// Fills and strokes an outline.
+func (d *drawing) shape(o geom.Outline, fill, stroke color.NRGBA, pen float64, style geom.Style) {
+	var points []vec
+	if o.Ellipse {
+		points = ellipse(d.px(o.Center), o.RX*d.scale, o.RY*d.scale, 12)
+	} else {
+		points = wound(d.pxs(o.Points))
+	}
+	fillPolygons(d.img, [][]vec{points}, fill)
+	fillPolygons(d.img, strokePolygons(points, true, pen*d.scale, d.dashes(style)), stroke)
+}
+
+// Returns the dash pattern for a style, in pixels.
+func (d *drawing) dashes(style geom.Style) []float64 {
+	pattern := geom.Dashes(style)
+	for i := range pattern {
+		pattern[i] *= d.scale
+	}
+	return pattern
+}
+
+// Draws a label centered on "center" in the bitmap font, each run of ink
+// in a row of a glyph as a rectangle.
+func (d *drawing) text(label string, center layout.Point, c gvcolor.Color) {
+	lines, baselines := geom.TextLines(label, center, layout.DefaultFontSize)
+	unit := layout.DefaultFontSize / 10.0 // points per font unit
+	var polys [][]vec
+	for i, line := range lines {
+		runes := []rune(line)
+		width := float64(len(runes)*glyphAdvance-1) * unit
+		x := baselines[i].X - width/2
+		for _, r := range runes {
+			g := glyph(r)
+			for row, bits := range g {
+				top := baselines[i].Y + float64(glyphAscent-row)*unit
+				for col := 0; col < glyphWidth; {
+					if bits&(1<<uint(glyphWidth-1-col)) == 0 {
+						col++
+						continue
+					}
+					start := col
+					for col < glyphWidth && bits&(1<<uint(glyphWidth-1-col)) != 0 {
+						col++
+					}
+					x0, x1 := x+float64(start)*unit, x+float64(col)*unit
+					polys = append(polys, wound(d.pxs([]layout.Point{
+						{X: x0, Y: top}, {X: x1, Y: top}, {X: x1, Y: top - unit}, {X: x0, Y: top - unit},
+					})))
+				}
+			}
+			x += glyphAdvance * unit
+		}
+	}
+	fillPolygons(d.img, polys, paint(geom.LineColor(c)))
+}
+
+var (
+	black       = color.NRGBA{0, 0, 0, 0xff}
+	lightgrey   = color.NRGBA{0xd3, 0xd3, 0xd3, 0xff}
+	transparent = color.NRGBA{}
+)
+
+// Returns the components of a color, or black if they are not known.
+func paint(c gvcolor.Color) color.NRGBA {
+	if c == nil {
+		return transparent
+	}
+	rgb, alpha, ok := gvcolor.Components(c)
+	if !ok {
+		return black
+	}
+	return color.NRGBA{rgb.R, rgb.G, rgb.B, alpha}
+}
@@ -0,0 +1,104 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package raster
+
+import "bytes"
+import "image/color"
+import "image/png"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/layout"
+
+func TestGlyphs(t *testing.T) {
+	if len(glyphs) != '~'-' '+1 {
+		t.Fatalf("Font has %d glyphs.", len(glyphs))
+	}
+	if glyph(' ') != ([glyphHeight]uint8{}) {
+		t.Errorf("Space is not blank.")
+	}
+	if glyph('é') != glyph('?') {
+		t.Errorf("Unknown characters are not drawn as '?'.")
+	}
+	if g := glyph('T'); g[0] != 0x1f || g[6] != 0x04 {
+		t.Errorf("Glyph T is %v.", g)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	src := `digraph {
+		a [shape=box, style=filled, fillcolor="#ff0000", width=2, height=1, label=""];
+		b [label="Label"];
+		a -> b;
+	}`
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	l := layout.Layered(g)
+	img, err := Draw(g, l, &Options{DPI: 72})
+	if err != nil {
+		t.Fatalf("Draw failed: %v", err)
+	}
+
+	w, h := l.Bounds.Size()
+	if b := img.Bounds(); b.Dx() != int(w+2*margin+0.5) || b.Dy() != int(h+2*margin+0.5) {
+		t.Errorf("Image is %v, layout is %vx%v.", b, w, h)
+	}
+	if c := img.At(0, 0); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("Background is %v.", c)
+	}
+	a := l.Nodes[g.Nodes()[0]].Center
+	x, y := int(a.X-l.Bounds.Min.X+margin), int(l.Bounds.Max.Y-a.Y+margin)
+	if c := img.At(x, y); c != (color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("Filled box is %v.", c)
+	}
+
+	// The label of b is drawn in black.
+	bl := l.Nodes[g.Nodes()[1]]
+	dark := 0
+	for py := int(l.Bounds.Max.Y - bl.Center.Y); py < int(l.Bounds.Max.Y-bl.Center.Y)+2*margin; py++ {
+		for px := int(bl.Center.X - 20); px < int(bl.Center.X+20); px++ {
+			if r, _, _, _ := img.At(px, py).RGBA(); r < 0x4000 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Errorf("Label is not drawn.")
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	g := builder.NewGraph(nil)
+	nodes := builder.GenNodes(3)
+	g.AddNodes(nodes...)
+	g.AddEdges(&builder.Edge{Src: nodes[0], Dst: nodes[1], Style: "dotted"})
+	layout.Circular(nodes, nil)
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, g, nil, nil); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() < 200 || b.Dy() < 200 {
+		t.Errorf("Image is only %v.", b)
+	}
+}
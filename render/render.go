@@ -0,0 +1,233 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package render draws graphs with a local Graphviz installation.
+
+A Renderer runs a Graphviz program, feeding it the dot representation of a
+graph on its standard input and returning what it writes to its standard
+output.  Warnings and errors the program writes to its standard error are
+returned as diagnostics.  A short example, drawing a graph as a PNG with the
+neato engine, giving up after ten seconds:
+
+  r := &render.Renderer{Engine: "neato", Format: "png", Timeout: 10 * time.Second}
+  res, err := r.Render(context.Background(), g.Build())
+  if err != nil {
+    return err
+  }
+  ioutil.WriteFile("graph.png", res.Output, 0644)
+*/
+package render
+
+import "bytes"
+import "context"
+import "errors"
+import "fmt"
+import "os/exec"
+import "regexp"
+import "strconv"
+import "strings"
+import "time"
+
+import "godot"
+
+// How long to wait for a killed program's output to close, in case it has
+// left children holding it open.
+const waitDelay = time.Second
+
+// Runs a Graphviz program to draw graphs.  The zero value runs "dot" from
+// the PATH and writes PNG.
+type Renderer struct {
+	// Path of the program to run.  Defaults to "dot", looked up in the
+	// PATH.
+	Path string
+
+	// Layout engine, passed as -K, for example "neato" or "fdp".  Defaults
+	// to the program's own engine.
+	Engine string
+
+	// Output format, passed as -T, for example "svg", "pdf" or "plain".
+	// Defaults to "png".
+	Format string
+
+	// Longest time the program may run.  Zero means no limit beyond the
+	// context's.
+	Timeout time.Duration
+
+	// Further arguments, for example "-Gdpi=150".
+	Args []string
+}
+
+// The output of a successful run.
+type Result struct {
+	// What the program wrote to its standard output.
+	Output []byte
+
+	// Warnings the program wrote to its standard error.
+	Diagnostics []Diagnostic
+}
+
+// A message from Graphviz.
+type Diagnostic struct {
+	// "Warning" or "Error", or empty for other messages.
+	Severity string
+
+	// The message, without its severity, continuation lines joined with
+	// newlines.
+	Message string
+
+	// The line of the input the message refers to, or 0 if it names none.
+	Line int
+}
+
+func (d Diagnostic) String() string {
+	if d.Severity == "" {
+		return d.Message
+	}
+	return d.Severity + ": " + d.Message
+}
+
+// Returned when the program fails.
+type Error struct {
+	// The error from running the program, for example an *exec.ExitError.
+	Err error
+
+	// What the program wrote to its standard error.
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string {
+	msg := "render: " + e.Err.Error()
+	for _, d := range e.Diagnostics {
+		if d.Severity == "Error" {
+			return msg + ": " + d.Message
+		}
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (r *Renderer) program() string {
+	if r.Path == "" {
+		return "dot"
+	}
+	return r.Path
+}
+
+// Returns the arguments the program is run with.
+func (r *Renderer) args() []string {
+	format := r.Format
+	if format == "" {
+		format = "png"
+	}
+	args := []string{"-T" + format}
+	if r.Engine != "" {
+		args = append(args, "-K"+r.Engine)
+	}
+	return append(args, r.Args...)
+}
+
+// Draws "dot" and returns the program's output.  If the context is done or
+// the timeout passes first, the program is killed and the context's error
+// returned.  If the program fails, an *Error holding its diagnostics is
+// returned.
+func (r *Renderer) Render(ctx context.Context, dot godot.Dot) (*Result, error) {
+	var input bytes.Buffer
+	if err := dot.Write(&input); err != nil {
+		return nil, err
+	}
+	return r.RenderSource(ctx, input.Bytes())
+}
+
+// Draws a graph given in the dot language, as Render does.
+func (r *Renderer) RenderSource(ctx context.Context, src []byte) (*Result, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, r.program(), r.args()...)
+	cmd.WaitDelay = waitDelay
+	cmd.Stdin = bytes.NewReader(src)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	diags := ParseDiagnostics(stderr.String())
+	if err != nil {
+		return nil, &Error{Err: err, Diagnostics: diags}
+	}
+	return &Result{Output: stdout.Bytes(), Diagnostics: diags}, nil
+}
+
+// Reports the version of the program, from the first line it writes when
+// run with -V, for example "dot - graphviz version 2.43.0 (0)".
+func (r *Renderer) Version(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, r.program(), "-V")
+	cmd.WaitDelay = waitDelay
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", &Error{Err: err, Diagnostics: ParseDiagnostics(string(out))}
+	}
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
+
+var severity = regexp.MustCompile(`^(Warning|Error): ?(.*)$`)
+var lineNumber = regexp.MustCompile(`\bline (\d+)`)
+
+// Parses the messages Graphviz writes to its standard error.  Each message
+// begins "Warning:" or "Error:"; lines without either continue the previous
+// message, or if there is none begin a message without a severity.
+func ParseDiagnostics(stderr string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := severity.FindStringSubmatch(line); m != nil {
+			diags = append(diags, Diagnostic{Severity: m[1], Message: m[2]})
+		} else if len(diags) > 0 {
+			d := &diags[len(diags)-1]
+			d.Message += "\n" + strings.TrimSpace(line)
+			continue
+		} else {
+			diags = append(diags, Diagnostic{Message: strings.TrimSpace(line)})
+		}
+		d := &diags[len(diags)-1]
+		if m := lineNumber.FindStringSubmatch(d.Message); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+		}
+	}
+	return diags
+}
+
+// Returned by Check when the program cannot be found.
+var ErrNotInstalled = errors.New("render: Graphviz is not installed")
+
+// Checks that the program can be found, returning ErrNotInstalled if not.
+func (r *Renderer) Check() error {
+	if _, err := exec.LookPath(r.program()); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotInstalled, err)
+	}
+	return nil
+}
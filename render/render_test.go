@@ -0,0 +1,121 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package render
+
+import "context"
+import "errors"
+import "io/ioutil"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+
+import "godot/attr"
+import "godot/builder"
+
+// Writes a shell script standing in for Graphviz, and returns its path.
+func stub(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "dot")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Cannot write stub: %v", err)
+	}
+	return path
+}
+
+func TestRender(t *testing.T) {
+	path := stub(t, `echo "$@"
+cat
+echo "Warning: node a, port x unrecognized in line 2" >&2
+echo "Warning: layout may be" >&2
+echo "  imperfect" >&2
+`)
+	g := builder.NewGraph(attr.Directed)
+	g.AddNodes(builder.GenNodes(1)...)
+
+	r := &Renderer{Path: path, Engine: "neato", Format: "svg", Args: []string{"-Gdpi=150"}}
+	res, err := r.Render(context.Background(), g.Build())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	out := string(res.Output)
+	if !strings.HasPrefix(out, "-Tsvg -Kneato -Gdpi=150\ndigraph {") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if len(res.Diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v.", res.Diagnostics)
+	}
+	if d := res.Diagnostics[0]; d.Severity != "Warning" || d.Line != 2 {
+		t.Errorf("Unexpected diagnostic %+v.", d)
+	}
+	if d := res.Diagnostics[1]; d.Message != "layout may be\nimperfect" {
+		t.Errorf("Continuation not joined: %q.", d.Message)
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	path := stub(t, `cat > /dev/null
+echo "Error: <stdin>: syntax error in line 1 near 'x'" >&2
+exit 1
+`)
+	r := &Renderer{Path: path}
+	_, err := r.RenderSource(context.Background(), []byte("x"))
+
+	var rerr *Error
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Expected *Error, got %v.", err)
+	}
+	if len(rerr.Diagnostics) != 1 || rerr.Diagnostics[0].Line != 1 {
+		t.Errorf("Unexpected diagnostics %v.", rerr.Diagnostics)
+	}
+	if !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("Error does not say why: %v.", err)
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	path := stub(t, "exec sleep 10\n")
+	r := &Renderer{Path: path, Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	_, err := r.RenderSource(context.Background(), nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v.", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Render took %v to give up.", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.Timeout = 0
+	if _, err := r.RenderSource(ctx, nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v.", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	r := &Renderer{Path: filepath.Join(t.TempDir(), "missing")}
+	if err := r.Check(); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Expected ErrNotInstalled, got %v.", err)
+	}
+	r.Path = stub(t, "echo 'dot - graphviz version 2.43.0 (0)' >&2\n")
+	if err := r.Check(); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+	if v, err := r.Version(context.Background()); err != nil || v != "dot - graphviz version 2.43.0 (0)" {
+		t.Errorf("Version is %q, %v.", v, err)
+	}
+}
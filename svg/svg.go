@@ -0,0 +1,317 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package svg draws laid out graphs as SVG, without Graphviz.
+
+The drawing follows the structure of Graphviz's SVG output: each cluster,
+node and edge is a group element with an id and class, a title, and its
+shapes and text.  A node's or edge's URL and tooltip become a link around
+its shapes.  A short example, laying out a graph with the layered engine and
+drawing it:
+
+  svg.Write(os.Stdout, g, layout.Layered(g))
+
+Graphs whose nodes all have a Position may be drawn without a layout by
+passing nil.
+*/
+package svg
+
+import "fmt"
+import "io"
+import "math"
+import "strings"
+
+import "godot"
+import "godot/attr"
+import "godot/attr/color"
+import "godot/builder"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("svg", []string{".svg"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return Write(w, g, layout.Default(g))
+		}, godot.Clusters|godot.Positions), nil)
+}
+
+// Space left around the drawing, in points.
+const margin = 4
+
+// The font used for labels.
+const fontFamily = "Times,serif"
+
+// Writes an SVG drawing of "g", laid out as "l", to "w".  If "l" is nil the
+// graph is drawn from the Position of each node, and layout.ErrUnpositioned
+// is returned if a node has none.
+func Write(w io.Writer, g *builder.Graph, l *layout.Layout) error {
+	if l == nil {
+		var err error
+		if l, err = layout.FromPositions(g); err != nil {
+			return err
+		}
+	}
+	d := &drawing{w: w, g: g, l: l}
+	d.draw()
+	return d.err
+}
+
+// The state of a drawing being written.  Errors are sticky: once writing
+// fails, later writes are skipped and the first error is kept.
+type drawing struct {
+	w   io.Writer
+	err error
+	g   *builder.Graph
+	l   *layout.Layout
+
+	// Origin of the drawing's coordinates, top left, in layout points.
+	left, top float64
+	clusters  int
+	index     map[*builder.Node]int
+}
+
+func (d *drawing) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// Converts a layout point to SVG coordinates, with y increasing downwards.
+func (d *drawing) xy(p layout.Point) (float64, float64) {
+	return p.X - d.left, d.top - p.Y
+}
+
+func (d *drawing) draw() {
+	b := d.l.Bounds
+	width, height := b.Size()
+	labelHeight := 0.0
+	if d.g.Label != "" {
+		labelHeight = layout.TextHeight(d.g.Label, layout.DefaultFontSize)
+		width = math.Max(width, layout.TextWidth(d.g.Label, layout.DefaultFontSize))
+	}
+	d.left, d.top = b.Min.X-margin, b.Max.Y+margin
+	width += 2 * margin
+	height += 2*margin + labelHeight
+
+	d.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	d.printf("<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\"")
+	d.printf(" width=\"%.0fpt\" height=\"%.0fpt\" viewBox=\"0.00 0.00 %.2f %.2f\">\n", width, height, width, height)
+	d.printf("<g id=\"graph0\" class=\"graph\">\n")
+	d.printf("<rect fill=\"white\" stroke=\"none\" x=\"0\" y=\"0\" width=\"%.2f\" height=\"%.2f\"/>\n", width, height)
+
+	d.drawClusters(d.g.Subgraphs())
+	d.index = make(map[*builder.Node]int)
+	for i, n := range d.g.Nodes() {
+		d.index[n] = i
+		d.drawNode(i, n)
+	}
+	for i, e := range d.g.Edges() {
+		d.drawEdge(i, e)
+	}
+	if d.g.Label != "" {
+		center := layout.Point{X: (b.Min.X + b.Max.X) / 2, Y: b.Min.Y - margin - labelHeight/2}
+		d.text(d.g.Label, center, nil)
+	}
+
+	d.printf("</g>\n</svg>\n")
+}
+
+// Draws the clusters among "subs", outer clusters before those nested in
+// them.
+func (d *drawing) drawClusters(subs []*builder.Subgraph) {
+	for _, sub := range subs {
+		if r, ok := d.l.Clusters[sub]; ok {
+			d.clusters++
+			style := geom.ParseStyle(sub.Style)
+			if !style.Invisible {
+				d.open(fmt.Sprintf("clust%d", d.clusters), sub.ID, "cluster", sub.Class, sub.Name, sub.URL, sub.Tooltip)
+				outline := geom.Outline{Points: []layout.Point{
+					r.Min, {X: r.Max.X, Y: r.Min.Y}, r.Max, {X: r.Min.X, Y: r.Max.Y},
+				}}
+				d.shape(outline, geom.FillColor(style, sub.FillColor, sub.Color), geom.LineColor(sub.Color), 1, style)
+				if sub.Label != "" {
+					h := layout.TextHeight(sub.Label, layout.DefaultFontSize)
+					d.text(sub.Label, layout.Point{X: (r.Min.X + r.Max.X) / 2, Y: r.Max.Y - h/2 - 4}, sub.FontColor)
+				}
+				d.close(sub.URL, sub.Tooltip)
+			}
+		}
+		d.drawClusters(sub.Subgraphs())
+	}
+}
+
+func (d *drawing) drawNode(i int, node *builder.Node) {
+	nl, ok := d.l.Nodes[node]
+	if !ok {
+		return
+	}
+	n := d.g.ResolveNode(node)
+	style := geom.ParseStyle(n.Style)
+	if style.Invisible {
+		return
+	}
+	d.open(fmt.Sprintf("node%d", i+1), n.ID, "node", n.Class, title(n, i), n.URL, n.Tooltip)
+	pen := geom.PenWidth(n.Penwidth, style)
+	for _, o := range geom.NodeOutlines(n.Shape, nl) {
+		f := geom.FillColor(style, n.FillColor, n.Color)
+		if o.Solid {
+			f = geom.LineColor(n.Color)
+		}
+		d.shape(o, f, geom.LineColor(n.Color), pen, style)
+	}
+	if n.Label != "" {
+		d.text(n.Label, nl.Center, n.FontColor)
+	}
+	d.close(n.URL, n.Tooltip)
+}
+
+func (d *drawing) drawEdge(i int, edge *builder.Edge) {
+	el, ok := d.l.Edges[edge]
+	if !ok || len(el.Spline) < 4 {
+		return
+	}
+	e := d.g.ResolveEdge(edge)
+	style := geom.ParseStyle(e.Style)
+	if style.Invisible {
+		return
+	}
+	name := title(e.Src, d.index[e.Src]) + d.g.Kind().Delimiter() + title(e.Dst, d.index[e.Dst])
+	d.open(fmt.Sprintf("edge%d", i+1), e.ID, "edge", e.Class, name, e.URL, e.Tooltip)
+
+	pen := geom.PenWidth(e.Penwidth, style)
+	arrows, spline := geom.EdgeArrows(e, el.Spline, d.g.Kind() == attr.Directed)
+
+	var path strings.Builder
+	x, y := d.xy(spline[0])
+	fmt.Fprintf(&path, "M%.2f,%.2f C", x, y)
+	for _, p := range spline[1:] {
+		x, y := d.xy(p)
+		fmt.Fprintf(&path, " %.2f,%.2f", x, y)
+	}
+	d.printf("<path fill=\"none\"%s stroke-width=\"%.2f\"%s d=\"%s\"/>\n",
+		paint("stroke", geom.LineColor(e.Color)), pen, dashes(style), path.String())
+
+	for _, a := range arrows {
+		d.shape(a.Outline, geom.ArrowFill(a, e.FillColor, e.Color), geom.LineColor(e.Color), pen, geom.Style{})
+	}
+	if e.Label != "" && el.LabelPos != nil {
+		d.text(e.Label, *el.LabelPos, e.FontColor)
+	}
+	d.close(e.URL, e.Tooltip)
+}
+
+// Opens the group element of a cluster, node or edge, and its link.
+func (d *drawing) open(id, userID, class, userClass, title, url, tooltip string) {
+	if userID != "" {
+		id = userID
+	}
+	if userClass != "" {
+		class += " " + userClass
+	}
+	d.printf("<g id=\"%s\" class=\"%s\">\n<title>%s</title>\n", esc(id), esc(class), esc(title))
+	if url != "" || tooltip != "" {
+		d.printf("<a")
+		if url != "" {
+			d.printf(" xlink:href=\"%s\"", esc(url))
+		}
+		if tooltip != "" {
+			d.printf(" xlink:title=\"%s\"", esc(tooltip))
+		}
+		d.printf(">\n")
+	}
+}
+
+func (d *drawing) close(url, tooltip string) {
+	if url != "" || tooltip != "" {
+		d.printf("</a>\n")
+	}
+	d.printf("</g>\n")
+}
+
+// Draws an outline as an ellipse or polygon element.
+func (d *drawing) shape(o geom.Outline, fill, stroke color.Color, pen float64, style geom.Style) {
+	attrs := fmt.Sprintf("%s%s stroke-width=\"%.2f\"%s", paint("fill", fill)[1:], paint("stroke", stroke), pen, dashes(style))
+	if o.Ellipse {
+		x, y := d.xy(o.Center)
+		d.printf("<ellipse %s cx=\"%.2f\" cy=\"%.2f\" rx=\"%.2f\" ry=\"%.2f\"/>\n", attrs, x, y, o.RX, o.RY)
+		return
+	}
+	var points strings.Builder
+	for i, p := range append(o.Points, o.Points[0]) {
+		x, y := d.xy(p)
+		if i > 0 {
+			points.WriteByte(' ')
+		}
+		fmt.Fprintf(&points, "%.2f,%.2f", x, y)
+	}
+	d.printf("<polygon %s points=\"%s\"/>\n", attrs, points.String())
+}
+
+// Draws a label centered on "center", one text element per line.
+func (d *drawing) text(label string, center layout.Point, c color.Color) {
+	lines, baselines := geom.TextLines(label, center, layout.DefaultFontSize)
+	for i, line := range lines {
+		x, y := d.xy(baselines[i])
+		d.printf("<text text-anchor=\"middle\" x=\"%.2f\" y=\"%.2f\" font-family=\"%s\" font-size=\"%.2f\"%s>%s</text>\n",
+			x, y, fontFamily, float64(layout.DefaultFontSize), paint("fill", geom.LineColor(c)), esc(line))
+	}
+}
+
+// Returns the attributes painting the fill or stroke, as "property" names,
+// with a color: "#rrggbb" and, if it is translucent, an opacity.  Nil colors
+// are "none", and colors whose components are not known are black, as they
+// are in package godot/raster.
+func paint(property string, c color.Color) string {
+	if c == nil {
+		return fmt.Sprintf(" %s=\"none\"", property)
+	}
+	rgb, alpha, ok := color.Components(c)
+	if !ok {
+		rgb, alpha = color.RGB{}, 255
+	}
+	attrs := fmt.Sprintf(" %s=\"%s\"", property, rgb)
+	if alpha < 255 {
+		attrs += fmt.Sprintf(" %s-opacity=\"%.3g\"", property, float64(alpha)/255)
+	}
+	return attrs
+}
+
+func dashes(style geom.Style) string {
+	pattern := geom.Dashes(style)
+	if pattern == nil {
+		return ""
+	}
+	parts := make([]string, len(pattern))
+	for i, v := range pattern {
+		parts[i] = fmt.Sprint(v)
+	}
+	return fmt.Sprintf(" stroke-dasharray=\"%s\"", strings.Join(parts, ","))
+}
+
+// Returns the title of a node: its label, or its index if it has none.
+func title(n *builder.Node, i int) string {
+	if n.Label != "" {
+		return n.Label
+	}
+	return fmt.Sprint(i)
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&#39;")
+
+func esc(s string) string {
+	return escaper.Replace(s)
+}
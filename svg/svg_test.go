@@ -0,0 +1,138 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package svg
+
+import "bytes"
+import "encoding/xml"
+import "io"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+import "godot/layout"
+
+// Checks that "data" is well formed XML, and counts its elements by name.
+func elements(t *testing.T, data []byte) map[string]int {
+	counts := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return counts
+		}
+		if err != nil {
+			t.Fatalf("Invalid XML: %v\n%s", err, data)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	src := `digraph {
+		label="A <graph>";
+		a [shape=box, style=filled, fillcolor=yellow, id=first, class=important];
+		b [URL="http://example.com/?x=1&y=2", tooltip="B \"node\""];
+		c [style=invis];
+		subgraph cluster_0 { label=Cluster; d; e [shape=doublecircle] }
+		a -> b [label=ab]; b -> d [style=dashed, dir=both, arrowtail=dot]; d -> e [arrowhead=none];
+		a -> c;
+	}`
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, g, layout.Layered(g)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	counts := elements(t, buf.Bytes())
+
+	// Four visible nodes, one cluster, and four edges.
+	if counts["g"] != 1+4+1+4 {
+		t.Errorf("Drawing has %d groups.", counts["g"])
+	}
+	// The box, cluster and three arrowheads; two ellipses, a doublecircle
+	// and an arrow dot.
+	if counts["polygon"] != 5 || counts["ellipse"] != 5 {
+		t.Errorf("Drawing has %d polygons and %d ellipses.", counts["polygon"], counts["ellipse"])
+	}
+	for _, want := range []string{
+		`id="first" class="node important"`,
+		`fill="#ffff00"`,
+		`xlink:href="http://example.com/?x=1&amp;y=2"`,
+		`xlink:title="B &quot;node&quot;"`,
+		`class="cluster"`,
+		`stroke-dasharray="5,2"`,
+		`>A &lt;graph&gt;</text>`,
+		`>ab</text>`,
+		`<title>a-&gt;b</title>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Drawing does not contain %s.", want)
+		}
+	}
+}
+
+func TestColors(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph {
+		a [style=filled, color="0.5 0.5 0.5", fillcolor="/blues9/3"];
+		b [style=filled, fillcolor="#ff000080", fontcolor=green];
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, g, layout.Layered(g)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`fill="#000000" stroke="#408080"`,
+		`fill="#ff0000" fill-opacity="0.502" stroke="#000000"`,
+		`fill="#00ff00">b</text>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Drawing does not contain %s.\n%s", want, out)
+		}
+	}
+}
+
+func TestWritePositions(t *testing.T) {
+	g := builder.NewGraph(attr.Undirected)
+	nodes := builder.GenNodes(2)
+	g.AddNodes(nodes...)
+	g.AddEdges(&builder.Edge{Src: nodes[0], Dst: nodes[1]})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, g, nil); err != layout.ErrUnpositioned {
+		t.Errorf("Expected ErrUnpositioned, got %v.", err)
+	}
+
+	layout.Circular(nodes, nil)
+	buf.Reset()
+	if err := Write(&buf, g, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	counts := elements(t, buf.Bytes())
+	if counts["ellipse"] != 2 || counts["path"] != 1 || counts["polygon"] != 0 {
+		t.Errorf("Drawing has %d ellipses, %d paths and %d polygons.",
+			counts["ellipse"], counts["path"], counts["polygon"])
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package table
+
+import "bufio"
+import "encoding/csv"
+import "errors"
+import "fmt"
+import "io"
+import "sort"
+import "strconv"
+import "strings"
+
+import "godot/attr"
+import "godot/builder"
+
+// The layout of an adjacency matrix.  The zero Matrix is a CSV matrix whose
+// first row and column name the nodes, and whose entries count the edges
+// between them.
+type Matrix struct {
+	// The delimiter of the fields of dense matrices, ',' by default.
+	Comma rune
+
+	// Whether a dense matrix has no row and column of node names.  Nodes
+	// are then labelled with their indices, from 1.
+	Unnamed bool
+
+	// The edge attribute entries hold, such as "penwidth".  If empty,
+	// entries count the edges between nodes.
+	Attribute string
+
+	// Limits on what is read, so that a small input cannot make a huge
+	// graph.  An entry counting more than MaxCount edges is an error, as is
+	// a sparse matrix declaring more than MaxNodes nodes and fewer than half
+	// as many entries.  DefaultMaxCount and DefaultMaxNodes are used if they
+	// are 0.
+	MaxCount int
+	MaxNodes int
+}
+
+// The limits of matrices whose MaxCount and MaxNodes are 0.
+const (
+	DefaultMaxCount = 1000
+	DefaultMaxNodes = 1 << 16
+)
+
+func (m *Matrix) maxCount() int {
+	if m.MaxCount > 0 {
+		return m.MaxCount
+	}
+	return DefaultMaxCount
+}
+
+func (m *Matrix) maxNodes() int {
+	if m.MaxNodes > 0 {
+		return m.MaxNodes
+	}
+	return DefaultMaxNodes
+}
+
+// An edge read from a matrix.
+type entry struct {
+	i, j  int
+	value string
+}
+
+// Builds a graph from the entries of a matrix of n nodes.  Without an
+// attribute, an entry of k adds k edges, and zero none.  With one, a nonzero
+// entry adds an edge with the attribute set to the entry.
+func (m *Matrix) build(kind *attr.GraphKind, labels []string, entries []entry) (*builder.Graph, error) {
+	g := builder.NewGraph(kind)
+	nodes := make([]*builder.Node, len(labels))
+	for i, label := range labels {
+		nodes[i] = &builder.Node{Label: label}
+		g.AddNodes(nodes[i])
+	}
+	for _, en := range entries {
+		value := strings.TrimSpace(en.value)
+		if value == "" {
+			continue
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f == 0 {
+			continue
+		}
+		count := 1
+		if m.Attribute == "" {
+			var err error
+			count, err = strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("table: entry (%d, %d) is %q, not a count of edges", en.i+1, en.j+1, value)
+			}
+			if count > m.maxCount() {
+				return nil, fmt.Errorf("table: entry (%d, %d) counts more than %d edges", en.i+1, en.j+1, m.maxCount())
+			}
+		}
+		for k := 0; k < count; k++ {
+			e := &builder.Edge{Src: nodes[en.i], Dst: nodes[en.j]}
+			if m.Attribute != "" {
+				if err := e.SetAttribute(m.Attribute, value); err != nil {
+					return nil, fmt.Errorf("table: %v", err)
+				}
+			}
+			g.AddEdges(e)
+		}
+	}
+	return g, nil
+}
+
+// Returns the entries of the matrix of a graph, as strings, by node indices,
+// with the entries of undirected graphs on both sides of the diagonal.
+func (m *Matrix) entries(g *builder.Graph) (map[[2]int]string, error) {
+	index := make(map[*builder.Node]int)
+	for i, n := range g.Nodes() {
+		index[n] = i
+	}
+	counts := make(map[[2]int]int)
+	values := make(map[[2]int]string)
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		keys := [][2]int{{index[e.Src], index[e.Dst]}}
+		if g.Kind() == attr.Undirected && e.Src != e.Dst {
+			keys = append(keys, [2]int{index[e.Dst], index[e.Src]})
+		}
+		value := "1"
+		if m.Attribute != "" {
+			for _, a := range builder.Attributes(g.ResolveEdge(e)) {
+				if a[0] == m.Attribute {
+					value = a[1]
+				}
+			}
+		}
+		for _, k := range keys {
+			counts[k]++
+			if m.Attribute != "" && counts[k] > 1 {
+				return nil, fmt.Errorf("table: several edges between nodes %d and %d", k[0], k[1])
+			}
+			values[k] = value
+		}
+	}
+	if m.Attribute == "" {
+		for k, c := range counts {
+			values[k] = strconv.Itoa(c)
+		}
+	}
+	return values, nil
+}
+
+// Reads a dense adjacency matrix.  The entries of an undirected graph are
+// read from the upper triangle, and must equal those of the lower.
+func (m *Matrix) ReadDense(r io.Reader, kind *attr.GraphKind) (*builder.Graph, error) {
+	t := &Table{Comma: m.Comma}
+	cr := csv.NewReader(r)
+	cr.Comma = t.comma()
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("table: %v", err)
+	}
+	var labels []string
+	if !m.Unnamed && len(records) > 0 {
+		labels = records[0][1:]
+		records = records[1:]
+		for i, rec := range records {
+			if i >= len(labels) || rec[0] != labels[i] {
+				return nil, fmt.Errorf("table: row %d is named %q, not as its column", i+1, rec[0])
+			}
+			records[i] = rec[1:]
+		}
+	} else {
+		for i := range records {
+			labels = append(labels, strconv.Itoa(i+1))
+		}
+	}
+	if len(records) != len(labels) {
+		return nil, fmt.Errorf("table: %d rows, but %d columns", len(records), len(labels))
+	}
+	var entries []entry
+	for i, rec := range records {
+		if len(rec) != len(labels) {
+			return nil, fmt.Errorf("table: row %d has %d entries, not %d", i+1, len(rec), len(labels))
+		}
+	}
+	for i, rec := range records {
+		for j, value := range rec {
+			if kind == attr.Undirected {
+				if strings.TrimSpace(value) != strings.TrimSpace(records[j][i]) {
+					return nil, fmt.Errorf("table: entries (%d, %d) and (%d, %d) differ in an undirected graph", i+1, j+1, j+1, i+1)
+				}
+				if j < i {
+					continue
+				}
+			}
+			entries = append(entries, entry{i, j, value})
+		}
+	}
+	return m.build(kind, labels, entries)
+}
+
+// Writes the dense adjacency matrix of a graph.  Nodes are named as they are
+// by Table.WriteNodes.  Nodes without an edge between them have the entry 0.
+// If the matrix has an attribute, there may be at most one edge between
+// nodes, and edges without the attribute have the entry 1.
+func (m *Matrix) WriteDense(w io.Writer, g *builder.Graph) error {
+	values, err := m.entries(g)
+	if err != nil {
+		return err
+	}
+	nodes := g.Nodes()
+	nm := names(g)
+	t := &Table{Comma: m.Comma}
+	cw := csv.NewWriter(w)
+	cw.Comma = t.comma()
+	if !m.Unnamed {
+		header := []string{""}
+		for _, n := range nodes {
+			header = append(header, nm[n])
+		}
+		cw.Write(header)
+	}
+	for i, n := range nodes {
+		var record []string
+		if !m.Unnamed {
+			record = append(record, nm[n])
+		}
+		for j := range nodes {
+			value, ok := values[[2]int{i, j}]
+			if !ok {
+				value = "0"
+			}
+			record = append(record, value)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Reads a sparse adjacency matrix in the MatrixMarket coordinate format.
+// Symmetric matrices are read as undirected graphs, from their lower
+// triangle, and general ones as directed graphs.  Nodes are labelled with
+// their indices, from 1.  Each entry of a pattern matrix adds an edge,
+// without setting the matrix's attribute.
+func (m *Matrix) ReadSparse(r io.Reader) (*builder.Graph, error) {
+	sc := bufio.NewScanner(r)
+	line := 0
+	next := func() ([]string, bool) {
+		for sc.Scan() {
+			line++
+			text := strings.TrimSpace(sc.Text())
+			if line > 1 && (text == "" || strings.HasPrefix(text, "%")) {
+				continue
+			}
+			return strings.Fields(text), true
+		}
+		return nil, false
+	}
+	header, ok := next()
+	if !ok || len(header) != 5 || !strings.EqualFold(header[0], "%%MatrixMarket") ||
+		!strings.EqualFold(header[1], "matrix") || !strings.EqualFold(header[2], "coordinate") {
+		return nil, errors.New("table: not a MatrixMarket coordinate matrix")
+	}
+	field, symmetry := strings.ToLower(header[3]), strings.ToLower(header[4])
+	if field != "pattern" && field != "integer" && field != "real" {
+		return nil, fmt.Errorf("table: unsupported MatrixMarket field %q", field)
+	}
+	kind := attr.Directed
+	switch symmetry {
+	case "general":
+	case "symmetric":
+		kind = attr.Undirected
+	default:
+		return nil, fmt.Errorf("table: unsupported MatrixMarket symmetry %q", symmetry)
+	}
+
+	size, ok := next()
+	if !ok || len(size) != 3 {
+		return nil, fmt.Errorf("table: line %d: expected the size of the matrix", line)
+	}
+	var dims [3]int
+	for i, s := range size {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("table: line %d: invalid size %q", line, s)
+		}
+		dims[i] = v
+	}
+	if dims[0] != dims[1] {
+		return nil, fmt.Errorf("table: line %d: the matrix is not square", line)
+	}
+	var entries []entry
+	for fields, ok := next(); ok; fields, ok = next() {
+		want := 3
+		if field == "pattern" {
+			want = 2
+		}
+		if len(fields) != want {
+			return nil, fmt.Errorf("table: line %d: expected %d fields", line, want)
+		}
+		i, err1 := strconv.Atoi(fields[0])
+		j, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || i < 1 || j < 1 || i > dims[0] || j > dims[1] {
+			return nil, fmt.Errorf("table: line %d: invalid entry", line)
+		}
+		if kind == attr.Undirected && j > i {
+			return nil, fmt.Errorf("table: line %d: entry above the diagonal of a symmetric matrix", line)
+		}
+		value := "1"
+		if field != "pattern" {
+			value = fields[2]
+		}
+		entries = append(entries, entry{i - 1, j - 1, value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) != dims[2] {
+		return nil, fmt.Errorf("table: %d entries, but %d declared", len(entries), dims[2])
+	}
+	if dims[0] > m.maxNodes() && dims[0] > 2*len(entries) {
+		return nil, fmt.Errorf("table: %d nodes, but at most %d may be read", dims[0], m.maxNodes())
+	}
+	labels := make([]string, dims[0])
+	for i := range labels {
+		labels[i] = strconv.Itoa(i + 1)
+	}
+	if field == "pattern" {
+		plain := *m
+		plain.Attribute = ""
+		m = &plain
+	}
+	return m.build(kind, labels, entries)
+}
+
+// Writes the sparse adjacency matrix of a graph in the MatrixMarket
+// coordinate format: undirected graphs as symmetric matrices, with entries
+// in the lower triangle, and directed graphs as general ones.  The matrix is
+// a pattern if it has no attribute and there is at most one edge between
+// nodes.  Entries must be numbers.
+func (m *Matrix) WriteSparse(w io.Writer, g *builder.Graph) error {
+	values, err := m.entries(g)
+	if err != nil {
+		return err
+	}
+	n := len(g.Nodes())
+	field := "pattern"
+	for _, v := range values {
+		if _, err := strconv.Atoi(v); err != nil {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return fmt.Errorf("table: entry %q is not a number", v)
+			}
+			field = "real"
+		} else if field == "pattern" && (v != "1" || m.Attribute != "") {
+			field = "integer"
+		}
+	}
+	symmetry := "general"
+	if g.Kind() == attr.Undirected {
+		symmetry = "symmetric"
+	}
+
+	keys := make([][2]int, 0, len(values))
+	for k := range values {
+		if symmetry == "general" || k[1] <= k[0] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a][0] != keys[b][0] {
+			return keys[a][0] < keys[b][0]
+		}
+		return keys[a][1] < keys[b][1]
+	})
+	var lines []string
+	for _, k := range keys {
+		if field == "pattern" {
+			lines = append(lines, fmt.Sprintf("%d %d", k[0]+1, k[1]+1))
+		} else {
+			lines = append(lines, fmt.Sprintf("%d %d %s", k[0]+1, k[1]+1, values[k]))
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%%%MatrixMarket matrix coordinate %s %s\n", field, symmetry)
+	fmt.Fprintf(bw, "%d %d %d\n", n, n, len(lines))
+	for _, l := range lines {
+		fmt.Fprintln(bw, l)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,344 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package table reads and writes graphs as tables: CSV and TSV lists of edges
+and of nodes, and adjacency matrices, dense as CSV and sparse in the
+MatrixMarket coordinate format.
+
+A Table describes the layout of a list: its delimiter, its columns, and
+which columns name nodes or map onto attributes.  Nodes are named in lists
+by their labels, which is what a list read gives them, so that graphs read
+from lists are written back as they were:
+
+  g := builder.NewGraph(attr.Directed)
+  names := make(map[string]*builder.Node)
+  edges := &table.Table{Attributes: map[string]string{"weight": "penwidth"}}
+  err := edges.ReadEdges(r, g, names)
+
+Resources:
+  https://math.nist.gov/MatrixMarket/formats.html
+*/
+package table
+
+import "bytes"
+import "encoding/csv"
+import "errors"
+import "fmt"
+import "io"
+import "sort"
+import "strconv"
+
+import "godot/attr"
+import "godot/builder"
+import "godot/format"
+
+// Edge lists are read as directed graphs, and cannot be sniffed.
+func init() {
+	for _, list := range []struct {
+		name  string
+		comma rune
+	}{{"csv", ','}, {"tsv", '\t'}} {
+		t := &Table{Comma: list.comma}
+		format.Register(list.name, []string{"." + list.name}, nil,
+			format.NewEncoder(t.WriteEdges, 0),
+			format.NewDecoder(func(r io.Reader) (*builder.Graph, error) {
+				g := builder.NewGraph(attr.Directed)
+				if err := t.ReadEdges(r, g, make(map[string]*builder.Node)); err != nil {
+					return nil, err
+				}
+				return g, nil
+			}))
+	}
+	m := new(Matrix)
+	format.Register("mtx", []string{".mtx"},
+		func(prefix []byte) bool { return bytes.HasPrefix(prefix, []byte("%%MatrixMarket")) },
+		format.NewEncoder(m.WriteSparse, 0), format.NewDecoder(m.ReadSparse))
+}
+
+// The layout of a list of nodes or edges.  The zero Table is a CSV list
+// with a header row naming its columns.
+type Table struct {
+	// The delimiter of fields, ',' by default.  TSV lists use '\t'.
+	Comma rune
+
+	// The names of the columns of a list without a header row.  If nil,
+	// the first row of the list names its columns.
+	Columns []string
+
+	// The columns naming nodes: the node of each row of a node list, "id"
+	// by default, and the endpoints of each edge of an edge list, "source"
+	// and "target" by default.
+	ID, Source, Target string
+
+	// The attributes which columns set, by column name.  A column not in
+	// the map sets the attribute of its own name, if the node or edge has
+	// one, and is otherwise ignored, as is a column mapped to "".
+	Attributes map[string]string
+}
+
+func (t *Table) comma() rune {
+	if t.Comma == 0 {
+		return ','
+	}
+	return t.Comma
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Returns the attribute a column sets, or "" if it sets none.
+func (t *Table) attribute(column string) string {
+	if name, ok := t.Attributes[column]; ok {
+		return name
+	}
+	return column
+}
+
+// Returns the column of an attribute, the inverse of attribute.
+func (t *Table) column(name string) string {
+	var columns []string
+	for c, a := range t.Attributes {
+		if a == name {
+			columns = append(columns, c)
+		}
+	}
+	if len(columns) == 0 {
+		if _, ok := t.Attributes[name]; ok {
+			return ""
+		}
+		return name
+	}
+	sort.Strings(columns)
+	return columns[0]
+}
+
+// Reads the rows of a list, calling "row" with the values of each by column
+// name.
+func (t *Table) read(r io.Reader, row func(line int, values map[string]string) error) error {
+	cr := csv.NewReader(r)
+	cr.Comma = t.comma()
+	cr.FieldsPerRecord = -1
+	columns := t.Columns
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("table: %v", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if columns == nil {
+			columns = record
+			continue
+		}
+		if len(record) > len(columns) {
+			return fmt.Errorf("table: line %d: %d fields, but %d columns", line, len(record), len(columns))
+		}
+		values := make(map[string]string)
+		for i, v := range record {
+			values[columns[i]] = v
+		}
+		if err := row(line, values); err != nil {
+			return err
+		}
+	}
+}
+
+// Sets the attributes of an object from the values of a row, but for those
+// of the columns naming nodes.
+func (t *Table) set(obj interface{ SetAttribute(string, string) error }, values map[string]string, skip ...string) error {
+	columns := make([]string, 0, len(values))
+	for c := range values {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+next:
+	for _, c := range columns {
+		for _, s := range skip {
+			if c == s {
+				continue next
+			}
+		}
+		name := t.attribute(c)
+		if name == "" || values[c] == "" {
+			continue
+		}
+		err := obj.SetAttribute(name, values[c])
+		if err != nil && !errors.Is(err, builder.ErrUnknownAttribute) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the node of a name, adding a node labelled with the name to the
+// graph if there is none.
+func node(g *builder.Graph, names map[string]*builder.Node, name string) *builder.Node {
+	n, ok := names[name]
+	if !ok {
+		n = &builder.Node{Label: name}
+		names[name] = n
+		g.AddNodes(n)
+	}
+	return n
+}
+
+// Reads a list of nodes into "g", setting their attributes.  "names" maps
+// the names of nodes to them, and nodes which it does not hold are added to
+// both.
+func (t *Table) ReadNodes(r io.Reader, g *builder.Graph, names map[string]*builder.Node) error {
+	id := or(t.ID, "id")
+	return t.read(r, func(line int, values map[string]string) error {
+		name, ok := values[id]
+		if !ok || name == "" {
+			return fmt.Errorf("table: line %d: no %s", line, id)
+		}
+		if err := t.set(node(g, names, name), values, id); err != nil {
+			return fmt.Errorf("table: line %d: %v", line, err)
+		}
+		return nil
+	})
+}
+
+// Reads a list of edges into "g".  "names" maps the names of nodes to them,
+// and nodes which it does not hold are added to both.
+func (t *Table) ReadEdges(r io.Reader, g *builder.Graph, names map[string]*builder.Node) error {
+	source, target := or(t.Source, "source"), or(t.Target, "target")
+	return t.read(r, func(line int, values map[string]string) error {
+		src, dst := values[source], values[target]
+		if src == "" || dst == "" {
+			return fmt.Errorf("table: line %d: no %s or %s", line, source, target)
+		}
+		e := &builder.Edge{Src: node(g, names, src), Dst: node(g, names, dst)}
+		if err := t.set(e, values, source, target); err != nil {
+			return fmt.Errorf("table: line %d: %v", line, err)
+		}
+		g.AddEdges(e)
+		return nil
+	})
+}
+
+// Returns the names of the nodes of a graph: their labels, if they are set
+// and unique, and otherwise their indices, as in dot, or if those are taken,
+// their indices suffixed with "_2", "_3" and so on.
+func names(g *builder.Graph) map[*builder.Node]string {
+	nodes := g.Nodes()
+	count := make(map[string]int)
+	for _, n := range nodes {
+		count[n.Label]++
+	}
+	used := make(map[string]bool)
+	for _, n := range nodes {
+		if n.Label != "" && count[n.Label] == 1 {
+			used[n.Label] = true
+		}
+	}
+	m := make(map[*builder.Node]string)
+	for i, n := range nodes {
+		if n.Label != "" && count[n.Label] == 1 {
+			m[n] = n.Label
+			continue
+		}
+		name := strconv.Itoa(i)
+		for j := 2; used[name]; j++ {
+			name = fmt.Sprintf("%d_%d", i, j)
+		}
+		used[name] = true
+		m[n] = name
+	}
+	return m
+}
+
+// Writes rows, with a header row naming the columns unless the table's
+// columns are given.  "fixed" are the columns naming nodes, and the other
+// columns are those of the attributes set on any object, in the order they
+// are first found.
+func (t *Table) write(w io.Writer, fixed []string, rows []map[string]string, attrs [][][2]string) error {
+	columns := t.Columns
+	if columns == nil {
+		columns = fixed
+		seen := make(map[string]bool)
+		for _, c := range fixed {
+			seen[c] = true
+		}
+		for _, as := range attrs {
+			for _, a := range as {
+				if c := t.column(a[0]); c != "" && !seen[c] {
+					seen[c] = true
+					columns = append(columns, c)
+				}
+			}
+		}
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = t.comma()
+	if t.Columns == nil {
+		cw.Write(columns)
+	}
+	for i, values := range rows {
+		for _, a := range attrs[i] {
+			if c := t.column(a[0]); c != "" {
+				if _, ok := values[c]; !ok {
+					values[c] = a[1]
+				}
+			}
+		}
+		record := make([]string, len(columns))
+		for j, c := range columns {
+			record[j] = values[c]
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Writes the nodes of a graph as a list, with a column for each attribute
+// set on any node.  Nodes are named by their labels, if they are set and
+// unique, and otherwise by their indices in the graph, as they are in dot.
+func (t *Table) WriteNodes(w io.Writer, g *builder.Graph) error {
+	id := or(t.ID, "id")
+	nm := names(g)
+	var rows []map[string]string
+	var attrs [][][2]string
+	for _, n := range g.Nodes() {
+		rows = append(rows, map[string]string{id: nm[n]})
+		attrs = append(attrs, builder.Attributes(n))
+	}
+	return t.write(w, []string{id}, rows, attrs)
+}
+
+// Writes the edges of a graph as a list, with a column for each attribute
+// set on any edge.  Nodes are named as they are by WriteNodes.  Edges
+// without both endpoints are not written.
+func (t *Table) WriteEdges(w io.Writer, g *builder.Graph) error {
+	source, target := or(t.Source, "source"), or(t.Target, "target")
+	nm := names(g)
+	var rows []map[string]string
+	var attrs [][][2]string
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		rows = append(rows, map[string]string{source: nm[e.Src], target: nm[e.Dst]})
+		attrs = append(attrs, builder.Attributes(e))
+	}
+	return t.write(w, []string{source, target}, rows, attrs)
+}
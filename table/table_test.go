@@ -0,0 +1,152 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package table
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func TestEdges(t *testing.T) {
+	g := builder.NewGraph(attr.Directed)
+	names := make(map[string]*builder.Node)
+	nodes := &Table{Comma: '\t', ID: "name"}
+	err := nodes.ReadNodes(strings.NewReader("name\tcolour\nb\tred\n"), g, names)
+	if err != nil {
+		t.Fatalf("ReadNodes failed: %v", err)
+	}
+	edges := &Table{
+		Columns:    []string{"from", "to", "weight", "note"},
+		Source:     "from",
+		Target:     "to",
+		Attributes: map[string]string{"weight": "penwidth", "note": ""},
+	}
+	err = edges.ReadEdges(strings.NewReader("a,b,2,x\nb,c,,y\n"), g, names)
+	if err != nil {
+		t.Fatalf("ReadEdges failed: %v", err)
+	}
+	if len(g.Nodes()) != 3 || len(g.Edges()) != 2 {
+		t.Fatalf("Read %d nodes and %d edges", len(g.Nodes()), len(g.Edges()))
+	}
+	if e := g.Edges()[0]; e.Src != names["a"] || e.Dst != names["b"] || e.Penwidth != "2" {
+		t.Errorf("First edge is %+v", e)
+	}
+	if names["b"] != g.Nodes()[0] || names["c"].Label != "c" {
+		t.Errorf("Nodes are %v", names)
+	}
+
+	var buf bytes.Buffer
+	if err := (&Table{Attributes: map[string]string{"weight": "penwidth"}}).WriteEdges(&buf, g); err != nil {
+		t.Fatalf("WriteEdges failed: %v", err)
+	}
+	if want := "source,target,weight\na,b,2\nb,c,\n"; buf.String() != want {
+		t.Errorf("WriteEdges wrote %q, want %q", buf.String(), want)
+	}
+
+	err = edges.ReadEdges(strings.NewReader("a\n"), g, names)
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("ReadEdges of a row without a target gave %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	g := builder.NewGraph(attr.Undirected)
+	g.AddNodes(&builder.Node{Label: "1"}, &builder.Node{Label: "x"}, &builder.Node{Label: "x"})
+	var buf bytes.Buffer
+	if err := new(Table).WriteNodes(&buf, g); err != nil {
+		t.Fatalf("WriteNodes failed: %v", err)
+	}
+	if want := "id,label\n1,1\n1_2,x\n2,x\n"; buf.String() != want {
+		t.Errorf("WriteNodes wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDense(t *testing.T) {
+	const in = ",a,b,c\na,0,2,0\nb,2,0,1\nc,0,1,1\n"
+	m := new(Matrix)
+	g, err := m.ReadDense(strings.NewReader(in), attr.Undirected)
+	if err != nil {
+		t.Fatalf("ReadDense failed: %v", err)
+	}
+	if len(g.Nodes()) != 3 || len(g.Edges()) != 4 {
+		t.Fatalf("Read %d nodes and %d edges", len(g.Nodes()), len(g.Edges()))
+	}
+	var buf bytes.Buffer
+	if err := m.WriteDense(&buf, g); err != nil {
+		t.Fatalf("WriteDense failed: %v", err)
+	}
+	if buf.String() != in {
+		t.Errorf("WriteDense wrote %q, want %q", buf.String(), in)
+	}
+
+	_, err = m.ReadDense(strings.NewReader(",a,b\na,0,1\nb,0,0\n"), attr.Undirected)
+	if err == nil {
+		t.Errorf("ReadDense of an asymmetric undirected matrix succeeded")
+	}
+}
+
+func TestSparse(t *testing.T) {
+	const in = "%%MatrixMarket matrix coordinate real general\n% comment\n3 3 2\n1 2 1.5\n3 1 2\n"
+	m := &Matrix{Attribute: "penwidth"}
+	g, err := m.ReadSparse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadSparse failed: %v", err)
+	}
+	if g.Kind() != attr.Directed || len(g.Edges()) != 2 || g.Edges()[0].Penwidth != "1.5" {
+		t.Fatalf("ReadSparse read %d edges", len(g.Edges()))
+	}
+	var buf bytes.Buffer
+	if err := m.WriteSparse(&buf, g); err != nil {
+		t.Fatalf("WriteSparse failed: %v", err)
+	}
+	want := "%%MatrixMarket matrix coordinate real general\n3 3 2\n1 2 1.5\n3 1 2\n"
+	if buf.String() != want {
+		t.Errorf("WriteSparse wrote %q, want %q", buf.String(), want)
+	}
+
+	g, err = new(Matrix).ReadSparse(strings.NewReader("%%MatrixMarket matrix coordinate pattern symmetric\n2 2 2\n1 1\n2 1\n"))
+	if err != nil {
+		t.Fatalf("ReadSparse failed: %v", err)
+	}
+	buf.Reset()
+	if err := new(Matrix).WriteSparse(&buf, g); err != nil {
+		t.Fatalf("WriteSparse failed: %v", err)
+	}
+	want = "%%MatrixMarket matrix coordinate pattern symmetric\n2 2 2\n1 1\n2 1\n"
+	if buf.String() != want {
+		t.Errorf("WriteSparse wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if _, err := new(Matrix).ReadDense(strings.NewReader(",a\na,2000000\n"), attr.Directed); err == nil {
+		t.Errorf("ReadDense read an entry of 2000000 edges")
+	}
+	m := &Matrix{Attribute: "penwidth"}
+	if g, err := m.ReadDense(strings.NewReader(",a\na,2000000\n"), attr.Directed); err != nil || len(g.Edges()) != 1 {
+		t.Errorf("ReadDense of a penwidth gave %v", err)
+	}
+	const huge = "%%MatrixMarket matrix coordinate pattern general\n20000000 20000000 1\n1 2\n"
+	if _, err := new(Matrix).ReadSparse(strings.NewReader(huge)); err == nil {
+		t.Errorf("ReadSparse read a matrix of 20000000 nodes")
+	}
+	small := &Matrix{MaxNodes: 2}
+	if _, err := small.ReadSparse(strings.NewReader("%%MatrixMarket matrix coordinate pattern general\n4 4 2\n1 2\n3 4\n")); err != nil {
+		t.Errorf("ReadSparse of two nodes for each entry failed: %v", err)
+	}
+}
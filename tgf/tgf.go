@@ -0,0 +1,149 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package tgf reads and writes graphs in the Trivial Graph Format: a line for
+each node, with its id and label, a line holding "#", and a line for each
+edge, with the ids of its endpoints and its label.  TGF does not say whether
+graphs are directed, so Decode is told.
+
+  1 First node
+  2 Second node
+  #
+  1 2 Edge label
+
+Resources:
+  https://en.wikipedia.org/wiki/Trivial_Graph_Format
+*/
+package tgf
+
+import "bufio"
+import "fmt"
+import "io"
+import "strings"
+
+import "godot/attr"
+import "godot/builder"
+import "godot/format"
+
+// TGF cannot be sniffed, and is read as directed, as yEd reads it.
+func init() {
+	format.Register("tgf", []string{".tgf"}, nil,
+		format.NewEncoder(Encode, 0),
+		format.NewDecoder(func(r io.Reader) (*builder.Graph, error) {
+			return Decode(r, attr.Directed)
+		}))
+}
+
+// Splits a line into its first "n" fields, separated by spaces or tabs, and
+// the rest of the line.
+func fields(line string, n int) ([]string, string) {
+	var fs []string
+	rest := strings.TrimSpace(line)
+	for len(fs) < n && rest != "" {
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			fs, rest = append(fs, rest), ""
+			break
+		}
+		fs, rest = append(fs, rest[:i]), strings.TrimSpace(rest[i:])
+	}
+	return fs, rest
+}
+
+// Reads a graph in TGF, of the kind given.  Nodes and edges are given their
+// labels.  Nodes without labels are labelled with their ids.  Edges may
+// name nodes without lines of their own, which are then added.
+func Decode(r io.Reader, kind *attr.GraphKind) (*builder.Graph, error) {
+	g := builder.NewGraph(kind)
+	nodes := make(map[string]*builder.Node)
+	node := func(id string) *builder.Node {
+		n, ok := nodes[id]
+		if !ok {
+			n = &builder.Node{Label: id}
+			nodes[id] = n
+			g.AddNodes(n)
+		}
+		return n
+	}
+	sc := bufio.NewScanner(r)
+	line, edges := 0, false
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, "#"):
+			if edges {
+				return nil, fmt.Errorf("tgf: line %d: second #", line)
+			}
+			edges = true
+			continue
+		}
+		if !edges {
+			fs, label := fields(text, 1)
+			if _, ok := nodes[fs[0]]; ok {
+				return nil, fmt.Errorf("tgf: line %d: duplicate node %s", line, fs[0])
+			}
+			n := node(fs[0])
+			if label != "" {
+				n.Label = label
+			}
+			continue
+		}
+		fs, label := fields(text, 2)
+		if len(fs) < 2 {
+			return nil, fmt.Errorf("tgf: line %d: edge without two endpoints", line)
+		}
+		g.AddEdges(&builder.Edge{Src: node(fs[0]), Dst: node(fs[1]), Label: label})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Puts a label on one line, as TGF requires, breaking it at its newlines
+// and at the dot escapes for them.
+var oneLine = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", `\n`, " ", `\l`, " ", `\r`, " ")
+
+// Writes "g" as TGF.  Nodes are given the ids 1, 2 and so on, in the order
+// of g.Nodes(), and nodes and edges are written with their labels, resolved
+// against the templates.  Edges without both endpoints are not written.
+func Encode(w io.Writer, g *builder.Graph) error {
+	bw := bufio.NewWriter(w)
+	ids := make(map[*builder.Node]int)
+	for i, n := range g.Nodes() {
+		ids[n] = i + 1
+		writeLine(bw, fmt.Sprint(i+1), g.ResolveNode(n).Label)
+	}
+	bw.WriteString("#\n")
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		writeLine(bw, fmt.Sprintf("%d %d", ids[e.Src], ids[e.Dst]), g.ResolveEdge(e).Label)
+	}
+	return bw.Flush()
+}
+
+func writeLine(w *bufio.Writer, ids, label string) {
+	w.WriteString(ids)
+	if label = strings.TrimSpace(oneLine.Replace(label)); label != "" {
+		w.WriteString(" " + label)
+	}
+	w.WriteString("\n")
+}
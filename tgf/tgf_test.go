@@ -0,0 +1,60 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tgf
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/attr"
+import "godot/builder"
+
+func TestDecode(t *testing.T) {
+	g, err := Decode(strings.NewReader("1 First node\n2\n#\n1 2 An edge\n2 3\n"), attr.Directed)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	nodes, edges := g.Nodes(), g.Edges()
+	if len(nodes) != 3 || len(edges) != 2 {
+		t.Fatalf("Decode read %d nodes and %d edges", len(nodes), len(edges))
+	}
+	if nodes[0].Label != "First node" || nodes[1].Label != "2" || nodes[2].Label != "3" {
+		t.Errorf("Nodes are labelled %q, %q and %q", nodes[0].Label, nodes[1].Label, nodes[2].Label)
+	}
+	if edges[0].Src != nodes[0] || edges[0].Dst != nodes[1] || edges[0].Label != "An edge" || edges[1].Label != "" {
+		t.Errorf("Edges are %+v and %+v", edges[0], edges[1])
+	}
+
+	for _, in := range []string{"1\n1\n", "1\n#\n1\n", "#\n#\n"} {
+		if _, err := Decode(strings.NewReader(in), attr.Directed); err == nil {
+			t.Errorf("Decode of %q succeeded", in)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph { node [label="n"]; a [label="A\nB"]; b; a -> b [label=e]; b -> a }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, g); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "1 A B\n2 n\n#\n1 2 e\n2 1\n"; buf.String() != want {
+		t.Errorf("Encode wrote %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,506 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package tikz writes graphs as TikZ pictures for LaTeX documents.
+
+Write draws a laid out graph with \node and \draw commands: nodes at their
+laid out positions, with their shapes and sizes, and edges along their
+routes.  WriteGraph leaves the layout to LaTeX, writing the graph in the
+syntax of TikZ's graph drawing library, which LuaLaTeX lays out:
+
+  err := tikz.Write(w, g, layout.Layered(g), nil)
+  err := tikz.WriteGraph(w, g, &tikz.Options{Standalone: true})
+
+Colors are defined with xcolor's \definecolor, before the picture.  Nodes
+are named "n0", "n1" and so on, in the order of the graph's nodes, so that
+a document may draw more on the picture.  Invisible nodes and clusters, and
+edges ending at invisible nodes, are not drawn, nor are URLs and tooltips.
+A picture needs the TikZ libraries arrows.meta, shapes.geometric and, for
+WriteGraph, graphs and graphdrawing, which standalone documents
+load.
+
+Resources:
+  https://tikz.dev/
+  https://tikz.dev/gd-usage-tikz
+*/
+package tikz
+
+import "fmt"
+import "io"
+import "math"
+import "strconv"
+import "strings"
+
+import "godot"
+import "godot/builder"
+import "godot/diagram"
+import "godot/format"
+import "godot/geom"
+import "godot/layout"
+
+func init() {
+	format.Register("tikz", []string{".tikz"}, nil,
+		format.NewEncoder(func(w io.Writer, g *builder.Graph) error {
+			return Write(w, g, layout.Default(g), nil)
+		}, godot.Clusters|godot.Positions), nil)
+}
+
+// The options of a picture.  The zero Options writes a tikzpicture to be
+// included in a document, laid out by WriteGraph with the layered layout.
+type Options struct {
+	// Whether to write a standalone LaTeX document holding the picture.
+	Standalone bool
+
+	// The graph drawing layout of WriteGraph, such as "spring layout" or
+	// "tree layout", "layered layout" by default.
+	Layout string
+}
+
+// The TikZ options of dot shapes.  Other shapes are drawn as rectangles,
+// and nodes without a shape as ellipses, as they are in dot.
+var shapes = map[string]string{
+	"box":          "rectangle",
+	"rect":         "rectangle",
+	"rectangle":    "rectangle",
+	"square":       "rectangle",
+	"ellipse":      "ellipse",
+	"oval":         "ellipse",
+	"circle":       "circle",
+	"doublecircle": "circle, double",
+	"point":        "circle, inner sep=1.5bp",
+	"diamond":      "diamond",
+	"triangle":     "regular polygon, regular polygon sides=3",
+	"pentagon":     "regular polygon, regular polygon sides=5",
+	"hexagon":      "regular polygon, regular polygon sides=6",
+	"septagon":     "regular polygon, regular polygon sides=7",
+	"octagon":      "regular polygon, regular polygon sides=8",
+	"trapezium":    "trapezium",
+	"cylinder":     "cylinder, shape border rotate=90",
+	"star":         "star",
+	"plaintext":    "rectangle",
+	"plain":        "rectangle",
+	"none":         "rectangle",
+}
+
+// The arrows.meta tips of dot arrowheads.  Other arrowheads are drawn as
+// ">", which pictures set to Latex.
+var tips = map[string]string{
+	"onormal":  "{Latex[open]}",
+	"empty":    "{Latex[open]}",
+	"vee":      "{Stealth}",
+	"dot":      "{Circle}",
+	"odot":     "{Circle[open]}",
+	"diamond":  "{Diamond}",
+	"odiamond": "{Diamond[open]}",
+	"box":      "{Square}",
+	"obox":     "{Square[open]}",
+	"tee":      "{Bar}",
+	"crow":     "{Straight Barb[reversed]}",
+}
+
+// The growth of graph drawing layouts of rank directions.
+var growth = map[string]string{
+	"LR": "grow=right",
+	"RL": "grow=left",
+	"BT": "grow=up",
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"$", `\$`,
+	"&", `\&`,
+	"#", `\#`,
+	"%", `\%`,
+	"_", `\_`,
+	"^", `\textasciicircum{}`,
+	"~", `\textasciitilde{}`,
+	"\n", `\\`,
+)
+
+// Returns a label as TeX text.
+func text(label string) string {
+	return escaper.Replace(label)
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+func coord(p layout.Point) string {
+	return fmt.Sprintf("(%sbp,%sbp)", num(p.X), num(p.Y))
+}
+
+// The state of a picture being written.  Errors are sticky: once writing
+// fails, later writes are skipped and the first error is kept.
+type picture struct {
+	w   io.Writer
+	err error
+	o   *Options
+	d   *diagram.Diagram
+
+	// The names of defined colors, by CSS color, and their definitions.
+	colors map[string]string
+	defs   []string
+}
+
+func (p *picture) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// Returns the name of a CSS color, defining it if it is new.
+func (p *picture) color(css string) string {
+	if name, ok := p.colors[css]; ok {
+		return name
+	}
+	name := fmt.Sprintf("color%d", len(p.colors)+1)
+	p.colors[css] = name
+	p.defs = append(p.defs, fmt.Sprintf("\\definecolor{%s}{HTML}{%s}\n", name, strings.ToUpper(strings.TrimPrefix(css, "#"))))
+	return name
+}
+
+// Returns the options of the lines of a shape or edge: their color, unless
+// it is "", and their style and width.
+func (p *picture) lines(c string, pen float64, style geom.Style) []string {
+	var opts []string
+	if c != "" {
+		opts = append(opts, "draw="+p.color(c))
+	}
+	switch {
+	case style.Dotted:
+		opts = append(opts, "dotted")
+	case style.Dashed:
+		opts = append(opts, "dashed")
+	}
+	if pen != 1 {
+		opts = append(opts, "line width="+num(pen)+"bp")
+	}
+	return opts
+}
+
+// Returns the options of a node.
+func (p *picture) node(n *diagram.Node) []string {
+	shape := "ellipse"
+	if n.Shape != "" {
+		shape = "rectangle"
+		if s, ok := shapes[n.Shape]; ok {
+			shape = s
+		}
+	}
+	opts := []string{shape}
+	switch n.Shape {
+	case "plaintext", "plain", "none":
+	default:
+		lines := p.lines(n.Color, geom.PenWidth(n.Node.Penwidth, n.Style), n.Style)
+		if n.Color == "" {
+			lines = append([]string{"draw"}, lines...)
+		}
+		opts = append(opts, lines...)
+	}
+	switch {
+	case n.Shape == "point":
+		fill := n.Color
+		if fill == "" {
+			fill = "#000000"
+		}
+		opts = append(opts, "fill="+p.color(fill))
+	case n.Fill != "":
+		opts = append(opts, "fill="+p.color(n.Fill))
+	}
+	if n.FontColor != "" {
+		opts = append(opts, "text="+p.color(n.FontColor))
+	}
+	if n.Style.Rounded {
+		opts = append(opts, "rounded corners")
+	}
+	if strings.Contains(n.Label, "\n") {
+		opts = append(opts, "align=center")
+	}
+	return opts
+}
+
+// Returns the label of a node, which points do not show.
+func label(n *diagram.Node) string {
+	if n.Shape == "point" {
+		return ""
+	}
+	return text(n.Label)
+}
+
+// Returns the arrows of an edge, such as "->", or "" if it has none.
+func arrows(e *diagram.Edge) string {
+	tip := func(drawn bool, kind, def string) string {
+		if !drawn {
+			return ""
+		}
+		if t, ok := tips[kind]; ok {
+			return t
+		}
+		return def
+	}
+	a := tip(e.TailArrow, e.Arrowtail, "<") + "-" + tip(e.HeadArrow, e.Arrowhead, ">")
+	if a == "-" {
+		return ""
+	}
+	return a
+}
+
+// Returns the options of an edge: its arrows, if it has any, and lines.
+func (p *picture) edge(e *diagram.Edge) []string {
+	var opts []string
+	if a := arrows(e); a != "" {
+		opts = append(opts, a)
+	}
+	if e.Color != "" {
+		opts = append(opts, p.color(e.Color))
+	}
+	return append(opts, p.lines("", geom.PenWidth(e.Edge.Penwidth, e.Style), e.Style)...)
+}
+
+// Returns the options of the label of an edge.
+func (p *picture) labelOptions(e *diagram.Edge) []string {
+	var opts []string
+	if e.FontColor != "" {
+		opts = append(opts, "text="+p.color(e.FontColor))
+	}
+	if strings.Contains(e.Label, "\n") {
+		opts = append(opts, "align=center")
+	}
+	return opts
+}
+
+func options(opts []string) string {
+	if len(opts) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(opts, ", ") + "]"
+}
+
+// Writes the picture, its colors first, and the document around it if it is
+// standalone.  "libraries" are the TikZ libraries it needs.
+func (p *picture) write(body string, libraries string, gd bool) error {
+	if p.o.Standalone {
+		p.printf("\\documentclass[tikz]{standalone}\n")
+		p.printf("\\usetikzlibrary{%s}\n", libraries)
+		if gd {
+			p.printf("\\usegdlibrary{layered, force, trees}\n")
+		}
+		p.printf("\\begin{document}\n")
+	}
+	for _, def := range p.defs {
+		p.printf("%s", def)
+	}
+	p.printf("%s", body)
+	if p.o.Standalone {
+		p.printf("\\end{document}\n")
+	}
+	return p.err
+}
+
+func newPicture(w io.Writer, g *builder.Graph, o *Options) *picture {
+	if o == nil {
+		o = new(Options)
+	}
+	return &picture{w: w, o: o, d: diagram.New(g), colors: make(map[string]string)}
+}
+
+// Returns the drawn nodes of a diagram, by the graph's nodes, and the drawn
+// edges, by the graph's edges.  "drawn" says whether a node is laid out.
+func visible(g *builder.Graph, d *diagram.Diagram, drawn func(*builder.Node) bool) (map[*builder.Node]*diagram.Node, map[*builder.Edge]*diagram.Edge) {
+	nodes := make(map[*builder.Node]*diagram.Node)
+	for i, n := range g.Nodes() {
+		if dn := d.AllNodes[i]; !dn.Style.Invisible && drawn(n) {
+			nodes[n] = dn
+		}
+	}
+	edges := make(map[*builder.Edge]*diagram.Edge)
+	i := 0
+	for _, e := range g.Edges() {
+		if e.Src == nil || e.Dst == nil {
+			continue
+		}
+		de := d.Edges[i]
+		i++
+		if nodes[e.Src] != nil && nodes[e.Dst] != nil && !de.Style.Invisible {
+			edges[e] = de
+		}
+	}
+	return nodes, edges
+}
+
+// Writes a TikZ picture of "g", laid out as "l", to "w".  If "l" is nil the
+// graph is drawn from the Position of each node, and layout.ErrUnpositioned
+// is returned if a node has none.  Coordinates are in big points, as in the
+// layout, and clusters are drawn as boxes behind their nodes.
+func Write(w io.Writer, g *builder.Graph, l *layout.Layout, o *Options) error {
+	if l == nil {
+		var err error
+		if l, err = layout.FromPositions(g); err != nil {
+			return err
+		}
+	}
+	p := newPicture(w, g, o)
+	nodes, edges := visible(g, p.d, func(n *builder.Node) bool { return l.Nodes[n] != nil })
+
+	var b strings.Builder
+	b.WriteString("\\begin{tikzpicture}[>={Latex}, line width=1bp]\n")
+	var clusters func(cs []*diagram.Cluster)
+	clusters = func(cs []*diagram.Cluster) {
+		for _, c := range cs {
+			if r, ok := l.Clusters[c.Subgraph]; ok && !c.Style.Invisible {
+				opts := p.lines(c.Color, 1, c.Style)
+				if c.Fill != "" {
+					opts = append(opts, "fill="+p.color(c.Fill))
+				}
+				if c.Style.Rounded {
+					opts = append(opts, "rounded corners")
+				}
+				fmt.Fprintf(&b, "  \\draw%s %s rectangle %s;\n", options(opts), coord(r.Min), coord(r.Max))
+				if c.Label != "" {
+					opts := []string{"anchor=north"}
+					if c.FontColor != "" {
+						opts = append(opts, "text="+p.color(c.FontColor))
+					}
+					if strings.Contains(c.Label, "\n") {
+						opts = append(opts, "align=center")
+					}
+					top := layout.Point{X: (r.Min.X + r.Max.X) / 2, Y: r.Max.Y}
+					fmt.Fprintf(&b, "  \\node%s at %s {%s};\n", options(opts), coord(top), text(c.Label))
+				}
+			}
+			clusters(c.Clusters)
+		}
+	}
+	clusters(p.d.Clusters)
+
+	for _, n := range g.Nodes() {
+		dn := nodes[n]
+		if dn == nil {
+			continue
+		}
+		nl := l.Nodes[n]
+		opts := p.node(dn)
+		if dn.Shape != "point" {
+			opts = append(opts, "minimum width="+num(nl.Width)+"bp", "minimum height="+num(nl.Height)+"bp")
+		}
+		fmt.Fprintf(&b, "  \\node%s (%s) at %s {%s};\n", options(opts), dn.ID, coord(nl.Center), label(dn))
+	}
+
+	for _, e := range g.Edges() {
+		de := edges[e]
+		if de == nil {
+			continue
+		}
+		opts := p.edge(de)
+		el := l.Edges[e]
+		var path string
+		if el != nil && len(el.Spline) >= 4 {
+			path = coord(el.Spline[0])
+			for i := 1; i+2 < len(el.Spline); i += 3 {
+				path += fmt.Sprintf(" .. controls %s and %s .. %s", coord(el.Spline[i]), coord(el.Spline[i+1]), coord(el.Spline[i+2]))
+			}
+		} else {
+			path = fmt.Sprintf("(%s) -- (%s)", de.Tail.ID, de.Head.ID)
+		}
+		if de.Label != "" && (el == nil || el.LabelPos == nil) {
+			opts := append([]string{"midway", "auto"}, p.labelOptions(de)...)
+			path += fmt.Sprintf(" node%s {%s}", options(opts), text(de.Label))
+		}
+		fmt.Fprintf(&b, "  \\draw%s %s;\n", options(opts), path)
+		if de.Label != "" && el != nil && el.LabelPos != nil {
+			fmt.Fprintf(&b, "  \\node%s at %s {%s};\n", options(p.labelOptions(de)), coord(*el.LabelPos), text(de.Label))
+		}
+	}
+
+	if p.d.Title != "" {
+		bounds := l.Bounds
+		bottom := layout.Point{X: (bounds.Min.X + bounds.Max.X) / 2, Y: bounds.Min.Y}
+		opts := []string{"anchor=north"}
+		if strings.Contains(p.d.Title, "\n") {
+			opts = append(opts, "align=center")
+		}
+		fmt.Fprintf(&b, "  \\node%s at %s {%s};\n", options(opts), coord(bottom), text(p.d.Title))
+	}
+	b.WriteString("\\end{tikzpicture}\n")
+	return p.write(b.String(), "arrows.meta, shapes.geometric", false)
+}
+
+// Writes "g" in the syntax of TikZ's graph drawing library, to be laid out
+// by LuaLaTeX, to "w".  Nodes are sized by their Width and Height, if they
+// are set, and the layout grows in the direction of the graph's Rankdir.
+// Clusters are not drawn.
+func WriteGraph(w io.Writer, g *builder.Graph, o *Options) error {
+	p := newPicture(w, g, o)
+	nodes, edges := visible(g, p.d, func(*builder.Node) bool { return true })
+
+	var b strings.Builder
+	b.WriteString("\\begin{tikzpicture}[>={Latex}, line width=1bp]\n")
+	opts := []string{p.o.Layout}
+	if opts[0] == "" {
+		opts[0] = "layered layout"
+	}
+	if grow, ok := growth[p.d.Rankdir]; ok {
+		opts = append(opts, grow)
+	}
+	fmt.Fprintf(&b, "\\graph%s {\n", options(opts))
+	for _, n := range g.Nodes() {
+		dn := nodes[n]
+		if dn == nil {
+			continue
+		}
+		opts := p.node(dn)
+		for _, size := range [][2]string{{"width", dn.Node.Width}, {"height", dn.Node.Height}} {
+			if v, err := strconv.ParseFloat(size[1], 64); err == nil && v > 0 {
+				opts = append(opts, "minimum "+size[0]+"="+num(v)+"in")
+			}
+		}
+		fmt.Fprintf(&b, "  %s/\"%s\"%s;\n", dn.ID, strings.ReplaceAll(label(dn), `"`, "''"), options(opts))
+	}
+	connector := "--"
+	if p.d.Directed {
+		connector = "->"
+	}
+	for _, e := range g.Edges() {
+		de := edges[e]
+		if de == nil {
+			continue
+		}
+		opts := p.edge(de)
+		if p.d.Directed && len(opts) > 0 && opts[0] == "->" {
+			opts = opts[1:]
+		} else if p.d.Directed && arrows(de) == "" {
+			opts = append([]string{"-"}, opts...)
+		}
+		if de.Label != "" {
+			lo := append([]string{"auto"}, p.labelOptions(de)...)
+			opts = append(opts, fmt.Sprintf("edge node={node%s {%s}}", options(lo), text(de.Label)))
+		}
+		fmt.Fprintf(&b, "  %s %s%s %s;\n", de.Tail.ID, connector, options(opts), de.Head.ID)
+	}
+	b.WriteString("};\n")
+	if p.d.Title != "" {
+		opts := []string{"anchor=north"}
+		if strings.Contains(p.d.Title, "\n") {
+			opts = append(opts, "align=center")
+		}
+		fmt.Fprintf(&b, "\\node%s at (current bounding box.south) {%s};\n", options(opts), text(p.d.Title))
+	}
+	b.WriteString("\\end{tikzpicture}\n")
+	return p.write(b.String(), "arrows.meta, shapes.geometric, graphs, graphdrawing", true)
+}
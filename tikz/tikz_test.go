@@ -0,0 +1,102 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tikz
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/layout"
+
+const src = `digraph {
+	label="A_1";
+	subgraph cluster_0 { label=C; a [shape=box, style=filled, fillcolor=red, pos="1,2!", label="x\ny"] }
+	b [shape=hexagon, color=blue, pos="1,0!", width=1];
+	c [style=invis, pos="3,0!"];
+	a -> b [label="50%", style=dashed, arrowhead=odot, color=red];
+	b -> a [dir=none];
+	b -> c;
+}`
+
+func TestWrite(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, g, nil, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"\\definecolor{color1}{HTML}{FF0000}\n\\definecolor{color2}{HTML}{0000FF}\n\\begin{tikzpicture}",
+		`\node[rectangle, draw, fill=color1, align=center, minimum width=54bp, minimum height=`,
+		`(n0) at (72bp,144bp) {x\\y};`,
+		`\node[regular polygon, regular polygon sides=6, draw=color2, minimum width=72bp, minimum height=36bp] (n1) at (72bp,0bp) {b};`,
+		`\draw[-{Circle[open]}, color1, dashed] (72bp,`,
+		`\node at (72bp,70.62bp) {50\%};`,
+		`\draw (72bp,`,
+		`\node[anchor=north] at (`,
+		`{A\_1};`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Write did not write %s in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "(n2)") || strings.Contains(out, "{c}") {
+		t.Errorf("Write drew an invisible node:\n%s", out)
+	}
+	if strings.Contains(out, `\documentclass`) {
+		t.Errorf("Write wrote a document:\n%s", out)
+	}
+
+	g.Nodes()[0].Position = nil
+	if err := Write(&buf, g, nil, nil); err != layout.ErrUnpositioned {
+		t.Errorf("Write of an unpositioned node gave %v", err)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, g, &Options{Standalone: true, Layout: "spring layout"}); err != nil {
+		t.Fatalf("WriteGraph failed: %v", err)
+	}
+	want := `\documentclass[tikz]{standalone}
+\usetikzlibrary{arrows.meta, shapes.geometric, graphs, graphdrawing}
+\usegdlibrary{layered, force, trees}
+\begin{document}
+\definecolor{color1}{HTML}{FF0000}
+\definecolor{color2}{HTML}{0000FF}
+\begin{tikzpicture}[>={Latex}, line width=1bp]
+\graph[spring layout] {
+  n0/"x\\y"[rectangle, draw, fill=color1, align=center];
+  n1/"b"[regular polygon, regular polygon sides=6, draw=color2, minimum width=1in];
+  n0 ->[-{Circle[open]}, color1, dashed, edge node={node[auto] {50\%}}] n1;
+  n1 ->[-] n0;
+};
+\node[anchor=north] at (current bounding box.south) {A\_1};
+\end{tikzpicture}
+\end{document}
+`
+	if buf.String() != want {
+		t.Errorf("WriteGraph wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
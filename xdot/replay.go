@@ -0,0 +1,191 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xdot
+
+import "strconv"
+import "strings"
+
+import "godot/builder"
+import "godot/geom"
+import "godot/gvout"
+
+// The drawing state in effect for a shape: the last colors, font and style
+// set by the operations before it.
+type State struct {
+	PenColor  string
+	FillColor string
+	FontName  string
+	FontSize  float64
+	FontChars FontChars
+
+	// Dashed, Dotted, Bold and Invisible are set by the styles of the same
+	// names, and cleared by "solid".
+	Style    geom.Style
+	PenWidth float64
+}
+
+// Returns the state at the start of an xdot attribute: black, in 14 point
+// Times-Roman, with solid lines of one point.
+func NewState() *State {
+	return &State{
+		PenColor:  "black",
+		FillColor: "black",
+		FontName:  "Times-Roman",
+		FontSize:  14,
+		PenWidth:  1,
+	}
+}
+
+// Updates the state for an operation which is not a shape, returning false
+// if the operation is a shape.
+func (s *State) apply(op Op) bool {
+	switch op := op.(type) {
+	case Color:
+		if op.Fill {
+			s.FillColor = op.Color
+		} else {
+			s.PenColor = op.Color
+		}
+	case Font:
+		s.FontSize, s.FontName = op.Size, op.Name
+	case FontChars:
+		s.FontChars = op
+	case Style:
+		s.setStyle(op.Style)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *State) setStyle(style string) {
+	switch {
+	case style == "solid":
+		s.Style.Dashed, s.Style.Dotted = false, false
+	case style == "dashed":
+		s.Style.Dashed, s.Style.Dotted = true, false
+	case style == "dotted":
+		s.Style.Dashed, s.Style.Dotted = false, true
+	case style == "bold":
+		s.Style.Bold = true
+		s.PenWidth = 2
+	case style == "invis" || style == "invisible":
+		s.Style.Invisible = true
+	case strings.HasPrefix(style, "setlinewidth(") && strings.HasSuffix(style, ")"):
+		arg := style[len("setlinewidth(") : len(style)-1]
+		if w, err := strconv.ParseFloat(arg, 64); err == nil && w >= 0 {
+			s.PenWidth = w
+		}
+	}
+}
+
+// Something shapes can be drawn on.  Each method is given the state in
+// effect for the shape, which it should not keep, as Replay changes it.
+// Filled shapes are filled with the state's FillColor and outlined with
+// its PenColor; text is drawn in the PenColor.
+type Canvas interface {
+	Ellipse(s *State, e Ellipse)
+	Polygon(s *State, p Polygon)
+	Polyline(s *State, p Polyline)
+	Bezier(s *State, b Bezier)
+	Text(s *State, t Text)
+	Image(s *State, i Image)
+}
+
+// Draws operations on a canvas, starting from NewState.
+func Replay(c Canvas, ops []Op) {
+	s := NewState()
+	for _, op := range ops {
+		if s.apply(op) {
+			continue
+		}
+		switch op := op.(type) {
+		case Ellipse:
+			c.Ellipse(s, op)
+		case Polygon:
+			c.Polygon(s, op)
+		case Polyline:
+			c.Polyline(s, op)
+		case Bezier:
+			c.Bezier(s, op)
+		case Text:
+			c.Text(s, op)
+		case Image:
+			c.Image(s, op)
+		}
+	}
+}
+
+// The xdot attributes of each kind of object, in the order drawn.
+var (
+	graphAttrs = []string{"_draw_", "_ldraw_"}
+	nodeAttrs  = []string{"_draw_", "_ldraw_"}
+	edgeAttrs  = []string{"_draw_", "_tdraw_", "_hdraw_", "_ldraw_", "_tldraw_", "_hldraw_"}
+)
+
+// Draws the operations read by gvout.ReadXdot for "g": the graph, then its
+// clusters outside in, then its nodes and its edges, as Graphviz does with
+// outputorder=nodesfirst.  Returns an error if any operations are invalid,
+// without drawing anything.
+func Draw(c Canvas, x *gvout.Xdot, g *builder.Graph) error {
+	var all [][]Op
+	add := func(obj interface{}, names []string) error {
+		for _, name := range names {
+			if src, ok := x.Ops[obj][name]; ok {
+				ops, err := Decode(src)
+				if err != nil {
+					return err
+				}
+				all = append(all, ops)
+			}
+		}
+		return nil
+	}
+
+	if err := add(g, graphAttrs); err != nil {
+		return err
+	}
+	var visit func(subs []*builder.Subgraph) error
+	visit = func(subs []*builder.Subgraph) error {
+		for _, sub := range subs {
+			if err := add(sub, graphAttrs); err != nil {
+				return err
+			}
+			if err := visit(sub.Subgraphs()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(g.Subgraphs()); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes() {
+		if err := add(n, nodeAttrs); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges() {
+		if err := add(e, edgeAttrs); err != nil {
+			return err
+		}
+	}
+
+	for _, ops := range all {
+		Replay(c, ops)
+	}
+	return nil
+}
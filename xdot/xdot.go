@@ -0,0 +1,350 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package xdot decodes the drawing operations of Graphviz's xdot output, and
+replays them on a Canvas.
+
+Graphviz's -Txdot output gives each object attributes such as "_draw_" and
+"_ldraw_", whose values are strings of operations: ellipses, polygons,
+polylines, B-splines, text and images, and changes of color, font and style.
+Decode turns such a string into typed operations, and Replay hands them to
+a Canvas, with the colors, font and style in effect for each shape, so that
+a front end can reproduce Graphviz's rendering exactly.  Draw replays every
+object of a graph read by package gvout, in the order Graphviz draws them.
+
+Coordinates are in points, as in package layout, with y increasing upwards.
+
+Resources:
+  http://www.graphviz.org/doc/info/output.html#d:xdot
+*/
+package xdot
+
+import "fmt"
+import "strconv"
+import "strings"
+
+import "godot/layout"
+
+// A drawing operation.  String returns the operation as written by xdot.
+type Op interface {
+	String() string
+}
+
+// An ellipse, "E" if filled and "e" if not.
+type Ellipse struct {
+	Center layout.Point
+	RX, RY float64
+	Filled bool
+}
+
+// A polygon, "P" if filled and "p" if not.
+type Polygon struct {
+	Points []layout.Point
+	Filled bool
+}
+
+// A polyline, "L".
+type Polyline struct {
+	Points []layout.Point
+}
+
+// A piecewise cubic Bezier curve, "B", or a closed one to be filled, "b".
+// The points are 3n+1 control points, as in layout.EdgeLayout.Spline.
+type Bezier struct {
+	Points []layout.Point
+	Filled bool
+}
+
+// The horizontal alignment of text about its position.
+type Align int
+
+const (
+	AlignLeft   Align = -1
+	AlignCenter Align = 0
+	AlignRight  Align = 1
+)
+
+// Text, "T", with its baseline at Pos.  Width is the width Graphviz
+// estimated for the text in the current font.
+type Text struct {
+	Pos   layout.Point
+	Align Align
+	Width float64
+	Text  string
+}
+
+// A color, "C" for filling and "c" for drawing lines and text.  The color
+// is a Graphviz color such as "#ff0000" or "red", which color.Parse reads,
+// or a gradient, beginning with "[" if linear and "(" if radial.
+type Color struct {
+	Color string
+	Fill  bool
+}
+
+// A font, "F", of a size in points.
+type Font struct {
+	Size float64
+	Name string
+}
+
+// Font characteristics, "t".
+type FontChars uint
+
+const (
+	Bold FontChars = 1 << iota
+	Italic
+	Underline
+	Superscript
+	Subscript
+	Strikethrough
+	Overline
+)
+
+// A style, "S", such as "dashed" or "setlinewidth(2)".
+type Style struct {
+	Style string
+}
+
+// An image, "I", filling a rectangle.
+type Image struct {
+	Rect layout.Rect
+	Name string
+}
+
+func (e Ellipse) String() string {
+	op := "e"
+	if e.Filled {
+		op = "E"
+	}
+	return fmt.Sprintf("%s %s %s %s %s", op, num(e.Center.X), num(e.Center.Y), num(e.RX), num(e.RY))
+}
+
+func (p Polygon) String() string {
+	if p.Filled {
+		return points("P", p.Points)
+	}
+	return points("p", p.Points)
+}
+
+func (p Polyline) String() string {
+	return points("L", p.Points)
+}
+
+func (b Bezier) String() string {
+	if b.Filled {
+		return points("b", b.Points)
+	}
+	return points("B", b.Points)
+}
+
+func (t Text) String() string {
+	return fmt.Sprintf("T %s %s %d %s %s", num(t.Pos.X), num(t.Pos.Y), t.Align, num(t.Width), text(t.Text))
+}
+
+func (c Color) String() string {
+	if c.Fill {
+		return "C " + text(c.Color)
+	}
+	return "c " + text(c.Color)
+}
+
+func (f Font) String() string {
+	return fmt.Sprintf("F %s %s", num(f.Size), text(f.Name))
+}
+
+func (f FontChars) String() string {
+	return fmt.Sprintf("t %d", uint(f))
+}
+
+func (s Style) String() string {
+	return "S " + text(s.Style)
+}
+
+func (i Image) String() string {
+	r := i.Rect
+	return fmt.Sprintf("I %s %s %s %s %s", num(r.Min.X), num(r.Min.Y), num(r.Max.X-r.Min.X), num(r.Max.Y-r.Min.Y), text(i.Name))
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Writes a string as xdot does, preceded by its length in bytes.
+func text(s string) string {
+	return fmt.Sprintf("%d -%s", len(s), s)
+}
+
+func points(op string, pts []layout.Point) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d", op, len(pts))
+	for _, p := range pts {
+		fmt.Fprintf(&b, " %s %s", num(p.X), num(p.Y))
+	}
+	return b.String()
+}
+
+// Writes operations as xdot does, separated by spaces.  Decode reads the
+// result back.
+func Encode(ops []Op) string {
+	parts := make([]string, len(ops))
+	for i, op := range ops {
+		parts[i] = op.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Reads operations from the value of an xdot attribute such as "_draw_".
+func Decode(s string) ([]Op, error) {
+	d := &decoder{src: s}
+	var ops []Op
+	for {
+		d.space()
+		if d.pos == len(d.src) {
+			return ops, nil
+		}
+		code := d.src[d.pos]
+		d.pos++
+		op, err := d.op(code)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+}
+
+type decoder struct {
+	src string
+	pos int
+	err error
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func (d *decoder) space() {
+	for d.pos < len(d.src) && isSpace(d.src[d.pos]) {
+		d.pos++
+	}
+}
+
+// Records the first error, at the current offset.
+func (d *decoder) errorf(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("xdot: offset %d: %s", d.pos, fmt.Sprintf(format, args...))
+	}
+}
+
+// Reads a number.  After an error, numbers read are zero.
+func (d *decoder) num() float64 {
+	if d.err != nil {
+		return 0
+	}
+	d.space()
+	start := d.pos
+	for d.pos < len(d.src) && !isSpace(d.src[d.pos]) {
+		d.pos++
+	}
+	f, err := strconv.ParseFloat(d.src[start:d.pos], 64)
+	if err != nil {
+		d.pos = start
+		d.errorf("expected a number")
+	}
+	return f
+}
+
+func (d *decoder) point() layout.Point {
+	x := d.num()
+	return layout.Point{X: x, Y: d.num()}
+}
+
+// Reads a count of points, and the points.
+func (d *decoder) points() []layout.Point {
+	n := int(d.num())
+	if n < 0 || n > len(d.src) {
+		d.errorf("invalid number of points %d", n)
+		return nil
+	}
+	pts := make([]layout.Point, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		pts = append(pts, d.point())
+	}
+	return pts
+}
+
+// Reads a string, written as its length in bytes, a '-', and the bytes.
+func (d *decoder) text() string {
+	n := int(d.num())
+	if d.err != nil {
+		return ""
+	}
+	d.space()
+	if d.pos == len(d.src) || d.src[d.pos] != '-' {
+		d.errorf("expected '-' before a string")
+		return ""
+	}
+	d.pos++
+	if n < 0 || d.pos+n > len(d.src) {
+		d.errorf("string of %d bytes runs past the end", n)
+		return ""
+	}
+	s := d.src[d.pos : d.pos+n]
+	d.pos += n
+	return s
+}
+
+func (d *decoder) op(code byte) (Op, error) {
+	var op Op
+	switch code {
+	case 'E', 'e':
+		center := d.point()
+		rx := d.num()
+		op = Ellipse{Center: center, RX: rx, RY: d.num(), Filled: code == 'E'}
+	case 'P', 'p':
+		op = Polygon{Points: d.points(), Filled: code == 'P'}
+	case 'L':
+		op = Polyline{Points: d.points()}
+	case 'B', 'b':
+		op = Bezier{Points: d.points(), Filled: code == 'b'}
+	case 'T':
+		pos := d.point()
+		align := Align(d.num())
+		width := d.num()
+		op = Text{Pos: pos, Align: align, Width: width, Text: d.text()}
+	case 'C', 'c':
+		op = Color{Color: d.text(), Fill: code == 'C'}
+	case 'F':
+		size := d.num()
+		op = Font{Size: size, Name: d.text()}
+	case 't':
+		op = FontChars(d.num())
+	case 'S':
+		op = Style{Style: d.text()}
+	case 'I':
+		min := d.point()
+		w := d.num()
+		h := d.num()
+		max := layout.Point{X: min.X + w, Y: min.Y + h}
+		op = Image{Rect: layout.Rect{Min: min, Max: max}, Name: d.text()}
+	default:
+		d.pos--
+		d.errorf("unknown operation %q", code)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return op, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2012 John Connor. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xdot
+
+import "fmt"
+import "reflect"
+import "strings"
+import "testing"
+
+import "godot/builder"
+import "godot/gvout"
+import "godot/layout"
+
+func TestDecode(t *testing.T) {
+	src := `S 5 -solid c 7 -#000000 C 9 -lightgrey E 27 18 27 18 ` +
+		`F 14 11 -Times-Roman t 3 T 27 14.3 0 7.77 3 -añ ` +
+		`B 4 27 215.7 27 193.6 27 176 27 154.1 p 3 0 0 1 1 2 0 L 2 0 0 1 1 ` +
+		`I 1 2 10 20 7 -img.png`
+	ops, err := Decode(src)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	want := []Op{
+		Style{"solid"},
+		Color{Color: "#000000"},
+		Color{Color: "lightgrey", Fill: true},
+		Ellipse{Center: layout.Point{X: 27, Y: 18}, RX: 27, RY: 18, Filled: true},
+		Font{Size: 14, Name: "Times-Roman"},
+		Bold | Italic,
+		Text{Pos: layout.Point{X: 27, Y: 14.3}, Align: AlignCenter, Width: 7.77, Text: "añ"},
+		Bezier{Points: []layout.Point{{X: 27, Y: 215.7}, {X: 27, Y: 193.6}, {X: 27, Y: 176}, {X: 27, Y: 154.1}}},
+		Polygon{Points: []layout.Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0}}},
+		Polyline{Points: []layout.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}},
+		Image{Rect: layout.Rect{Min: layout.Point{X: 1, Y: 2}, Max: layout.Point{X: 11, Y: 22}}, Name: "img.png"},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("Decode returned\n%v\nwanted\n%v", ops, want)
+	}
+
+	// Encoding and decoding again gives the same operations.
+	again, err := Decode(Encode(ops))
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("Decode of %q returned %v, %v", Encode(ops), again, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, src := range []string{
+		"X 1 2",
+		"E 1 2 3",
+		"P 3 0 0 1 1",
+		"P -1",
+		"c 7 #000000",
+		"c 9 -#000000",
+		"T 1 2 0 3 4 -a",
+	} {
+		if ops, err := Decode(src); err == nil {
+			t.Errorf("Decode(%q) returned %v without an error.", src, ops)
+		}
+	}
+}
+
+// Records the shapes drawn on it, with the state of each.
+type recorder []string
+
+func (r *recorder) add(s *State, op Op) {
+	*r = append(*r, fmt.Sprintf("%s|%s|%s|%g|%v", op, s.PenColor, s.FillColor, s.PenWidth, s.Style.Dashed))
+}
+
+func (r *recorder) Ellipse(s *State, e Ellipse)   { r.add(s, e) }
+func (r *recorder) Polygon(s *State, p Polygon)   { r.add(s, p) }
+func (r *recorder) Polyline(s *State, p Polyline) { r.add(s, p) }
+func (r *recorder) Bezier(s *State, b Bezier)     { r.add(s, b) }
+func (r *recorder) Text(s *State, t Text)         { r.add(s, t) }
+func (r *recorder) Image(s *State, i Image)       { r.add(s, i) }
+
+func TestReplay(t *testing.T) {
+	ops, err := Decode("e 0 0 1 1 S 6 -dashed S 15 -setlinewidth(2) c 3 -red C 4 -blue P 3 0 0 1 1 2 0 S 5 -solid L 2 0 0 1 1")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	var r recorder
+	Replay(&r, ops)
+	want := recorder{
+		"e 0 0 1 1|black|black|1|false",
+		"P 3 0 0 1 1 2 0|red|blue|2|true",
+		"L 2 0 0 1 1|red|blue|2|false",
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Replay drew\n%v\nwanted\n%v", r, want)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	g, err := builder.Parse(strings.NewReader(`digraph { subgraph cluster_0 { a } a -> b }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	out := `digraph {
+		graph [bb="0,0,70,160", _draw_="C 7 -#ffffff P 4 0 0 0 160 70 160 70 0 "];
+		subgraph cluster_0 { graph [bb="8,80,62,152", _draw_="c 7 -#000000 p 4 8 80 8 152 62 152 62 80 "];
+			0 [pos="35,116", width=0.75, height=0.5, _draw_="c 7 -#000000 e 35 116 27 18 ", _ldraw_="F 14 11 -Times-Roman c 7 -#000000 T 35 112.3 0 7 1 -a "];
+		}
+		1 [pos="35,18", width=0.75, height=0.5, _draw_="c 7 -#000000 e 35 18 27 18 "];
+		0 -> 1 [pos="e,35,36.1 35,97.7 35,81 35,61 35,46.1", _draw_="c 7 -#000000 B 4 35 97.7 35 81 35 61 35 46.1 ", _hdraw_="S 5 -solid c 7 -#000000 C 7 -#000000 P 3 38.5 46.1 35 36.1 31.5 46.1 "];
+	}`
+	x, err := gvout.ReadXdot(strings.NewReader(out), g)
+	if err != nil {
+		t.Fatalf("ReadXdot failed: %v", err)
+	}
+	var r recorder
+	if err := Draw(&r, x, g); err != nil {
+		t.Fatalf("Draw failed: %v", err)
+	}
+	var kinds []string
+	for _, s := range r {
+		kinds = append(kinds, s[:1])
+	}
+	// The background, the cluster, node a and its label, node b, the edge
+	// and its arrowhead.
+	if got := strings.Join(kinds, ""); got != "PpeTeBP" {
+		t.Errorf("Draw drew %v", r)
+	}
+
+	x.Ops[g.Nodes()[1]]["_draw_"] = "e 1 2"
+	r = nil
+	if err := Draw(&r, x, g); err == nil || len(r) != 0 {
+		t.Errorf("Draw of invalid operations drew %v and returned %v.", r, err)
+	}
+}